@@ -253,6 +253,13 @@ func getArchiveTransactions(c *gin.Context) {
 			transaction.Splits = splits
 		}
 
+		shares, err := loadTransactionShares(t.ID)
+		if err != nil {
+			log.Printf("Error loading shares for archived transaction %s: %v", transaction.ID, err)
+		} else {
+			transaction.Shares = shares
+		}
+
 		transactions = append(transactions, transaction)
 	}
 
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"jointanalysis/db/generated"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	budgetStatusOK   = "ok"
+	budgetStatusOver = "over"
+
+	// budgetMonthLayout is the format of budget and report months
+	budgetMonthLayout = "2006-01"
+)
+
+// budgetKey identifies the budgets that replace each other over time: those for
+// the same category and person. The household has the zero person.
+type budgetKey struct {
+	category pgtype.UUID
+	person   pgtype.UUID
+}
+
+// budgetSpendingKey identifies the spending of a category, including its
+// subcategories for a top-level category, by a person or the household in a month
+type budgetSpendingKey struct {
+	month    time.Time
+	category pgtype.UUID
+	person   pgtype.UUID
+}
+
+// Budget conversion and validation functions
+
+// parseBudgetMonth parses a YYYY-MM month to its first day
+func parseBudgetMonth(value string) (time.Time, error) {
+	month, err := time.Parse(budgetMonthLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("month must be formatted as YYYY-MM")
+	}
+	return month, nil
+}
+
+// signedCents converts an amount to cents, keeping its sign
+func signedCents(n pgtype.Numeric) int64 {
+	value, err := n.Float64Value()
+	if err != nil {
+		return 0
+	}
+	return int64(math.Round(value.Float64 * 100))
+}
+
+// convertBudget converts a generated.GetBudgetsRow to our Budget struct
+func convertBudget(b generated.GetBudgetsRow) Budget {
+	amount, _ := b.Amount.Float64Value()
+	budget := Budget{
+		ID:           uuid.UUID(b.ID.Bytes).String(),
+		CategoryID:   uuid.UUID(b.CategoryID.Bytes).String(),
+		CategoryName: b.CategoryName,
+		Month:        b.Month.Time.Format(budgetMonthLayout),
+		Amount:       amount.Float64,
+		Rollover:     b.Rollover,
+		CreatedAt:    b.CreatedAt.Time,
+		UpdatedAt:    b.UpdatedAt.Time,
+	}
+	if b.PersonID.Valid {
+		personID := uuid.UUID(b.PersonID.Bytes).String()
+		budget.PersonID = &personID
+	}
+	if b.PersonName.Valid {
+		budget.PersonName = &b.PersonName.String
+	}
+	return budget
+}
+
+// budgetParamsFromRequest validates a budget request and converts it to query
+// parameters. The category must be in use: not retired and not excluded from
+// totals, itself or through its parent, since its spending would never count.
+func budgetParamsFromRequest(ctx context.Context, q *generated.Queries, req Budget) (generated.CreateBudgetParams, error) {
+	var params generated.CreateBudgetParams
+
+	categoryUUID, err := uuid.Parse(req.CategoryID)
+	if err != nil {
+		return params, fmt.Errorf("invalid category_id format")
+	}
+	params.CategoryID = pgtype.UUID{Bytes: categoryUUID, Valid: true}
+	category, err := q.GetCategoryByID(ctx, params.CategoryID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return params, fmt.Errorf("category %s not found", categoryUUID)
+		}
+		return params, err
+	}
+	if category.RetiredAt.Valid {
+		return params, fmt.Errorf("category %s is retired", category.Name)
+	}
+	excluded := category.ExcludeFromTotals
+	if category.ParentID.Valid {
+		parent, err := q.GetCategoryByID(ctx, category.ParentID)
+		if err != nil {
+			return params, err
+		}
+		excluded = excluded || parent.ExcludeFromTotals
+	}
+	if excluded {
+		return params, fmt.Errorf("category %s is excluded from totals", category.Name)
+	}
+
+	if req.PersonID != nil && *req.PersonID != "" {
+		personUUID, err := uuid.Parse(*req.PersonID)
+		if err != nil {
+			return params, fmt.Errorf("invalid person_id format")
+		}
+		params.PersonID = pgtype.UUID{Bytes: personUUID, Valid: true}
+		if _, err := q.GetPersonByID(ctx, params.PersonID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return params, fmt.Errorf("person %s not found", personUUID)
+			}
+			return params, err
+		}
+	}
+
+	month, err := parseBudgetMonth(req.Month)
+	if err != nil {
+		return params, err
+	}
+	params.Month = pgtype.Date{Time: month, Valid: true}
+
+	if req.Amount < 0 {
+		return params, fmt.Errorf("amount cannot be negative")
+	}
+	if err := params.Amount.Scan(fmt.Sprintf("%.2f", req.Amount)); err != nil {
+		return params, fmt.Errorf("invalid amount")
+	}
+	params.Rollover = req.Rollover
+
+	return params, nil
+}
+
+// Budget report functions
+
+// loadBudgetSpending returns the spending per month, category and person, or the
+// household, from the first day of from until the end of the month to. Spending in
+// a subcategory also counts for its parent. Categories excluded from totals, or
+// whose parent is, are left out.
+func loadBudgetSpending(ctx context.Context, from, to time.Time) (map[budgetSpendingKey]int64, error) {
+	categories, err := queries.GetCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+	byID := make(map[pgtype.UUID]generated.GetCategoriesRow, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	spending := make(map[budgetSpendingKey]int64)
+	add := func(month pgtype.Date, categoryID, personID pgtype.UUID, total pgtype.Numeric) {
+		category, exists := byID[categoryID]
+		if !exists || category.ExcludeFromTotals {
+			return
+		}
+		parent, hasParent := byID[category.ParentID]
+		if hasParent && parent.ExcludeFromTotals {
+			return
+		}
+
+		cents := signedCents(total)
+		spending[budgetSpendingKey{month: month.Time, category: categoryID, person: personID}] += cents
+		if hasParent {
+			spending[budgetSpendingKey{month: month.Time, category: parent.ID, person: personID}] += cents
+		}
+	}
+
+	spendingRange := generated.GetMonthlyCategorySpendingParams{
+		FromMonth: pgtype.Date{Time: from, Valid: true},
+		ToMonth:   pgtype.Date{Time: to.AddDate(0, 1, 0), Valid: true},
+	}
+	household, err := queries.GetMonthlyCategorySpending(ctx, spendingRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spending: %w", err)
+	}
+	for _, row := range household {
+		add(row.Month, row.CategoryID, pgtype.UUID{}, row.Total)
+	}
+
+	perPerson, err := queries.GetMonthlyCategorySpendingByPerson(ctx, generated.GetMonthlyCategorySpendingByPersonParams(spendingRange))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spending by person: %w", err)
+	}
+	for _, row := range perPerson {
+		add(row.Month, row.CategoryID, row.PersonID, row.Total)
+	}
+
+	return spending, nil
+}
+
+// buildBudgetReport compares the spending of a month with the budgets in effect.
+// Budgets must be ordered by month within each category and person. For a budget
+// with rollover, the unspent amount of each month since the category and person
+// were first budgeted carries into the next; overspending is not carried.
+func buildBudgetReport(month time.Time, budgets []generated.GetBudgetsRow, spending map[budgetSpendingKey]int64) BudgetReport {
+	report := BudgetReport{
+		Month: month.Format(budgetMonthLayout),
+		Lines: make([]BudgetReportLine, 0),
+	}
+
+	var keys []budgetKey
+	byKey := make(map[budgetKey][]generated.GetBudgetsRow)
+	for _, budget := range budgets {
+		key := budgetKey{category: budget.CategoryID, person: budget.PersonID}
+		if _, exists := byKey[key]; !exists {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], budget)
+	}
+
+	for _, key := range keys {
+		series := byKey[key]
+		if series[0].Month.Time.After(month) {
+			continue
+		}
+
+		var current generated.GetBudgetsRow
+		var carry int64
+		next := 0
+		for m := series[0].Month.Time; !m.After(month); m = m.AddDate(0, 1, 0) {
+			// The latest budget starting on or before this month is in effect
+			for next < len(series) && !series[next].Month.Time.After(m) {
+				current = series[next]
+				next++
+			}
+
+			budgeted := signedCents(current.Amount)
+			available := budgeted
+			if current.Rollover {
+				available += carry
+			}
+			spent := spending[budgetSpendingKey{month: m, category: key.category, person: key.person}]
+
+			if m.Equal(month) {
+				line := convertBudget(current)
+				reportLine := BudgetReportLine{
+					BudgetID:     line.ID,
+					CategoryID:   line.CategoryID,
+					CategoryName: line.CategoryName,
+					PersonID:     line.PersonID,
+					PersonName:   line.PersonName,
+					Budgeted:     float64(budgeted) / 100,
+					RolledOver:   float64(available-budgeted) / 100,
+					Available:    float64(available) / 100,
+					Spent:        float64(spent) / 100,
+					Remaining:    float64(available-spent) / 100,
+					Status:       budgetStatusOK,
+				}
+				if spent > available {
+					reportLine.Status = budgetStatusOver
+					report.OverBudget++
+				}
+				report.Lines = append(report.Lines, reportLine)
+				break
+			}
+			carry = max(available-spent, 0)
+		}
+	}
+
+	return report
+}
+
+// Budget handler functions
+
+// @Summary Get all budgets
+// @Description Retrieve all budgets ordered by category, person (household first) and month
+// @Tags budgets
+// @Produce json
+// @Success 200 {array} Budget "List of budgets"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/budgets [get]
+func getBudgets(c *gin.Context) {
+	dbBudgets, err := queries.GetBudgets(context.Background())
+	if err != nil {
+		log.Printf("Error fetching budgets: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching budgets"})
+		return
+	}
+
+	budgets := make([]Budget, 0, len(dbBudgets))
+	for _, dbBudget := range dbBudgets {
+		budgets = append(budgets, convertBudget(dbBudget))
+	}
+
+	c.JSON(http.StatusOK, budgets)
+}
+
+// @Summary Create budget
+// @Description Create a monthly budget for a category, for one person or, without person_id, for the household. It applies from its month until a later budget for the same category and person. With rollover, unspent amounts carry over into the next month.
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param budget body Budget true "Budget data (category_id, month as YYYY-MM and amount required; person_id and rollover optional)"
+// @Success 201 {object} Budget "Created budget"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 409 {object} map[string]interface{} "Budget already exists"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/budgets [post]
+func createBudget(c *gin.Context) {
+	var req Budget
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	ctx := context.Background()
+	params, err := budgetParamsFromRequest(ctx, queries, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dbBudget, err := queries.CreateBudget(ctx, params)
+	if err != nil {
+		log.Printf("Error creating budget: %v", err)
+		statusCode, message := handleDatabaseError(err)
+		c.JSON(statusCode, gin.H{"error": message})
+		return
+	}
+
+	budget, err := queries.GetBudgetByID(ctx, dbBudget.ID)
+	if err != nil {
+		log.Printf("Error fetching created budget: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching created budget"})
+		return
+	}
+	c.JSON(http.StatusCreated, convertBudget(generated.GetBudgetsRow(budget)))
+}
+
+// @Summary Update budget
+// @Description Update an existing budget
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param id path string true "Budget ID"
+// @Param budget body Budget true "Updated budget data"
+// @Success 200 {object} Budget "Updated budget"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Budget not found"
+// @Failure 409 {object} map[string]interface{} "Budget already exists"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/budgets/{id} [put]
+func updateBudget(c *gin.Context) {
+	parsedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID"})
+		return
+	}
+
+	var req Budget
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	ctx := context.Background()
+	params, err := budgetParamsFromRequest(ctx, queries, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dbBudget, err := queries.UpdateBudget(ctx, generated.UpdateBudgetParams{
+		ID:         pgtype.UUID{Bytes: parsedID, Valid: true},
+		CategoryID: params.CategoryID,
+		PersonID:   params.PersonID,
+		Month:      params.Month,
+		Amount:     params.Amount,
+		Rollover:   params.Rollover,
+	})
+	if err != nil {
+		log.Printf("Error updating budget: %v", err)
+		statusCode, message := handleDatabaseError(err)
+		c.JSON(statusCode, gin.H{"error": message})
+		return
+	}
+
+	budget, err := queries.GetBudgetByID(ctx, dbBudget.ID)
+	if err != nil {
+		log.Printf("Error fetching updated budget: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching updated budget"})
+		return
+	}
+	c.JSON(http.StatusOK, convertBudget(generated.GetBudgetsRow(budget)))
+}
+
+// @Summary Delete budget
+// @Description Delete a budget. The budget it replaced, if any, applies again from its month.
+// @Tags budgets
+// @Param id path string true "Budget ID"
+// @Success 204 "No content"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Budget not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/budgets/{id} [delete]
+func deleteBudget(c *gin.Context) {
+	parsedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID"})
+		return
+	}
+
+	deleted, err := queries.DeleteBudget(context.Background(), pgtype.UUID{Bytes: parsedID, Valid: true})
+	if err != nil {
+		log.Printf("Error deleting budget: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting budget"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Get budget report
+// @Description Compare a month's spending with the budgets in effect, from active and archived transactions. Transactions are dated by transaction date, else posted date, else upload date. A top-level category's spending includes its subcategories; a person's spending is their share of the transactions assigned to them. Categories excluded from totals are left out. Lines spending more than is available have status over.
+// @Tags budgets
+// @Produce json
+// @Param month query string false "Month as YYYY-MM (default current month)"
+// @Success 200 {object} BudgetReport "Budget vs actual for the month"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/budgets/report [get]
+func getBudgetReport(c *gin.Context) {
+	monthParam := c.DefaultQuery("month", time.Now().Format(budgetMonthLayout))
+	month, err := parseBudgetMonth(monthParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	budgets, err := queries.GetBudgets(ctx)
+	if err != nil {
+		log.Printf("Error fetching budgets: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error calculating budget report"})
+		return
+	}
+
+	// Rollover needs the spending since the earliest budget in effect
+	from := month
+	for _, budget := range budgets {
+		if budget.Month.Time.Before(from) {
+			from = budget.Month.Time
+		}
+	}
+	spending, err := loadBudgetSpending(ctx, from, month)
+	if err != nil {
+		log.Printf("Error loading budget spending: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error calculating budget report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildBudgetReport(month, budgets, spending))
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"jointanalysis/db/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBudgetRow returns a budget row for a category, or a person's budget with a valid person
+func testBudgetRow(category, person pgtype.UUID, month string, amount float64, rollover bool) generated.GetBudgetsRow {
+	start, _ := parseBudgetMonth(month)
+	row := generated.GetBudgetsRow{
+		ID:           pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		CategoryID:   category,
+		CategoryName: "Groceries",
+		PersonID:     person,
+		Month:        pgtype.Date{Time: start, Valid: true},
+		Rollover:     rollover,
+	}
+	row.Amount.Scan(fmt.Sprintf("%.2f", amount))
+	return row
+}
+
+// testSpendingKey returns the spending key of a category and person in a YYYY-MM month
+func testSpendingKey(month string, category, person pgtype.UUID) budgetSpendingKey {
+	start, _ := parseBudgetMonth(month)
+	return budgetSpendingKey{month: start, category: category, person: person}
+}
+
+func TestBuildBudgetReport(t *testing.T) {
+	groceries := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	person := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	household := pgtype.UUID{}
+
+	report := func(month string, budgets []generated.GetBudgetsRow, spending map[budgetSpendingKey]int64) BudgetReport {
+		start, err := parseBudgetMonth(month)
+		require.NoError(t, err)
+		return buildBudgetReport(start, budgets, spending)
+	}
+
+	t.Run("compares the month's spending with the budget", func(t *testing.T) {
+		budgets := []generated.GetBudgetsRow{testBudgetRow(groceries, household, "2024-01", 100, false)}
+		spending := map[budgetSpendingKey]int64{testSpendingKey("2024-01", groceries, household): 4550}
+
+		result := report("2024-01", budgets, spending)
+		require.Len(t, result.Lines, 1)
+		line := result.Lines[0]
+		assert.Equal(t, 100.0, line.Budgeted)
+		assert.Equal(t, 45.5, line.Spent)
+		assert.Equal(t, 54.5, line.Remaining)
+		assert.Equal(t, budgetStatusOK, line.Status)
+		assert.Equal(t, 0, result.OverBudget)
+	})
+
+	t.Run("budgets apply until replaced by a later budget", func(t *testing.T) {
+		budgets := []generated.GetBudgetsRow{
+			testBudgetRow(groceries, household, "2024-01", 100, false),
+			testBudgetRow(groceries, household, "2024-03", 200, false),
+		}
+
+		result := report("2024-02", budgets, nil)
+		require.Len(t, result.Lines, 1)
+		assert.Equal(t, 100.0, result.Lines[0].Budgeted)
+		assert.Equal(t, budgets[0].ID.String(), result.Lines[0].BudgetID)
+
+		result = report("2024-05", budgets, nil)
+		require.Len(t, result.Lines, 1)
+		assert.Equal(t, 200.0, result.Lines[0].Budgeted)
+	})
+
+	t.Run("leaves out budgets that start after the month", func(t *testing.T) {
+		budgets := []generated.GetBudgetsRow{testBudgetRow(groceries, household, "2024-04", 100, false)}
+
+		result := report("2024-03", budgets, nil)
+		assert.NotNil(t, result.Lines)
+		assert.Empty(t, result.Lines)
+	})
+
+	t.Run("rollover carries unspent amounts but not overspending", func(t *testing.T) {
+		budgets := []generated.GetBudgetsRow{testBudgetRow(groceries, household, "2024-01", 100, true)}
+		spending := map[budgetSpendingKey]int64{
+			testSpendingKey("2024-01", groceries, household): 6000,
+			testSpendingKey("2024-02", groceries, household): 15000,
+			testSpendingKey("2024-03", groceries, household): 2500,
+		}
+
+		result := report("2024-02", budgets, spending)
+		require.Len(t, result.Lines, 1)
+		assert.Equal(t, 40.0, result.Lines[0].RolledOver)
+		assert.Equal(t, 140.0, result.Lines[0].Available)
+		assert.Equal(t, -10.0, result.Lines[0].Remaining)
+		assert.Equal(t, budgetStatusOver, result.Lines[0].Status)
+		assert.Equal(t, 1, result.OverBudget)
+
+		result = report("2024-03", budgets, spending)
+		require.Len(t, result.Lines, 1)
+		assert.Equal(t, 0.0, result.Lines[0].RolledOver)
+		assert.Equal(t, 75.0, result.Lines[0].Remaining)
+	})
+
+	t.Run("refunds add to the carried amount", func(t *testing.T) {
+		budgets := []generated.GetBudgetsRow{testBudgetRow(groceries, household, "2024-01", 100, true)}
+		spending := map[budgetSpendingKey]int64{testSpendingKey("2024-01", groceries, household): -2000}
+
+		result := report("2024-02", budgets, spending)
+		require.Len(t, result.Lines, 1)
+		assert.Equal(t, 120.0, result.Lines[0].RolledOver)
+	})
+
+	t.Run("a later budget without rollover drops the carried amount", func(t *testing.T) {
+		budgets := []generated.GetBudgetsRow{
+			testBudgetRow(groceries, household, "2024-01", 100, true),
+			testBudgetRow(groceries, household, "2024-02", 100, false),
+		}
+
+		result := report("2024-02", budgets, nil)
+		require.Len(t, result.Lines, 1)
+		assert.Equal(t, 0.0, result.Lines[0].RolledOver)
+		assert.Equal(t, 100.0, result.Lines[0].Available)
+	})
+
+	t.Run("keeps household and person budgets apart", func(t *testing.T) {
+		budgets := []generated.GetBudgetsRow{
+			testBudgetRow(groceries, household, "2024-01", 100, false),
+			testBudgetRow(groceries, person, "2024-01", 30, false),
+		}
+		spending := map[budgetSpendingKey]int64{
+			testSpendingKey("2024-01", groceries, household): 8000,
+			testSpendingKey("2024-01", groceries, person):    4000,
+		}
+
+		result := report("2024-01", budgets, spending)
+		require.Len(t, result.Lines, 2)
+		assert.Nil(t, result.Lines[0].PersonID)
+		assert.Equal(t, budgetStatusOK, result.Lines[0].Status)
+		require.NotNil(t, result.Lines[1].PersonID)
+		assert.Equal(t, budgetStatusOver, result.Lines[1].Status)
+		assert.Equal(t, 1, result.OverBudget)
+	})
+}
+
+func TestBudgets(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	month := time.Now().Format(budgetMonthLayout)
+	otherID := testCategoryID("Other")
+
+	t.Run("should create, update and delete a budget", func(t *testing.T) {
+		body, _ := json.Marshal(Budget{CategoryID: otherID, Month: month, Amount: 250})
+		resp := makeRequest("POST", "/api/budgets", bytes.NewBuffer(body))
+
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		var created Budget
+		assertNoError(t, parseJSONResponse(resp, &created))
+		if created.CategoryName != "Other" || created.Month != month || created.Amount != 250 || created.PersonID != nil {
+			t.Errorf("Unexpected created budget: %+v", created)
+		}
+
+		body, _ = json.Marshal(Budget{CategoryID: otherID, Month: month, Amount: 300, Rollover: true})
+		resp = makeRequest("PUT", "/api/budgets/"+created.ID, bytes.NewBuffer(body))
+
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var updated Budget
+		assertNoError(t, parseJSONResponse(resp, &updated))
+		if updated.Amount != 300 || !updated.Rollover {
+			t.Errorf("Unexpected updated budget: %+v", updated)
+		}
+
+		resp = makeRequest("DELETE", "/api/budgets/"+created.ID, nil)
+		assertStatusCode(t, http.StatusNoContent, resp.Code)
+
+		resp = makeRequest("DELETE", "/api/budgets/"+created.ID, nil)
+		assertStatusCode(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("should reject invalid budgets", func(t *testing.T) {
+		invalid := []Budget{
+			{CategoryID: otherID, Month: "2024-13", Amount: 100},
+			{CategoryID: otherID, Month: month, Amount: -1},
+			{CategoryID: uuid.New().String(), Month: month, Amount: 100},
+			{CategoryID: testCategoryID("Reimbursable"), Month: month, Amount: 100},
+		}
+		for _, budget := range invalid {
+			body, _ := json.Marshal(budget)
+			resp := makeRequest("POST", "/api/budgets", bytes.NewBuffer(body))
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %+v, got %d", budget, resp.Code)
+			}
+		}
+	})
+
+	t.Run("should reject a second budget for the same month", func(t *testing.T) {
+		body, _ := json.Marshal(Budget{CategoryID: otherID, Month: "2024-01", Amount: 100})
+		resp := makeRequest("POST", "/api/budgets", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		resp = makeRequest("POST", "/api/budgets", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("should report spending against household and person budgets", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		alice, err := createTestPerson("Alice", "")
+		assertNoError(t, err)
+		bob, err := createTestPerson("Bob", "")
+		assertNoError(t, err)
+
+		_, err = createTestTransaction("Groceries", 80, "test.csv", []string{alice, bob})
+		assertNoError(t, err)
+		_, err = createTestTransaction("Refund", -20, "test.csv", []string{alice})
+		assertNoError(t, err)
+
+		for _, budget := range []Budget{
+			{CategoryID: otherID, Month: month, Amount: 50},
+			{CategoryID: otherID, PersonID: &alice, Month: month, Amount: 30},
+		} {
+			body, _ := json.Marshal(budget)
+			resp := makeRequest("POST", "/api/budgets", bytes.NewBuffer(body))
+			assertStatusCode(t, http.StatusCreated, resp.Code)
+		}
+
+		resp := makeRequest("GET", "/api/budgets/report?month="+month, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var report BudgetReport
+		assertNoError(t, parseJSONResponse(resp, &report))
+		if len(report.Lines) != 2 {
+			t.Fatalf("Expected 2 report lines, got %+v", report.Lines)
+		}
+
+		// Household: 80 - 20 = 60 against 50
+		if report.Lines[0].PersonID != nil || report.Lines[0].Spent != 60 || report.Lines[0].Status != budgetStatusOver {
+			t.Errorf("Unexpected household line: %+v", report.Lines[0])
+		}
+		// Alice: half of 80, less the 20 refund
+		if report.Lines[1].PersonID == nil || report.Lines[1].Spent != 20 || report.Lines[1].Status != budgetStatusOK {
+			t.Errorf("Unexpected person line: %+v", report.Lines[1])
+		}
+		if report.OverBudget != 1 {
+			t.Errorf("Expected 1 line over budget, got %d", report.OverBudget)
+		}
+	})
+
+	t.Run("should reject an invalid report month", func(t *testing.T) {
+		resp := makeRequest("GET", "/api/budgets/report?month=January", nil)
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 
@@ -12,16 +15,44 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// convertCategory converts a generated.GetCategoryByIDRow to our Category struct
+func convertCategory(dbCategory generated.GetCategoryByIDRow) Category {
+	category := Category{
+		ID:                uuid.UUID(dbCategory.ID.Bytes).String(),
+		Name:              dbCategory.Name,
+		ExcludeFromTotals: dbCategory.ExcludeFromTotals,
+		CreatedAt:         dbCategory.CreatedAt.Time,
+		UpdatedAt:         dbCategory.UpdatedAt.Time,
+	}
+	if dbCategory.Description.Valid {
+		category.Description = &dbCategory.Description.String
+	}
+	if dbCategory.Color.Valid {
+		category.Color = &dbCategory.Color.String
+	}
+	if dbCategory.ParentID.Valid {
+		parentIDStr := uuid.UUID(dbCategory.ParentID.Bytes).String()
+		category.ParentID = &parentIDStr
+	}
+	if dbCategory.RetiredAt.Valid {
+		category.RetiredAt = &dbCategory.RetiredAt.Time
+	}
+	return category
+}
+
 // Category handler functions
 
 // @Summary Get all categories
-// @Description Retrieve all categories as a nested tree (top-level categories with subcategories embedded)
+// @Description Retrieve all categories as a nested tree (top-level categories with subcategories embedded). Retired categories are left out unless include_retired is true.
 // @Tags categories
 // @Produce json
+// @Param include_retired query bool false "Include retired categories (default false)"
 // @Success 200 {array} Category "Nested list of top-level categories with subcategories"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /api/categories [get]
 func getCategories(c *gin.Context) {
+	includeRetired := c.Query("include_retired") == "true"
+
 	dbCategories, err := queries.GetCategories(context.Background())
 	if err != nil {
 		log.Printf("Error fetching categories: %v", err)
@@ -34,12 +65,16 @@ func getCategories(c *gin.Context) {
 	var topLevel []*Category
 
 	for _, dbCategory := range dbCategories {
+		if dbCategory.RetiredAt.Valid && !includeRetired {
+			continue
+		}
 		category := &Category{
-			ID:            uuid.UUID(dbCategory.ID.Bytes).String(),
-			Name:          dbCategory.Name,
-			Subcategories: []Category{},
-			CreatedAt:     dbCategory.CreatedAt.Time,
-			UpdatedAt:     dbCategory.UpdatedAt.Time,
+			ID:                uuid.UUID(dbCategory.ID.Bytes).String(),
+			Name:              dbCategory.Name,
+			Subcategories:     []Category{},
+			ExcludeFromTotals: dbCategory.ExcludeFromTotals,
+			CreatedAt:         dbCategory.CreatedAt.Time,
+			UpdatedAt:         dbCategory.UpdatedAt.Time,
 		}
 		if dbCategory.Description.Valid {
 			category.Description = &dbCategory.Description.String
@@ -51,6 +86,9 @@ func getCategories(c *gin.Context) {
 			parentIDStr := uuid.UUID(dbCategory.ParentID.Bytes).String()
 			category.ParentID = &parentIDStr
 		}
+		if dbCategory.RetiredAt.Valid {
+			category.RetiredAt = &dbCategory.RetiredAt.Time
+		}
 		categoryMap[category.ID] = category
 	}
 
@@ -75,11 +113,11 @@ func getCategories(c *gin.Context) {
 }
 
 // @Summary Create category
-// @Description Create a new category in the system. Use parent_id to create a subcategory (max 2 levels).
+// @Description Create a new category in the system. Use parent_id to create a subcategory (max 2 levels). With exclude_from_totals, the category and its subcategories are left out of person totals and spending reports.
 // @Tags categories
 // @Accept json
 // @Produce json
-// @Param category body Category true "Category data (name required; description, color, parent_id optional)"
+// @Param category body Category true "Category data (name required; description, color, parent_id, exclude_from_totals optional)"
 // @Success 201 {object} Category "Created category"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 409 {object} map[string]interface{} "Category already exists"
@@ -128,12 +166,17 @@ func createCategory(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot create subcategory of a subcategory (max 2 levels)"})
 			return
 		}
+		if parent.RetiredAt.Valid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent category is retired"})
+			return
+		}
 	}
 
 	// Create parameters for the generated function
 	params := generated.CreateCategoryParams{
-		Name:     category.Name,
-		ParentID: parentIDpg,
+		Name:              category.Name,
+		ParentID:          parentIDpg,
+		ExcludeFromTotals: category.ExcludeFromTotals,
 	}
 
 	// Handle optional fields
@@ -151,13 +194,15 @@ func createCategory(c *gin.Context) {
 		c.JSON(statusCode, gin.H{"error": message})
 		return
 	}
+	categoryMappings.invalidate()
 
 	// Convert back to API type
 	resultCategory := Category{
-		ID:        uuid.UUID(dbCategory.ID.Bytes).String(),
-		Name:      dbCategory.Name,
-		CreatedAt: dbCategory.CreatedAt.Time,
-		UpdatedAt: dbCategory.UpdatedAt.Time,
+		ID:                uuid.UUID(dbCategory.ID.Bytes).String(),
+		Name:              dbCategory.Name,
+		ExcludeFromTotals: dbCategory.ExcludeFromTotals,
+		CreatedAt:         dbCategory.CreatedAt.Time,
+		UpdatedAt:         dbCategory.UpdatedAt.Time,
 	}
 
 	if dbCategory.Description.Valid {
@@ -225,8 +270,9 @@ func updateCategory(c *gin.Context) {
 
 	// Create parameters for the generated function
 	params := generated.UpdateCategoryParams{
-		ID:   categoryUUIDpg,
-		Name: category.Name,
+		ID:                categoryUUIDpg,
+		Name:              category.Name,
+		ExcludeFromTotals: category.ExcludeFromTotals,
 	}
 
 	// Handle optional fields
@@ -244,13 +290,15 @@ func updateCategory(c *gin.Context) {
 		c.JSON(statusCode, gin.H{"error": message})
 		return
 	}
+	categoryMappings.invalidate()
 
 	// Convert back to API type
 	resultCategory := Category{
-		ID:        uuid.UUID(dbCategory.ID.Bytes).String(),
-		Name:      dbCategory.Name,
-		CreatedAt: dbCategory.CreatedAt.Time,
-		UpdatedAt: dbCategory.UpdatedAt.Time,
+		ID:                uuid.UUID(dbCategory.ID.Bytes).String(),
+		Name:              dbCategory.Name,
+		ExcludeFromTotals: dbCategory.ExcludeFromTotals,
+		CreatedAt:         dbCategory.CreatedAt.Time,
+		UpdatedAt:         dbCategory.UpdatedAt.Time,
 	}
 
 	if dbCategory.Description.Valid {
@@ -263,6 +311,9 @@ func updateCategory(c *gin.Context) {
 		parentIDStr := uuid.UUID(dbCategory.ParentID.Bytes).String()
 		resultCategory.ParentID = &parentIDStr
 	}
+	if dbCategory.RetiredAt.Valid {
+		resultCategory.RetiredAt = &dbCategory.RetiredAt.Time
+	}
 
 	c.JSON(http.StatusOK, resultCategory)
 }
@@ -305,6 +356,349 @@ func deleteCategory(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting category"})
 		return
 	}
+	categoryMappings.invalidate()
+	// Deleting a category deletes its rules
+	ruleMatchers.invalidate()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
 }
+
+// mergeCategoryInto moves a category's transaction splits, rules, rule split
+// template lines and budgets to the target category and deletes it, adding what
+// it moved to result. A budget for a person and month the target already has a
+// budget for is added to that budget. Subcategories must have been moved or merged first, as deleting the
+// category deletes them.
+func mergeCategoryInto(ctx context.Context, q *generated.Queries, sourceID, targetID pgtype.UUID, result *CategoryMergeResult) error {
+	splits, err := q.MoveTransactionSplitsToCategory(ctx, generated.MoveTransactionSplitsToCategoryParams{TargetID: targetID, SourceID: sourceID})
+	if err != nil {
+		return fmt.Errorf("failed to move transaction splits: %w", err)
+	}
+	rules, err := q.MoveRulesToCategory(ctx, generated.MoveRulesToCategoryParams{TargetID: targetID, SourceID: sourceID})
+	if err != nil {
+		return fmt.Errorf("failed to move rules: %w", err)
+	}
+	ruleSplits, err := q.MoveRuleSplitsToCategory(ctx, generated.MoveRuleSplitsToCategoryParams{TargetID: targetID, SourceID: sourceID})
+	if err != nil {
+		return fmt.Errorf("failed to move rule split templates: %w", err)
+	}
+	// Budgets are deleted with their category, so they are copied to the target
+	budgets, err := q.MoveBudgetsToCategory(ctx, generated.MoveBudgetsToCategoryParams{TargetID: targetID, SourceID: sourceID})
+	if err != nil {
+		return fmt.Errorf("failed to move budgets: %w", err)
+	}
+	if err := q.DeleteCategory(ctx, sourceID); err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+
+	result.SplitsMoved += splits
+	result.RulesMoved += rules
+	result.RuleSplitsMoved += ruleSplits
+	result.BudgetsMoved += budgets
+	return nil
+}
+
+// @Summary Merge category
+// @Description Merge a category into a target category and delete it, in one database transaction. Every transaction split, rule, rule split template line and budget in the category moves to the target; budgets for a person and month the target already budgets are added to its budget. Its subcategories are re-parented under the target, or merged into the target too when the target is a subcategory. The Other category cannot be merged, nor can a category be merged into its own subcategory.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the category to merge"
+// @Param request body CategoryMergeRequest true "target_id is the category to merge into"
+// @Success 200 {object} CategoryMergeResult "The target category and what was moved into it"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Category not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/categories/{id}/merge [post]
+func mergeCategory(c *gin.Context) {
+	sourceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+	var req CategoryMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	targetUUID, err := uuid.Parse(req.TargetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target_id format"})
+		return
+	}
+	if sourceUUID == targetUUID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a category into itself"})
+		return
+	}
+	sourceID := pgtype.UUID{Bytes: sourceUUID, Valid: true}
+	targetID := pgtype.UUID{Bytes: targetUUID, Valid: true}
+
+	ctx := context.Background()
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error merging category"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	source, err := qtx.GetCategoryByID(ctx, sourceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	target, err := qtx.GetCategoryByID(ctx, targetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target category not found"})
+		return
+	}
+	if target.RetiredAt.Valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target category is retired"})
+		return
+	}
+	// Transactions no rule matches go to Other, so it must keep existing
+	if source.Name == "Other" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge the Other category"})
+		return
+	}
+	if target.ParentID == sourceID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a category into its own subcategory"})
+		return
+	}
+
+	result := CategoryMergeResult{SubcategoriesMerged: make([]string, 0)}
+	if target.ParentID.Valid {
+		// Subcategories cannot be nested under a subcategory (max 2 levels)
+		subs, err := qtx.GetSubcategoriesByParent(ctx, sourceID)
+		if err != nil {
+			log.Printf("Error fetching subcategories: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error merging category"})
+			return
+		}
+		for _, sub := range subs {
+			if err := mergeCategoryInto(ctx, qtx, sub.ID, targetID, &result); err != nil {
+				log.Printf("Error merging subcategory %s: %v", sub.Name, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error merging category"})
+				return
+			}
+			result.SubcategoriesMerged = append(result.SubcategoriesMerged, sub.Name)
+		}
+	} else {
+		result.SubcategoriesReparented, err = qtx.ReparentSubcategories(ctx, generated.ReparentSubcategoriesParams{TargetID: targetID, SourceID: sourceID})
+		if err != nil {
+			log.Printf("Error re-parenting subcategories: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error merging category"})
+			return
+		}
+	}
+
+	if err := mergeCategoryInto(ctx, qtx, sourceID, targetID, &result); err != nil {
+		log.Printf("Error merging category: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error merging category"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing category merge: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error merging category"})
+		return
+	}
+	categoryMappings.invalidate()
+	ruleMatchers.invalidate()
+
+	result.Target = convertCategory(target)
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Retire category
+// @Description Retire a category and its subcategories, in one database transaction. Retired categories are hidden from GET /api/categories and cannot be chosen for rules, but existing transaction splits and archives keep them. With reassign_rules_to, the rules and rule split template lines of the retired categories move to that category; otherwise those rules are kept but no longer match. Other cannot be retired.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param request body CategoryRetireRequest false "reassign_rules_to is optional"
+// @Success 200 {object} CategoryRetireResult "The retired category and what retiring it changed"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Category not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/categories/{id}/retire [post]
+func retireCategory(c *gin.Context) {
+	categoryUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+	categoryID := pgtype.UUID{Bytes: categoryUUID, Valid: true}
+
+	// The body is optional
+	var req CategoryRetireRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	var reassignID pgtype.UUID
+	if req.ReassignRulesTo != nil && *req.ReassignRulesTo != "" {
+		reassignUUID, err := uuid.Parse(*req.ReassignRulesTo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reassign_rules_to format"})
+			return
+		}
+		reassignID = pgtype.UUID{Bytes: reassignUUID, Valid: true}
+	}
+
+	ctx := context.Background()
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retiring category"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	category, err := qtx.GetCategoryByID(ctx, categoryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	// Transactions no rule matches go to Other, so it must stay available
+	if category.Name == "Other" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot retire the Other category"})
+		return
+	}
+	subs, err := qtx.GetSubcategoriesByParent(ctx, categoryID)
+	if err != nil {
+		log.Printf("Error fetching subcategories: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retiring category"})
+		return
+	}
+
+	result := CategoryRetireResult{}
+	retiring := []pgtype.UUID{categoryID}
+	for _, sub := range subs {
+		retiring = append(retiring, sub.ID)
+		if !sub.RetiredAt.Valid {
+			result.SubcategoriesRetired++
+		}
+	}
+
+	if reassignID.Valid {
+		target, err := qtx.GetCategoryByID(ctx, reassignID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reassign_rules_to category not found"})
+			return
+		}
+		if target.RetiredAt.Valid || target.ID == categoryID || target.ParentID == categoryID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reassign_rules_to must be a category that is not retired or being retired"})
+			return
+		}
+		for _, id := range retiring {
+			rules, err := qtx.MoveRulesToCategory(ctx, generated.MoveRulesToCategoryParams{TargetID: reassignID, SourceID: id})
+			if err != nil {
+				log.Printf("Error reassigning rules: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retiring category"})
+				return
+			}
+			ruleSplits, err := qtx.MoveRuleSplitsToCategory(ctx, generated.MoveRuleSplitsToCategoryParams{TargetID: reassignID, SourceID: id})
+			if err != nil {
+				log.Printf("Error reassigning rule split templates: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retiring category"})
+				return
+			}
+			result.RulesReassigned += rules
+			result.RuleSplitsReassigned += ruleSplits
+		}
+	}
+
+	// Rules that can no longer match are the ones retiring removes from matching
+	before, err := qtx.GetRulesForMatching(ctx)
+	if err != nil {
+		log.Printf("Error fetching rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retiring category"})
+		return
+	}
+	if _, err := qtx.RetireCategory(ctx, categoryID); err != nil {
+		log.Printf("Error retiring category: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retiring category"})
+		return
+	}
+	after, err := qtx.GetRulesForMatching(ctx)
+	if err != nil {
+		log.Printf("Error fetching rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retiring category"})
+		return
+	}
+	result.RulesDisabled = len(before) - len(after)
+
+	retired, err := qtx.GetCategoryByID(ctx, categoryID)
+	if err != nil {
+		log.Printf("Error fetching retired category: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retiring category"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing category retirement: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retiring category"})
+		return
+	}
+	categoryMappings.invalidate()
+	ruleMatchers.invalidate()
+
+	result.Category = convertCategory(retired)
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Restore category
+// @Description Restore a retired category, together with the subcategories that were retired with it. The rules that use it match again; rules reassigned when it was retired stay with their new category. A subcategory cannot be restored while its parent is retired.
+// @Tags categories
+// @Produce json
+// @Param id path string true "Category ID"
+// @Success 200 {object} Category "Restored category"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Category not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/categories/{id}/restore [post]
+func restoreCategory(c *gin.Context) {
+	categoryUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+	categoryID := pgtype.UUID{Bytes: categoryUUID, Valid: true}
+
+	ctx := context.Background()
+	category, err := queries.GetCategoryByID(ctx, categoryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	if category.ParentID.Valid {
+		parent, err := queries.GetCategoryByID(ctx, category.ParentID)
+		if err != nil {
+			log.Printf("Error fetching parent category: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error restoring category"})
+			return
+		}
+		if parent.RetiredAt.Valid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Restore the parent category first"})
+			return
+		}
+	}
+
+	if _, err := queries.RestoreCategory(ctx, categoryID); err != nil {
+		log.Printf("Error restoring category: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error restoring category"})
+		return
+	}
+	categoryMappings.invalidate()
+	ruleMatchers.invalidate()
+
+	category, err = queries.GetCategoryByID(ctx, categoryID)
+	if err != nil {
+		log.Printf("Error fetching restored category: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error restoring category"})
+		return
+	}
+	c.JSON(http.StatusOK, convertCategory(category))
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 // TestGetCategories tests the GET /api/categories endpoint
@@ -491,3 +494,445 @@ func TestSubcategorySupport(t *testing.T) {
 		}
 	})
 }
+
+// TestCategoryMappingReload tests that category changes reach the importer
+// without restarting the server
+func TestCategoryMappingReload(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	mappingHas := func(name string) bool {
+		_, exists := categoryMappings.get().categoriesByName[name]
+		return exists
+	}
+	var categoryID string
+
+	t.Run("should categorize imports with a category created through the API", func(t *testing.T) {
+		resp := makeRequest("POST", "/api/categories", bytes.NewBufferString(`{"name": "Coffee"}`))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+		var category Category
+		assertNoError(t, parseJSONResponse(resp, &category))
+		categoryID = category.ID
+
+		_, err := createTestRule("ESPRESSO", categoryID, 0)
+		assertNoError(t, err)
+
+		csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-08-01,2024-08-02,1234,ESPRESSO BAR,,4.00,`
+		resp = uploadTestFile(t, "coffee.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var name string
+		assertNoError(t, testDB.QueryRow(context.Background(),
+			"SELECT c.name FROM transaction_splits s JOIN categories c ON c.id = s.category_id").Scan(&name))
+		if name != "Coffee" {
+			t.Errorf("Expected the transaction to be categorized as Coffee, got %s", name)
+		}
+	})
+
+	t.Run("should reflect renamed and deleted categories", func(t *testing.T) {
+		resp := makeRequest("PUT", "/api/categories/"+categoryID, bytes.NewBufferString(`{"name": "Cafe"}`))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		if !mappingHas("Cafe") || mappingHas("Coffee") {
+			t.Error("Expected the category mapping to have the new name only")
+		}
+
+		resp = makeRequest("DELETE", "/api/categories/"+categoryID, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		if mappingHas("Cafe") {
+			t.Error("Expected the deleted category to leave the category mapping")
+		}
+	})
+
+	t.Run("should pick up changes made outside the server", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go listenForCacheChanges(ctx, testDB)
+
+		_, err := testDB.Exec(ctx, "INSERT INTO categories (name) VALUES ('External')")
+		assertNoError(t, err)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for !mappingHas("External") {
+			if time.Now().After(deadline) {
+				t.Fatal("Expected the category mapping to be reloaded after the notification")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+// TestMergeCategory tests the POST /api/categories/:id/merge endpoint
+func TestMergeCategory(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	createSubcategory := func(name, parentID string) string {
+		body, err := json.Marshal(map[string]string{"name": name, "parent_id": parentID})
+		assertNoError(t, err)
+		resp := makeRequest("POST", "/api/categories", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+		var category Category
+		assertNoError(t, parseJSONResponse(resp, &category))
+		return category.ID
+	}
+	merge := func(sourceID, targetID string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(map[string]string{"target_id": targetID})
+		assertNoError(t, err)
+		return makeRequest("POST", "/api/categories/"+sourceID+"/merge", bytes.NewBuffer(body))
+	}
+	splitCategories := func() []string {
+		rows, err := testDB.Query(context.Background(),
+			"SELECT c.name FROM transaction_splits s JOIN categories c ON c.id = s.category_id ORDER BY c.name")
+		assertNoError(t, err)
+		defer rows.Close()
+		var names []string
+		for rows.Next() {
+			var name string
+			assertNoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		return names
+	}
+
+	t.Run("should move splits, rules and subcategories into the target", func(t *testing.T) {
+		diningID, err := createTestCategory("Dining", "", "")
+		assertNoError(t, err)
+		restaurantsID, err := createTestCategory("Restaurants", "", "")
+		assertNoError(t, err)
+		createSubcategory("Dining Out", diningID)
+
+		body, err := json.Marshal(map[string]interface{}{
+			"match_value": "BISTRO",
+			"category_id": diningID,
+			"splits": []map[string]interface{}{
+				{"category_id": diningID, "percentage": 50},
+				{"category_id": testCategoryID("Reimbursable"), "percentage": 50},
+			},
+		})
+		assertNoError(t, err)
+		resp := makeRequest("POST", "/api/rules", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-09-01,2024-09-02,1234,CORNER BISTRO,,40.00,`
+		resp = uploadTestFile(t, "dining.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		resp = merge(diningID, restaurantsID)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var result CategoryMergeResult
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if result.Target.Name != "Restaurants" || result.SplitsMoved != 1 || result.RulesMoved != 1 || result.RuleSplitsMoved != 1 || result.SubcategoriesReparented != 1 {
+			t.Errorf("Expected 1 split, rule, rule split line and subcategory moved into Restaurants, got %+v", result)
+		}
+
+		if names := splitCategories(); len(names) != 2 || names[0] != "Reimbursable" || names[1] != "Restaurants" {
+			t.Errorf("Expected the splits in Reimbursable and Restaurants, got %v", names)
+		}
+
+		resp = makeRequest("GET", "/api/categories", nil)
+		var categories []Category
+		assertNoError(t, parseJSONResponse(resp, &categories))
+		for _, category := range categories {
+			if category.Name == "Dining" {
+				t.Error("Expected Dining to be deleted")
+			}
+			if category.Name == "Restaurants" && (len(category.Subcategories) != 1 || category.Subcategories[0].Name != "Dining Out") {
+				t.Errorf("Expected Dining Out under Restaurants, got %+v", category.Subcategories)
+			}
+		}
+
+		// The moved rule categorizes new imports into the target
+		csvContent = `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-09-03,2024-09-04,1234,UPTOWN BISTRO,,30.00,`
+		resp = uploadTestFile(t, "dining2.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		if names := splitCategories(); len(names) != 4 || names[3] != "Restaurants" {
+			t.Errorf("Expected the new transaction in Restaurants, got %v", names)
+		}
+	})
+
+	t.Run("should merge subcategories into a subcategory target", func(t *testing.T) {
+		barsID, err := createTestCategory("Bars", "", "")
+		assertNoError(t, err)
+		pubsID := createSubcategory("Pubs", barsID)
+		nightlifeID, err := createTestCategory("Nightlife", "", "")
+		assertNoError(t, err)
+		clubsID := createSubcategory("Clubs", nightlifeID)
+		_, err = createTestRule("TAVERN", pubsID, 0)
+		assertNoError(t, err)
+
+		resp := merge(barsID, clubsID)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var result CategoryMergeResult
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if len(result.SubcategoriesMerged) != 1 || result.SubcategoriesMerged[0] != "Pubs" || result.RulesMoved != 1 || result.SubcategoriesReparented != 0 {
+			t.Errorf("Expected Pubs and its rule merged into Clubs, got %+v", result)
+		}
+
+		resp = makeRequest("DELETE", "/api/categories/"+pubsID, nil)
+		assertStatusCode(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("should move budgets into the target", func(t *testing.T) {
+		groceriesID, err := createTestCategory("Groceries", "", "")
+		assertNoError(t, err)
+		foodID, err := createTestCategory("Food", "", "")
+		assertNoError(t, err)
+		personID, err := createTestPerson("Merge Alice", "")
+		assertNoError(t, err)
+
+		for _, budget := range []map[string]interface{}{
+			{"category_id": groceriesID, "month": "2024-09", "amount": 100},
+			{"category_id": groceriesID, "person_id": personID, "month": "2024-10", "amount": 30},
+			{"category_id": foodID, "month": "2024-09", "amount": 50},
+		} {
+			body, err := json.Marshal(budget)
+			assertNoError(t, err)
+			resp := makeRequest("POST", "/api/budgets", bytes.NewBuffer(body))
+			assertStatusCode(t, http.StatusCreated, resp.Code)
+		}
+
+		resp := merge(groceriesID, foodID)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var result CategoryMergeResult
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if result.BudgetsMoved != 2 {
+			t.Errorf("Expected 2 budgets moved, got %+v", result)
+		}
+
+		resp = makeRequest("GET", "/api/budgets", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var budgets []Budget
+		assertNoError(t, parseJSONResponse(resp, &budgets))
+		amounts := make(map[string]float64)
+		for _, budget := range budgets {
+			if budget.CategoryID != foodID {
+				t.Errorf("Expected every budget in Food, got %+v", budget)
+				continue
+			}
+			key := budget.Month
+			if budget.PersonName != nil {
+				key += " " + *budget.PersonName
+			}
+			amounts[key] = budget.Amount
+		}
+		if len(amounts) != 2 || amounts["2024-09"] != 150 || amounts["2024-10 Merge Alice"] != 30 {
+			t.Errorf("Expected the household budgets added up and Merge Alice's moved, got %v", amounts)
+		}
+	})
+
+	t.Run("should reject invalid merges", func(t *testing.T) {
+		parentID, err := createTestCategory("Groceries", "", "")
+		assertNoError(t, err)
+		childID := createSubcategory("Farmers Market", parentID)
+		otherID := testCategoryID("Other")
+		fakeID := "550e8400-e29b-41d4-a716-446655440000"
+
+		cases := []struct {
+			sourceID, targetID string
+			status             int
+		}{
+			{parentID, parentID, http.StatusBadRequest},
+			{parentID, childID, http.StatusBadRequest},
+			{otherID, parentID, http.StatusBadRequest},
+			{parentID, "not-a-uuid", http.StatusBadRequest},
+			{parentID, fakeID, http.StatusBadRequest},
+			{"not-a-uuid", parentID, http.StatusBadRequest},
+			{fakeID, parentID, http.StatusNotFound},
+		}
+		for _, tc := range cases {
+			resp := merge(tc.sourceID, tc.targetID)
+			if resp.Code != tc.status {
+				t.Errorf("Expected %d merging %s into %s, got %d", tc.status, tc.sourceID, tc.targetID, resp.Code)
+			}
+		}
+	})
+}
+
+func TestRetireCategory(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	createSubcategory := func(name, parentID string) string {
+		body, err := json.Marshal(map[string]string{"name": name, "parent_id": parentID})
+		assertNoError(t, err)
+		resp := makeRequest("POST", "/api/categories", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+		var category Category
+		assertNoError(t, parseJSONResponse(resp, &category))
+		return category.ID
+	}
+	retire := func(categoryID string, body map[string]string) *httptest.ResponseRecorder {
+		if body == nil {
+			return makeRequest("POST", "/api/categories/"+categoryID+"/retire", nil)
+		}
+		encoded, err := json.Marshal(body)
+		assertNoError(t, err)
+		return makeRequest("POST", "/api/categories/"+categoryID+"/retire", bytes.NewBuffer(encoded))
+	}
+	listed := func(query string) map[string]Category {
+		resp := makeRequest("GET", "/api/categories"+query, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var categories []Category
+		assertNoError(t, parseJSONResponse(resp, &categories))
+		names := make(map[string]Category)
+		for _, category := range categories {
+			names[category.Name] = category
+			for _, sub := range category.Subcategories {
+				names[sub.Name] = sub
+			}
+		}
+		return names
+	}
+	splitCategories := func() []string {
+		rows, err := testDB.Query(context.Background(),
+			"SELECT c.name FROM transaction_splits s JOIN categories c ON c.id = s.category_id ORDER BY s.created_at, c.name")
+		assertNoError(t, err)
+		defer rows.Close()
+		var names []string
+		for rows.Next() {
+			var name string
+			assertNoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		return names
+	}
+
+	t.Run("should hide the category and its subcategories but keep history", func(t *testing.T) {
+		hobbiesID, err := createTestCategory("Hobbies", "", "")
+		assertNoError(t, err)
+		createSubcategory("Model Trains", hobbiesID)
+		_, err = createTestRule("HOBBY SHOP", hobbiesID, 0)
+		assertNoError(t, err)
+
+		csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-09-01,2024-09-02,1234,HOBBY SHOP,,25.00,`
+		resp := uploadTestFile(t, "hobbies.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		resp = retire(hobbiesID, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var result CategoryRetireResult
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if result.Category.RetiredAt == nil || result.SubcategoriesRetired != 1 || result.RulesDisabled != 1 || result.RulesReassigned != 0 {
+			t.Errorf("Expected Hobbies and Model Trains retired with its rule disabled, got %+v", result)
+		}
+
+		active := listed("")
+		if _, exists := active["Hobbies"]; exists {
+			t.Error("Expected Hobbies to be hidden")
+		}
+		if _, exists := active["Model Trains"]; exists {
+			t.Error("Expected Model Trains to be hidden")
+		}
+		all := listed("?include_retired=true")
+		if all["Hobbies"].RetiredAt == nil || all["Model Trains"].RetiredAt == nil {
+			t.Errorf("Expected retired categories with include_retired, got %+v", all)
+		}
+
+		// The rule no longer matches, and the existing split keeps its category
+		csvContent = `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-09-03,2024-09-04,1234,HOBBY SHOP,,15.00,`
+		resp = uploadTestFile(t, "hobbies2.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		if names := splitCategories(); len(names) != 2 || names[0] != "Hobbies" || names[1] != "Other" {
+			t.Errorf("Expected the old split in Hobbies and the new one in Other, got %v", names)
+		}
+
+		body, err := json.Marshal(map[string]interface{}{"match_value": "TRAIN", "category_id": hobbiesID})
+		assertNoError(t, err)
+		resp = makeRequest("POST", "/api/rules", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+
+		body, err = json.Marshal(map[string]string{"name": "Board Games", "parent_id": hobbiesID})
+		assertNoError(t, err)
+		resp = makeRequest("POST", "/api/categories", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("should restore the category with its subcategories", func(t *testing.T) {
+		all := listed("?include_retired=true")
+		resp := makeRequest("POST", "/api/categories/"+all["Model Trains"].ID+"/restore", nil)
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+
+		resp = makeRequest("POST", "/api/categories/"+all["Hobbies"].ID+"/restore", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var category Category
+		assertNoError(t, parseJSONResponse(resp, &category))
+		if category.RetiredAt != nil {
+			t.Errorf("Expected Hobbies to be restored, got %+v", category)
+		}
+
+		active := listed("")
+		if _, exists := active["Model Trains"]; !exists {
+			t.Error("Expected Model Trains to be restored with Hobbies")
+		}
+	})
+
+	t.Run("should reassign rules to another category", func(t *testing.T) {
+		giftsID, err := createTestCategory("Gifts", "", "")
+		assertNoError(t, err)
+		flowersID := createSubcategory("Flowers", giftsID)
+		presentsID, err := createTestCategory("Presents", "", "")
+		assertNoError(t, err)
+		_, err = createTestRule("GIFT SHOP", giftsID, 0)
+		assertNoError(t, err)
+		_, err = createTestRule("FLORIST", flowersID, 0)
+		assertNoError(t, err)
+
+		resp := retire(giftsID, map[string]string{"reassign_rules_to": presentsID})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var result CategoryRetireResult
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if result.RulesReassigned != 2 || result.RulesDisabled != 0 {
+			t.Errorf("Expected both rules reassigned to Presents, got %+v", result)
+		}
+
+		csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-09-05,2024-09-06,1234,CITY FLORIST,,45.00,`
+		resp = uploadTestFile(t, "gifts.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		if names := splitCategories(); len(names) == 0 || names[len(names)-1] != "Presents" {
+			t.Errorf("Expected the new transaction in Presents, got %v", names)
+		}
+	})
+
+	t.Run("should reject invalid retirements", func(t *testing.T) {
+		parentID, err := createTestCategory("Pets", "", "")
+		assertNoError(t, err)
+		childID := createSubcategory("Vet", parentID)
+		retiredID, err := createTestCategory("Old Pets", "", "")
+		assertNoError(t, err)
+		assertStatusCode(t, http.StatusOK, retire(retiredID, nil).Code)
+		fakeID := "550e8400-e29b-41d4-a716-446655440000"
+
+		cases := []struct {
+			categoryID string
+			body       map[string]string
+			status     int
+		}{
+			{testCategoryID("Other"), nil, http.StatusBadRequest},
+			{parentID, map[string]string{"reassign_rules_to": parentID}, http.StatusBadRequest},
+			{parentID, map[string]string{"reassign_rules_to": childID}, http.StatusBadRequest},
+			{parentID, map[string]string{"reassign_rules_to": retiredID}, http.StatusBadRequest},
+			{parentID, map[string]string{"reassign_rules_to": fakeID}, http.StatusBadRequest},
+			{parentID, map[string]string{"reassign_rules_to": "not-a-uuid"}, http.StatusBadRequest},
+			{"not-a-uuid", nil, http.StatusBadRequest},
+			{fakeID, nil, http.StatusNotFound},
+		}
+		for _, tc := range cases {
+			resp := retire(tc.categoryID, tc.body)
+			if resp.Code != tc.status {
+				t.Errorf("Expected %d retiring %s with %v, got %d", tc.status, tc.categoryID, tc.body, resp.Code)
+			}
+		}
+		if _, exists := listed("")["Pets"]; !exists {
+			t.Error("Expected Pets to stay active after rejected retirements")
+		}
+	})
+}
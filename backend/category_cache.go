@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// categoriesChannel is the Postgres notification channel the categories table's
+// trigger notifies on every change
+const categoriesChannel = "categories_changed"
+
+// rulesChannel is the Postgres notification channel the categorization_rules and
+// rule_splits triggers notify on every change the rule matcher depends on
+const rulesChannel = "rules_changed"
+
+// cacheListenerRetryDelay is how long the listener waits before reconnecting
+// after losing its connection
+const cacheListenerRetryDelay = 5 * time.Second
+
+// categoryMappingCache holds the category mapping shared by every request. Every
+// change to the categories calls invalidate, and the mapping is reloaded on next
+// use. A mapping is never modified after it is loaded, so requests can keep using
+// the one they got while it is replaced.
+type categoryMappingCache struct {
+	mu         sync.Mutex
+	mapping    *CategoryMapping
+	fresh      bool
+	generation uint64
+}
+
+// categoryMappings is the shared category mapping cache
+var categoryMappings categoryMappingCache
+
+// get returns the current category mapping, reloading it first if the categories
+// changed. When reloading fails the previous mapping, possibly nil, is returned
+// and the reload is retried on next use.
+func (c *categoryMappingCache) get() *CategoryMapping {
+	c.mu.Lock()
+	mapping, fresh := c.mapping, c.fresh
+	c.mu.Unlock()
+	if fresh {
+		return mapping
+	}
+
+	reloaded, err := c.reload()
+	if err != nil {
+		log.Printf("Warning: failed to reload categories: %v", err)
+		return mapping
+	}
+	return reloaded
+}
+
+// reload loads the categories from the database and caches the mapping, unless
+// they changed again while loading
+func (c *categoryMappingCache) reload() (*CategoryMapping, error) {
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	mapping, err := initializeCategoryMapping()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.mapping = mapping
+		c.fresh = true
+	}
+	c.mu.Unlock()
+	return mapping, nil
+}
+
+// set replaces the cached mapping
+func (c *categoryMappingCache) set(mapping *CategoryMapping) {
+	c.mu.Lock()
+	c.mapping = mapping
+	c.fresh = true
+	c.generation++
+	c.mu.Unlock()
+}
+
+// invalidate marks the cached mapping as out of date. Call it after committing a
+// change to the categories.
+func (c *categoryMappingCache) invalidate() {
+	c.mu.Lock()
+	c.fresh = false
+	c.generation++
+	c.mu.Unlock()
+}
+
+// listenForCacheChanges invalidates the category mapping whenever the categories
+// table changes, and the rule matcher whenever the rules change, including
+// changes made outside this server, until ctx is done. It reconnects after losing
+// its connection.
+func listenForCacheChanges(ctx context.Context, pool *pgxpool.Pool) {
+	for {
+		err := waitForCacheChanges(ctx, pool)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Warning: cache change listener stopped, reconnecting: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cacheListenerRetryDelay):
+		}
+	}
+}
+
+// waitForCacheChanges listens for category and rule changes on a connection of
+// its own and invalidates the cache each one is for, until the connection fails
+func waitForCacheChanges(ctx context.Context, pool *pgxpool.Pool) error {
+	pooled, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection stays in LISTEN mode, so it must not go back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	for _, channel := range []string{categoriesChannel, rulesChannel} {
+		if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+			return err
+		}
+	}
+	// Changes made while not listening were missed
+	categoryMappings.invalidate()
+	ruleMatchers.invalidate()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		switch notification.Channel {
+		case rulesChannel:
+			ruleMatchers.invalidate()
+		default:
+			categoryMappings.invalidate()
+		}
+	}
+}
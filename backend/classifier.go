@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"jointanalysis/db/generated"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// classifierMinConfidence is how sure the classifier must be before its category
+// is used instead of "Other"
+const classifierMinConfidence = 0.6
+
+// defaultCategorySuggestions is how many categories are suggested per transaction
+const defaultCategorySuggestions = 3
+
+// categoryClassifier is a naive Bayes classifier predicting a transaction's
+// category from the words in its description. Each training example counts every
+// distinct word once, weighted by the share of the transaction's amount split to
+// the category, and word likelihoods use add-one smoothing.
+type categoryClassifier struct {
+	categoryWeights map[pgtype.UUID]float64
+	wordWeights     map[pgtype.UUID]map[string]float64
+	wordTotals      map[pgtype.UUID]float64
+	vocabulary      map[string]bool
+	totalWeight     float64
+}
+
+// categoryPrediction is a category the classifier predicts and the probability it
+// assigns to it
+type categoryPrediction struct {
+	CategoryID pgtype.UUID
+	Confidence float64
+}
+
+// descriptionTokens splits a description into distinct lower-case words. Numbers,
+// punctuation and words shorter than three letters, such as store numbers and
+// state codes, carry little meaning and are dropped.
+func descriptionTokens(description string) []string {
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	seen := make(map[string]bool, len(words))
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		if len([]rune(word)) < 3 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// trainCategoryClassifier trains a classifier on categorized transactions. Examples
+// in the excluded category, "Other", are skipped: it means no category was found,
+// so it should never be predicted.
+func trainCategoryClassifier(examples []generated.GetCategoryTrainingExamplesRow, excluded pgtype.UUID) *categoryClassifier {
+	classifier := &categoryClassifier{
+		categoryWeights: make(map[pgtype.UUID]float64),
+		wordWeights:     make(map[pgtype.UUID]map[string]float64),
+		wordTotals:      make(map[pgtype.UUID]float64),
+		vocabulary:      make(map[string]bool),
+	}
+
+	for _, example := range examples {
+		if example.CategoryID == excluded || example.Weight <= 0 {
+			continue
+		}
+		tokens := descriptionTokens(example.Description)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		classifier.categoryWeights[example.CategoryID] += example.Weight
+		classifier.totalWeight += example.Weight
+		words := classifier.wordWeights[example.CategoryID]
+		if words == nil {
+			words = make(map[string]float64)
+			classifier.wordWeights[example.CategoryID] = words
+		}
+		for _, token := range tokens {
+			words[token] += example.Weight
+			classifier.wordTotals[example.CategoryID] += example.Weight
+			classifier.vocabulary[token] = true
+		}
+	}
+
+	return classifier
+}
+
+// classify returns every trained category ordered from most to least likely. It
+// returns nothing when the description has no word seen in training, as the
+// prediction would then rest on category frequency alone.
+func (cc *categoryClassifier) classify(description string) []categoryPrediction {
+	known := make([]string, 0)
+	for _, token := range descriptionTokens(description) {
+		if cc.vocabulary[token] {
+			known = append(known, token)
+		}
+	}
+	if len(known) == 0 {
+		return nil
+	}
+
+	vocabularySize := float64(len(cc.vocabulary))
+	predictions := make([]categoryPrediction, 0, len(cc.categoryWeights))
+	scores := make([]float64, 0, len(cc.categoryWeights))
+	best := math.Inf(-1)
+	for categoryID, weight := range cc.categoryWeights {
+		score := math.Log(weight / cc.totalWeight)
+		for _, token := range known {
+			score += math.Log((cc.wordWeights[categoryID][token] + 1) / (cc.wordTotals[categoryID] + vocabularySize))
+		}
+		predictions = append(predictions, categoryPrediction{CategoryID: categoryID})
+		scores = append(scores, score)
+		best = max(best, score)
+	}
+
+	// Normalize the log scores to probabilities, shifting by the best score so the
+	// exponentials cannot underflow
+	var sum float64
+	for i, score := range scores {
+		predictions[i].Confidence = math.Exp(score - best)
+		sum += predictions[i].Confidence
+	}
+	for i := range predictions {
+		predictions[i].Confidence /= sum
+	}
+
+	sort.Slice(predictions, func(i, j int) bool {
+		if predictions[i].Confidence != predictions[j].Confidence {
+			return predictions[i].Confidence > predictions[j].Confidence
+		}
+		return uuid.UUID(predictions[i].CategoryID.Bytes).String() < uuid.UUID(predictions[j].CategoryID.Bytes).String()
+	})
+	return predictions
+}
+
+// loadCategoryClassifier trains a classifier on the split categories of the
+// transactions whose splits were edited by hand, archived included. Categories
+// chosen by rules or by the classifier itself are not learned from.
+func loadCategoryClassifier(ctx context.Context, mapping *CategoryMapping) (*categoryClassifier, error) {
+	examples, err := queries.GetCategoryTrainingExamples(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var other pgtype.UUID
+	if mapping != nil {
+		other = mapping.categoriesByName["Other"].ID
+	}
+	return trainCategoryClassifier(examples, other), nil
+}
+
+// categoryClassifierCache holds the classifier trained on the current examples,
+// so it is trained once rather than for every upload, preview, rule test and
+// suggestion. Every change to the hand-edited splits calls invalidate, and the
+// classifier is retrained on next use; it is also retrained for a different
+// category mapping, which decides the Other category it skips.
+type categoryClassifierCache struct {
+	mu         sync.Mutex
+	classifier *categoryClassifier
+	mapping    *CategoryMapping
+	generation uint64
+}
+
+// categoryClassifiers is the shared category classifier cache
+var categoryClassifiers categoryClassifierCache
+
+// get returns the classifier for the current examples and the given category
+// mapping. A classifier is never modified after it is trained, so concurrent
+// requests can share it.
+func (c *categoryClassifierCache) get(ctx context.Context, mapping *CategoryMapping) (*categoryClassifier, error) {
+	c.mu.Lock()
+	classifier, trainedFor, generation := c.classifier, c.mapping, c.generation
+	c.mu.Unlock()
+	if classifier != nil && trainedFor == mapping {
+		return classifier, nil
+	}
+
+	classifier, err := loadCategoryClassifier(ctx, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	// Examples changed while these were loading may be missing from them, so the
+	// classifier is only good for this caller
+	c.mu.Lock()
+	if c.generation == generation {
+		c.classifier = classifier
+		c.mapping = mapping
+	}
+	c.mu.Unlock()
+	return classifier, nil
+}
+
+// invalidate discards the cached classifier. Call it after committing a change to
+// hand-edited splits or the transactions they belong to.
+func (c *categoryClassifierCache) invalidate() {
+	c.mu.Lock()
+	c.classifier = nil
+	c.generation++
+	c.mu.Unlock()
+}
+
+// categoryByID returns the category with the given ID, or nil if there is none
+func (cm *CategoryMapping) categoryByID(id pgtype.UUID) *generated.GetCategoriesRow {
+	return cm.categoriesByID[id]
+}
+
+// classifyRecord returns the classifier's category for a record and its confidence,
+// or nil when the classifier is less than classifierMinConfidence sure
+func (cm *CategoryMapping) classifyRecord(classifier *categoryClassifier, record importRecord) (*generated.GetCategoriesRow, float64) {
+	predictions := classifier.classify(record.Description)
+	if len(predictions) == 0 || predictions[0].Confidence < classifierMinConfidence {
+		return nil, 0
+	}
+	category := cm.categoryByID(predictions[0].CategoryID)
+	if category == nil {
+		return nil, 0
+	}
+	return category, predictions[0].Confidence
+}
+
+// @Summary Get category suggestions
+// @Description Suggest categories for active transactions that are entirely in Other, using a naive Bayes classifier trained on the descriptions and split categories of all categorized transactions. Suggestions are ordered from most to least likely; a transaction whose description has no word seen in training gets none.
+// @Tags transactions
+// @Produce json
+// @Param limit query int false "Maximum number of suggestions per transaction (default 3)"
+// @Success 200 {array} TransactionCategorySuggestions "Suggestions for each uncategorized transaction"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/transactions/suggestions [get]
+func getCategorySuggestions(c *gin.Context) {
+	limit := defaultCategorySuggestions
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		limit = parsed
+	}
+
+	result := make([]TransactionCategorySuggestions, 0)
+	categoryMapping := categoryMappings.get()
+	if categoryMapping == nil {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	other, exists := categoryMapping.categoriesByName["Other"]
+	if !exists {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	ctx := context.Background()
+	transactions, err := queries.GetTransactionsInCategory(ctx, other.ID)
+	if err != nil {
+		log.Printf("Error fetching uncategorized transactions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error suggesting categories"})
+		return
+	}
+	classifier, err := categoryClassifiers.get(ctx, categoryMapping)
+	if err != nil {
+		log.Printf("Error training category classifier: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error suggesting categories"})
+		return
+	}
+
+	for _, t := range transactions {
+		amount, _ := t.Amount.Float64Value()
+		item := TransactionCategorySuggestions{
+			TransactionID: uuid.UUID(t.ID.Bytes).String(),
+			Description:   t.Description,
+			Amount:        amount.Float64,
+			Suggestions:   make([]CategorySuggestion, 0, limit),
+		}
+		if t.TransactionDate.Valid {
+			transactionDate := t.TransactionDate.Time.Format("2006-01-02")
+			item.TransactionDate = &transactionDate
+		}
+		for _, prediction := range classifier.classify(t.Description) {
+			if len(item.Suggestions) == limit {
+				break
+			}
+			category := categoryMapping.categoryByID(prediction.CategoryID)
+			if category == nil {
+				continue
+			}
+			item.Suggestions = append(item.Suggestions, CategorySuggestion{
+				CategoryID:   uuid.UUID(category.ID.Bytes).String(),
+				CategoryName: category.Name,
+				Confidence:   prediction.Confidence,
+			})
+		}
+		result = append(result, item)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"jointanalysis/db/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescriptionTokens(t *testing.T) {
+	assert.Equal(t, []string{"blue", "bottle", "coffee"}, descriptionTokens("BLUE BOTTLE COFFEE #123 SF CA"))
+	assert.Equal(t, []string{"amazon", "mktp", "amzn", "com"}, descriptionTokens("AMAZON MKTP*AB12 AMZN.COM AMAZON"))
+	assert.Equal(t, []string{"café"}, descriptionTokens("Café 42"))
+	assert.Empty(t, descriptionTokens("12345 - #6"))
+}
+
+func TestCategoryClassifier(t *testing.T) {
+	food := generated.GetCategoriesRow{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, Name: "Food & Dining"}
+	shopping := generated.GetCategoriesRow{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, Name: "Shopping"}
+	other := generated.GetCategoriesRow{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, Name: "Other"}
+	example := func(description string, category generated.GetCategoriesRow, weight float64) generated.GetCategoryTrainingExamplesRow {
+		return generated.GetCategoryTrainingExamplesRow{Description: description, CategoryID: category.ID, Weight: weight}
+	}
+	classifier := trainCategoryClassifier([]generated.GetCategoryTrainingExamplesRow{
+		example("BLUE BOTTLE COFFEE", food, 1),
+		example("CORNER COFFEE SHOP", food, 1),
+		example("CITY BOOKS", shopping, 1),
+		example("BOOKS PLUS", shopping, 1),
+		example("HARDWARE STORE", other, 1),
+	}, other.ID)
+
+	t.Run("should rank categories by probability", func(t *testing.T) {
+		predictions := classifier.classify("BLUE BOTTLE SF")
+		require.Len(t, predictions, 2)
+		assert.Equal(t, food.ID, predictions[0].CategoryID)
+		assert.InDelta(t, 144.0/193, predictions[0].Confidence, 1e-9)
+		assert.InDelta(t, 1, predictions[0].Confidence+predictions[1].Confidence, 1e-9)
+
+		predictions = classifier.classify("books & more")
+		require.Len(t, predictions, 2)
+		assert.Equal(t, shopping.ID, predictions[0].CategoryID)
+	})
+
+	t.Run("should not predict without known words or from Other", func(t *testing.T) {
+		assert.Empty(t, classifier.classify("HARDWARE STORE"))
+		assert.Empty(t, classifier.classify("1234"))
+		assert.Empty(t, trainCategoryClassifier(nil, other.ID).classify("COFFEE"))
+	})
+
+	t.Run("should weight examples by their split share", func(t *testing.T) {
+		split := trainCategoryClassifier([]generated.GetCategoryTrainingExamplesRow{
+			example("TEAM LUNCH", food, 0.25),
+			example("TEAM LUNCH", shopping, 0.75),
+		}, other.ID)
+		predictions := split.classify("TEAM LUNCH")
+		require.Len(t, predictions, 2)
+		assert.Equal(t, shopping.ID, predictions[0].CategoryID)
+	})
+
+	t.Run("should only use confident predictions", func(t *testing.T) {
+		mapping := newCategoryMapping([]generated.GetCategoriesRow{food, shopping, other})
+
+		category, confidence := mapping.classifyRecord(classifier, importRecord{Description: "BLUE BOTTLE"})
+		require.NotNil(t, category)
+		assert.Equal(t, food.Name, category.Name)
+		assert.Greater(t, confidence, classifierMinConfidence)
+
+		// Coffee points to Food & Dining and city to Shopping, about evenly
+		category, _ = mapping.classifyRecord(classifier, importRecord{Description: "CITY COFFEE"})
+		assert.Nil(t, category)
+	})
+}
+
+func TestCategorySuggestions(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	foodID := testCategoryID("Food & Dining")
+	shoppingID := testCategoryID("Shopping")
+
+	// The history is imported as Other and categorized by hand, which is what the
+	// classifier learns from. HARDWARE STORE is left as imported.
+	history := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-05-01,2024-05-02,1234,BLUE BOTTLE COFFEE,,5.00,
+2024-05-02,2024-05-03,1234,CORNER COFFEE SHOP,,4.00,
+2024-05-03,2024-05-04,1234,CITY BOOKS,,20.00,
+2024-05-04,2024-05-05,1234,BOOKS PLUS,,15.00,
+2024-05-05,2024-05-06,1234,HARDWARE STORE,,12.00,`
+	resp := uploadTestFile(t, "history.csv", history, nil)
+	assertStatusCode(t, http.StatusOK, resp.Code)
+	var imported struct {
+		Transactions []Transaction `json:"transactions"`
+	}
+	assertNoError(t, parseJSONResponse(resp, &imported))
+	if len(imported.Transactions) != 5 {
+		t.Fatalf("Expected 5 transactions, got %d", len(imported.Transactions))
+	}
+	for i, categoryID := range []string{foodID, foodID, shoppingID, shoppingID} {
+		transaction := imported.Transactions[i]
+		body, err := json.Marshal(map[string]interface{}{
+			"splits": []map[string]interface{}{{"category_id": categoryID, "amount": transaction.Amount}},
+		})
+		assertNoError(t, err)
+		resp = makeRequest("PUT", "/api/transactions/"+transaction.ID+"/splits", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+	}
+
+	csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-06-01,2024-06-02,1234,BLUE BOTTLE SF,,6.00,
+2024-06-02,2024-06-03,1234,CITY COFFEE,,3.00,`
+
+	t.Run("should fall back to the classifier on import", func(t *testing.T) {
+		body, contentType := createCSVFile(t, "new.csv", csvContent)
+		req, err := http.NewRequest("POST", "/api/upload-csv/preview", body)
+		assertNoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		resp := makeRequestWithCustomRequest(req)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result struct {
+			Rows []ImportPreviewRow `json:"rows"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if len(result.Rows) != 2 {
+			t.Fatalf("Expected 2 preview rows, got %d", len(result.Rows))
+		}
+		blueBottle, cityCoffee := result.Rows[0], result.Rows[1]
+		if blueBottle.CategoryName == nil || *blueBottle.CategoryName != "Food & Dining" || blueBottle.Confidence == nil {
+			t.Errorf("Expected BLUE BOTTLE to be classified as Food & Dining, got %+v", blueBottle)
+		}
+		if cityCoffee.CategoryName == nil || *cityCoffee.CategoryName != "Other" || cityCoffee.Confidence != nil {
+			t.Errorf("Expected the uncertain CITY COFFEE to stay in Other, got %+v", cityCoffee)
+		}
+	})
+
+	t.Run("should report the classifier in rule tests", func(t *testing.T) {
+		body, err := json.Marshal(map[string]interface{}{"description": "BLUE BOTTLE SF", "amount": 6})
+		assertNoError(t, err)
+		resp := makeRequest("POST", "/api/rules/test", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result RuleTestResult
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if result.Fallback || result.Confidence == nil || result.CategoryName == nil || *result.CategoryName != "Food & Dining" {
+			t.Errorf("Expected the classifier to choose Food & Dining without the Other fallback, got %+v", result)
+		}
+	})
+
+	t.Run("should only learn from hand-edited splits", func(t *testing.T) {
+		resp := uploadTestFile(t, "classified.csv", `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-06-05,2024-06-06,1234,BLUE BOTTLE OAKLAND,,7.00,`, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		examples, err := testQueries.GetCategoryTrainingExamples(context.Background())
+		assertNoError(t, err)
+		descriptions := make([]string, 0, len(examples))
+		for _, example := range examples {
+			descriptions = append(descriptions, example.Description)
+		}
+		assert.ElementsMatch(t, []string{"BLUE BOTTLE COFFEE", "CORNER COFFEE SHOP", "CITY BOOKS", "BOOKS PLUS"}, descriptions,
+			"the classifier's own choice and the unedited Other transaction are not examples")
+
+		resp = makeRequest("DELETE", "/api/imports/"+importIDOf(t, resp), nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("should suggest categories for Other transactions", func(t *testing.T) {
+		resp := uploadTestFile(t, "new.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		resp = makeRequest("GET", "/api/transactions/suggestions", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var suggestions []TransactionCategorySuggestions
+		assertNoError(t, parseJSONResponse(resp, &suggestions))
+		byDescription := make(map[string]TransactionCategorySuggestions, len(suggestions))
+		for _, item := range suggestions {
+			byDescription[item.Description] = item
+		}
+		if len(suggestions) != 2 {
+			t.Fatalf("Expected suggestions for CITY COFFEE and HARDWARE STORE, got %+v", suggestions)
+		}
+
+		cityCoffee := byDescription["CITY COFFEE"]
+		if len(cityCoffee.Suggestions) != 2 || cityCoffee.Suggestions[0].CategoryName != "Food & Dining" || cityCoffee.Suggestions[1].CategoryName != "Shopping" {
+			t.Errorf("Expected Food & Dining then Shopping for CITY COFFEE, got %+v", cityCoffee.Suggestions)
+		}
+		if len(byDescription["HARDWARE STORE"].Suggestions) != 0 {
+			t.Errorf("Expected no suggestions for unseen words, got %+v", byDescription["HARDWARE STORE"].Suggestions)
+		}
+
+		resp = makeRequest("GET", "/api/transactions/suggestions?limit=1", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		assertNoError(t, parseJSONResponse(resp, &suggestions))
+		for _, item := range suggestions {
+			if len(item.Suggestions) > 1 {
+				t.Errorf("Expected at most 1 suggestion, got %+v", item.Suggestions)
+			}
+		}
+
+		resp = makeRequest("GET", "/api/transactions/suggestions?limit=0", nil)
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+}
@@ -43,23 +43,91 @@ type ArchivePersonTotal struct {
 	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
 }
 
-type CategorizationRule struct {
+type Budget struct {
 	ID         pgtype.UUID      `json:"id"`
-	MatchValue string           `json:"match_value"`
 	CategoryID pgtype.UUID      `json:"category_id"`
-	Priority   int32            `json:"priority"`
+	PersonID   pgtype.UUID      `json:"person_id"`
+	Month      pgtype.Date      `json:"month"`
+	Amount     pgtype.Numeric   `json:"amount"`
+	Rollover   bool             `json:"rollover"`
 	CreatedAt  pgtype.Timestamp `json:"created_at"`
 	UpdatedAt  pgtype.Timestamp `json:"updated_at"`
 }
 
+type CategorizationRule struct {
+	ID            pgtype.UUID      `json:"id"`
+	MatchValue    string           `json:"match_value"`
+	CategoryID    pgtype.UUID      `json:"category_id"`
+	Priority      int32            `json:"priority"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
+	MatchType     string           `json:"match_type"`
+	MatchField    string           `json:"match_field"`
+	MinAmount     pgtype.Numeric   `json:"min_amount"`
+	MaxAmount     pgtype.Numeric   `json:"max_amount"`
+	Direction     pgtype.Text      `json:"direction"`
+	CardNumber    pgtype.Text      `json:"card_number"`
+	StartDate     pgtype.Date      `json:"start_date"`
+	EndDate       pgtype.Date      `json:"end_date"`
+	AssignTo      []pgtype.UUID    `json:"assign_to"`
+	HitCount      int32            `json:"hit_count"`
+	LastMatchedAt pgtype.Timestamp `json:"last_matched_at"`
+	OverrideCount int32            `json:"override_count"`
+}
+
 type Category struct {
-	ID          pgtype.UUID      `json:"id"`
-	Name        string           `json:"name"`
-	Description pgtype.Text      `json:"description"`
-	Color       pgtype.Text      `json:"color"`
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
-	ParentID    pgtype.UUID      `json:"parent_id"`
+	ID                pgtype.UUID      `json:"id"`
+	Name              string           `json:"name"`
+	Description       pgtype.Text      `json:"description"`
+	Color             pgtype.Text      `json:"color"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+	ParentID          pgtype.UUID      `json:"parent_id"`
+	RetiredAt         pgtype.Timestamp `json:"retired_at"`
+	ExcludeFromTotals bool             `json:"exclude_from_totals"`
+}
+
+type Import struct {
+	ID           pgtype.UUID      `json:"id"`
+	FileName     pgtype.Text      `json:"file_name"`
+	Format       string           `json:"format"`
+	ImportedRows int32            `json:"imported_rows"`
+	SkippedRows  int32            `json:"skipped_rows"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+	FileHash     pgtype.Text      `json:"file_hash"`
+	TotalRows    int32            `json:"total_rows"`
+	UploadedBy   pgtype.UUID      `json:"uploaded_by"`
+}
+
+type ImportProfile struct {
+	ID                    pgtype.UUID      `json:"id"`
+	Name                  string           `json:"name"`
+	Delimiter             string           `json:"delimiter"`
+	HasHeader             bool             `json:"has_header"`
+	HeaderColumns         []string         `json:"header_columns"`
+	DateFormat            string           `json:"date_format"`
+	AmountFormat          string           `json:"amount_format"`
+	ExpenseSign           string           `json:"expense_sign"`
+	TransactionDateColumn pgtype.Int4      `json:"transaction_date_column"`
+	PostedDateColumn      pgtype.Int4      `json:"posted_date_column"`
+	CardNumberColumn      pgtype.Int4      `json:"card_number_column"`
+	DescriptionColumn     int32            `json:"description_column"`
+	CategoryColumn        pgtype.Int4      `json:"category_column"`
+	AmountColumn          pgtype.Int4      `json:"amount_column"`
+	DebitColumn           pgtype.Int4      `json:"debit_column"`
+	CreditColumn          pgtype.Int4      `json:"credit_column"`
+	CreatedAt             pgtype.Timestamp `json:"created_at"`
+	UpdatedAt             pgtype.Timestamp `json:"updated_at"`
+	DecimalSeparator      string           `json:"decimal_separator"`
+}
+
+type ImportRejection struct {
+	ID         pgtype.UUID `json:"id"`
+	ImportID   pgtype.UUID `json:"import_id"`
+	LineNumber int32       `json:"line_number"`
+	RawContent string      `json:"raw_content"`
+	Reason     string      `json:"reason"`
+	Message    string      `json:"message"`
 }
 
 type Person struct {
@@ -70,6 +138,22 @@ type Person struct {
 	UpdatedAt pgtype.Timestamp `json:"updated_at"`
 }
 
+type RuleSplit struct {
+	ID         pgtype.UUID      `json:"id"`
+	RuleID     pgtype.UUID      `json:"rule_id"`
+	CategoryID pgtype.UUID      `json:"category_id"`
+	Percentage pgtype.Numeric   `json:"percentage"`
+	Position   int32            `json:"position"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+}
+
+type SplitPersonShare struct {
+	SplitID       pgtype.UUID    `json:"split_id"`
+	TransactionID pgtype.UUID    `json:"transaction_id"`
+	PersonID      pgtype.UUID    `json:"person_id"`
+	Share         pgtype.Numeric `json:"share"`
+}
+
 type Transaction struct {
 	ID              pgtype.UUID      `json:"id"`
 	Description     string           `json:"description"`
@@ -83,6 +167,27 @@ type Transaction struct {
 	CreatedAt       pgtype.Timestamp `json:"created_at"`
 	UpdatedAt       pgtype.Timestamp `json:"updated_at"`
 	ArchiveID       pgtype.UUID      `json:"archive_id"`
+	ExternalID      pgtype.Text      `json:"external_id"`
+	ImportID        pgtype.UUID      `json:"import_id"`
+	RuleID          pgtype.UUID      `json:"rule_id"`
+	SplitsEditedAt  pgtype.Timestamp `json:"splits_edited_at"`
+	CsvCategory     pgtype.Text      `json:"csv_category"`
+}
+
+type TransactionPersonShare struct {
+	TransactionID pgtype.UUID    `json:"transaction_id"`
+	PersonID      pgtype.UUID    `json:"person_id"`
+	Share         pgtype.Numeric `json:"share"`
+}
+
+type TransactionShare struct {
+	ID            pgtype.UUID      `json:"id"`
+	TransactionID pgtype.UUID      `json:"transaction_id"`
+	PersonID      pgtype.UUID      `json:"person_id"`
+	Weight        pgtype.Numeric   `json:"weight"`
+	FixedAmount   pgtype.Numeric   `json:"fixed_amount"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
 }
 
 type TransactionSplit struct {
@@ -93,4 +198,5 @@ type TransactionSplit struct {
 	Notes         pgtype.Text      `json:"notes"`
 	CreatedAt     pgtype.Timestamp `json:"created_at"`
 	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
+	AssignedTo    []pgtype.UUID    `json:"assigned_to"`
 }
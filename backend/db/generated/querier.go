@@ -13,24 +13,44 @@ import (
 type Querier interface {
 	AddPersonToTransaction(ctx context.Context, arg AddPersonToTransactionParams) (AddPersonToTransactionRow, error)
 	ArchiveTransactions(ctx context.Context, archiveID pgtype.UUID) error
+	CountArchivedTransactionsByImportID(ctx context.Context, importID pgtype.UUID) (int64, error)
 	// Archive queries
 	CreateArchive(ctx context.Context, arg CreateArchiveParams) (Archive, error)
 	// Archive person totals queries
 	CreateArchivePersonTotal(ctx context.Context, arg CreateArchivePersonTotalParams) (ArchivePersonTotal, error)
+	CreateBudget(ctx context.Context, arg CreateBudgetParams) (Budget, error)
 	CreateCategory(ctx context.Context, arg CreateCategoryParams) (CreateCategoryRow, error)
+	// Import queries
+	CreateImport(ctx context.Context, arg CreateImportParams) (Import, error)
+	CreateImportProfile(ctx context.Context, arg CreateImportProfileParams) (ImportProfile, error)
+	CreateImportRejections(ctx context.Context, arg []CreateImportRejectionsParams) (int64, error)
 	CreatePerson(ctx context.Context, arg CreatePersonParams) (Person, error)
 	CreateRule(ctx context.Context, arg CreateRuleParams) (CategorizationRule, error)
-	CreateTransaction(ctx context.Context, arg CreateTransactionParams) (CreateTransactionRow, error)
+	CreateRuleSplit(ctx context.Context, arg CreateRuleSplitParams) error
+	CreateTransactionShare(ctx context.Context, arg CreateTransactionShareParams) error
 	CreateTransactionSplit(ctx context.Context, arg CreateTransactionSplitParams) (TransactionSplit, error)
+	CreateTransactionSplits(ctx context.Context, arg []CreateTransactionSplitsParams) (int64, error)
+	CreateTransactions(ctx context.Context, arg []CreateTransactionsParams) (int64, error)
+	DeleteAllRules(ctx context.Context) (int64, error)
 	DeleteAllTransactions(ctx context.Context) error
 	DeleteArchive(ctx context.Context, id pgtype.UUID) error
 	DeleteArchivePersonTotals(ctx context.Context, archiveID pgtype.UUID) error
+	DeleteBudget(ctx context.Context, id pgtype.UUID) (int64, error)
 	DeleteCategory(ctx context.Context, id pgtype.UUID) error
+	DeleteImport(ctx context.Context, id pgtype.UUID) error
+	DeleteImportProfile(ctx context.Context, id pgtype.UUID) error
 	DeletePerson(ctx context.Context, id pgtype.UUID) error
 	DeleteRule(ctx context.Context, id pgtype.UUID) error
+	DeleteRuleSplits(ctx context.Context, ruleID pgtype.UUID) error
 	DeleteTransaction(ctx context.Context, id pgtype.UUID) error
+	DeleteTransactionShares(ctx context.Context, transactionID pgtype.UUID) error
 	DeleteTransactionSplitsByTransactionID(ctx context.Context, transactionID pgtype.UUID) error
-	FindDuplicateTransaction(ctx context.Context, arg FindDuplicateTransactionParams) (int64, error)
+	DeleteTransactionSplitsByTransactionIDs(ctx context.Context, transactionIds []pgtype.UUID) error
+	DeleteTransactionsByImportID(ctx context.Context, importID pgtype.UUID) (int64, error)
+	// Seen is how many times each row has appeared in the file so far, itself included.
+	// Rows the import has already saved from earlier batches are not existing copies.
+	FindDuplicateImportRows(ctx context.Context, arg FindDuplicateImportRowsParams) ([]int32, error)
+	FindImportByFileHash(ctx context.Context, fileHash pgtype.Text) (pgtype.UUID, error)
 	GetActiveTransactionGrandTotal(ctx context.Context) (pgtype.Numeric, error)
 	GetActiveTransactionTotals(ctx context.Context) ([]GetActiveTransactionTotalsRow, error)
 	GetActiveTransactions(ctx context.Context) ([]GetActiveTransactionsRow, error)
@@ -38,32 +58,79 @@ type Querier interface {
 	GetArchivePersonTotals(ctx context.Context, archiveID pgtype.UUID) ([]GetArchivePersonTotalsRow, error)
 	GetArchivedTransactions(ctx context.Context, archiveID pgtype.UUID) ([]GetArchivedTransactionsRow, error)
 	GetArchives(ctx context.Context) ([]Archive, error)
+	GetBudgetByID(ctx context.Context, id pgtype.UUID) (GetBudgetByIDRow, error)
+	// Budget queries
+	GetBudgets(ctx context.Context) ([]GetBudgetsRow, error)
 	// Categories queries
 	GetCategories(ctx context.Context) ([]GetCategoriesRow, error)
 	GetCategoryByID(ctx context.Context, id pgtype.UUID) (GetCategoryByIDRow, error)
 	GetCategoryByName(ctx context.Context, name string) (GetCategoryByNameRow, error)
+	// Category classifier queries
+	GetCategoryTrainingExamples(ctx context.Context) ([]GetCategoryTrainingExamplesRow, error)
+	GetImportByID(ctx context.Context, id pgtype.UUID) (GetImportByIDRow, error)
+	GetImportProfileByID(ctx context.Context, id pgtype.UUID) (ImportProfile, error)
+	GetImportProfileByName(ctx context.Context, name string) (ImportProfile, error)
+	// Import profile queries
+	GetImportProfiles(ctx context.Context) ([]ImportProfile, error)
+	GetImportRejectionsByImportID(ctx context.Context, importID pgtype.UUID) ([]ImportRejection, error)
+	GetImports(ctx context.Context) ([]GetImportsRow, error)
+	GetMonthlyCategorySpending(ctx context.Context, arg GetMonthlyCategorySpendingParams) ([]GetMonthlyCategorySpendingRow, error)
+	GetMonthlyCategorySpendingByPerson(ctx context.Context, arg GetMonthlyCategorySpendingByPersonParams) ([]GetMonthlyCategorySpendingByPersonRow, error)
 	// People queries
 	GetPeople(ctx context.Context) ([]Person, error)
 	GetPersonByID(ctx context.Context, id pgtype.UUID) (Person, error)
 	GetPersonByName(ctx context.Context, name string) (Person, error)
 	GetRuleByID(ctx context.Context, id pgtype.UUID) (GetRuleByIDRow, error)
+	// Rule split template queries
+	GetRuleSplits(ctx context.Context) ([]GetRuleSplitsRow, error)
+	GetRuleSplitsByRuleID(ctx context.Context, ruleID pgtype.UUID) ([]GetRuleSplitsByRuleIDRow, error)
 	// Categorization rules queries
 	GetRules(ctx context.Context) ([]GetRulesRow, error)
 	GetRulesForMatching(ctx context.Context) ([]GetRulesForMatchingRow, error)
+	GetSplitsForRuleApplication(ctx context.Context, arg GetSplitsForRuleApplicationParams) ([]GetSplitsForRuleApplicationRow, error)
 	GetSubcategoriesByParent(ctx context.Context, parentID pgtype.UUID) ([]GetSubcategoriesByParentRow, error)
 	GetTopLevelCategories(ctx context.Context) ([]GetTopLevelCategoriesRow, error)
 	GetTotalsByAssignedTo(ctx context.Context) ([]GetTotalsByAssignedToRow, error)
 	GetTotalsByCategory(ctx context.Context) ([]GetTotalsByCategoryRow, error)
 	GetTransactionByID(ctx context.Context, id pgtype.UUID) (GetTransactionByIDRow, error)
+	// The part of a transaction that its fixed amounts are taken from
+	GetTransactionSharedAmount(ctx context.Context, transactionID pgtype.UUID) (pgtype.Numeric, error)
+	GetTransactionShares(ctx context.Context, transactionID pgtype.UUID) ([]GetTransactionSharesRow, error)
 	GetTransactionSplitsByTransactionID(ctx context.Context, transactionID pgtype.UUID) ([]TransactionSplit, error)
 	// Transactions queries
 	GetTransactions(ctx context.Context) ([]GetTransactionsRow, error)
 	GetTransactionsByAssignedTo(ctx context.Context, assignedTo []pgtype.UUID) ([]GetTransactionsByAssignedToRow, error)
 	GetTransactionsByFileName(ctx context.Context, fileName pgtype.Text) ([]GetTransactionsByFileNameRow, error)
+	// Retroactive rule application queries
+	GetTransactionsForRuleApplication(ctx context.Context, arg GetTransactionsForRuleApplicationParams) ([]GetTransactionsForRuleApplicationRow, error)
+	GetTransactionsInCategory(ctx context.Context, categoryID pgtype.UUID) ([]GetTransactionsInCategoryRow, error)
+	MarkTransactionSplitsEdited(ctx context.Context, id pgtype.UUID) error
+	// Budgets for the same person and month as a target budget are added to it
+	MoveBudgetsToCategory(ctx context.Context, arg MoveBudgetsToCategoryParams) (int64, error)
+	MoveRuleSplitsToCategory(ctx context.Context, arg MoveRuleSplitsToCategoryParams) (int64, error)
+	MoveRulesToCategory(ctx context.Context, arg MoveRulesToCategoryParams) (int64, error)
+	// Category merge queries
+	MoveTransactionSplitsToCategory(ctx context.Context, arg MoveTransactionSplitsToCategoryParams) (int64, error)
+	RecordRuleHits(ctx context.Context, arg RecordRuleHitsParams) error
+	// Counts the first manual edit of a rule-categorized transaction that moves money
+	// to a category the rule never assigns
+	RecordRuleOverride(ctx context.Context, arg RecordRuleOverrideParams) error
+	// Takes back the hits of the rules that categorized an import's transactions
+	RemoveImportRuleHits(ctx context.Context, importID pgtype.UUID) error
+	RemovePersonFromRules(ctx context.Context, arrayRemove interface{}) error
 	RemovePersonFromTransaction(ctx context.Context, arg RemovePersonFromTransactionParams) (RemovePersonFromTransactionRow, error)
+	ReparentSubcategories(ctx context.Context, arg ReparentSubcategoriesParams) (int64, error)
+	RestoreCategory(ctx context.Context, id pgtype.UUID) (int64, error)
+	// Category retirement queries
+	RetireCategory(ctx context.Context, id pgtype.UUID) (int64, error)
+	SetTransactionRules(ctx context.Context, arg SetTransactionRulesParams) error
+	UnassignTransactionSplitsByPerson(ctx context.Context, arrayRemove interface{}) error
 	UnassignTransactionsByPerson(ctx context.Context, arrayRemove interface{}) error
 	UpdateArchiveTotals(ctx context.Context, arg UpdateArchiveTotalsParams) (Archive, error)
+	UpdateBudget(ctx context.Context, arg UpdateBudgetParams) (Budget, error)
 	UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (UpdateCategoryRow, error)
+	UpdateImportCounts(ctx context.Context, arg UpdateImportCountsParams) error
+	UpdateImportProfile(ctx context.Context, arg UpdateImportProfileParams) (ImportProfile, error)
 	UpdatePerson(ctx context.Context, arg UpdatePersonParams) (Person, error)
 	UpdateRule(ctx context.Context, arg UpdateRuleParams) (CategorizationRule, error)
 	UpdateTransactionAssignment(ctx context.Context, arg UpdateTransactionAssignmentParams) (UpdateTransactionAssignmentRow, error)
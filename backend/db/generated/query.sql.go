@@ -69,6 +69,20 @@ func (q *Queries) ArchiveTransactions(ctx context.Context, archiveID pgtype.UUID
 	return err
 }
 
+const countArchivedTransactionsByImportID = `-- name: CountArchivedTransactionsByImportID :one
+SELECT COUNT(*)
+FROM transactions
+WHERE import_id = $1
+  AND archive_id IS NOT NULL
+`
+
+func (q *Queries) CountArchivedTransactionsByImportID(ctx context.Context, importID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countArchivedTransactionsByImportID, importID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createArchive = `-- name: CreateArchive :one
 INSERT INTO archives (description, transaction_count, total_amount)
 VALUES ($1, $2, $3)
@@ -124,27 +138,65 @@ func (q *Queries) CreateArchivePersonTotal(ctx context.Context, arg CreateArchiv
 	return i, err
 }
 
+const createBudget = `-- name: CreateBudget :one
+INSERT INTO budgets (category_id, person_id, month, amount, rollover)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, category_id, person_id, month, amount, rollover, created_at, updated_at
+`
+
+type CreateBudgetParams struct {
+	CategoryID pgtype.UUID    `json:"category_id"`
+	PersonID   pgtype.UUID    `json:"person_id"`
+	Month      pgtype.Date    `json:"month"`
+	Amount     pgtype.Numeric `json:"amount"`
+	Rollover   bool           `json:"rollover"`
+}
+
+func (q *Queries) CreateBudget(ctx context.Context, arg CreateBudgetParams) (Budget, error) {
+	row := q.db.QueryRow(ctx, createBudget,
+		arg.CategoryID,
+		arg.PersonID,
+		arg.Month,
+		arg.Amount,
+		arg.Rollover,
+	)
+	var i Budget
+	err := row.Scan(
+		&i.ID,
+		&i.CategoryID,
+		&i.PersonID,
+		&i.Month,
+		&i.Amount,
+		&i.Rollover,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const createCategory = `-- name: CreateCategory :one
-INSERT INTO categories (name, description, color, parent_id)
-VALUES ($1, $2, $3, $4)
-RETURNING id, name, description, color, parent_id, created_at, updated_at
+INSERT INTO categories (name, description, color, parent_id, exclude_from_totals)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, name, description, color, parent_id, created_at, updated_at, exclude_from_totals
 `
 
 type CreateCategoryParams struct {
-	Name        string      `json:"name"`
-	Description pgtype.Text `json:"description"`
-	Color       pgtype.Text `json:"color"`
-	ParentID    pgtype.UUID `json:"parent_id"`
+	Name              string      `json:"name"`
+	Description       pgtype.Text `json:"description"`
+	Color             pgtype.Text `json:"color"`
+	ParentID          pgtype.UUID `json:"parent_id"`
+	ExcludeFromTotals bool        `json:"exclude_from_totals"`
 }
 
 type CreateCategoryRow struct {
-	ID          pgtype.UUID      `json:"id"`
-	Name        string           `json:"name"`
-	Description pgtype.Text      `json:"description"`
-	Color       pgtype.Text      `json:"color"`
-	ParentID    pgtype.UUID      `json:"parent_id"`
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+	ID                pgtype.UUID      `json:"id"`
+	Name              string           `json:"name"`
+	Description       pgtype.Text      `json:"description"`
+	Color             pgtype.Text      `json:"color"`
+	ParentID          pgtype.UUID      `json:"parent_id"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+	ExcludeFromTotals bool             `json:"exclude_from_totals"`
 }
 
 func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams) (CreateCategoryRow, error) {
@@ -153,6 +205,7 @@ func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams)
 		arg.Description,
 		arg.Color,
 		arg.ParentID,
+		arg.ExcludeFromTotals,
 	)
 	var i CreateCategoryRow
 	err := row.Scan(
@@ -163,10 +216,131 @@ func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams)
 		&i.ParentID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExcludeFromTotals,
+	)
+	return i, err
+}
+
+const createImport = `-- name: CreateImport :one
+INSERT INTO imports (file_name, format, file_hash, uploaded_by)
+VALUES ($1, $2, $3, $4)
+RETURNING id, file_name, format, imported_rows, skipped_rows, created_at, file_hash, total_rows, uploaded_by
+`
+
+type CreateImportParams struct {
+	FileName   pgtype.Text `json:"file_name"`
+	Format     string      `json:"format"`
+	FileHash   pgtype.Text `json:"file_hash"`
+	UploadedBy pgtype.UUID `json:"uploaded_by"`
+}
+
+// Import queries
+func (q *Queries) CreateImport(ctx context.Context, arg CreateImportParams) (Import, error) {
+	row := q.db.QueryRow(ctx, createImport,
+		arg.FileName,
+		arg.Format,
+		arg.FileHash,
+		arg.UploadedBy,
+	)
+	var i Import
+	err := row.Scan(
+		&i.ID,
+		&i.FileName,
+		&i.Format,
+		&i.ImportedRows,
+		&i.SkippedRows,
+		&i.CreatedAt,
+		&i.FileHash,
+		&i.TotalRows,
+		&i.UploadedBy,
+	)
+	return i, err
+}
+
+const createImportProfile = `-- name: CreateImportProfile :one
+INSERT INTO import_profiles (
+    name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+    transaction_date_column, posted_date_column, card_number_column, description_column,
+    category_column, amount_column, debit_column, credit_column, decimal_separator
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+RETURNING id, name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+          transaction_date_column, posted_date_column, card_number_column, description_column,
+          category_column, amount_column, debit_column, credit_column, created_at, updated_at,
+          decimal_separator
+`
+
+type CreateImportProfileParams struct {
+	Name                  string      `json:"name"`
+	Delimiter             string      `json:"delimiter"`
+	HasHeader             bool        `json:"has_header"`
+	HeaderColumns         []string    `json:"header_columns"`
+	DateFormat            string      `json:"date_format"`
+	AmountFormat          string      `json:"amount_format"`
+	ExpenseSign           string      `json:"expense_sign"`
+	TransactionDateColumn pgtype.Int4 `json:"transaction_date_column"`
+	PostedDateColumn      pgtype.Int4 `json:"posted_date_column"`
+	CardNumberColumn      pgtype.Int4 `json:"card_number_column"`
+	DescriptionColumn     int32       `json:"description_column"`
+	CategoryColumn        pgtype.Int4 `json:"category_column"`
+	AmountColumn          pgtype.Int4 `json:"amount_column"`
+	DebitColumn           pgtype.Int4 `json:"debit_column"`
+	CreditColumn          pgtype.Int4 `json:"credit_column"`
+	DecimalSeparator      string      `json:"decimal_separator"`
+}
+
+func (q *Queries) CreateImportProfile(ctx context.Context, arg CreateImportProfileParams) (ImportProfile, error) {
+	row := q.db.QueryRow(ctx, createImportProfile,
+		arg.Name,
+		arg.Delimiter,
+		arg.HasHeader,
+		arg.HeaderColumns,
+		arg.DateFormat,
+		arg.AmountFormat,
+		arg.ExpenseSign,
+		arg.TransactionDateColumn,
+		arg.PostedDateColumn,
+		arg.CardNumberColumn,
+		arg.DescriptionColumn,
+		arg.CategoryColumn,
+		arg.AmountColumn,
+		arg.DebitColumn,
+		arg.CreditColumn,
+		arg.DecimalSeparator,
+	)
+	var i ImportProfile
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Delimiter,
+		&i.HasHeader,
+		&i.HeaderColumns,
+		&i.DateFormat,
+		&i.AmountFormat,
+		&i.ExpenseSign,
+		&i.TransactionDateColumn,
+		&i.PostedDateColumn,
+		&i.CardNumberColumn,
+		&i.DescriptionColumn,
+		&i.CategoryColumn,
+		&i.AmountColumn,
+		&i.DebitColumn,
+		&i.CreditColumn,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DecimalSeparator,
 	)
 	return i, err
 }
 
+type CreateImportRejectionsParams struct {
+	ImportID   pgtype.UUID `json:"import_id"`
+	LineNumber int32       `json:"line_number"`
+	RawContent string      `json:"raw_content"`
+	Reason     string      `json:"reason"`
+	Message    string      `json:"message"`
+}
+
 const createPerson = `-- name: CreatePerson :one
 INSERT INTO people (name, email)
 VALUES ($1, $2)
@@ -192,19 +366,46 @@ func (q *Queries) CreatePerson(ctx context.Context, arg CreatePersonParams) (Per
 }
 
 const createRule = `-- name: CreateRule :one
-INSERT INTO categorization_rules (match_value, category_id, priority)
-VALUES ($1, $2, $3)
-RETURNING id, match_value, category_id, priority, created_at, updated_at
+INSERT INTO categorization_rules (
+    match_value, category_id, priority, match_type, match_field, min_amount, max_amount,
+    direction, card_number, start_date, end_date, assign_to
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING id, match_value, category_id, priority, created_at, updated_at, match_type,
+          match_field, min_amount, max_amount, direction, card_number, start_date, end_date,
+          assign_to, hit_count, last_matched_at, override_count
 `
 
 type CreateRuleParams struct {
-	MatchValue string      `json:"match_value"`
-	CategoryID pgtype.UUID `json:"category_id"`
-	Priority   int32       `json:"priority"`
+	MatchValue string         `json:"match_value"`
+	CategoryID pgtype.UUID    `json:"category_id"`
+	Priority   int32          `json:"priority"`
+	MatchType  string         `json:"match_type"`
+	MatchField string         `json:"match_field"`
+	MinAmount  pgtype.Numeric `json:"min_amount"`
+	MaxAmount  pgtype.Numeric `json:"max_amount"`
+	Direction  pgtype.Text    `json:"direction"`
+	CardNumber pgtype.Text    `json:"card_number"`
+	StartDate  pgtype.Date    `json:"start_date"`
+	EndDate    pgtype.Date    `json:"end_date"`
+	AssignTo   []pgtype.UUID  `json:"assign_to"`
 }
 
 func (q *Queries) CreateRule(ctx context.Context, arg CreateRuleParams) (CategorizationRule, error) {
-	row := q.db.QueryRow(ctx, createRule, arg.MatchValue, arg.CategoryID, arg.Priority)
+	row := q.db.QueryRow(ctx, createRule,
+		arg.MatchValue,
+		arg.CategoryID,
+		arg.Priority,
+		arg.MatchType,
+		arg.MatchField,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Direction,
+		arg.CardNumber,
+		arg.StartDate,
+		arg.EndDate,
+		arg.AssignTo,
+	)
 	var i CategorizationRule
 	err := row.Scan(
 		&i.ID,
@@ -213,71 +414,70 @@ func (q *Queries) CreateRule(ctx context.Context, arg CreateRuleParams) (Categor
 		&i.Priority,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MatchType,
+		&i.MatchField,
+		&i.MinAmount,
+		&i.MaxAmount,
+		&i.Direction,
+		&i.CardNumber,
+		&i.StartDate,
+		&i.EndDate,
+		&i.AssignTo,
+		&i.HitCount,
+		&i.LastMatchedAt,
+		&i.OverrideCount,
 	)
 	return i, err
 }
 
-const createTransaction = `-- name: CreateTransaction :one
-INSERT INTO transactions (description, amount, file_name, transaction_date, posted_date, card_number)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, description, amount, assigned_to, date_uploaded, file_name,
-          transaction_date, posted_date, card_number,
-          created_at, updated_at
+const createRuleSplit = `-- name: CreateRuleSplit :exec
+INSERT INTO rule_splits (rule_id, category_id, percentage, position)
+VALUES ($1, $2, $3, $4)
 `
 
-type CreateTransactionParams struct {
-	Description     string         `json:"description"`
-	Amount          pgtype.Numeric `json:"amount"`
-	FileName        pgtype.Text    `json:"file_name"`
-	TransactionDate pgtype.Date    `json:"transaction_date"`
-	PostedDate      pgtype.Date    `json:"posted_date"`
-	CardNumber      pgtype.Text    `json:"card_number"`
+type CreateRuleSplitParams struct {
+	RuleID     pgtype.UUID    `json:"rule_id"`
+	CategoryID pgtype.UUID    `json:"category_id"`
+	Percentage pgtype.Numeric `json:"percentage"`
+	Position   int32          `json:"position"`
 }
 
-type CreateTransactionRow struct {
-	ID              pgtype.UUID      `json:"id"`
-	Description     string           `json:"description"`
-	Amount          pgtype.Numeric   `json:"amount"`
-	AssignedTo      []pgtype.UUID    `json:"assigned_to"`
-	DateUploaded    pgtype.Timestamp `json:"date_uploaded"`
-	FileName        pgtype.Text      `json:"file_name"`
-	TransactionDate pgtype.Date      `json:"transaction_date"`
-	PostedDate      pgtype.Date      `json:"posted_date"`
-	CardNumber      pgtype.Text      `json:"card_number"`
-	CreatedAt       pgtype.Timestamp `json:"created_at"`
-	UpdatedAt       pgtype.Timestamp `json:"updated_at"`
+func (q *Queries) CreateRuleSplit(ctx context.Context, arg CreateRuleSplitParams) error {
+	_, err := q.db.Exec(ctx, createRuleSplit,
+		arg.RuleID,
+		arg.CategoryID,
+		arg.Percentage,
+		arg.Position,
+	)
+	return err
 }
 
-func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionParams) (CreateTransactionRow, error) {
-	row := q.db.QueryRow(ctx, createTransaction,
-		arg.Description,
-		arg.Amount,
-		arg.FileName,
-		arg.TransactionDate,
-		arg.PostedDate,
-		arg.CardNumber,
-	)
-	var i CreateTransactionRow
-	err := row.Scan(
-		&i.ID,
-		&i.Description,
-		&i.Amount,
-		&i.AssignedTo,
-		&i.DateUploaded,
-		&i.FileName,
-		&i.TransactionDate,
-		&i.PostedDate,
-		&i.CardNumber,
-		&i.CreatedAt,
-		&i.UpdatedAt,
+const createTransactionShare = `-- name: CreateTransactionShare :exec
+INSERT INTO transaction_shares (transaction_id, person_id, weight, fixed_amount)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateTransactionShareParams struct {
+	TransactionID pgtype.UUID    `json:"transaction_id"`
+	PersonID      pgtype.UUID    `json:"person_id"`
+	Weight        pgtype.Numeric `json:"weight"`
+	FixedAmount   pgtype.Numeric `json:"fixed_amount"`
+}
+
+func (q *Queries) CreateTransactionShare(ctx context.Context, arg CreateTransactionShareParams) error {
+	_, err := q.db.Exec(ctx, createTransactionShare,
+		arg.TransactionID,
+		arg.PersonID,
+		arg.Weight,
+		arg.FixedAmount,
 	)
-	return i, err
+	return err
 }
 
 const createTransactionSplit = `-- name: CreateTransactionSplit :one
-INSERT INTO transaction_splits (transaction_id, amount, category_id, notes)
-VALUES ($1, $2, $3, $4)
-RETURNING id, transaction_id, amount, category_id, notes, created_at, updated_at
+INSERT INTO transaction_splits (transaction_id, amount, category_id, notes, assigned_to)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, transaction_id, amount, category_id, notes, created_at, updated_at, assigned_to
 `
 
 type CreateTransactionSplitParams struct {
@@ -285,6 +485,7 @@ type CreateTransactionSplitParams struct {
 	Amount        pgtype.Numeric `json:"amount"`
 	CategoryID    pgtype.UUID    `json:"category_id"`
 	Notes         pgtype.Text    `json:"notes"`
+	AssignedTo    []pgtype.UUID  `json:"assigned_to"`
 }
 
 func (q *Queries) CreateTransactionSplit(ctx context.Context, arg CreateTransactionSplitParams) (TransactionSplit, error) {
@@ -293,6 +494,7 @@ func (q *Queries) CreateTransactionSplit(ctx context.Context, arg CreateTransact
 		arg.Amount,
 		arg.CategoryID,
 		arg.Notes,
+		arg.AssignedTo,
 	)
 	var i TransactionSplit
 	err := row.Scan(
@@ -303,10 +505,46 @@ func (q *Queries) CreateTransactionSplit(ctx context.Context, arg CreateTransact
 		&i.Notes,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.AssignedTo,
 	)
 	return i, err
 }
 
+type CreateTransactionSplitsParams struct {
+	TransactionID pgtype.UUID    `json:"transaction_id"`
+	Amount        pgtype.Numeric `json:"amount"`
+	CategoryID    pgtype.UUID    `json:"category_id"`
+	Notes         pgtype.Text    `json:"notes"`
+	AssignedTo    []pgtype.UUID  `json:"assigned_to"`
+}
+
+type CreateTransactionsParams struct {
+	ID              pgtype.UUID    `json:"id"`
+	Description     string         `json:"description"`
+	Amount          pgtype.Numeric `json:"amount"`
+	AssignedTo      []pgtype.UUID  `json:"assigned_to"`
+	FileName        pgtype.Text    `json:"file_name"`
+	TransactionDate pgtype.Date    `json:"transaction_date"`
+	PostedDate      pgtype.Date    `json:"posted_date"`
+	CardNumber      pgtype.Text    `json:"card_number"`
+	ExternalID      pgtype.Text    `json:"external_id"`
+	ImportID        pgtype.UUID    `json:"import_id"`
+	RuleID          pgtype.UUID    `json:"rule_id"`
+	CsvCategory     pgtype.Text    `json:"csv_category"`
+}
+
+const deleteAllRules = `-- name: DeleteAllRules :execrows
+DELETE FROM categorization_rules
+`
+
+func (q *Queries) DeleteAllRules(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAllRules)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteAllTransactions = `-- name: DeleteAllTransactions :exec
 DELETE FROM transactions
 WHERE archive_id IS NULL
@@ -337,6 +575,19 @@ func (q *Queries) DeleteArchivePersonTotals(ctx context.Context, archiveID pgtyp
 	return err
 }
 
+const deleteBudget = `-- name: DeleteBudget :execrows
+DELETE FROM budgets
+WHERE id = $1
+`
+
+func (q *Queries) DeleteBudget(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteBudget, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteCategory = `-- name: DeleteCategory :exec
 DELETE FROM categories
 WHERE id = $1
@@ -347,6 +598,26 @@ func (q *Queries) DeleteCategory(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteImport = `-- name: DeleteImport :exec
+DELETE FROM imports
+WHERE id = $1
+`
+
+func (q *Queries) DeleteImport(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteImport, id)
+	return err
+}
+
+const deleteImportProfile = `-- name: DeleteImportProfile :exec
+DELETE FROM import_profiles
+WHERE id = $1
+`
+
+func (q *Queries) DeleteImportProfile(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteImportProfile, id)
+	return err
+}
+
 const deletePerson = `-- name: DeletePerson :exec
 DELETE FROM people
 WHERE id = $1
@@ -367,6 +638,16 @@ func (q *Queries) DeleteRule(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteRuleSplits = `-- name: DeleteRuleSplits :exec
+DELETE FROM rule_splits
+WHERE rule_id = $1
+`
+
+func (q *Queries) DeleteRuleSplits(ctx context.Context, ruleID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteRuleSplits, ruleID)
+	return err
+}
+
 const deleteTransaction = `-- name: DeleteTransaction :exec
 DELETE FROM transactions
 WHERE id = $1
@@ -377,6 +658,16 @@ func (q *Queries) DeleteTransaction(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteTransactionShares = `-- name: DeleteTransactionShares :exec
+DELETE FROM transaction_shares
+WHERE transaction_id = $1
+`
+
+func (q *Queries) DeleteTransactionShares(ctx context.Context, transactionID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteTransactionShares, transactionID)
+	return err
+}
+
 const deleteTransactionSplitsByTransactionID = `-- name: DeleteTransactionSplitsByTransactionID :exec
 DELETE FROM transaction_splits
 WHERE transaction_id = $1
@@ -387,54 +678,154 @@ func (q *Queries) DeleteTransactionSplitsByTransactionID(ctx context.Context, tr
 	return err
 }
 
-const findDuplicateTransaction = `-- name: FindDuplicateTransaction :one
-SELECT COUNT(*)
-FROM transactions
-WHERE description = $1
-  AND amount = $2
-  AND transaction_date = $3
-  AND posted_date = $4
-  AND card_number = $5
-  AND archive_id IS NULL
+const deleteTransactionSplitsByTransactionIDs = `-- name: DeleteTransactionSplitsByTransactionIDs :exec
+DELETE FROM transaction_splits
+WHERE transaction_id = ANY($1::uuid[])
 `
 
-type FindDuplicateTransactionParams struct {
-	Description     string         `json:"description"`
-	Amount          pgtype.Numeric `json:"amount"`
-	TransactionDate pgtype.Date    `json:"transaction_date"`
-	PostedDate      pgtype.Date    `json:"posted_date"`
-	CardNumber      pgtype.Text    `json:"card_number"`
+func (q *Queries) DeleteTransactionSplitsByTransactionIDs(ctx context.Context, transactionIds []pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteTransactionSplitsByTransactionIDs, transactionIds)
+	return err
 }
 
-func (q *Queries) FindDuplicateTransaction(ctx context.Context, arg FindDuplicateTransactionParams) (int64, error) {
-	row := q.db.QueryRow(ctx, findDuplicateTransaction,
-		arg.Description,
-		arg.Amount,
-		arg.TransactionDate,
-		arg.PostedDate,
-		arg.CardNumber,
+const deleteTransactionsByImportID = `-- name: DeleteTransactionsByImportID :execrows
+DELETE FROM transactions
+WHERE import_id = $1
+`
+
+func (q *Queries) DeleteTransactionsByImportID(ctx context.Context, importID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTransactionsByImportID, importID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const findDuplicateImportRows = `-- name: FindDuplicateImportRows :many
+-- Seen is how many times each row has appeared in the file so far, itself included.
+-- Rows the import has already saved from earlier batches are not existing copies.
+WITH candidates AS (
+    SELECT c.position, c.description, c.amount, c.transaction_date, c.posted_date,
+           NULLIF(c.card_number, '') AS card_number,
+           NULLIF(c.external_id, '') AS external_id,
+           c.seen
+    FROM unnest(
+        $1::int[], $2::text[], $3::numeric[],
+        $4::date[], $5::date[],
+        $6::text[], $7::text[], $8::int[]
+    ) AS c(position, description, amount, transaction_date, posted_date, card_number, external_id, seen)
+),
+existing AS (
+    SELECT t.description, t.amount, t.transaction_date, t.posted_date, t.card_number, COUNT(*) AS copies
+    FROM transactions t
+    JOIN (
+        SELECT DISTINCT description, amount, transaction_date, posted_date, card_number
+        FROM candidates
+        WHERE external_id IS NULL
+    ) k ON k.description = t.description
+       AND k.amount = t.amount
+       AND k.transaction_date = t.transaction_date
+       AND k.posted_date = t.posted_date
+       AND k.card_number = t.card_number
+    WHERE t.archive_id IS NULL
+      AND NOT COALESCE(t.import_id = $9::uuid, FALSE)
+    GROUP BY t.description, t.amount, t.transaction_date, t.posted_date, t.card_number
+)
+SELECT c.position::int AS position
+FROM candidates c
+LEFT JOIN existing e ON c.external_id IS NULL
+                    AND e.description = c.description
+                    AND e.amount = c.amount
+                    AND e.transaction_date = c.transaction_date
+                    AND e.posted_date = c.posted_date
+                    AND e.card_number = c.card_number
+WHERE (c.external_id IS NOT NULL AND (
+           c.seen > 1
+           OR EXISTS (
+               SELECT 1
+               FROM transactions t
+               WHERE t.external_id = c.external_id
+                 AND t.card_number IS NOT DISTINCT FROM c.card_number
+           )
+       ))
+   OR (c.external_id IS NULL AND COALESCE(e.copies, 0) >= c.seen)
+ORDER BY c.position
+`
+
+type FindDuplicateImportRowsParams struct {
+	Positions        []int32          `json:"positions"`
+	Descriptions     []string         `json:"descriptions"`
+	Amounts          []pgtype.Numeric `json:"amounts"`
+	TransactionDates []pgtype.Date    `json:"transaction_dates"`
+	PostedDates      []pgtype.Date    `json:"posted_dates"`
+	CardNumbers      []string         `json:"card_numbers"`
+	ExternalIDs      []string         `json:"external_ids"`
+	Seen             []int32          `json:"seen"`
+	ImportID         pgtype.UUID      `json:"import_id"`
+}
+
+// Seen is how many times each row has appeared in the file so far, itself included.
+// Rows the import has already saved from earlier batches are not existing copies.
+func (q *Queries) FindDuplicateImportRows(ctx context.Context, arg FindDuplicateImportRowsParams) ([]int32, error) {
+	rows, err := q.db.Query(ctx, findDuplicateImportRows,
+		arg.Positions,
+		arg.Descriptions,
+		arg.Amounts,
+		arg.TransactionDates,
+		arg.PostedDates,
+		arg.CardNumbers,
+		arg.ExternalIDs,
+		arg.Seen,
+		arg.ImportID,
 	)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var position int32
+		if err := rows.Scan(&position); err != nil {
+			return nil, err
+		}
+		items = append(items, position)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findImportByFileHash = `-- name: FindImportByFileHash :one
+SELECT id
+FROM imports
+WHERE file_hash = $1
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) FindImportByFileHash(ctx context.Context, fileHash pgtype.Text) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, findImportByFileHash, fileHash)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
 }
 
 const getActiveTransactionGrandTotal = `-- name: GetActiveTransactionGrandTotal :one
-WITH normalized_transaction_totals AS (
-    SELECT t.id,
-           SUM(CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END)::numeric AS normalized_amount
+WITH normalized_split_amounts AS (
+    SELECT ts.id,
+           CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END AS normalized_amount
     FROM transactions t
     JOIN transaction_splits ts ON ts.transaction_id = t.id
+    JOIN categories c ON c.id = ts.category_id
+    LEFT JOIN categories pc ON pc.id = c.parent_id
     WHERE t.archive_id IS NULL
-    GROUP BY t.id
+      AND NOT c.exclude_from_totals
+      AND NOT COALESCE(pc.exclude_from_totals, FALSE)
 )
-SELECT COALESCE(SUM(nt.normalized_amount / array_length(t.assigned_to, 1)), 0)::numeric as grand_total
-FROM transactions t
-JOIN normalized_transaction_totals nt ON nt.id = t.id
-CROSS JOIN LATERAL unnest(t.assigned_to) AS person_id
-WHERE t.assigned_to IS NOT NULL
-  AND array_length(t.assigned_to, 1) > 0
-  AND t.archive_id IS NULL
+SELECT COALESCE(SUM(ns.normalized_amount * sps.share), 0)::numeric as grand_total
+FROM normalized_split_amounts ns
+JOIN split_person_shares sps ON sps.split_id = ns.id
 `
 
 func (q *Queries) GetActiveTransactionGrandTotal(ctx context.Context) (pgtype.Numeric, error) {
@@ -445,22 +836,21 @@ func (q *Queries) GetActiveTransactionGrandTotal(ctx context.Context) (pgtype.Nu
 }
 
 const getActiveTransactionTotals = `-- name: GetActiveTransactionTotals :many
-WITH normalized_transaction_totals AS (
-    SELECT t.id,
-           SUM(CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END)::numeric AS normalized_amount
+WITH normalized_split_amounts AS (
+    SELECT ts.id,
+           CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END AS normalized_amount
     FROM transactions t
     JOIN transaction_splits ts ON ts.transaction_id = t.id
+    JOIN categories c ON c.id = ts.category_id
+    LEFT JOIN categories pc ON pc.id = c.parent_id
     WHERE t.archive_id IS NULL
-    GROUP BY t.id
+      AND NOT c.exclude_from_totals
+      AND NOT COALESCE(pc.exclude_from_totals, FALSE)
 )
-SELECT p.name as assigned_to, SUM(nt.normalized_amount / array_length(t.assigned_to, 1))::numeric as total
-FROM transactions t
-JOIN normalized_transaction_totals nt ON nt.id = t.id
-CROSS JOIN LATERAL unnest(t.assigned_to) AS person_id
-JOIN people p ON p.id = person_id
-WHERE t.assigned_to IS NOT NULL
-  AND array_length(t.assigned_to, 1) > 0
-  AND t.archive_id IS NULL
+SELECT p.name as assigned_to, SUM(ns.normalized_amount * sps.share)::numeric as total
+FROM normalized_split_amounts ns
+JOIN split_person_shares sps ON sps.split_id = ns.id
+JOIN people p ON p.id = sps.person_id
 GROUP BY p.id, p.name
 ORDER BY p.name
 `
@@ -703,41 +1093,139 @@ func (q *Queries) GetArchives(ctx context.Context) ([]Archive, error) {
 	return items, nil
 }
 
-const getCategories = `-- name: GetCategories :many
-SELECT id, name, description, color, parent_id, created_at, updated_at
-FROM categories
-ORDER BY name
+const getBudgetByID = `-- name: GetBudgetByID :one
+SELECT b.id, b.category_id, c.name as category_name, b.person_id, p.name as person_name,
+       b.month, b.amount, b.rollover, b.created_at, b.updated_at
+FROM budgets b
+JOIN categories c ON b.category_id = c.id
+LEFT JOIN people p ON b.person_id = p.id
+WHERE b.id = $1
 `
 
-type GetCategoriesRow struct {
-	ID          pgtype.UUID      `json:"id"`
-	Name        string           `json:"name"`
-	Description pgtype.Text      `json:"description"`
-	Color       pgtype.Text      `json:"color"`
-	ParentID    pgtype.UUID      `json:"parent_id"`
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+type GetBudgetByIDRow struct {
+	ID           pgtype.UUID      `json:"id"`
+	CategoryID   pgtype.UUID      `json:"category_id"`
+	CategoryName string           `json:"category_name"`
+	PersonID     pgtype.UUID      `json:"person_id"`
+	PersonName   pgtype.Text      `json:"person_name"`
+	Month        pgtype.Date      `json:"month"`
+	Amount       pgtype.Numeric   `json:"amount"`
+	Rollover     bool             `json:"rollover"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
 }
 
-// Categories queries
-func (q *Queries) GetCategories(ctx context.Context) ([]GetCategoriesRow, error) {
-	rows, err := q.db.Query(ctx, getCategories)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []GetCategoriesRow
-	for rows.Next() {
-		var i GetCategoriesRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.Description,
-			&i.Color,
-			&i.ParentID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
+func (q *Queries) GetBudgetByID(ctx context.Context, id pgtype.UUID) (GetBudgetByIDRow, error) {
+	row := q.db.QueryRow(ctx, getBudgetByID, id)
+	var i GetBudgetByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.CategoryID,
+		&i.CategoryName,
+		&i.PersonID,
+		&i.PersonName,
+		&i.Month,
+		&i.Amount,
+		&i.Rollover,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getBudgets = `-- name: GetBudgets :many
+SELECT b.id, b.category_id, c.name as category_name, b.person_id, p.name as person_name,
+       b.month, b.amount, b.rollover, b.created_at, b.updated_at
+FROM budgets b
+JOIN categories c ON b.category_id = c.id
+LEFT JOIN people p ON b.person_id = p.id
+ORDER BY c.name, p.name NULLS FIRST, b.month
+`
+
+type GetBudgetsRow struct {
+	ID           pgtype.UUID      `json:"id"`
+	CategoryID   pgtype.UUID      `json:"category_id"`
+	CategoryName string           `json:"category_name"`
+	PersonID     pgtype.UUID      `json:"person_id"`
+	PersonName   pgtype.Text      `json:"person_name"`
+	Month        pgtype.Date      `json:"month"`
+	Amount       pgtype.Numeric   `json:"amount"`
+	Rollover     bool             `json:"rollover"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+}
+
+// Budget queries
+func (q *Queries) GetBudgets(ctx context.Context) ([]GetBudgetsRow, error) {
+	rows, err := q.db.Query(ctx, getBudgets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBudgetsRow
+	for rows.Next() {
+		var i GetBudgetsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CategoryID,
+			&i.CategoryName,
+			&i.PersonID,
+			&i.PersonName,
+			&i.Month,
+			&i.Amount,
+			&i.Rollover,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCategories = `-- name: GetCategories :many
+SELECT id, name, description, color, parent_id, created_at, updated_at, retired_at, exclude_from_totals
+FROM categories
+ORDER BY name
+`
+
+type GetCategoriesRow struct {
+	ID                pgtype.UUID      `json:"id"`
+	Name              string           `json:"name"`
+	Description       pgtype.Text      `json:"description"`
+	Color             pgtype.Text      `json:"color"`
+	ParentID          pgtype.UUID      `json:"parent_id"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+	RetiredAt         pgtype.Timestamp `json:"retired_at"`
+	ExcludeFromTotals bool             `json:"exclude_from_totals"`
+}
+
+// Categories queries
+func (q *Queries) GetCategories(ctx context.Context) ([]GetCategoriesRow, error) {
+	rows, err := q.db.Query(ctx, getCategories)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCategoriesRow
+	for rows.Next() {
+		var i GetCategoriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Color,
+			&i.ParentID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.RetiredAt,
+			&i.ExcludeFromTotals,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -749,19 +1237,21 @@ func (q *Queries) GetCategories(ctx context.Context) ([]GetCategoriesRow, error)
 }
 
 const getCategoryByID = `-- name: GetCategoryByID :one
-SELECT id, name, description, color, parent_id, created_at, updated_at
+SELECT id, name, description, color, parent_id, created_at, updated_at, retired_at, exclude_from_totals
 FROM categories
 WHERE id = $1
 `
 
 type GetCategoryByIDRow struct {
-	ID          pgtype.UUID      `json:"id"`
-	Name        string           `json:"name"`
-	Description pgtype.Text      `json:"description"`
-	Color       pgtype.Text      `json:"color"`
-	ParentID    pgtype.UUID      `json:"parent_id"`
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+	ID                pgtype.UUID      `json:"id"`
+	Name              string           `json:"name"`
+	Description       pgtype.Text      `json:"description"`
+	Color             pgtype.Text      `json:"color"`
+	ParentID          pgtype.UUID      `json:"parent_id"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+	RetiredAt         pgtype.Timestamp `json:"retired_at"`
+	ExcludeFromTotals bool             `json:"exclude_from_totals"`
 }
 
 func (q *Queries) GetCategoryByID(ctx context.Context, id pgtype.UUID) (GetCategoryByIDRow, error) {
@@ -775,6 +1265,8 @@ func (q *Queries) GetCategoryByID(ctx context.Context, id pgtype.UUID) (GetCateg
 		&i.ParentID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RetiredAt,
+		&i.ExcludeFromTotals,
 	)
 	return i, err
 }
@@ -810,6 +1302,380 @@ func (q *Queries) GetCategoryByName(ctx context.Context, name string) (GetCatego
 	return i, err
 }
 
+const getCategoryTrainingExamples = `-- name: GetCategoryTrainingExamples :many
+SELECT t.description, s.category_id,
+       (s.amount / SUM(s.amount) OVER (PARTITION BY s.transaction_id))::float8 AS weight
+FROM transaction_splits s
+JOIN transactions t ON s.transaction_id = t.id
+WHERE t.splits_edited_at IS NOT NULL
+`
+
+type GetCategoryTrainingExamplesRow struct {
+	Description string      `json:"description"`
+	CategoryID  pgtype.UUID `json:"category_id"`
+	Weight      float64     `json:"weight"`
+}
+
+// Category classifier queries
+func (q *Queries) GetCategoryTrainingExamples(ctx context.Context) ([]GetCategoryTrainingExamplesRow, error) {
+	rows, err := q.db.Query(ctx, getCategoryTrainingExamples)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCategoryTrainingExamplesRow
+	for rows.Next() {
+		var i GetCategoryTrainingExamplesRow
+		if err := rows.Scan(&i.Description, &i.CategoryID, &i.Weight); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getImportByID = `-- name: GetImportByID :one
+SELECT i.id, i.file_name, i.format, i.file_hash, i.total_rows, i.imported_rows, i.skipped_rows,
+       i.uploaded_by, p.name AS uploaded_by_name, i.created_at
+FROM imports i
+LEFT JOIN people p ON p.id = i.uploaded_by
+WHERE i.id = $1
+`
+
+type GetImportByIDRow struct {
+	ID             pgtype.UUID      `json:"id"`
+	FileName       pgtype.Text      `json:"file_name"`
+	Format         string           `json:"format"`
+	FileHash       pgtype.Text      `json:"file_hash"`
+	TotalRows      int32            `json:"total_rows"`
+	ImportedRows   int32            `json:"imported_rows"`
+	SkippedRows    int32            `json:"skipped_rows"`
+	UploadedBy     pgtype.UUID      `json:"uploaded_by"`
+	UploadedByName pgtype.Text      `json:"uploaded_by_name"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+}
+
+func (q *Queries) GetImportByID(ctx context.Context, id pgtype.UUID) (GetImportByIDRow, error) {
+	row := q.db.QueryRow(ctx, getImportByID, id)
+	var i GetImportByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.FileName,
+		&i.Format,
+		&i.FileHash,
+		&i.TotalRows,
+		&i.ImportedRows,
+		&i.SkippedRows,
+		&i.UploadedBy,
+		&i.UploadedByName,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getImportProfileByID = `-- name: GetImportProfileByID :one
+SELECT id, name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+       transaction_date_column, posted_date_column, card_number_column, description_column,
+       category_column, amount_column, debit_column, credit_column, created_at, updated_at,
+       decimal_separator
+FROM import_profiles
+WHERE id = $1
+`
+
+func (q *Queries) GetImportProfileByID(ctx context.Context, id pgtype.UUID) (ImportProfile, error) {
+	row := q.db.QueryRow(ctx, getImportProfileByID, id)
+	var i ImportProfile
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Delimiter,
+		&i.HasHeader,
+		&i.HeaderColumns,
+		&i.DateFormat,
+		&i.AmountFormat,
+		&i.ExpenseSign,
+		&i.TransactionDateColumn,
+		&i.PostedDateColumn,
+		&i.CardNumberColumn,
+		&i.DescriptionColumn,
+		&i.CategoryColumn,
+		&i.AmountColumn,
+		&i.DebitColumn,
+		&i.CreditColumn,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DecimalSeparator,
+	)
+	return i, err
+}
+
+const getImportProfileByName = `-- name: GetImportProfileByName :one
+SELECT id, name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+       transaction_date_column, posted_date_column, card_number_column, description_column,
+       category_column, amount_column, debit_column, credit_column, created_at, updated_at,
+       decimal_separator
+FROM import_profiles
+WHERE name = $1
+`
+
+func (q *Queries) GetImportProfileByName(ctx context.Context, name string) (ImportProfile, error) {
+	row := q.db.QueryRow(ctx, getImportProfileByName, name)
+	var i ImportProfile
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Delimiter,
+		&i.HasHeader,
+		&i.HeaderColumns,
+		&i.DateFormat,
+		&i.AmountFormat,
+		&i.ExpenseSign,
+		&i.TransactionDateColumn,
+		&i.PostedDateColumn,
+		&i.CardNumberColumn,
+		&i.DescriptionColumn,
+		&i.CategoryColumn,
+		&i.AmountColumn,
+		&i.DebitColumn,
+		&i.CreditColumn,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DecimalSeparator,
+	)
+	return i, err
+}
+
+const getImportProfiles = `-- name: GetImportProfiles :many
+SELECT id, name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+       transaction_date_column, posted_date_column, card_number_column, description_column,
+       category_column, amount_column, debit_column, credit_column, created_at, updated_at,
+       decimal_separator
+FROM import_profiles
+ORDER BY name
+`
+
+// Import profile queries
+func (q *Queries) GetImportProfiles(ctx context.Context) ([]ImportProfile, error) {
+	rows, err := q.db.Query(ctx, getImportProfiles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ImportProfile
+	for rows.Next() {
+		var i ImportProfile
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Delimiter,
+			&i.HasHeader,
+			&i.HeaderColumns,
+			&i.DateFormat,
+			&i.AmountFormat,
+			&i.ExpenseSign,
+			&i.TransactionDateColumn,
+			&i.PostedDateColumn,
+			&i.CardNumberColumn,
+			&i.DescriptionColumn,
+			&i.CategoryColumn,
+			&i.AmountColumn,
+			&i.DebitColumn,
+			&i.CreditColumn,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DecimalSeparator,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getImportRejectionsByImportID = `-- name: GetImportRejectionsByImportID :many
+SELECT id, import_id, line_number, raw_content, reason, message
+FROM import_rejections
+WHERE import_id = $1
+ORDER BY line_number ASC
+`
+
+func (q *Queries) GetImportRejectionsByImportID(ctx context.Context, importID pgtype.UUID) ([]ImportRejection, error) {
+	rows, err := q.db.Query(ctx, getImportRejectionsByImportID, importID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ImportRejection
+	for rows.Next() {
+		var i ImportRejection
+		if err := rows.Scan(
+			&i.ID,
+			&i.ImportID,
+			&i.LineNumber,
+			&i.RawContent,
+			&i.Reason,
+			&i.Message,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getImports = `-- name: GetImports :many
+SELECT i.id, i.file_name, i.format, i.file_hash, i.total_rows, i.imported_rows, i.skipped_rows,
+       i.uploaded_by, p.name AS uploaded_by_name, i.created_at
+FROM imports i
+LEFT JOIN people p ON p.id = i.uploaded_by
+ORDER BY i.created_at DESC
+`
+
+type GetImportsRow struct {
+	ID             pgtype.UUID      `json:"id"`
+	FileName       pgtype.Text      `json:"file_name"`
+	Format         string           `json:"format"`
+	FileHash       pgtype.Text      `json:"file_hash"`
+	TotalRows      int32            `json:"total_rows"`
+	ImportedRows   int32            `json:"imported_rows"`
+	SkippedRows    int32            `json:"skipped_rows"`
+	UploadedBy     pgtype.UUID      `json:"uploaded_by"`
+	UploadedByName pgtype.Text      `json:"uploaded_by_name"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+}
+
+func (q *Queries) GetImports(ctx context.Context) ([]GetImportsRow, error) {
+	rows, err := q.db.Query(ctx, getImports)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetImportsRow
+	for rows.Next() {
+		var i GetImportsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FileName,
+			&i.Format,
+			&i.FileHash,
+			&i.TotalRows,
+			&i.ImportedRows,
+			&i.SkippedRows,
+			&i.UploadedBy,
+			&i.UploadedByName,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMonthlyCategorySpending = `-- name: GetMonthlyCategorySpending :many
+SELECT date_trunc('month', COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date))::date AS month,
+       ts.category_id,
+       SUM(CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END)::numeric AS total
+FROM transactions t
+JOIN transaction_splits ts ON ts.transaction_id = t.id
+WHERE COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date) >= $1::date
+  AND COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date) < $2::date
+GROUP BY 1, ts.category_id
+`
+
+type GetMonthlyCategorySpendingParams struct {
+	FromMonth pgtype.Date `json:"from_month"`
+	ToMonth   pgtype.Date `json:"to_month"`
+}
+
+type GetMonthlyCategorySpendingRow struct {
+	Month      pgtype.Date    `json:"month"`
+	CategoryID pgtype.UUID    `json:"category_id"`
+	Total      pgtype.Numeric `json:"total"`
+}
+
+func (q *Queries) GetMonthlyCategorySpending(ctx context.Context, arg GetMonthlyCategorySpendingParams) ([]GetMonthlyCategorySpendingRow, error) {
+	rows, err := q.db.Query(ctx, getMonthlyCategorySpending, arg.FromMonth, arg.ToMonth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMonthlyCategorySpendingRow
+	for rows.Next() {
+		var i GetMonthlyCategorySpendingRow
+		if err := rows.Scan(&i.Month, &i.CategoryID, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMonthlyCategorySpendingByPerson = `-- name: GetMonthlyCategorySpendingByPerson :many
+SELECT date_trunc('month', COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date))::date AS month,
+       ts.category_id,
+       sps.person_id,
+       SUM(CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END * sps.share)::numeric AS total
+FROM transactions t
+JOIN transaction_splits ts ON ts.transaction_id = t.id
+JOIN split_person_shares sps ON sps.split_id = ts.id
+WHERE COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date) >= $1::date
+  AND COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date) < $2::date
+GROUP BY 1, ts.category_id, sps.person_id
+`
+
+type GetMonthlyCategorySpendingByPersonParams struct {
+	FromMonth pgtype.Date `json:"from_month"`
+	ToMonth   pgtype.Date `json:"to_month"`
+}
+
+type GetMonthlyCategorySpendingByPersonRow struct {
+	Month      pgtype.Date    `json:"month"`
+	CategoryID pgtype.UUID    `json:"category_id"`
+	PersonID   pgtype.UUID    `json:"person_id"`
+	Total      pgtype.Numeric `json:"total"`
+}
+
+func (q *Queries) GetMonthlyCategorySpendingByPerson(ctx context.Context, arg GetMonthlyCategorySpendingByPersonParams) ([]GetMonthlyCategorySpendingByPersonRow, error) {
+	rows, err := q.db.Query(ctx, getMonthlyCategorySpendingByPerson, arg.FromMonth, arg.ToMonth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMonthlyCategorySpendingByPersonRow
+	for rows.Next() {
+		var i GetMonthlyCategorySpendingByPersonRow
+		if err := rows.Scan(
+			&i.Month,
+			&i.CategoryID,
+			&i.PersonID,
+			&i.Total,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPeople = `-- name: GetPeople :many
 SELECT id, name, email, created_at, updated_at
 FROM people
@@ -882,20 +1748,35 @@ func (q *Queries) GetPersonByName(ctx context.Context, name string) (Person, err
 }
 
 const getRuleByID = `-- name: GetRuleByID :one
-SELECT r.id, r.match_value, r.category_id, c.name as category_name, r.priority, r.created_at, r.updated_at
+SELECT r.id, r.match_value, r.match_type, r.match_field, r.min_amount, r.max_amount, r.direction,
+       r.card_number, r.start_date, r.end_date,
+       r.category_id, c.name as category_name, r.assign_to, r.priority,
+       r.hit_count, r.last_matched_at, r.override_count, r.created_at, r.updated_at
 FROM categorization_rules r
 JOIN categories c ON r.category_id = c.id
 WHERE r.id = $1
 `
 
 type GetRuleByIDRow struct {
-	ID           pgtype.UUID      `json:"id"`
-	MatchValue   string           `json:"match_value"`
-	CategoryID   pgtype.UUID      `json:"category_id"`
-	CategoryName string           `json:"category_name"`
-	Priority     int32            `json:"priority"`
-	CreatedAt    pgtype.Timestamp `json:"created_at"`
-	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+	ID            pgtype.UUID      `json:"id"`
+	MatchValue    string           `json:"match_value"`
+	MatchType     string           `json:"match_type"`
+	MatchField    string           `json:"match_field"`
+	MinAmount     pgtype.Numeric   `json:"min_amount"`
+	MaxAmount     pgtype.Numeric   `json:"max_amount"`
+	Direction     pgtype.Text      `json:"direction"`
+	CardNumber    pgtype.Text      `json:"card_number"`
+	StartDate     pgtype.Date      `json:"start_date"`
+	EndDate       pgtype.Date      `json:"end_date"`
+	CategoryID    pgtype.UUID      `json:"category_id"`
+	CategoryName  string           `json:"category_name"`
+	AssignTo      []pgtype.UUID    `json:"assign_to"`
+	Priority      int32            `json:"priority"`
+	HitCount      int32            `json:"hit_count"`
+	LastMatchedAt pgtype.Timestamp `json:"last_matched_at"`
+	OverrideCount int32            `json:"override_count"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
 }
 
 func (q *Queries) GetRuleByID(ctx context.Context, id pgtype.UUID) (GetRuleByIDRow, error) {
@@ -904,30 +1785,137 @@ func (q *Queries) GetRuleByID(ctx context.Context, id pgtype.UUID) (GetRuleByIDR
 	err := row.Scan(
 		&i.ID,
 		&i.MatchValue,
+		&i.MatchType,
+		&i.MatchField,
+		&i.MinAmount,
+		&i.MaxAmount,
+		&i.Direction,
+		&i.CardNumber,
+		&i.StartDate,
+		&i.EndDate,
 		&i.CategoryID,
 		&i.CategoryName,
+		&i.AssignTo,
 		&i.Priority,
+		&i.HitCount,
+		&i.LastMatchedAt,
+		&i.OverrideCount,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const getRuleSplits = `-- name: GetRuleSplits :many
+SELECT rs.rule_id, rs.category_id, c.name as category_name, rs.percentage
+FROM rule_splits rs
+JOIN categories c ON rs.category_id = c.id
+ORDER BY rs.rule_id, rs.position
+`
+
+type GetRuleSplitsRow struct {
+	RuleID       pgtype.UUID    `json:"rule_id"`
+	CategoryID   pgtype.UUID    `json:"category_id"`
+	CategoryName string         `json:"category_name"`
+	Percentage   pgtype.Numeric `json:"percentage"`
+}
+
+// Rule split template queries
+func (q *Queries) GetRuleSplits(ctx context.Context) ([]GetRuleSplitsRow, error) {
+	rows, err := q.db.Query(ctx, getRuleSplits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRuleSplitsRow
+	for rows.Next() {
+		var i GetRuleSplitsRow
+		if err := rows.Scan(
+			&i.RuleID,
+			&i.CategoryID,
+			&i.CategoryName,
+			&i.Percentage,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRuleSplitsByRuleID = `-- name: GetRuleSplitsByRuleID :many
+SELECT rs.rule_id, rs.category_id, c.name as category_name, rs.percentage
+FROM rule_splits rs
+JOIN categories c ON rs.category_id = c.id
+WHERE rs.rule_id = $1
+ORDER BY rs.position
+`
+
+type GetRuleSplitsByRuleIDRow struct {
+	RuleID       pgtype.UUID    `json:"rule_id"`
+	CategoryID   pgtype.UUID    `json:"category_id"`
+	CategoryName string         `json:"category_name"`
+	Percentage   pgtype.Numeric `json:"percentage"`
+}
+
+func (q *Queries) GetRuleSplitsByRuleID(ctx context.Context, ruleID pgtype.UUID) ([]GetRuleSplitsByRuleIDRow, error) {
+	rows, err := q.db.Query(ctx, getRuleSplitsByRuleID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRuleSplitsByRuleIDRow
+	for rows.Next() {
+		var i GetRuleSplitsByRuleIDRow
+		if err := rows.Scan(
+			&i.RuleID,
+			&i.CategoryID,
+			&i.CategoryName,
+			&i.Percentage,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRules = `-- name: GetRules :many
-SELECT r.id, r.match_value, r.category_id, c.name as category_name, r.priority, r.created_at, r.updated_at
+SELECT r.id, r.match_value, r.match_type, r.match_field, r.min_amount, r.max_amount, r.direction,
+       r.card_number, r.start_date, r.end_date,
+       r.category_id, c.name as category_name, r.assign_to, r.priority,
+       r.hit_count, r.last_matched_at, r.override_count, r.created_at, r.updated_at
 FROM categorization_rules r
 JOIN categories c ON r.category_id = c.id
 ORDER BY r.priority ASC, r.created_at ASC
 `
 
 type GetRulesRow struct {
-	ID           pgtype.UUID      `json:"id"`
-	MatchValue   string           `json:"match_value"`
-	CategoryID   pgtype.UUID      `json:"category_id"`
-	CategoryName string           `json:"category_name"`
-	Priority     int32            `json:"priority"`
-	CreatedAt    pgtype.Timestamp `json:"created_at"`
-	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+	ID            pgtype.UUID      `json:"id"`
+	MatchValue    string           `json:"match_value"`
+	MatchType     string           `json:"match_type"`
+	MatchField    string           `json:"match_field"`
+	MinAmount     pgtype.Numeric   `json:"min_amount"`
+	MaxAmount     pgtype.Numeric   `json:"max_amount"`
+	Direction     pgtype.Text      `json:"direction"`
+	CardNumber    pgtype.Text      `json:"card_number"`
+	StartDate     pgtype.Date      `json:"start_date"`
+	EndDate       pgtype.Date      `json:"end_date"`
+	CategoryID    pgtype.UUID      `json:"category_id"`
+	CategoryName  string           `json:"category_name"`
+	AssignTo      []pgtype.UUID    `json:"assign_to"`
+	Priority      int32            `json:"priority"`
+	HitCount      int32            `json:"hit_count"`
+	LastMatchedAt pgtype.Timestamp `json:"last_matched_at"`
+	OverrideCount int32            `json:"override_count"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
 }
 
 // Categorization rules queries
@@ -943,9 +1931,21 @@ func (q *Queries) GetRules(ctx context.Context) ([]GetRulesRow, error) {
 		if err := rows.Scan(
 			&i.ID,
 			&i.MatchValue,
+			&i.MatchType,
+			&i.MatchField,
+			&i.MinAmount,
+			&i.MaxAmount,
+			&i.Direction,
+			&i.CardNumber,
+			&i.StartDate,
+			&i.EndDate,
 			&i.CategoryID,
 			&i.CategoryName,
+			&i.AssignTo,
 			&i.Priority,
+			&i.HitCount,
+			&i.LastMatchedAt,
+			&i.OverrideCount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -960,15 +1960,33 @@ func (q *Queries) GetRules(ctx context.Context) ([]GetRulesRow, error) {
 }
 
 const getRulesForMatching = `-- name: GetRulesForMatching :many
-SELECT id, match_value, category_id
-FROM categorization_rules
-ORDER BY priority ASC, created_at ASC
+SELECT r.id, r.match_value, r.match_type, r.match_field, r.min_amount, r.max_amount, r.direction,
+       r.card_number, r.start_date, r.end_date, r.category_id, r.assign_to
+FROM categorization_rules r
+JOIN categories c ON r.category_id = c.id
+WHERE c.retired_at IS NULL
+  AND NOT EXISTS (
+      SELECT 1
+      FROM rule_splits rs
+      JOIN categories sc ON rs.category_id = sc.id
+      WHERE rs.rule_id = r.id AND sc.retired_at IS NOT NULL
+  )
+ORDER BY r.priority ASC, r.created_at ASC
 `
 
 type GetRulesForMatchingRow struct {
-	ID         pgtype.UUID `json:"id"`
-	MatchValue string      `json:"match_value"`
-	CategoryID pgtype.UUID `json:"category_id"`
+	ID         pgtype.UUID    `json:"id"`
+	MatchValue string         `json:"match_value"`
+	MatchType  string         `json:"match_type"`
+	MatchField string         `json:"match_field"`
+	MinAmount  pgtype.Numeric `json:"min_amount"`
+	MaxAmount  pgtype.Numeric `json:"max_amount"`
+	Direction  pgtype.Text    `json:"direction"`
+	CardNumber pgtype.Text    `json:"card_number"`
+	StartDate  pgtype.Date    `json:"start_date"`
+	EndDate    pgtype.Date    `json:"end_date"`
+	CategoryID pgtype.UUID    `json:"category_id"`
+	AssignTo   []pgtype.UUID  `json:"assign_to"`
 }
 
 func (q *Queries) GetRulesForMatching(ctx context.Context) ([]GetRulesForMatchingRow, error) {
@@ -980,7 +1998,71 @@ func (q *Queries) GetRulesForMatching(ctx context.Context) ([]GetRulesForMatchin
 	var items []GetRulesForMatchingRow
 	for rows.Next() {
 		var i GetRulesForMatchingRow
-		if err := rows.Scan(&i.ID, &i.MatchValue, &i.CategoryID); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.MatchValue,
+			&i.MatchType,
+			&i.MatchField,
+			&i.MinAmount,
+			&i.MaxAmount,
+			&i.Direction,
+			&i.CardNumber,
+			&i.StartDate,
+			&i.EndDate,
+			&i.CategoryID,
+			&i.AssignTo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSplitsForRuleApplication = `-- name: GetSplitsForRuleApplication :many
+SELECT s.transaction_id, s.category_id, c.name AS category_name, s.amount, s.notes, s.assigned_to
+FROM transaction_splits s
+JOIN categories c ON s.category_id = c.id
+JOIN transactions t ON s.transaction_id = t.id
+WHERE (t.archive_id IS NULL AND $1::boolean)
+   OR (t.archive_id IS NOT NULL AND $2::boolean)
+ORDER BY s.transaction_id, s.created_at
+`
+
+type GetSplitsForRuleApplicationParams struct {
+	IncludeActive   bool `json:"include_active"`
+	IncludeArchived bool `json:"include_archived"`
+}
+
+type GetSplitsForRuleApplicationRow struct {
+	TransactionID pgtype.UUID    `json:"transaction_id"`
+	CategoryID    pgtype.UUID    `json:"category_id"`
+	CategoryName  string         `json:"category_name"`
+	Amount        pgtype.Numeric `json:"amount"`
+	Notes         pgtype.Text    `json:"notes"`
+	AssignedTo    []pgtype.UUID  `json:"assigned_to"`
+}
+
+func (q *Queries) GetSplitsForRuleApplication(ctx context.Context, arg GetSplitsForRuleApplicationParams) ([]GetSplitsForRuleApplicationRow, error) {
+	rows, err := q.db.Query(ctx, getSplitsForRuleApplication, arg.IncludeActive, arg.IncludeArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSplitsForRuleApplicationRow
+	for rows.Next() {
+		var i GetSplitsForRuleApplicationRow
+		if err := rows.Scan(
+			&i.TransactionID,
+			&i.CategoryID,
+			&i.CategoryName,
+			&i.Amount,
+			&i.Notes,
+			&i.AssignedTo,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -992,20 +2074,22 @@ func (q *Queries) GetRulesForMatching(ctx context.Context) ([]GetRulesForMatchin
 }
 
 const getSubcategoriesByParent = `-- name: GetSubcategoriesByParent :many
-SELECT id, name, description, color, parent_id, created_at, updated_at
+SELECT id, name, description, color, parent_id, created_at, updated_at, retired_at, exclude_from_totals
 FROM categories
 WHERE parent_id = $1
 ORDER BY name
 `
 
 type GetSubcategoriesByParentRow struct {
-	ID          pgtype.UUID      `json:"id"`
-	Name        string           `json:"name"`
-	Description pgtype.Text      `json:"description"`
-	Color       pgtype.Text      `json:"color"`
-	ParentID    pgtype.UUID      `json:"parent_id"`
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+	ID                pgtype.UUID      `json:"id"`
+	Name              string           `json:"name"`
+	Description       pgtype.Text      `json:"description"`
+	Color             pgtype.Text      `json:"color"`
+	ParentID          pgtype.UUID      `json:"parent_id"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+	RetiredAt         pgtype.Timestamp `json:"retired_at"`
+	ExcludeFromTotals bool             `json:"exclude_from_totals"`
 }
 
 func (q *Queries) GetSubcategoriesByParent(ctx context.Context, parentID pgtype.UUID) ([]GetSubcategoriesByParentRow, error) {
@@ -1025,6 +2109,8 @@ func (q *Queries) GetSubcategoriesByParent(ctx context.Context, parentID pgtype.
 			&i.ParentID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.RetiredAt,
+			&i.ExcludeFromTotals,
 		); err != nil {
 			return nil, err
 		}
@@ -1082,19 +2168,20 @@ func (q *Queries) GetTopLevelCategories(ctx context.Context) ([]GetTopLevelCateg
 }
 
 const getTotalsByAssignedTo = `-- name: GetTotalsByAssignedTo :many
-WITH normalized_transaction_totals AS (
-    SELECT t.id,
-           SUM(CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END)::numeric AS normalized_amount
+WITH normalized_split_amounts AS (
+    SELECT ts.id,
+           CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END AS normalized_amount
     FROM transactions t
     JOIN transaction_splits ts ON ts.transaction_id = t.id
-    GROUP BY t.id
+    JOIN categories c ON c.id = ts.category_id
+    LEFT JOIN categories pc ON pc.id = c.parent_id
+    WHERE NOT c.exclude_from_totals
+      AND NOT COALESCE(pc.exclude_from_totals, FALSE)
 )
-SELECT p.name as assigned_to, SUM(nt.normalized_amount / array_length(t.assigned_to, 1))::numeric as total
-FROM transactions t
-JOIN normalized_transaction_totals nt ON nt.id = t.id
-CROSS JOIN LATERAL unnest(t.assigned_to) AS person_id
-JOIN people p ON p.id = person_id
-WHERE t.assigned_to IS NOT NULL AND array_length(t.assigned_to, 1) > 0
+SELECT p.name as assigned_to, SUM(ns.normalized_amount * sps.share)::numeric as total
+FROM normalized_split_amounts ns
+JOIN split_person_shares sps ON sps.split_id = ns.id
+JOIN people p ON p.id = sps.person_id
 GROUP BY p.id, p.name
 ORDER BY p.name
 `
@@ -1134,6 +2221,9 @@ WITH normalized_category_amounts AS (
 SELECT c.name as category_name, SUM(nca.signed_amount)::numeric as total
 FROM normalized_category_amounts nca
 JOIN categories c ON nca.category_id = c.id
+LEFT JOIN categories pc ON pc.id = c.parent_id
+WHERE NOT c.exclude_from_totals
+  AND NOT COALESCE(pc.exclude_from_totals, FALSE)
 GROUP BY c.id, c.name
 ORDER BY c.name
 `
@@ -1204,8 +2294,66 @@ func (q *Queries) GetTransactionByID(ctx context.Context, id pgtype.UUID) (GetTr
 	return i, err
 }
 
+const getTransactionSharedAmount = `-- name: GetTransactionSharedAmount :one
+-- The part of a transaction that its fixed amounts are taken from
+SELECT COALESCE(SUM(amount), 0)::numeric AS shared_amount
+FROM transaction_shared_amounts
+WHERE transaction_id = $1
+`
+
+// The part of a transaction that its fixed amounts are taken from
+func (q *Queries) GetTransactionSharedAmount(ctx context.Context, transactionID pgtype.UUID) (pgtype.Numeric, error) {
+	row := q.db.QueryRow(ctx, getTransactionSharedAmount, transactionID)
+	var shared_amount pgtype.Numeric
+	err := row.Scan(&shared_amount)
+	return shared_amount, err
+}
+
+const getTransactionShares = `-- name: GetTransactionShares :many
+SELECT tps.person_id, p.name AS person_name, s.weight, s.fixed_amount, tps.share
+FROM transaction_person_shares tps
+JOIN people p ON p.id = tps.person_id
+LEFT JOIN transaction_shares s ON s.transaction_id = tps.transaction_id AND s.person_id = tps.person_id
+WHERE tps.transaction_id = $1
+ORDER BY p.name
+`
+
+type GetTransactionSharesRow struct {
+	PersonID    pgtype.UUID    `json:"person_id"`
+	PersonName  string         `json:"person_name"`
+	Weight      pgtype.Numeric `json:"weight"`
+	FixedAmount pgtype.Numeric `json:"fixed_amount"`
+	Share       pgtype.Numeric `json:"share"`
+}
+
+func (q *Queries) GetTransactionShares(ctx context.Context, transactionID pgtype.UUID) ([]GetTransactionSharesRow, error) {
+	rows, err := q.db.Query(ctx, getTransactionShares, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTransactionSharesRow
+	for rows.Next() {
+		var i GetTransactionSharesRow
+		if err := rows.Scan(
+			&i.PersonID,
+			&i.PersonName,
+			&i.Weight,
+			&i.FixedAmount,
+			&i.Share,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTransactionSplitsByTransactionID = `-- name: GetTransactionSplitsByTransactionID :many
-SELECT id, transaction_id, amount, category_id, notes, created_at, updated_at
+SELECT id, transaction_id, amount, category_id, notes, created_at, updated_at, assigned_to
 FROM transaction_splits
 WHERE transaction_id = $1
 ORDER BY created_at ASC
@@ -1228,6 +2376,7 @@ func (q *Queries) GetTransactionSplitsByTransactionID(ctx context.Context, trans
 			&i.Notes,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.AssignedTo,
 		); err != nil {
 			return nil, err
 		}
@@ -1404,6 +2553,275 @@ func (q *Queries) GetTransactionsByFileName(ctx context.Context, fileName pgtype
 	return items, nil
 }
 
+const getTransactionsForRuleApplication = `-- name: GetTransactionsForRuleApplication :many
+SELECT t.id, t.description, t.amount, t.transaction_date, t.posted_date, t.card_number,
+       t.archive_id, t.rule_id, t.splits_edited_at, t.assigned_to, t.csv_category
+FROM transactions t
+WHERE (t.archive_id IS NULL AND $1::boolean)
+   OR (t.archive_id IS NOT NULL AND $2::boolean)
+ORDER BY t.transaction_date DESC NULLS LAST, t.created_at DESC
+`
+
+type GetTransactionsForRuleApplicationParams struct {
+	IncludeActive   bool `json:"include_active"`
+	IncludeArchived bool `json:"include_archived"`
+}
+
+type GetTransactionsForRuleApplicationRow struct {
+	ID              pgtype.UUID      `json:"id"`
+	Description     string           `json:"description"`
+	Amount          pgtype.Numeric   `json:"amount"`
+	TransactionDate pgtype.Date      `json:"transaction_date"`
+	PostedDate      pgtype.Date      `json:"posted_date"`
+	CardNumber      pgtype.Text      `json:"card_number"`
+	ArchiveID       pgtype.UUID      `json:"archive_id"`
+	RuleID          pgtype.UUID      `json:"rule_id"`
+	SplitsEditedAt  pgtype.Timestamp `json:"splits_edited_at"`
+	AssignedTo      []pgtype.UUID    `json:"assigned_to"`
+	CsvCategory     pgtype.Text      `json:"csv_category"`
+}
+
+// Retroactive rule application queries
+func (q *Queries) GetTransactionsForRuleApplication(ctx context.Context, arg GetTransactionsForRuleApplicationParams) ([]GetTransactionsForRuleApplicationRow, error) {
+	rows, err := q.db.Query(ctx, getTransactionsForRuleApplication, arg.IncludeActive, arg.IncludeArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTransactionsForRuleApplicationRow
+	for rows.Next() {
+		var i GetTransactionsForRuleApplicationRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.Amount,
+			&i.TransactionDate,
+			&i.PostedDate,
+			&i.CardNumber,
+			&i.ArchiveID,
+			&i.RuleID,
+			&i.SplitsEditedAt,
+			&i.AssignedTo,
+			&i.CsvCategory,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTransactionsInCategory = `-- name: GetTransactionsInCategory :many
+SELECT t.id, t.description, t.amount, t.transaction_date
+FROM transactions t
+WHERE t.archive_id IS NULL
+  AND EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id)
+  AND NOT EXISTS (
+      SELECT 1 FROM transaction_splits s
+      WHERE s.transaction_id = t.id AND s.category_id <> $1::uuid
+  )
+ORDER BY t.transaction_date DESC NULLS LAST, t.created_at DESC
+`
+
+type GetTransactionsInCategoryRow struct {
+	ID              pgtype.UUID    `json:"id"`
+	Description     string         `json:"description"`
+	Amount          pgtype.Numeric `json:"amount"`
+	TransactionDate pgtype.Date    `json:"transaction_date"`
+}
+
+func (q *Queries) GetTransactionsInCategory(ctx context.Context, categoryID pgtype.UUID) ([]GetTransactionsInCategoryRow, error) {
+	rows, err := q.db.Query(ctx, getTransactionsInCategory, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTransactionsInCategoryRow
+	for rows.Next() {
+		var i GetTransactionsInCategoryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.Amount,
+			&i.TransactionDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markTransactionSplitsEdited = `-- name: MarkTransactionSplitsEdited :exec
+UPDATE transactions
+SET splits_edited_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (q *Queries) MarkTransactionSplitsEdited(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markTransactionSplitsEdited, id)
+	return err
+}
+
+const moveBudgetsToCategory = `-- name: MoveBudgetsToCategory :execrows
+INSERT INTO budgets (category_id, person_id, month, amount, rollover)
+SELECT $1::uuid, person_id, month, amount, rollover
+FROM budgets
+WHERE category_id = $2::uuid
+ON CONFLICT (category_id, COALESCE(person_id, '00000000-0000-0000-0000-000000000000'::uuid), month)
+DO UPDATE SET amount = budgets.amount + EXCLUDED.amount, updated_at = CURRENT_TIMESTAMP
+`
+
+type MoveBudgetsToCategoryParams struct {
+	TargetID pgtype.UUID `json:"target_id"`
+	SourceID pgtype.UUID `json:"source_id"`
+}
+
+// Budgets for the same person and month as a target budget are added to it
+func (q *Queries) MoveBudgetsToCategory(ctx context.Context, arg MoveBudgetsToCategoryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveBudgetsToCategory, arg.TargetID, arg.SourceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveRuleSplitsToCategory = `-- name: MoveRuleSplitsToCategory :execrows
+UPDATE rule_splits
+SET category_id = $1::uuid
+WHERE category_id = $2::uuid
+`
+
+type MoveRuleSplitsToCategoryParams struct {
+	TargetID pgtype.UUID `json:"target_id"`
+	SourceID pgtype.UUID `json:"source_id"`
+}
+
+func (q *Queries) MoveRuleSplitsToCategory(ctx context.Context, arg MoveRuleSplitsToCategoryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveRuleSplitsToCategory, arg.TargetID, arg.SourceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveRulesToCategory = `-- name: MoveRulesToCategory :execrows
+UPDATE categorization_rules
+SET category_id = $1::uuid, updated_at = CURRENT_TIMESTAMP
+WHERE category_id = $2::uuid
+`
+
+type MoveRulesToCategoryParams struct {
+	TargetID pgtype.UUID `json:"target_id"`
+	SourceID pgtype.UUID `json:"source_id"`
+}
+
+func (q *Queries) MoveRulesToCategory(ctx context.Context, arg MoveRulesToCategoryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveRulesToCategory, arg.TargetID, arg.SourceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveTransactionSplitsToCategory = `-- name: MoveTransactionSplitsToCategory :execrows
+UPDATE transaction_splits
+SET category_id = $1::uuid, updated_at = CURRENT_TIMESTAMP
+WHERE category_id = $2::uuid
+`
+
+type MoveTransactionSplitsToCategoryParams struct {
+	TargetID pgtype.UUID `json:"target_id"`
+	SourceID pgtype.UUID `json:"source_id"`
+}
+
+// Category merge queries
+func (q *Queries) MoveTransactionSplitsToCategory(ctx context.Context, arg MoveTransactionSplitsToCategoryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveTransactionSplitsToCategory, arg.TargetID, arg.SourceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordRuleHits = `-- name: RecordRuleHits :exec
+UPDATE categorization_rules r
+SET hit_count = r.hit_count + u.hits, last_matched_at = CURRENT_TIMESTAMP
+FROM unnest($1::uuid[], $2::int[]) AS u(rule_id, hits)
+WHERE r.id = u.rule_id
+`
+
+type RecordRuleHitsParams struct {
+	RuleIds []pgtype.UUID `json:"rule_ids"`
+	Hits    []int32       `json:"hits"`
+}
+
+func (q *Queries) RecordRuleHits(ctx context.Context, arg RecordRuleHitsParams) error {
+	_, err := q.db.Exec(ctx, recordRuleHits, arg.RuleIds, arg.Hits)
+	return err
+}
+
+const recordRuleOverride = `-- name: RecordRuleOverride :exec
+UPDATE categorization_rules r
+SET override_count = r.override_count + 1
+FROM transactions t
+WHERE t.id = $1
+  AND t.rule_id = r.id
+  AND t.splits_edited_at IS NULL
+  AND EXISTS (
+    SELECT 1 FROM unnest($2::uuid[]) AS c(category_id)
+    WHERE c.category_id <> r.category_id
+      AND c.category_id NOT IN (SELECT rs.category_id FROM rule_splits rs WHERE rs.rule_id = r.id)
+  )
+`
+
+type RecordRuleOverrideParams struct {
+	TransactionID pgtype.UUID   `json:"transaction_id"`
+	CategoryIds   []pgtype.UUID `json:"category_ids"`
+}
+
+// Counts the first manual edit of a rule-categorized transaction that moves money
+// to a category the rule never assigns
+func (q *Queries) RecordRuleOverride(ctx context.Context, arg RecordRuleOverrideParams) error {
+	_, err := q.db.Exec(ctx, recordRuleOverride, arg.TransactionID, arg.CategoryIds)
+	return err
+}
+
+const removeImportRuleHits = `-- name: RemoveImportRuleHits :exec
+UPDATE categorization_rules r
+SET hit_count = GREATEST(r.hit_count - h.hits, 0)
+FROM (
+    SELECT rule_id, COUNT(*)::int AS hits
+    FROM transactions
+    WHERE import_id = $1 AND rule_id IS NOT NULL
+    GROUP BY rule_id
+) h
+WHERE r.id = h.rule_id
+`
+
+// Takes back the hits of the rules that categorized an import's transactions
+func (q *Queries) RemoveImportRuleHits(ctx context.Context, importID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, removeImportRuleHits, importID)
+	return err
+}
+
+const removePersonFromRules = `-- name: RemovePersonFromRules :exec
+UPDATE categorization_rules
+SET assign_to = array_remove(assign_to, $1), updated_at = CURRENT_TIMESTAMP
+WHERE $1 = ANY(assign_to)
+`
+
+func (q *Queries) RemovePersonFromRules(ctx context.Context, arrayRemove interface{}) error {
+	_, err := q.db.Exec(ctx, removePersonFromRules, arrayRemove)
+	return err
+}
+
 const removePersonFromTransaction = `-- name: RemovePersonFromTransaction :one
 UPDATE transactions
 SET assigned_to = array_remove(assigned_to, $2), updated_at = CURRENT_TIMESTAMP
@@ -1451,6 +2869,83 @@ func (q *Queries) RemovePersonFromTransaction(ctx context.Context, arg RemovePer
 	return i, err
 }
 
+const reparentSubcategories = `-- name: ReparentSubcategories :execrows
+UPDATE categories
+SET parent_id = $1::uuid, updated_at = CURRENT_TIMESTAMP
+WHERE parent_id = $2::uuid
+`
+
+type ReparentSubcategoriesParams struct {
+	TargetID pgtype.UUID `json:"target_id"`
+	SourceID pgtype.UUID `json:"source_id"`
+}
+
+func (q *Queries) ReparentSubcategories(ctx context.Context, arg ReparentSubcategoriesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reparentSubcategories, arg.TargetID, arg.SourceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const restoreCategory = `-- name: RestoreCategory :execrows
+UPDATE categories
+SET retired_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+   OR (parent_id = $1 AND retired_at = (SELECT retired_at FROM categories WHERE id = $1))
+`
+
+func (q *Queries) RestoreCategory(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, restoreCategory, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const retireCategory = `-- name: RetireCategory :execrows
+UPDATE categories
+SET retired_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE (id = $1 OR parent_id = $1) AND retired_at IS NULL
+`
+
+// Category retirement queries
+func (q *Queries) RetireCategory(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, retireCategory, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setTransactionRules = `-- name: SetTransactionRules :exec
+UPDATE transactions t
+SET rule_id = u.rule_id, splits_edited_at = NULL, updated_at = CURRENT_TIMESTAMP
+FROM unnest($1::uuid[], $2::uuid[]) AS u(transaction_id, rule_id)
+WHERE t.id = u.transaction_id
+`
+
+type SetTransactionRulesParams struct {
+	TransactionIds []pgtype.UUID `json:"transaction_ids"`
+	RuleIds        []pgtype.UUID `json:"rule_ids"`
+}
+
+func (q *Queries) SetTransactionRules(ctx context.Context, arg SetTransactionRulesParams) error {
+	_, err := q.db.Exec(ctx, setTransactionRules, arg.TransactionIds, arg.RuleIds)
+	return err
+}
+
+const unassignTransactionSplitsByPerson = `-- name: UnassignTransactionSplitsByPerson :exec
+UPDATE transaction_splits
+SET assigned_to = NULLIF(array_remove(assigned_to, $1), '{}'), updated_at = CURRENT_TIMESTAMP
+WHERE $1 = ANY(assigned_to)
+`
+
+func (q *Queries) UnassignTransactionSplitsByPerson(ctx context.Context, arrayRemove interface{}) error {
+	_, err := q.db.Exec(ctx, unassignTransactionSplitsByPerson, arrayRemove)
+	return err
+}
+
 const unassignTransactionsByPerson = `-- name: UnassignTransactionsByPerson :exec
 UPDATE transactions
 SET assigned_to = array_remove(assigned_to, $1), updated_at = CURRENT_TIMESTAMP
@@ -1490,28 +2985,70 @@ func (q *Queries) UpdateArchiveTotals(ctx context.Context, arg UpdateArchiveTota
 	return i, err
 }
 
+const updateBudget = `-- name: UpdateBudget :one
+UPDATE budgets
+SET category_id = $2, person_id = $3, month = $4, amount = $5, rollover = $6, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, category_id, person_id, month, amount, rollover, created_at, updated_at
+`
+
+type UpdateBudgetParams struct {
+	ID         pgtype.UUID    `json:"id"`
+	CategoryID pgtype.UUID    `json:"category_id"`
+	PersonID   pgtype.UUID    `json:"person_id"`
+	Month      pgtype.Date    `json:"month"`
+	Amount     pgtype.Numeric `json:"amount"`
+	Rollover   bool           `json:"rollover"`
+}
+
+func (q *Queries) UpdateBudget(ctx context.Context, arg UpdateBudgetParams) (Budget, error) {
+	row := q.db.QueryRow(ctx, updateBudget,
+		arg.ID,
+		arg.CategoryID,
+		arg.PersonID,
+		arg.Month,
+		arg.Amount,
+		arg.Rollover,
+	)
+	var i Budget
+	err := row.Scan(
+		&i.ID,
+		&i.CategoryID,
+		&i.PersonID,
+		&i.Month,
+		&i.Amount,
+		&i.Rollover,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const updateCategory = `-- name: UpdateCategory :one
 UPDATE categories
-SET name = $2, description = $3, color = $4, updated_at = CURRENT_TIMESTAMP
+SET name = $2, description = $3, color = $4, exclude_from_totals = $5, updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
-RETURNING id, name, description, color, parent_id, created_at, updated_at
+RETURNING id, name, description, color, parent_id, created_at, updated_at, retired_at, exclude_from_totals
 `
 
 type UpdateCategoryParams struct {
-	ID          pgtype.UUID `json:"id"`
-	Name        string      `json:"name"`
-	Description pgtype.Text `json:"description"`
-	Color       pgtype.Text `json:"color"`
+	ID                pgtype.UUID `json:"id"`
+	Name              string      `json:"name"`
+	Description       pgtype.Text `json:"description"`
+	Color             pgtype.Text `json:"color"`
+	ExcludeFromTotals bool        `json:"exclude_from_totals"`
 }
 
 type UpdateCategoryRow struct {
-	ID          pgtype.UUID      `json:"id"`
-	Name        string           `json:"name"`
-	Description pgtype.Text      `json:"description"`
-	Color       pgtype.Text      `json:"color"`
-	ParentID    pgtype.UUID      `json:"parent_id"`
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+	ID                pgtype.UUID      `json:"id"`
+	Name              string           `json:"name"`
+	Description       pgtype.Text      `json:"description"`
+	Color             pgtype.Text      `json:"color"`
+	ParentID          pgtype.UUID      `json:"parent_id"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+	RetiredAt         pgtype.Timestamp `json:"retired_at"`
+	ExcludeFromTotals bool             `json:"exclude_from_totals"`
 }
 
 func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (UpdateCategoryRow, error) {
@@ -1520,6 +3057,7 @@ func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams)
 		arg.Name,
 		arg.Description,
 		arg.Color,
+		arg.ExcludeFromTotals,
 	)
 	var i UpdateCategoryRow
 	err := row.Scan(
@@ -1530,6 +3068,110 @@ func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams)
 		&i.ParentID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RetiredAt,
+		&i.ExcludeFromTotals,
+	)
+	return i, err
+}
+
+const updateImportCounts = `-- name: UpdateImportCounts :exec
+UPDATE imports
+SET total_rows = $2, imported_rows = $3, skipped_rows = $4
+WHERE id = $1
+`
+
+type UpdateImportCountsParams struct {
+	ID           pgtype.UUID `json:"id"`
+	TotalRows    int32       `json:"total_rows"`
+	ImportedRows int32       `json:"imported_rows"`
+	SkippedRows  int32       `json:"skipped_rows"`
+}
+
+func (q *Queries) UpdateImportCounts(ctx context.Context, arg UpdateImportCountsParams) error {
+	_, err := q.db.Exec(ctx, updateImportCounts,
+		arg.ID,
+		arg.TotalRows,
+		arg.ImportedRows,
+		arg.SkippedRows,
+	)
+	return err
+}
+
+const updateImportProfile = `-- name: UpdateImportProfile :one
+UPDATE import_profiles
+SET name = $2, delimiter = $3, has_header = $4, header_columns = $5, date_format = $6,
+    amount_format = $7, expense_sign = $8, transaction_date_column = $9, posted_date_column = $10,
+    card_number_column = $11, description_column = $12, category_column = $13,
+    amount_column = $14, debit_column = $15, credit_column = $16, decimal_separator = $17,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+          transaction_date_column, posted_date_column, card_number_column, description_column,
+          category_column, amount_column, debit_column, credit_column, created_at, updated_at,
+          decimal_separator
+`
+
+type UpdateImportProfileParams struct {
+	ID                    pgtype.UUID `json:"id"`
+	Name                  string      `json:"name"`
+	Delimiter             string      `json:"delimiter"`
+	HasHeader             bool        `json:"has_header"`
+	HeaderColumns         []string    `json:"header_columns"`
+	DateFormat            string      `json:"date_format"`
+	AmountFormat          string      `json:"amount_format"`
+	ExpenseSign           string      `json:"expense_sign"`
+	TransactionDateColumn pgtype.Int4 `json:"transaction_date_column"`
+	PostedDateColumn      pgtype.Int4 `json:"posted_date_column"`
+	CardNumberColumn      pgtype.Int4 `json:"card_number_column"`
+	DescriptionColumn     int32       `json:"description_column"`
+	CategoryColumn        pgtype.Int4 `json:"category_column"`
+	AmountColumn          pgtype.Int4 `json:"amount_column"`
+	DebitColumn           pgtype.Int4 `json:"debit_column"`
+	CreditColumn          pgtype.Int4 `json:"credit_column"`
+	DecimalSeparator      string      `json:"decimal_separator"`
+}
+
+func (q *Queries) UpdateImportProfile(ctx context.Context, arg UpdateImportProfileParams) (ImportProfile, error) {
+	row := q.db.QueryRow(ctx, updateImportProfile,
+		arg.ID,
+		arg.Name,
+		arg.Delimiter,
+		arg.HasHeader,
+		arg.HeaderColumns,
+		arg.DateFormat,
+		arg.AmountFormat,
+		arg.ExpenseSign,
+		arg.TransactionDateColumn,
+		arg.PostedDateColumn,
+		arg.CardNumberColumn,
+		arg.DescriptionColumn,
+		arg.CategoryColumn,
+		arg.AmountColumn,
+		arg.DebitColumn,
+		arg.CreditColumn,
+		arg.DecimalSeparator,
+	)
+	var i ImportProfile
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Delimiter,
+		&i.HasHeader,
+		&i.HeaderColumns,
+		&i.DateFormat,
+		&i.AmountFormat,
+		&i.ExpenseSign,
+		&i.TransactionDateColumn,
+		&i.PostedDateColumn,
+		&i.CardNumberColumn,
+		&i.DescriptionColumn,
+		&i.CategoryColumn,
+		&i.AmountColumn,
+		&i.DebitColumn,
+		&i.CreditColumn,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DecimalSeparator,
 	)
 	return i, err
 }
@@ -1562,16 +3204,29 @@ func (q *Queries) UpdatePerson(ctx context.Context, arg UpdatePersonParams) (Per
 
 const updateRule = `-- name: UpdateRule :one
 UPDATE categorization_rules
-SET match_value = $2, category_id = $3, priority = $4, updated_at = CURRENT_TIMESTAMP
+SET match_value = $2, category_id = $3, priority = $4, match_type = $5, match_field = $6,
+    min_amount = $7, max_amount = $8, direction = $9, card_number = $10, start_date = $11,
+    end_date = $12, assign_to = $13, updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
-RETURNING id, match_value, category_id, priority, created_at, updated_at
+RETURNING id, match_value, category_id, priority, created_at, updated_at, match_type,
+          match_field, min_amount, max_amount, direction, card_number, start_date, end_date,
+          assign_to, hit_count, last_matched_at, override_count
 `
 
 type UpdateRuleParams struct {
-	ID         pgtype.UUID `json:"id"`
-	MatchValue string      `json:"match_value"`
-	CategoryID pgtype.UUID `json:"category_id"`
-	Priority   int32       `json:"priority"`
+	ID         pgtype.UUID    `json:"id"`
+	MatchValue string         `json:"match_value"`
+	CategoryID pgtype.UUID    `json:"category_id"`
+	Priority   int32          `json:"priority"`
+	MatchType  string         `json:"match_type"`
+	MatchField string         `json:"match_field"`
+	MinAmount  pgtype.Numeric `json:"min_amount"`
+	MaxAmount  pgtype.Numeric `json:"max_amount"`
+	Direction  pgtype.Text    `json:"direction"`
+	CardNumber pgtype.Text    `json:"card_number"`
+	StartDate  pgtype.Date    `json:"start_date"`
+	EndDate    pgtype.Date    `json:"end_date"`
+	AssignTo   []pgtype.UUID  `json:"assign_to"`
 }
 
 func (q *Queries) UpdateRule(ctx context.Context, arg UpdateRuleParams) (CategorizationRule, error) {
@@ -1580,6 +3235,15 @@ func (q *Queries) UpdateRule(ctx context.Context, arg UpdateRuleParams) (Categor
 		arg.MatchValue,
 		arg.CategoryID,
 		arg.Priority,
+		arg.MatchType,
+		arg.MatchField,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Direction,
+		arg.CardNumber,
+		arg.StartDate,
+		arg.EndDate,
+		arg.AssignTo,
 	)
 	var i CategorizationRule
 	err := row.Scan(
@@ -1589,6 +3253,18 @@ func (q *Queries) UpdateRule(ctx context.Context, arg UpdateRuleParams) (Categor
 		&i.Priority,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MatchType,
+		&i.MatchField,
+		&i.MinAmount,
+		&i.MaxAmount,
+		&i.Direction,
+		&i.CardNumber,
+		&i.StartDate,
+		&i.EndDate,
+		&i.AssignTo,
+		&i.HitCount,
+		&i.LastMatchedAt,
+		&i.OverrideCount,
 	)
 	return i, err
 }
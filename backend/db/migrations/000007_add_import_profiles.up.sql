@@ -0,0 +1,36 @@
+-- Import profiles describe how a bank's CSV export maps onto transaction fields.
+-- Column positions are zero-based indexes into each CSV record.
+CREATE TABLE import_profiles (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) UNIQUE NOT NULL,
+    delimiter VARCHAR(1) NOT NULL DEFAULT ',',
+    has_header BOOLEAN NOT NULL DEFAULT TRUE,
+    header_columns TEXT[] NOT NULL DEFAULT '{}', -- expected header row, used for auto-detection
+    date_format VARCHAR(50) NOT NULL DEFAULT '2006-01-02', -- Go reference layout
+    amount_format VARCHAR(20) NOT NULL DEFAULT 'debit_credit'
+        CHECK (amount_format IN ('debit_credit', 'signed')),
+    expense_sign VARCHAR(10) NOT NULL DEFAULT 'positive'
+        CHECK (expense_sign IN ('positive', 'negative')),
+    transaction_date_column INT,
+    posted_date_column INT,
+    card_number_column INT,
+    description_column INT NOT NULL,
+    category_column INT,
+    amount_column INT,
+    debit_column INT,
+    credit_column INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Seed the original hardcoded 7-column layout as the default profile
+INSERT INTO import_profiles (
+    name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+    transaction_date_column, posted_date_column, card_number_column, description_column,
+    category_column, debit_column, credit_column
+) VALUES (
+    'Default', ',', TRUE,
+    ARRAY['Transaction Date', 'Posted Date', 'Card No.', 'Description', 'Category', 'Debit', 'Credit'],
+    '2006-01-02', 'debit_credit', 'positive',
+    0, 1, 2, 3, 4, 5, 6
+);
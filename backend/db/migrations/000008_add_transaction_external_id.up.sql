@@ -0,0 +1,9 @@
+-- Stable bank-provided transaction identifier (OFX FITID) used for import dedup
+
+ALTER TABLE transactions
+ADD COLUMN external_id VARCHAR(255);
+
+-- FITIDs are unique per account; card_number holds the account suffix
+CREATE UNIQUE INDEX idx_transactions_external_id
+ON transactions(card_number, external_id)
+WHERE external_id IS NOT NULL;
@@ -0,0 +1,21 @@
+-- Record the outcome of each upload so skipped rows can be reviewed later
+
+CREATE TABLE import_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    file_name VARCHAR(255),
+    format VARCHAR(10) NOT NULL,
+    imported_rows INT NOT NULL DEFAULT 0,
+    skipped_rows INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE import_rejections (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    report_id UUID NOT NULL REFERENCES import_reports(id) ON DELETE CASCADE,
+    line_number INT NOT NULL,
+    raw_content TEXT NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL
+);
+
+CREATE INDEX idx_import_rejections_report_id ON import_rejections(report_id);
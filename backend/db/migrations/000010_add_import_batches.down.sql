@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_transactions_import_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS import_id;
+
+DROP INDEX IF EXISTS idx_imports_file_hash;
+ALTER TABLE imports
+    DROP COLUMN IF EXISTS uploaded_by,
+    DROP COLUMN IF EXISTS total_rows,
+    DROP COLUMN IF EXISTS file_hash;
+
+ALTER INDEX idx_import_rejections_import_id RENAME TO idx_import_rejections_report_id;
+ALTER TABLE import_rejections RENAME CONSTRAINT import_rejections_import_id_fkey TO import_rejections_report_id_fkey;
+ALTER TABLE import_rejections RENAME COLUMN import_id TO report_id;
+
+ALTER INDEX imports_pkey RENAME TO import_reports_pkey;
+ALTER TABLE imports RENAME TO import_reports;
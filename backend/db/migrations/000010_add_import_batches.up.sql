@@ -0,0 +1,20 @@
+-- Promote import reports to first-class import batches that own their transactions
+
+ALTER TABLE import_reports RENAME TO imports;
+ALTER INDEX import_reports_pkey RENAME TO imports_pkey;
+
+ALTER TABLE import_rejections RENAME COLUMN report_id TO import_id;
+ALTER TABLE import_rejections RENAME CONSTRAINT import_rejections_report_id_fkey TO import_rejections_import_id_fkey;
+ALTER INDEX idx_import_rejections_report_id RENAME TO idx_import_rejections_import_id;
+
+ALTER TABLE imports
+    ADD COLUMN file_hash VARCHAR(64),
+    ADD COLUMN total_rows INT NOT NULL DEFAULT 0,
+    ADD COLUMN uploaded_by UUID REFERENCES people(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_imports_file_hash ON imports(file_hash);
+
+ALTER TABLE transactions
+ADD COLUMN import_id UUID REFERENCES imports(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_transactions_import_id ON transactions(import_id);
@@ -0,0 +1,2 @@
+ALTER TABLE import_profiles
+DROP COLUMN IF EXISTS decimal_separator;
@@ -0,0 +1,5 @@
+-- Decimal separator used by amount columns; the other of '.' and ',' is treated
+-- as a thousands separator
+ALTER TABLE import_profiles
+ADD COLUMN decimal_separator VARCHAR(1) NOT NULL DEFAULT '.'
+    CHECK (decimal_separator IN ('.', ','));
@@ -0,0 +1 @@
+ALTER TABLE categorization_rules DROP COLUMN match_type;
@@ -0,0 +1,4 @@
+-- Rules match by substring unless another match type is chosen
+ALTER TABLE categorization_rules
+    ADD COLUMN match_type VARCHAR(20) NOT NULL DEFAULT 'contains'
+    CHECK (match_type IN ('contains', 'exact', 'prefix', 'suffix', 'regex'));
@@ -0,0 +1,10 @@
+ALTER TABLE categorization_rules
+    DROP CONSTRAINT categorization_rules_date_window,
+    DROP CONSTRAINT categorization_rules_amount_range,
+    DROP COLUMN end_date,
+    DROP COLUMN start_date,
+    DROP COLUMN card_number,
+    DROP COLUMN direction,
+    DROP COLUMN max_amount,
+    DROP COLUMN min_amount,
+    DROP COLUMN match_field;
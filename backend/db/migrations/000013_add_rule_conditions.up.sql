@@ -0,0 +1,15 @@
+-- Optional conditions a transaction must also meet for a rule to match. Amount
+-- bounds apply to the absolute amount; debit means an expense (positive amount).
+ALTER TABLE categorization_rules
+    ADD COLUMN match_field VARCHAR(20) NOT NULL DEFAULT 'any'
+        CHECK (match_field IN ('any', 'description', 'category')),
+    ADD COLUMN min_amount DECIMAL(12, 2) CHECK (min_amount >= 0),
+    ADD COLUMN max_amount DECIMAL(12, 2) CHECK (max_amount >= 0),
+    ADD COLUMN direction VARCHAR(10) CHECK (direction IN ('debit', 'credit')),
+    ADD COLUMN card_number VARCHAR(20),
+    ADD COLUMN start_date DATE,
+    ADD COLUMN end_date DATE,
+    ADD CONSTRAINT categorization_rules_amount_range
+        CHECK (min_amount IS NULL OR max_amount IS NULL OR min_amount <= max_amount),
+    ADD CONSTRAINT categorization_rules_date_window
+        CHECK (start_date IS NULL OR end_date IS NULL OR start_date <= end_date);
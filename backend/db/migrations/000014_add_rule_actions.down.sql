@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rule_splits;
+ALTER TABLE categorization_rules DROP COLUMN assign_to;
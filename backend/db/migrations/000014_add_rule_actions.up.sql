@@ -0,0 +1,15 @@
+-- People a matched transaction is assigned to, like transactions.assigned_to
+ALTER TABLE categorization_rules ADD COLUMN assign_to UUID[];
+
+-- Split template lines: a matched transaction is split between these categories
+-- by percentage instead of getting a single split in the rule's category
+CREATE TABLE rule_splits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rule_id UUID NOT NULL REFERENCES categorization_rules(id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    percentage DECIMAL(5, 2) NOT NULL CHECK (percentage > 0 AND percentage <= 100),
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_rule_splits_rule_id ON rule_splits(rule_id, position);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transactions_rule_id;
+ALTER TABLE transactions
+    DROP COLUMN splits_edited_at,
+    DROP COLUMN rule_id;
@@ -0,0 +1,7 @@
+-- The rule that categorized a transaction, and when its splits were last edited by
+-- hand. Re-applying rules leaves manually edited transactions alone by default.
+ALTER TABLE transactions
+    ADD COLUMN rule_id UUID REFERENCES categorization_rules(id) ON DELETE SET NULL,
+    ADD COLUMN splits_edited_at TIMESTAMP;
+
+CREATE INDEX idx_transactions_rule_id ON transactions(rule_id);
@@ -0,0 +1,4 @@
+ALTER TABLE categorization_rules
+    DROP COLUMN override_count,
+    DROP COLUMN last_matched_at,
+    DROP COLUMN hit_count;
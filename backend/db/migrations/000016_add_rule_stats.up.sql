@@ -0,0 +1,17 @@
+-- Per-rule hit statistics: how many transactions a rule categorized, when it last
+-- did, and how often its result was overridden by editing the splits by hand
+ALTER TABLE categorization_rules
+    ADD COLUMN hit_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN last_matched_at TIMESTAMP,
+    ADD COLUMN override_count INTEGER NOT NULL DEFAULT 0;
+
+-- Backfill from the transactions that already record their rule
+UPDATE categorization_rules r
+SET hit_count = s.hits, last_matched_at = s.last_matched_at
+FROM (
+    SELECT rule_id, COUNT(*) AS hits, MAX(created_at) AS last_matched_at
+    FROM transactions
+    WHERE rule_id IS NOT NULL
+    GROUP BY rule_id
+) s
+WHERE r.id = s.rule_id;
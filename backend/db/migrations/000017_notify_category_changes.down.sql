@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trigger_notify_categories_changed ON categories;
+DROP FUNCTION IF EXISTS notify_categories_changed();
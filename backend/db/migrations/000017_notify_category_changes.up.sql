@@ -0,0 +1,14 @@
+-- Notify listeners when categories change, so servers can reload their cached
+-- category mapping whichever process made the change
+CREATE OR REPLACE FUNCTION notify_categories_changed()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('categories_changed', TG_OP);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_notify_categories_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON categories
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION notify_categories_changed();
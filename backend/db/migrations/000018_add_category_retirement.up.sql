@@ -0,0 +1,3 @@
+-- Retired categories are hidden from pickers and cannot be rule targets, but stay
+-- in place for the transaction splits and archives that use them
+ALTER TABLE categories ADD COLUMN retired_at TIMESTAMP;
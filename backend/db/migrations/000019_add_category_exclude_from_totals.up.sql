@@ -0,0 +1,5 @@
+-- Categories excluded from person totals and spending reports, together with
+-- their subcategories. Reimbursable was already left out of the trends charts.
+ALTER TABLE categories ADD COLUMN exclude_from_totals BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE categories SET exclude_from_totals = TRUE WHERE name = 'Reimbursable';
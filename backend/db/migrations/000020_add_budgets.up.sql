@@ -0,0 +1,17 @@
+-- Monthly spending budgets per category, for one person or, without person_id,
+-- for the household. A budget applies from its month until a later budget for the
+-- same category and person replaces it.
+CREATE TABLE budgets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    person_id UUID REFERENCES people(id) ON DELETE CASCADE,
+    month DATE NOT NULL CHECK (EXTRACT(DAY FROM month) = 1), -- first day of the month
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount >= 0),
+    rollover BOOLEAN NOT NULL DEFAULT FALSE, -- carry unspent amounts into the next month
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One budget per category, person or household, and month
+CREATE UNIQUE INDEX idx_budgets_category_person_month
+    ON budgets (category_id, COALESCE(person_id, '00000000-0000-0000-0000-000000000000'::uuid), month);
@@ -0,0 +1,2 @@
+DROP VIEW IF EXISTS transaction_person_shares;
+DROP TABLE IF EXISTS transaction_shares;
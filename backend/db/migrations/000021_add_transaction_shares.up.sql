@@ -0,0 +1,50 @@
+-- Unequal shares of a transaction between the people it is assigned to. A person
+-- has either a weight or a fixed amount; the amount left after the fixed amounts is
+-- divided by weight between the others, whose weight defaults to 1. Without rows,
+-- the transaction is divided equally.
+CREATE TABLE transaction_shares (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transaction_id UUID NOT NULL REFERENCES transactions(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    person_id UUID NOT NULL REFERENCES people(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    weight DECIMAL(12, 4) CHECK (weight > 0),
+    fixed_amount DECIMAL(12, 2) CHECK (fixed_amount >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(transaction_id, person_id),
+    CHECK ((weight IS NULL) <> (fixed_amount IS NULL))
+);
+
+-- The fraction of each transaction's amount that counts for each assigned person.
+-- Shares of people no longer assigned are ignored. If the fixed amounts cover the
+-- whole amount, or nobody is left for the remainder, the fixed amounts are taken in
+-- proportion, so the fractions of a transaction always add up to 1.
+CREATE VIEW transaction_person_shares AS
+WITH assignments AS (
+    SELECT t.id AS transaction_id,
+           a.person_id,
+           ABS(t.amount) AS total,
+           s.fixed_amount,
+           CASE WHEN s.fixed_amount IS NULL THEN COALESCE(s.weight, 1) END AS weight
+    FROM transactions t
+    CROSS JOIN LATERAL unnest(t.assigned_to) AS a(person_id)
+    LEFT JOIN transaction_shares s ON s.transaction_id = t.id AND s.person_id = a.person_id
+),
+assignment_totals AS (
+    SELECT a.*,
+           COALESCE(SUM(a.fixed_amount) OVER w, 0) AS fixed_total,
+           SUM(a.weight) OVER w AS weight_total,
+           COUNT(*) OVER w AS assignees
+    FROM assignments a
+    WINDOW w AS (PARTITION BY a.transaction_id)
+)
+SELECT transaction_id,
+       person_id,
+       (CASE
+           WHEN total = 0 THEN 1.0 / assignees
+           WHEN weight_total IS NULL OR fixed_total >= total THEN
+               CASE WHEN fixed_total = 0 THEN 1.0 / assignees
+                    ELSE COALESCE(fixed_amount, 0) / fixed_total END
+           WHEN fixed_amount IS NOT NULL THEN fixed_amount / total
+           ELSE (total - fixed_total) / total * weight / weight_total
+       END)::numeric AS share
+FROM assignment_totals;
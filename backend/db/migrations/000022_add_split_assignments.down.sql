@@ -0,0 +1,2 @@
+DROP VIEW IF EXISTS split_person_shares;
+ALTER TABLE transaction_splits DROP COLUMN IF EXISTS assigned_to;
@@ -0,0 +1,18 @@
+-- People a split is assigned to, overriding the transaction's assigned_to for the
+-- split's amount. NULL uses the transaction's assignment and shares.
+ALTER TABLE transaction_splits ADD COLUMN assigned_to UUID[]
+    CHECK (assigned_to IS NULL OR cardinality(assigned_to) > 0);
+
+-- The fraction of each split's amount that counts for each person: the split's own
+-- assignees equally, else the transaction's shares
+CREATE VIEW split_person_shares AS
+SELECT ts.id AS split_id, ts.transaction_id, tps.person_id, tps.share
+FROM transaction_splits ts
+JOIN transaction_person_shares tps ON tps.transaction_id = ts.transaction_id
+WHERE ts.assigned_to IS NULL
+UNION ALL
+SELECT ts.id AS split_id, ts.transaction_id, a.person_id,
+       (1.0 / cardinality(ts.assigned_to))::numeric AS share
+FROM transaction_splits ts
+CROSS JOIN LATERAL unnest(ts.assigned_to) AS a(person_id)
+WHERE ts.assigned_to IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_imports_file_hash;
+CREATE INDEX idx_imports_file_hash ON imports(file_hash);
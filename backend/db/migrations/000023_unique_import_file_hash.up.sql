@@ -0,0 +1,17 @@
+-- At most one import records each file's hash, so concurrent uploads of the same
+-- file cannot both pass the duplicate check. Forced re-imports record no hash, so
+-- those already saved give theirs up and the first import of each file keeps it.
+UPDATE imports
+SET file_hash = NULL
+WHERE id IN (
+    SELECT id
+    FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY file_hash ORDER BY created_at, id) AS copy
+        FROM imports
+        WHERE file_hash IS NOT NULL
+    ) copies
+    WHERE copy > 1
+);
+
+DROP INDEX IF EXISTS idx_imports_file_hash;
+CREATE UNIQUE INDEX idx_imports_file_hash ON imports(file_hash);
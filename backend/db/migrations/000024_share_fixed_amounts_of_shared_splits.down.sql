@@ -0,0 +1,33 @@
+-- Fixed amounts are fractions of the whole transaction again
+CREATE OR REPLACE VIEW transaction_person_shares AS
+WITH assignments AS (
+    SELECT t.id AS transaction_id,
+           a.person_id,
+           ABS(t.amount) AS total,
+           s.fixed_amount,
+           CASE WHEN s.fixed_amount IS NULL THEN COALESCE(s.weight, 1) END AS weight
+    FROM transactions t
+    CROSS JOIN LATERAL unnest(t.assigned_to) AS a(person_id)
+    LEFT JOIN transaction_shares s ON s.transaction_id = t.id AND s.person_id = a.person_id
+),
+assignment_totals AS (
+    SELECT a.*,
+           COALESCE(SUM(a.fixed_amount) OVER w, 0) AS fixed_total,
+           SUM(a.weight) OVER w AS weight_total,
+           COUNT(*) OVER w AS assignees
+    FROM assignments a
+    WINDOW w AS (PARTITION BY a.transaction_id)
+)
+SELECT transaction_id,
+       person_id,
+       (CASE
+           WHEN total = 0 THEN 1.0 / assignees
+           WHEN weight_total IS NULL OR fixed_total >= total THEN
+               CASE WHEN fixed_total = 0 THEN 1.0 / assignees
+                    ELSE COALESCE(fixed_amount, 0) / fixed_total END
+           WHEN fixed_amount IS NOT NULL THEN fixed_amount / total
+           ELSE (total - fixed_total) / total * weight / weight_total
+       END)::numeric AS share
+FROM assignment_totals;
+
+DROP VIEW IF EXISTS transaction_shared_amounts;
@@ -0,0 +1,47 @@
+-- The part of each transaction its assigned people share: the splits without
+-- people of their own that count in totals. Transactions with nothing shared have
+-- no row.
+CREATE VIEW transaction_shared_amounts AS
+SELECT ts.transaction_id, SUM(ts.amount) AS amount
+FROM transaction_splits ts
+JOIN categories c ON c.id = ts.category_id
+LEFT JOIN categories pc ON pc.id = c.parent_id
+WHERE ts.assigned_to IS NULL
+  AND NOT c.exclude_from_totals
+  AND NOT COALESCE(pc.exclude_from_totals, FALSE)
+GROUP BY ts.transaction_id;
+
+-- Fixed amounts are now taken from the shared amount rather than the whole
+-- transaction, so a split assigned to its own people or excluded from totals no
+-- longer shrinks them. The fractions apply to the shared splits.
+CREATE OR REPLACE VIEW transaction_person_shares AS
+WITH assignments AS (
+    SELECT t.id AS transaction_id,
+           a.person_id,
+           COALESCE(sa.amount, 0) AS total,
+           s.fixed_amount,
+           CASE WHEN s.fixed_amount IS NULL THEN COALESCE(s.weight, 1) END AS weight
+    FROM transactions t
+    CROSS JOIN LATERAL unnest(t.assigned_to) AS a(person_id)
+    LEFT JOIN transaction_shared_amounts sa ON sa.transaction_id = t.id
+    LEFT JOIN transaction_shares s ON s.transaction_id = t.id AND s.person_id = a.person_id
+),
+assignment_totals AS (
+    SELECT a.*,
+           COALESCE(SUM(a.fixed_amount) OVER w, 0) AS fixed_total,
+           SUM(a.weight) OVER w AS weight_total,
+           COUNT(*) OVER w AS assignees
+    FROM assignments a
+    WINDOW w AS (PARTITION BY a.transaction_id)
+)
+SELECT transaction_id,
+       person_id,
+       (CASE
+           WHEN total = 0 THEN 1.0 / assignees
+           WHEN weight_total IS NULL OR fixed_total >= total THEN
+               CASE WHEN fixed_total = 0 THEN 1.0 / assignees
+                    ELSE COALESCE(fixed_amount, 0) / fixed_total END
+           WHEN fixed_amount IS NOT NULL THEN fixed_amount / total
+           ELSE (total - fixed_total) / total * weight / weight_total
+       END)::numeric AS share
+FROM assignment_totals;
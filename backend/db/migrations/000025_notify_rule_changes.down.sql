@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trigger_notify_rule_splits_changed ON rule_splits;
+DROP TRIGGER IF EXISTS trigger_notify_rules_changed ON categorization_rules;
+DROP FUNCTION IF EXISTS notify_rules_changed();
@@ -0,0 +1,23 @@
+-- Notify listeners when the rules change, so servers can recompile their cached
+-- rule matcher whichever process made the change. Updates that only record hit
+-- and override statistics leave the matcher as it is and do not notify.
+CREATE OR REPLACE FUNCTION notify_rules_changed()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('rules_changed', TG_TABLE_NAME || ' ' || TG_OP);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_notify_rules_changed
+    AFTER INSERT OR DELETE OR TRUNCATE
+        OR UPDATE OF match_value, match_type, match_field, min_amount, max_amount, direction,
+                     card_number, start_date, end_date, category_id, priority, assign_to
+    ON categorization_rules
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION notify_rules_changed();
+
+CREATE TRIGGER trigger_notify_rule_splits_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON rule_splits
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION notify_rules_changed();
@@ -0,0 +1,4 @@
+-- The category column of the CSV row a transaction was imported from, so rules
+-- that match on it can be re-applied later. Transactions imported earlier have
+-- none.
+ALTER TABLE transactions ADD COLUMN csv_category TEXT;
@@ -31,7 +31,7 @@ WHERE id = $1;
 
 -- Categories queries
 -- name: GetCategories :many
-SELECT id, name, description, color, parent_id, created_at, updated_at
+SELECT id, name, description, color, parent_id, created_at, updated_at, retired_at, exclude_from_totals
 FROM categories
 ORDER BY name;
 
@@ -42,13 +42,13 @@ WHERE parent_id IS NULL
 ORDER BY name;
 
 -- name: GetSubcategoriesByParent :many
-SELECT id, name, description, color, parent_id, created_at, updated_at
+SELECT id, name, description, color, parent_id, created_at, updated_at, retired_at, exclude_from_totals
 FROM categories
 WHERE parent_id = $1
 ORDER BY name;
 
 -- name: GetCategoryByID :one
-SELECT id, name, description, color, parent_id, created_at, updated_at
+SELECT id, name, description, color, parent_id, created_at, updated_at, retired_at, exclude_from_totals
 FROM categories
 WHERE id = $1;
 
@@ -58,20 +58,62 @@ FROM categories
 WHERE name = $1;
 
 -- name: CreateCategory :one
-INSERT INTO categories (name, description, color, parent_id)
-VALUES ($1, $2, $3, $4)
-RETURNING id, name, description, color, parent_id, created_at, updated_at;
+INSERT INTO categories (name, description, color, parent_id, exclude_from_totals)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, name, description, color, parent_id, created_at, updated_at, exclude_from_totals;
 
 -- name: UpdateCategory :one
 UPDATE categories
-SET name = $2, description = $3, color = $4, updated_at = CURRENT_TIMESTAMP
+SET name = $2, description = $3, color = $4, exclude_from_totals = $5, updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
-RETURNING id, name, description, color, parent_id, created_at, updated_at;
+RETURNING id, name, description, color, parent_id, created_at, updated_at, retired_at, exclude_from_totals;
 
 -- name: DeleteCategory :exec
 DELETE FROM categories
 WHERE id = $1;
 
+-- Category merge queries
+-- name: MoveTransactionSplitsToCategory :execrows
+UPDATE transaction_splits
+SET category_id = @target_id::uuid, updated_at = CURRENT_TIMESTAMP
+WHERE category_id = @source_id::uuid;
+
+-- name: MoveRulesToCategory :execrows
+UPDATE categorization_rules
+SET category_id = @target_id::uuid, updated_at = CURRENT_TIMESTAMP
+WHERE category_id = @source_id::uuid;
+
+-- name: MoveRuleSplitsToCategory :execrows
+UPDATE rule_splits
+SET category_id = @target_id::uuid
+WHERE category_id = @source_id::uuid;
+
+-- Budgets for the same person and month as a target budget are added to it
+-- name: MoveBudgetsToCategory :execrows
+INSERT INTO budgets (category_id, person_id, month, amount, rollover)
+SELECT @target_id::uuid, person_id, month, amount, rollover
+FROM budgets
+WHERE category_id = @source_id::uuid
+ON CONFLICT (category_id, COALESCE(person_id, '00000000-0000-0000-0000-000000000000'::uuid), month)
+DO UPDATE SET amount = budgets.amount + EXCLUDED.amount, updated_at = CURRENT_TIMESTAMP;
+
+-- name: ReparentSubcategories :execrows
+UPDATE categories
+SET parent_id = @target_id::uuid, updated_at = CURRENT_TIMESTAMP
+WHERE parent_id = @source_id::uuid;
+
+-- Category retirement queries
+-- name: RetireCategory :execrows
+UPDATE categories
+SET retired_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE (id = $1 OR parent_id = $1) AND retired_at IS NULL;
+
+-- name: RestoreCategory :execrows
+UPDATE categories
+SET retired_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+   OR (parent_id = $1 AND retired_at = (SELECT retired_at FROM categories WHERE id = $1));
+
 -- Transactions queries
 -- name: GetTransactions :many
 SELECT id, description, amount, assigned_to, date_uploaded, file_name,
@@ -103,22 +145,59 @@ FROM transactions
 WHERE file_name = $1
 ORDER BY date_uploaded DESC;
 
--- name: CreateTransaction :one
-INSERT INTO transactions (description, amount, file_name, transaction_date, posted_date, card_number)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, description, amount, assigned_to, date_uploaded, file_name,
-          transaction_date, posted_date, card_number,
-          created_at, updated_at;
-
--- name: FindDuplicateTransaction :one
-SELECT COUNT(*)
-FROM transactions
-WHERE description = $1
-  AND amount = $2
-  AND transaction_date = $3
-  AND posted_date = $4
-  AND card_number = $5
-  AND archive_id IS NULL;
+-- name: CreateTransactions :copyfrom
+INSERT INTO transactions (id, description, amount, assigned_to, file_name, transaction_date, posted_date, card_number, external_id, import_id, rule_id, csv_category)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+
+-- name: FindDuplicateImportRows :many
+-- Seen is how many times each row has appeared in the file so far, itself included.
+-- Rows the import has already saved from earlier batches are not existing copies.
+WITH candidates AS (
+    SELECT c.position, c.description, c.amount, c.transaction_date, c.posted_date,
+           NULLIF(c.card_number, '') AS card_number,
+           NULLIF(c.external_id, '') AS external_id,
+           c.seen
+    FROM unnest(
+        @positions::int[], @descriptions::text[], @amounts::numeric[],
+        @transaction_dates::date[], @posted_dates::date[],
+        @card_numbers::text[], @external_ids::text[], @seen::int[]
+    ) AS c(position, description, amount, transaction_date, posted_date, card_number, external_id, seen)
+),
+existing AS (
+    SELECT t.description, t.amount, t.transaction_date, t.posted_date, t.card_number, COUNT(*) AS copies
+    FROM transactions t
+    JOIN (
+        SELECT DISTINCT description, amount, transaction_date, posted_date, card_number
+        FROM candidates
+        WHERE external_id IS NULL
+    ) k ON k.description = t.description
+       AND k.amount = t.amount
+       AND k.transaction_date = t.transaction_date
+       AND k.posted_date = t.posted_date
+       AND k.card_number = t.card_number
+    WHERE t.archive_id IS NULL
+      AND NOT COALESCE(t.import_id = @import_id::uuid, FALSE)
+    GROUP BY t.description, t.amount, t.transaction_date, t.posted_date, t.card_number
+)
+SELECT c.position::int AS position
+FROM candidates c
+LEFT JOIN existing e ON c.external_id IS NULL
+                    AND e.description = c.description
+                    AND e.amount = c.amount
+                    AND e.transaction_date = c.transaction_date
+                    AND e.posted_date = c.posted_date
+                    AND e.card_number = c.card_number
+WHERE (c.external_id IS NOT NULL AND (
+           c.seen > 1
+           OR EXISTS (
+               SELECT 1
+               FROM transactions t
+               WHERE t.external_id = c.external_id
+                 AND t.card_number IS NOT DISTINCT FROM c.card_number
+           )
+       ))
+   OR (c.external_id IS NULL AND COALESCE(e.copies, 0) >= c.seen)
+ORDER BY c.position;
 
 -- name: UpdateTransactionAssignment :one
 UPDATE transactions
@@ -149,8 +228,13 @@ UPDATE transactions
 SET assigned_to = array_remove(assigned_to, $1), updated_at = CURRENT_TIMESTAMP
 WHERE $1 = ANY(assigned_to);
 
+-- name: UnassignTransactionSplitsByPerson :exec
+UPDATE transaction_splits
+SET assigned_to = NULLIF(array_remove(assigned_to, $1), '{}'), updated_at = CURRENT_TIMESTAMP
+WHERE $1 = ANY(assigned_to);
+
 -- name: GetTransactionSplitsByTransactionID :many
-SELECT id, transaction_id, amount, category_id, notes, created_at, updated_at
+SELECT id, transaction_id, amount, category_id, notes, created_at, updated_at, assigned_to
 FROM transaction_splits
 WHERE transaction_id = $1
 ORDER BY created_at ASC;
@@ -160,28 +244,60 @@ DELETE FROM transaction_splits
 WHERE transaction_id = $1;
 
 -- name: CreateTransactionSplit :one
-INSERT INTO transaction_splits (transaction_id, amount, category_id, notes)
-VALUES ($1, $2, $3, $4)
-RETURNING id, transaction_id, amount, category_id, notes, created_at, updated_at;
+INSERT INTO transaction_splits (transaction_id, amount, category_id, notes, assigned_to)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, transaction_id, amount, category_id, notes, created_at, updated_at, assigned_to;
+
+-- name: CreateTransactionSplits :copyfrom
+INSERT INTO transaction_splits (transaction_id, amount, category_id, notes, assigned_to)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: MarkTransactionSplitsEdited :exec
+UPDATE transactions
+SET splits_edited_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: GetTransactionShares :many
+SELECT tps.person_id, p.name AS person_name, s.weight, s.fixed_amount, tps.share
+FROM transaction_person_shares tps
+JOIN people p ON p.id = tps.person_id
+LEFT JOIN transaction_shares s ON s.transaction_id = tps.transaction_id AND s.person_id = tps.person_id
+WHERE tps.transaction_id = $1
+ORDER BY p.name;
+
+-- name: GetTransactionSharedAmount :one
+-- The part of a transaction that its fixed amounts are taken from
+SELECT COALESCE(SUM(amount), 0)::numeric AS shared_amount
+FROM transaction_shared_amounts
+WHERE transaction_id = $1;
+
+-- name: DeleteTransactionShares :exec
+DELETE FROM transaction_shares
+WHERE transaction_id = $1;
+
+-- name: CreateTransactionShare :exec
+INSERT INTO transaction_shares (transaction_id, person_id, weight, fixed_amount)
+VALUES ($1, $2, $3, $4);
 
 -- name: DeleteTransaction :exec
 DELETE FROM transactions
 WHERE id = $1;
 
 -- name: GetTotalsByAssignedTo :many
-WITH normalized_transaction_totals AS (
-    SELECT t.id,
-           SUM(CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END)::numeric AS normalized_amount
+WITH normalized_split_amounts AS (
+    SELECT ts.id,
+           CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END AS normalized_amount
     FROM transactions t
     JOIN transaction_splits ts ON ts.transaction_id = t.id
-    GROUP BY t.id
+    JOIN categories c ON c.id = ts.category_id
+    LEFT JOIN categories pc ON pc.id = c.parent_id
+    WHERE NOT c.exclude_from_totals
+      AND NOT COALESCE(pc.exclude_from_totals, FALSE)
 )
-SELECT p.name as assigned_to, SUM(nt.normalized_amount / array_length(t.assigned_to, 1))::numeric as total
-FROM transactions t
-JOIN normalized_transaction_totals nt ON nt.id = t.id
-CROSS JOIN LATERAL unnest(t.assigned_to) AS person_id
-JOIN people p ON p.id = person_id
-WHERE t.assigned_to IS NOT NULL AND array_length(t.assigned_to, 1) > 0
+SELECT p.name as assigned_to, SUM(ns.normalized_amount * sps.share)::numeric as total
+FROM normalized_split_amounts ns
+JOIN split_person_shares sps ON sps.split_id = ns.id
+JOIN people p ON p.id = sps.person_id
 GROUP BY p.id, p.name
 ORDER BY p.name;
 
@@ -195,6 +311,9 @@ WITH normalized_category_amounts AS (
 SELECT c.name as category_name, SUM(nca.signed_amount)::numeric as total
 FROM normalized_category_amounts nca
 JOIN categories c ON nca.category_id = c.id
+LEFT JOIN categories pc ON pc.id = c.parent_id
+WHERE NOT c.exclude_from_totals
+  AND NOT COALESCE(pc.exclude_from_totals, FALSE)
 GROUP BY c.id, c.name
 ORDER BY c.name;
 
@@ -250,22 +369,21 @@ SET archive_id = $1, updated_at = CURRENT_TIMESTAMP
 WHERE archive_id IS NULL;
 
 -- name: GetActiveTransactionTotals :many
-WITH normalized_transaction_totals AS (
-    SELECT t.id,
-           SUM(CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END)::numeric AS normalized_amount
+WITH normalized_split_amounts AS (
+    SELECT ts.id,
+           CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END AS normalized_amount
     FROM transactions t
     JOIN transaction_splits ts ON ts.transaction_id = t.id
+    JOIN categories c ON c.id = ts.category_id
+    LEFT JOIN categories pc ON pc.id = c.parent_id
     WHERE t.archive_id IS NULL
-    GROUP BY t.id
+      AND NOT c.exclude_from_totals
+      AND NOT COALESCE(pc.exclude_from_totals, FALSE)
 )
-SELECT p.name as assigned_to, SUM(nt.normalized_amount / array_length(t.assigned_to, 1))::numeric as total
-FROM transactions t
-JOIN normalized_transaction_totals nt ON nt.id = t.id
-CROSS JOIN LATERAL unnest(t.assigned_to) AS person_id
-JOIN people p ON p.id = person_id
-WHERE t.assigned_to IS NOT NULL
-  AND array_length(t.assigned_to, 1) > 0
-  AND t.archive_id IS NULL
+SELECT p.name as assigned_to, SUM(ns.normalized_amount * sps.share)::numeric as total
+FROM normalized_split_amounts ns
+JOIN split_person_shares sps ON sps.split_id = ns.id
+JOIN people p ON p.id = sps.person_id
 GROUP BY p.id, p.name
 ORDER BY p.name;
 
@@ -287,51 +405,348 @@ DELETE FROM archive_person_totals
 WHERE archive_id = $1;
 
 -- name: GetActiveTransactionGrandTotal :one
-WITH normalized_transaction_totals AS (
-    SELECT t.id,
-           SUM(CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END)::numeric AS normalized_amount
+WITH normalized_split_amounts AS (
+    SELECT ts.id,
+           CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END AS normalized_amount
     FROM transactions t
     JOIN transaction_splits ts ON ts.transaction_id = t.id
+    JOIN categories c ON c.id = ts.category_id
+    LEFT JOIN categories pc ON pc.id = c.parent_id
     WHERE t.archive_id IS NULL
-    GROUP BY t.id
+      AND NOT c.exclude_from_totals
+      AND NOT COALESCE(pc.exclude_from_totals, FALSE)
 )
-SELECT COALESCE(SUM(nt.normalized_amount / array_length(t.assigned_to, 1)), 0)::numeric as grand_total
-FROM transactions t
-JOIN normalized_transaction_totals nt ON nt.id = t.id
-CROSS JOIN LATERAL unnest(t.assigned_to) AS person_id
-WHERE t.assigned_to IS NOT NULL
-  AND array_length(t.assigned_to, 1) > 0
-  AND t.archive_id IS NULL;
+SELECT COALESCE(SUM(ns.normalized_amount * sps.share), 0)::numeric as grand_total
+FROM normalized_split_amounts ns
+JOIN split_person_shares sps ON sps.split_id = ns.id;
 
 -- Categorization rules queries
 -- name: GetRules :many
-SELECT r.id, r.match_value, r.category_id, c.name as category_name, r.priority, r.created_at, r.updated_at
+SELECT r.id, r.match_value, r.match_type, r.match_field, r.min_amount, r.max_amount, r.direction,
+       r.card_number, r.start_date, r.end_date,
+       r.category_id, c.name as category_name, r.assign_to, r.priority,
+       r.hit_count, r.last_matched_at, r.override_count, r.created_at, r.updated_at
 FROM categorization_rules r
 JOIN categories c ON r.category_id = c.id
 ORDER BY r.priority ASC, r.created_at ASC;
 
 -- name: GetRuleByID :one
-SELECT r.id, r.match_value, r.category_id, c.name as category_name, r.priority, r.created_at, r.updated_at
+SELECT r.id, r.match_value, r.match_type, r.match_field, r.min_amount, r.max_amount, r.direction,
+       r.card_number, r.start_date, r.end_date,
+       r.category_id, c.name as category_name, r.assign_to, r.priority,
+       r.hit_count, r.last_matched_at, r.override_count, r.created_at, r.updated_at
 FROM categorization_rules r
 JOIN categories c ON r.category_id = c.id
 WHERE r.id = $1;
 
 -- name: GetRulesForMatching :many
-SELECT id, match_value, category_id
-FROM categorization_rules
-ORDER BY priority ASC, created_at ASC;
+SELECT r.id, r.match_value, r.match_type, r.match_field, r.min_amount, r.max_amount, r.direction,
+       r.card_number, r.start_date, r.end_date, r.category_id, r.assign_to
+FROM categorization_rules r
+JOIN categories c ON r.category_id = c.id
+WHERE c.retired_at IS NULL
+  AND NOT EXISTS (
+      SELECT 1
+      FROM rule_splits rs
+      JOIN categories sc ON rs.category_id = sc.id
+      WHERE rs.rule_id = r.id AND sc.retired_at IS NOT NULL
+  )
+ORDER BY r.priority ASC, r.created_at ASC;
 
 -- name: CreateRule :one
-INSERT INTO categorization_rules (match_value, category_id, priority)
-VALUES ($1, $2, $3)
-RETURNING id, match_value, category_id, priority, created_at, updated_at;
+INSERT INTO categorization_rules (
+    match_value, category_id, priority, match_type, match_field, min_amount, max_amount,
+    direction, card_number, start_date, end_date, assign_to
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING id, match_value, category_id, priority, created_at, updated_at, match_type,
+          match_field, min_amount, max_amount, direction, card_number, start_date, end_date,
+          assign_to, hit_count, last_matched_at, override_count;
 
 -- name: UpdateRule :one
 UPDATE categorization_rules
-SET match_value = $2, category_id = $3, priority = $4, updated_at = CURRENT_TIMESTAMP
+SET match_value = $2, category_id = $3, priority = $4, match_type = $5, match_field = $6,
+    min_amount = $7, max_amount = $8, direction = $9, card_number = $10, start_date = $11,
+    end_date = $12, assign_to = $13, updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
-RETURNING id, match_value, category_id, priority, created_at, updated_at;
+RETURNING id, match_value, category_id, priority, created_at, updated_at, match_type,
+          match_field, min_amount, max_amount, direction, card_number, start_date, end_date,
+          assign_to, hit_count, last_matched_at, override_count;
 
 -- name: DeleteRule :exec
 DELETE FROM categorization_rules
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: DeleteAllRules :execrows
+DELETE FROM categorization_rules;
+
+-- name: RecordRuleHits :exec
+UPDATE categorization_rules r
+SET hit_count = r.hit_count + u.hits, last_matched_at = CURRENT_TIMESTAMP
+FROM unnest(@rule_ids::uuid[], @hits::int[]) AS u(rule_id, hits)
+WHERE r.id = u.rule_id;
+
+-- Counts the first manual edit of a rule-categorized transaction that moves money
+-- to a category the rule never assigns
+-- name: RecordRuleOverride :exec
+UPDATE categorization_rules r
+SET override_count = r.override_count + 1
+FROM transactions t
+WHERE t.id = @transaction_id
+  AND t.rule_id = r.id
+  AND t.splits_edited_at IS NULL
+  AND EXISTS (
+    SELECT 1 FROM unnest(@category_ids::uuid[]) AS c(category_id)
+    WHERE c.category_id <> r.category_id
+      AND c.category_id NOT IN (SELECT rs.category_id FROM rule_splits rs WHERE rs.rule_id = r.id)
+  );
+
+-- name: RemovePersonFromRules :exec
+UPDATE categorization_rules
+SET assign_to = array_remove(assign_to, $1), updated_at = CURRENT_TIMESTAMP
+WHERE $1 = ANY(assign_to);
+
+-- Rule split template queries
+-- name: GetRuleSplits :many
+SELECT rs.rule_id, rs.category_id, c.name as category_name, rs.percentage
+FROM rule_splits rs
+JOIN categories c ON rs.category_id = c.id
+ORDER BY rs.rule_id, rs.position;
+
+-- name: GetRuleSplitsByRuleID :many
+SELECT rs.rule_id, rs.category_id, c.name as category_name, rs.percentage
+FROM rule_splits rs
+JOIN categories c ON rs.category_id = c.id
+WHERE rs.rule_id = $1
+ORDER BY rs.position;
+
+-- name: CreateRuleSplit :exec
+INSERT INTO rule_splits (rule_id, category_id, percentage, position)
+VALUES ($1, $2, $3, $4);
+
+-- name: DeleteRuleSplits :exec
+DELETE FROM rule_splits
+WHERE rule_id = $1;
+
+-- Retroactive rule application queries
+-- name: GetTransactionsForRuleApplication :many
+SELECT t.id, t.description, t.amount, t.transaction_date, t.posted_date, t.card_number,
+       t.archive_id, t.rule_id, t.splits_edited_at, t.assigned_to, t.csv_category
+FROM transactions t
+WHERE (t.archive_id IS NULL AND @include_active::boolean)
+   OR (t.archive_id IS NOT NULL AND @include_archived::boolean)
+ORDER BY t.transaction_date DESC NULLS LAST, t.created_at DESC;
+
+-- name: GetSplitsForRuleApplication :many
+SELECT s.transaction_id, s.category_id, c.name AS category_name, s.amount, s.notes, s.assigned_to
+FROM transaction_splits s
+JOIN categories c ON s.category_id = c.id
+JOIN transactions t ON s.transaction_id = t.id
+WHERE (t.archive_id IS NULL AND @include_active::boolean)
+   OR (t.archive_id IS NOT NULL AND @include_archived::boolean)
+ORDER BY s.transaction_id, s.created_at;
+
+-- name: DeleteTransactionSplitsByTransactionIDs :exec
+DELETE FROM transaction_splits
+WHERE transaction_id = ANY(@transaction_ids::uuid[]);
+
+-- name: SetTransactionRules :exec
+UPDATE transactions t
+SET rule_id = u.rule_id, splits_edited_at = NULL, updated_at = CURRENT_TIMESTAMP
+FROM unnest(@transaction_ids::uuid[], @rule_ids::uuid[]) AS u(transaction_id, rule_id)
+WHERE t.id = u.transaction_id;
+
+-- Category classifier queries
+-- name: GetCategoryTrainingExamples :many
+SELECT t.description, s.category_id,
+       (s.amount / SUM(s.amount) OVER (PARTITION BY s.transaction_id))::float8 AS weight
+FROM transaction_splits s
+JOIN transactions t ON s.transaction_id = t.id
+WHERE t.splits_edited_at IS NOT NULL;
+
+-- name: GetTransactionsInCategory :many
+SELECT t.id, t.description, t.amount, t.transaction_date
+FROM transactions t
+WHERE t.archive_id IS NULL
+  AND EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id)
+  AND NOT EXISTS (
+      SELECT 1 FROM transaction_splits s
+      WHERE s.transaction_id = t.id AND s.category_id <> @category_id::uuid
+  )
+ORDER BY t.transaction_date DESC NULLS LAST, t.created_at DESC;
+
+-- Import profile queries
+-- name: GetImportProfiles :many
+SELECT id, name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+       transaction_date_column, posted_date_column, card_number_column, description_column,
+       category_column, amount_column, debit_column, credit_column, created_at, updated_at,
+       decimal_separator
+FROM import_profiles
+ORDER BY name;
+
+-- name: GetImportProfileByID :one
+SELECT id, name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+       transaction_date_column, posted_date_column, card_number_column, description_column,
+       category_column, amount_column, debit_column, credit_column, created_at, updated_at,
+       decimal_separator
+FROM import_profiles
+WHERE id = $1;
+
+-- name: GetImportProfileByName :one
+SELECT id, name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+       transaction_date_column, posted_date_column, card_number_column, description_column,
+       category_column, amount_column, debit_column, credit_column, created_at, updated_at,
+       decimal_separator
+FROM import_profiles
+WHERE name = $1;
+
+-- name: CreateImportProfile :one
+INSERT INTO import_profiles (
+    name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+    transaction_date_column, posted_date_column, card_number_column, description_column,
+    category_column, amount_column, debit_column, credit_column, decimal_separator
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+RETURNING id, name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+          transaction_date_column, posted_date_column, card_number_column, description_column,
+          category_column, amount_column, debit_column, credit_column, created_at, updated_at,
+          decimal_separator;
+
+-- name: UpdateImportProfile :one
+UPDATE import_profiles
+SET name = $2, delimiter = $3, has_header = $4, header_columns = $5, date_format = $6,
+    amount_format = $7, expense_sign = $8, transaction_date_column = $9, posted_date_column = $10,
+    card_number_column = $11, description_column = $12, category_column = $13,
+    amount_column = $14, debit_column = $15, credit_column = $16, decimal_separator = $17,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, name, delimiter, has_header, header_columns, date_format, amount_format, expense_sign,
+          transaction_date_column, posted_date_column, card_number_column, description_column,
+          category_column, amount_column, debit_column, credit_column, created_at, updated_at,
+          decimal_separator;
+
+-- name: DeleteImportProfile :exec
+DELETE FROM import_profiles
+WHERE id = $1;
+
+-- Import queries
+-- name: CreateImport :one
+INSERT INTO imports (file_name, format, file_hash, uploaded_by)
+VALUES ($1, $2, $3, $4)
+RETURNING id, file_name, format, imported_rows, skipped_rows, created_at, file_hash, total_rows, uploaded_by;
+
+-- name: UpdateImportCounts :exec
+UPDATE imports
+SET total_rows = $2, imported_rows = $3, skipped_rows = $4
+WHERE id = $1;
+
+-- name: GetImports :many
+SELECT i.id, i.file_name, i.format, i.file_hash, i.total_rows, i.imported_rows, i.skipped_rows,
+       i.uploaded_by, p.name AS uploaded_by_name, i.created_at
+FROM imports i
+LEFT JOIN people p ON p.id = i.uploaded_by
+ORDER BY i.created_at DESC;
+
+-- name: GetImportByID :one
+SELECT i.id, i.file_name, i.format, i.file_hash, i.total_rows, i.imported_rows, i.skipped_rows,
+       i.uploaded_by, p.name AS uploaded_by_name, i.created_at
+FROM imports i
+LEFT JOIN people p ON p.id = i.uploaded_by
+WHERE i.id = $1;
+
+-- name: FindImportByFileHash :one
+SELECT id
+FROM imports
+WHERE file_hash = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: CountArchivedTransactionsByImportID :one
+SELECT COUNT(*)
+FROM transactions
+WHERE import_id = $1
+  AND archive_id IS NOT NULL;
+
+-- Takes back the hits of the rules that categorized an import's transactions
+-- name: RemoveImportRuleHits :exec
+UPDATE categorization_rules r
+SET hit_count = GREATEST(r.hit_count - h.hits, 0)
+FROM (
+    SELECT rule_id, COUNT(*)::int AS hits
+    FROM transactions
+    WHERE import_id = $1 AND rule_id IS NOT NULL
+    GROUP BY rule_id
+) h
+WHERE r.id = h.rule_id;
+
+-- name: DeleteTransactionsByImportID :execrows
+DELETE FROM transactions
+WHERE import_id = $1;
+
+-- name: DeleteImport :exec
+DELETE FROM imports
+WHERE id = $1;
+
+-- name: CreateImportRejections :copyfrom
+INSERT INTO import_rejections (import_id, line_number, raw_content, reason, message)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetImportRejectionsByImportID :many
+SELECT id, import_id, line_number, raw_content, reason, message
+FROM import_rejections
+WHERE import_id = $1
+ORDER BY line_number ASC;
+
+-- Budget queries
+-- name: GetBudgets :many
+SELECT b.id, b.category_id, c.name as category_name, b.person_id, p.name as person_name,
+       b.month, b.amount, b.rollover, b.created_at, b.updated_at
+FROM budgets b
+JOIN categories c ON b.category_id = c.id
+LEFT JOIN people p ON b.person_id = p.id
+ORDER BY c.name, p.name NULLS FIRST, b.month;
+
+-- name: GetBudgetByID :one
+SELECT b.id, b.category_id, c.name as category_name, b.person_id, p.name as person_name,
+       b.month, b.amount, b.rollover, b.created_at, b.updated_at
+FROM budgets b
+JOIN categories c ON b.category_id = c.id
+LEFT JOIN people p ON b.person_id = p.id
+WHERE b.id = $1;
+
+-- name: CreateBudget :one
+INSERT INTO budgets (category_id, person_id, month, amount, rollover)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, category_id, person_id, month, amount, rollover, created_at, updated_at;
+
+-- name: UpdateBudget :one
+UPDATE budgets
+SET category_id = $2, person_id = $3, month = $4, amount = $5, rollover = $6, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, category_id, person_id, month, amount, rollover, created_at, updated_at;
+
+-- name: DeleteBudget :execrows
+DELETE FROM budgets
+WHERE id = $1;
+
+-- name: GetMonthlyCategorySpending :many
+SELECT date_trunc('month', COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date))::date AS month,
+       ts.category_id,
+       SUM(CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END)::numeric AS total
+FROM transactions t
+JOIN transaction_splits ts ON ts.transaction_id = t.id
+WHERE COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date) >= @from_month::date
+  AND COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date) < @to_month::date
+GROUP BY 1, ts.category_id;
+
+-- name: GetMonthlyCategorySpendingByPerson :many
+SELECT date_trunc('month', COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date))::date AS month,
+       ts.category_id,
+       sps.person_id,
+       SUM(CASE WHEN t.amount < 0 THEN -ts.amount ELSE ts.amount END * sps.share)::numeric AS total
+FROM transactions t
+JOIN transaction_splits ts ON ts.transaction_id = t.id
+JOIN split_person_shares sps ON sps.split_id = ts.id
+WHERE COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date) >= @from_month::date
+  AND COALESCE(t.transaction_date, t.posted_date, t.date_uploaded::date) < @to_month::date
+GROUP BY 1, ts.category_id, sps.person_id;
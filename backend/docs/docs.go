@@ -145,23 +145,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/categories": {
+        "/api/budgets": {
             "get": {
-                "description": "Retrieve all categories as a nested tree (top-level categories with subcategories embedded)",
+                "description": "Retrieve all budgets ordered by category, person (household first) and month",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "categories"
+                    "budgets"
                 ],
-                "summary": "Get all categories",
+                "summary": "Get all budgets",
                 "responses": {
                     "200": {
-                        "description": "Nested list of top-level categories with subcategories",
+                        "description": "List of budgets",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/main.Category"
+                                "$ref": "#/definitions/main.Budget"
                             }
                         }
                     },
@@ -175,7 +175,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new category in the system. Use parent_id to create a subcategory (max 2 levels).",
+                "description": "Create a monthly budget for a category, for one person or, without person_id, for the household. It applies from its month until a later budget for the same category and person. With rollover, unspent amounts carry over into the next month.",
                 "consumes": [
                     "application/json"
                 ],
@@ -183,25 +183,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "categories"
+                    "budgets"
                 ],
-                "summary": "Create category",
+                "summary": "Create budget",
                 "parameters": [
                     {
-                        "description": "Category data (name required; description, color, parent_id optional)",
-                        "name": "category",
+                        "description": "Budget data (category_id, month as YYYY-MM and amount required; person_id and rollover optional)",
+                        "name": "budget",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.Category"
+                            "$ref": "#/definitions/main.Budget"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Created category",
+                        "description": "Created budget",
                         "schema": {
-                            "$ref": "#/definitions/main.Category"
+                            "$ref": "#/definitions/main.Budget"
                         }
                     },
                     "400": {
@@ -212,7 +212,7 @@ const docTemplate = `{
                         }
                     },
                     "409": {
-                        "description": "Category already exists",
+                        "description": "Budget already exists",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -228,9 +228,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/categories/{id}": {
+        "/api/budgets/report": {
+            "get": {
+                "description": "Compare a month's spending with the budgets in effect, from active and archived transactions. Transactions are dated by transaction date, else posted date, else upload date. A top-level category's spending includes its subcategories; a person's spending is their share of the transactions assigned to them. Categories excluded from totals are left out. Lines spending more than is available have status over.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Get budget report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Month as YYYY-MM (default current month)",
+                        "name": "month",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Budget vs actual for the month",
+                        "schema": {
+                            "$ref": "#/definitions/main.BudgetReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/budgets/{id}": {
             "put": {
-                "description": "Update an existing category. Cannot re-parent a category that has children.",
+                "description": "Update an existing budget",
                 "consumes": [
                     "application/json"
                 ],
@@ -238,32 +280,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "categories"
+                    "budgets"
                 ],
-                "summary": "Update category",
+                "summary": "Update budget",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Category ID",
+                        "description": "Budget ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated category data",
-                        "name": "category",
+                        "description": "Updated budget data",
+                        "name": "budget",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.Category"
+                            "$ref": "#/definitions/main.Budget"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Updated category",
+                        "description": "Updated budget",
                         "schema": {
-                            "$ref": "#/definitions/main.Category"
+                            "$ref": "#/definitions/main.Budget"
                         }
                     },
                     "400": {
@@ -274,7 +316,14 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Category not found",
+                        "description": "Budget not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Budget already exists",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -290,30 +339,23 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete a specific category by ID",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Delete a budget. The budget it replaced, if any, applies again from its month.",
                 "tags": [
-                    "categories"
+                    "budgets"
                 ],
-                "summary": "Delete category",
+                "summary": "Delete budget",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Category ID",
+                        "description": "Budget ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Category deleted successfully",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
+                    "204": {
+                        "description": "No content"
                     },
                     "400": {
                         "description": "Bad request",
@@ -323,7 +365,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Category not found",
+                        "description": "Budget not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -339,23 +381,31 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/people": {
+        "/api/categories": {
             "get": {
-                "description": "Retrieve all people from the database",
+                "description": "Retrieve all categories as a nested tree (top-level categories with subcategories embedded). Retired categories are left out unless include_retired is true.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "people"
+                    "categories"
+                ],
+                "summary": "Get all categories",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Include retired categories (default false)",
+                        "name": "include_retired",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get all people",
                 "responses": {
                     "200": {
-                        "description": "List of people",
+                        "description": "Nested list of top-level categories with subcategories",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/main.Person"
+                                "$ref": "#/definitions/main.Category"
                             }
                         }
                     },
@@ -369,7 +419,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new person in the system",
+                "description": "Create a new category in the system. Use parent_id to create a subcategory (max 2 levels). With exclude_from_totals, the category and its subcategories are left out of person totals and spending reports.",
                 "consumes": [
                     "application/json"
                 ],
@@ -377,25 +427,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "people"
+                    "categories"
                 ],
-                "summary": "Create person",
+                "summary": "Create category",
                 "parameters": [
                     {
-                        "description": "Person data (name required, email optional)",
-                        "name": "person",
+                        "description": "Category data (name required; description, color, parent_id, exclude_from_totals optional)",
+                        "name": "category",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.Person"
+                            "$ref": "#/definitions/main.Category"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Created person",
+                        "description": "Created category",
                         "schema": {
-                            "$ref": "#/definitions/main.Person"
+                            "$ref": "#/definitions/main.Category"
                         }
                     },
                     "400": {
@@ -406,7 +456,7 @@ const docTemplate = `{
                         }
                     },
                     "409": {
-                        "description": "Person already exists",
+                        "description": "Category already exists",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -422,31 +472,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/people/{id}": {
-            "delete": {
-                "description": "Delete a specific person by ID",
+        "/api/categories/{id}": {
+            "put": {
+                "description": "Update an existing category. Cannot re-parent a category that has children.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "people"
+                    "categories"
                 ],
-                "summary": "Delete person",
+                "summary": "Update category",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Person ID",
+                        "description": "Category ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Updated category data",
+                        "name": "category",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.Category"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Person deleted successfully",
+                        "description": "Updated category",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/main.Category"
                         }
                     },
                     "400": {
@@ -457,41 +518,11 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Person not found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "500": {
-                        "description": "Internal server error",
+                        "description": "Category not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
-                    }
-                }
-            }
-        },
-        "/api/rules": {
-            "get": {
-                "description": "Retrieve all categorization rules ordered by priority",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "rules"
-                ],
-                "summary": "Get all rules",
-                "responses": {
-                    "200": {
-                        "description": "List of categorization rules",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.Rule"
-                            }
-                        }
                     },
                     "500": {
                         "description": "Internal server error",
@@ -502,34 +533,30 @@ const docTemplate = `{
                     }
                 }
             },
-            "post": {
-                "description": "Create a new categorization rule",
-                "consumes": [
-                    "application/json"
-                ],
+            "delete": {
+                "description": "Delete a specific category by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "rules"
+                    "categories"
                 ],
-                "summary": "Create rule",
+                "summary": "Delete category",
                 "parameters": [
                     {
-                        "description": "Rule data (match_value, category_id, priority required)",
-                        "name": "rule",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.Rule"
-                        }
+                        "type": "string",
+                        "description": "Category ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created rule",
+                    "200": {
+                        "description": "Category deleted successfully",
                         "schema": {
-                            "$ref": "#/definitions/main.Rule"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
@@ -539,6 +566,13 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "404": {
+                        "description": "Category not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -549,9 +583,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/rules/{id}": {
-            "put": {
-                "description": "Update an existing categorization rule",
+        "/api/categories/{id}/merge": {
+            "post": {
+                "description": "Merge a category into a target category and delete it, in one database transaction. Every transaction split, rule, rule split template line and budget in the category moves to the target; budgets for a person and month the target already budgets are added to its budget. Its subcategories are re-parented under the target, or merged into the target too when the target is a subcategory. The Other category cannot be merged, nor can a category be merged into its own subcategory.",
                 "consumes": [
                     "application/json"
                 ],
@@ -559,32 +593,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "rules"
+                    "categories"
                 ],
-                "summary": "Update rule",
+                "summary": "Merge category",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Rule ID",
+                        "description": "ID of the category to merge",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Rule data",
-                        "name": "rule",
+                        "description": "target_id is the category to merge into",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.Rule"
+                            "$ref": "#/definitions/main.CategoryMergeRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Updated rule",
+                        "description": "The target category and what was moved into it",
                         "schema": {
-                            "$ref": "#/definitions/main.Rule"
+                            "$ref": "#/definitions/main.CategoryMergeResult"
                         }
                     },
                     "400": {
@@ -595,7 +629,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Rule not found",
+                        "description": "Category not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -609,25 +643,33 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a categorization rule",
+            }
+        },
+        "/api/categories/{id}/restore": {
+            "post": {
+                "description": "Restore a retired category, together with the subcategories that were retired with it. The rules that use it match again; rules reassigned when it was retired stay with their new category. A subcategory cannot be restored while its parent is retired.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "rules"
+                    "categories"
                 ],
-                "summary": "Delete rule",
+                "summary": "Restore category",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Rule ID",
+                        "description": "Category ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No content"
+                    "200": {
+                        "description": "Restored category",
+                        "schema": {
+                            "$ref": "#/definitions/main.Category"
+                        }
                     },
                     "400": {
                         "description": "Bad request",
@@ -636,6 +678,13 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "404": {
+                        "description": "Category not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -646,24 +695,55 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/totals": {
-            "get": {
-                "description": "Get calculated expense totals for each person from active transactions",
+        "/api/categories/{id}/retire": {
+            "post": {
+                "description": "Retire a category and its subcategories, in one database transaction. Retired categories are hidden from GET /api/categories and cannot be chosen for rules, but existing transaction splits and archives keep them. With reassign_rules_to, the rules and rule split template lines of the retired categories move to that category; otherwise those rules are kept but no longer match. Other cannot be retired.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "totals"
+                    "categories"
                 ],
-                "summary": "Get totals by person",
-                "responses": {
-                    "200": {
-                        "description": "List of totals by person",
+                "summary": "Retire category",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Category ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "reassign_rules_to is optional",
+                        "name": "request",
+                        "in": "body",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.Total"
-                            }
+                            "$ref": "#/definitions/main.CategoryRetireRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The retired category and what retiring it changed",
+                        "schema": {
+                            "$ref": "#/definitions/main.CategoryRetireResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Category not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "500": {
@@ -676,23 +756,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/transactions": {
+        "/api/import-profiles": {
             "get": {
-                "description": "Retrieve all active (non-archived) transactions from the database",
+                "description": "Retrieve all CSV import profiles ordered by name",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "import-profiles"
                 ],
-                "summary": "Get all transactions",
+                "summary": "Get all import profiles",
                 "responses": {
                     "200": {
-                        "description": "List of transactions",
+                        "description": "List of import profiles",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/main.Transaction"
+                                "$ref": "#/definitions/main.ImportProfile"
                             }
                         }
                     },
@@ -705,18 +785,45 @@ const docTemplate = `{
                     }
                 }
             },
-            "delete": {
-                "description": "Clear all active transactions from the database",
+            "post": {
+                "description": "Create a CSV import profile describing a bank's export layout. Column fields are zero-based indexes.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "import-profiles"
+                ],
+                "summary": "Create import profile",
+                "parameters": [
+                    {
+                        "description": "Import profile (name and description_column required; debit_column/credit_column or amount_column depending on amount_format)",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ImportProfile"
+                        }
+                    }
                 ],
-                "summary": "Delete all transactions",
                 "responses": {
-                    "200": {
-                        "description": "All transactions cleared successfully",
+                    "201": {
+                        "description": "Created import profile",
+                        "schema": {
+                            "$ref": "#/definitions/main.ImportProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Import profile already exists",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -732,31 +839,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/transactions/{id}": {
-            "delete": {
-                "description": "Delete a specific transaction by ID",
+        "/api/import-profiles/{id}": {
+            "put": {
+                "description": "Update an existing CSV import profile. The Default profile, which uploads fall back to, cannot be renamed.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "import-profiles"
                 ],
-                "summary": "Delete single transaction",
+                "summary": "Update import profile",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Transaction ID",
+                        "description": "Import profile ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Import profile data",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ImportProfile"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Transaction deleted successfully",
+                        "description": "Updated import profile",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/main.ImportProfile"
                         }
                     },
                     "400": {
@@ -767,7 +885,14 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Transaction not found",
+                        "description": "Import profile not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Import profile already exists",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -781,53 +906,25 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/transactions/{id}/assign": {
-            "put": {
-                "description": "Assign a specific transaction to one or more people",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+            },
+            "delete": {
+                "description": "Delete a CSV import profile. The Default profile, which uploads fall back to, cannot be deleted.",
                 "tags": [
-                    "transactions"
+                    "import-profiles"
                 ],
-                "summary": "Assign transaction to person",
+                "summary": "Delete import profile",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Transaction ID",
+                        "description": "Import profile ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Assignment data with array of person names",
-                        "name": "assignment",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "assigned_to": {
-                                    "type": "array",
-                                    "items": {
-                                        "type": "string"
-                                    }
-                                }
-                            }
-                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Updated transaction with assignments",
-                        "schema": {
-                            "$ref": "#/definitions/main.Transaction"
-                        }
+                    "204": {
+                        "description": "No content"
                     },
                     "400": {
                         "description": "Bad request",
@@ -836,12 +933,35 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
-                    "404": {
-                        "description": "Transaction not found",
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
+                    }
+                }
+            }
+        },
+        "/api/imports": {
+            "get": {
+                "description": "Retrieve all import batches, most recent first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "imports"
+                ],
+                "summary": "Get all imports",
+                "responses": {
+                    "200": {
+                        "description": "List of imports",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Import"
+                            }
+                        }
                     },
                     "500": {
                         "description": "Internal server error",
@@ -853,20 +973,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/transactions/{id}/splits": {
+        "/api/imports/{id}": {
             "get": {
-                "description": "Retrieve split rows for a transaction.",
+                "description": "Retrieve an import batch, including every rejected row with its line number, raw content and reason code",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "imports"
                 ],
-                "summary": "Get transaction splits",
+                "summary": "Get import",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Transaction ID",
+                        "description": "Import ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -874,12 +994,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of splits",
+                        "description": "Import with rejected rows",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.TransactionSplit"
-                            }
+                            "$ref": "#/definitions/main.Import"
                         }
                     },
                     "400": {
@@ -890,7 +1007,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Transaction not found",
+                        "description": "Import not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -905,44 +1022,30 @@ const docTemplate = `{
                     }
                 }
             },
-            "put": {
-                "description": "Replace all split rows for a transaction.",
-                "consumes": [
-                    "application/json"
-                ],
+            "delete": {
+                "description": "Roll back an import batch by deleting every transaction and split it created. Imports with archived transactions cannot be undone.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "imports"
                 ],
-                "summary": "Replace transaction splits",
+                "summary": "Undo import",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Transaction ID",
+                        "description": "Import ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Split rows",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.splitRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Updated splits",
+                        "description": "Import rolled back - returns deleted_transactions count",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.TransactionSplit"
-                            }
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
@@ -953,7 +1056,14 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Transaction not found",
+                        "description": "Import not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Import contains archived transactions",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -969,34 +1079,63 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/upload-csv": {
+        "/api/people": {
+            "get": {
+                "description": "Retrieve all people from the database",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "Get all people",
+                "responses": {
+                    "200": {
+                        "description": "List of people",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Person"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Upload a CSV file containing transaction data. Returns the successfully imported transactions and count of skipped rows.",
+                "description": "Create a new person in the system",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "people"
                 ],
-                "summary": "Upload CSV file",
+                "summary": "Create person",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "CSV file to upload",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "description": "Person data (name required, email optional)",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.Person"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Upload successful - returns message, transactions array, and skipped_rows count",
+                    "201": {
+                        "description": "Created person",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/main.Person"
                         }
                     },
                     "400": {
@@ -1006,143 +1145,1970 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "409": {
+                        "description": "Person already exists",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/people/{id}": {
+            "delete": {
+                "description": "Delete a specific person by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "Delete person",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Person deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Person not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/rules": {
+            "get": {
+                "description": "Retrieve all categorization rules ordered by priority, each with the warnings about it from the rule set analysis",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Get all rules",
+                "responses": {
+                    "200": {
+                        "description": "List of categorization rules",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Rule"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new categorization rule. The response includes warnings when the rule is shadowed by, shadows or conflicts with another rule.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Create rule",
+                "parameters": [
+                    {
+                        "description": "Rule data (match_value, category_id, priority required; match_type defaults to contains; splits must add up to 100%)",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.Rule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created rule",
+                        "schema": {
+                            "$ref": "#/definitions/main.Rule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/rules/apply": {
+            "post": {
+                "description": "Re-evaluate the categorization rules against existing transactions and replace the splits, and for rules that assign people the people, of those that change, in a single database transaction. transaction_ids limits the changes to a chosen subset of the preview. Protected transactions are skipped unless include_protected is true.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Apply rules to existing transactions",
+                "parameters": [
+                    {
+                        "description": "scope (active, archived or all; defaults to active), transaction_ids and include_protected",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.ruleApplicationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Result - returns applied and skipped counts and the applied changes",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/rules/apply/preview": {
+            "post": {
+                "description": "Re-evaluate the categorization rules against existing transactions without saving anything. Rules see the CSV category each transaction was imported with. Returns each transaction whose splits would change, or whose people would for rules that assign people, with its current and proposed splits and people. Transactions whose splits were edited by hand, or split into several rows that no rule created, are marked protected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Preview rule application",
+                "parameters": [
+                    {
+                        "description": "scope (active, archived or all; defaults to active)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.ruleApplicationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Preview - returns changes array, would_apply and protected counts",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/rules/export": {
+            "get": {
+                "description": "Export all categorization rules as a portable rule set. Categories and people are referenced by name so the rule set can be imported into another installation.",
+                "produces": [
+                    "application/json",
+                    "application/x-yaml"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Export rules",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "json (default) or yaml",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rule set",
+                        "schema": {
+                            "$ref": "#/definitions/main.RuleSet"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/rules/import": {
+            "post": {
+                "description": "Import a rule set in the export's JSON or YAML format, in a single database transaction. Categories and people are resolved by name, case-insensitively. With mode=merge (the default) a rule with the same match type, field, value and conditions as an existing one updates it, and other rules are added; mode=replace deletes all existing rules first. Missing categories are created when create_categories is true and are an error otherwise. Missing people are skipped with a warning.",
+                "consumes": [
+                    "application/json",
+                    "application/x-yaml"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Import rules",
+                "parameters": [
+                    {
+                        "description": "Rule set",
+                        "name": "rules",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RuleSet"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "merge (default) or replace",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Create categories that do not exist",
+                        "name": "create_categories",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import result",
+                        "schema": {
+                            "$ref": "#/definitions/main.ruleImportResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/rules/lint": {
+            "get": {
+                "description": "Analyse the whole rule set for problems: rules that can never match because an earlier rule matches everything they do (shadowed), and rules with the same priority that can match the same transaction but assign different categories (conflict)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Lint rules",
+                "responses": {
+                    "200": {
+                        "description": "Warnings, empty when the rule set has no problems",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.RuleWarning"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/rules/report": {
+            "get": {
+                "description": "Report rules that may need attention: rules that never categorized a transaction, rules that have not matched for stale_days, and rules whose transactions were re-split by hand into other categories at least min_override_rate of the time",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Get rule report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Days without a match after which a rule is stale (default 90)",
+                        "name": "stale_days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Share of overridden transactions from which a rule is frequently overridden, between 0 and 1 (default 0.25)",
+                        "name": "min_override_rate",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rule report",
+                        "schema": {
+                            "$ref": "#/definitions/main.RuleReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/rules/suggestions": {
+            "get": {
+                "description": "Propose new rules for active transactions that are entirely in Other. Transactions are grouped by merchant, the first meaningful word of the description, and each group of at least min_count gets a contains rule on the description with the category the classifier suggests and the number of Other transactions the rule would match.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Get rule suggestions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Minimum number of transactions sharing a merchant (default 2)",
+                        "name": "min_count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Suggested rules, most transactions first",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.RuleSuggestion"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/rules/suggestions/accept": {
+            "post": {
+                "description": "Create the contains rule on the description that a rule suggestion proposes, with the chosen category. With apply set, the rule is also applied to the active transactions it wins, in the same database transaction; transactions whose splits were edited by hand are skipped.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Accept rule suggestion",
+                "parameters": [
+                    {
+                        "description": "match_value and category_id required; priority defaults to 0",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RuleSuggestionAcceptRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Result - returns the created rule, applied and skipped counts and the applied changes",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/rules/test": {
+            "post": {
+                "description": "Run a sample transaction through the categorization rules without saving anything. Returns every matching rule in priority order, the rule that wins and the category it resolves to, or fallback true when no rule matched, the category classifier was not confident and Other applies. Rules whose category no longer exists are left out, since imports cannot use them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Test rules",
+                "parameters": [
+                    {
+                        "description": "Sample transaction (description or category required; amount is positive for expenses)",
+                        "name": "transaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RuleTestRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "How the transaction would be categorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.RuleTestResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/rules/{id}": {
+            "put": {
+                "description": "Update an existing categorization rule. The response includes warnings when the rule is shadowed by, shadows or conflicts with another rule.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Update rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Rule data",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.Rule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated rule",
+                        "schema": {
+                            "$ref": "#/definitions/main.Rule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Rule not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a categorization rule",
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Delete rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/totals": {
+            "get": {
+                "description": "Get calculated expense totals for each person from active transactions. Splits in categories excluded from totals, or whose parent category is, are left out.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "totals"
+                ],
+                "summary": "Get totals by person",
+                "responses": {
+                    "200": {
+                        "description": "List of totals by person",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Total"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/transactions": {
+            "get": {
+                "description": "Retrieve all active (non-archived) transactions from the database",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get all transactions",
+                "responses": {
+                    "200": {
+                        "description": "List of transactions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Transaction"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Clear all active transactions from the database",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Delete all transactions",
+                "responses": {
+                    "200": {
+                        "description": "All transactions cleared successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/transactions/suggestions": {
+            "get": {
+                "description": "Suggest categories for active transactions that are entirely in Other, using a naive Bayes classifier trained on the descriptions and split categories of all categorized transactions. Suggestions are ordered from most to least likely; a transaction whose description has no word seen in training gets none.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get category suggestions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of suggestions per transaction (default 3)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Suggestions for each uncategorized transaction",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.TransactionCategorySuggestions"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/transactions/{id}": {
+            "delete": {
+                "description": "Delete a specific transaction by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Delete single transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transaction deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/transactions/{id}/assign": {
+            "put": {
+                "description": "Assign a specific transaction to one or more people. By default the transaction is divided equally between them; shares give a person a weight, such as 70 and 30, or a fixed amount, with the remainder divided by weight between the others. The shares are replaced with the assignment.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Assign transaction to person",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Assignment data with array of person IDs and optional shares",
+                        "name": "assignment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "assigned_to": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    }
+                                },
+                                "shares": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/main.shareRequest"
+                                    }
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated transaction with assignments",
+                        "schema": {
+                            "$ref": "#/definitions/main.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/transactions/{id}/splits": {
+            "get": {
+                "description": "Retrieve split rows for a transaction.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction splits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of splits",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.TransactionSplit"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace all split rows for a transaction. A split can be assigned to its own people, overriding the transaction's assigned_to for its amount.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Replace transaction splits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Split rows",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.splitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated splits",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.TransactionSplit"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/upload-csv": {
+            "post": {
+                "description": "Upload a CSV or OFX/QFX statement containing transaction data. CSV columns are read using an import profile, either the one given by profile_id or the one whose header matches the file. OFX files are detected by extension or header and deduplicated by FITID. Each upload is saved atomically as an import batch that can be undone; a file identical to an earlier import is rejected unless force is set. Returns the successfully imported transactions and count of skipped rows.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Upload CSV file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV or OFX/QFX file to upload",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Import profile ID for CSV files (detected from the header row when omitted)",
+                        "name": "profile_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Date format for CSV files, overriding the profile's (e.g. DD.MM.YYYY)",
+                        "name": "date_format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Decimal separator for CSV amounts, overriding the profile's ('.' or ',')",
+                        "name": "decimal_separator",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the person uploading the file",
+                        "name": "uploaded_by",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Import the file even if an identical file was already imported",
+                        "name": "force",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Upload successful - returns message, format, import_id, transactions array, skipped_rows count, rejections array (line, raw, reason, message), and the import_profile used for CSV files",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "File already imported - returns the existing import_id",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/upload-csv/preview": {
+            "post": {
+                "description": "Run the upload pipeline on a CSV or OFX/QFX file without saving anything. Returns, for each row, the category and rule that would be applied, whether it is a duplicate, and whether it would be imported.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Preview file import",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV or OFX/QFX file to preview",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Import profile ID for CSV files (detected from the header row when omitted)",
+                        "name": "profile_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Date format for CSV files, overriding the profile's (e.g. DD.MM.YYYY)",
+                        "name": "date_format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Decimal separator for CSV amounts, overriding the profile's ('.' or ',')",
+                        "name": "decimal_separator",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Preview - returns format, rows array, would_import and would_skip counts, the import_profile used for CSV files, and duplicate_import_id when the same file was already imported",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "main.Archive": {
+            "type": "object",
+            "properties": {
+                "archived_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "person_totals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.PersonTotal"
+                    }
+                },
+                "total_amount": {
+                    "type": "number"
+                },
+                "transaction_count": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ArchiveRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Budget": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "category_id": {
+                    "type": "string"
+                },
+                "category_name": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "person_id": {
+                    "type": "string"
+                },
+                "person_name": {
+                    "type": "string"
+                },
+                "rollover": {
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.BudgetReport": {
+            "type": "object",
+            "properties": {
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BudgetReportLine"
+                    }
+                },
+                "month": {
+                    "type": "string"
+                },
+                "over_budget": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.BudgetReportLine": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "number"
+                },
+                "budget_id": {
+                    "type": "string"
+                },
+                "budgeted": {
+                    "type": "number"
+                },
+                "category_id": {
+                    "type": "string"
+                },
+                "category_name": {
+                    "type": "string"
+                },
+                "person_id": {
+                    "type": "string"
+                },
+                "person_name": {
+                    "type": "string"
+                },
+                "remaining": {
+                    "type": "number"
+                },
+                "rolled_over": {
+                    "type": "number"
+                },
+                "spent": {
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Category": {
+            "type": "object",
+            "properties": {
+                "color": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "exclude_from_totals": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "type": "string"
+                },
+                "retired_at": {
+                    "type": "string"
+                },
+                "subcategories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Category"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CategoryMergeRequest": {
+            "type": "object",
+            "properties": {
+                "target_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CategoryMergeResult": {
+            "type": "object",
+            "properties": {
+                "budgets_moved": {
+                    "type": "integer"
+                },
+                "rule_splits_moved": {
+                    "type": "integer"
+                },
+                "rules_moved": {
+                    "type": "integer"
+                },
+                "splits_moved": {
+                    "type": "integer"
+                },
+                "subcategories_merged": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "subcategories_reparented": {
+                    "type": "integer"
+                },
+                "target": {
+                    "$ref": "#/definitions/main.Category"
+                }
+            }
+        },
+        "main.CategoryRetireRequest": {
+            "type": "object",
+            "properties": {
+                "reassign_rules_to": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CategoryRetireResult": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "$ref": "#/definitions/main.Category"
+                },
+                "rule_splits_reassigned": {
+                    "type": "integer"
+                },
+                "rules_disabled": {
+                    "type": "integer"
+                },
+                "rules_reassigned": {
+                    "type": "integer"
+                },
+                "subcategories_retired": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.CategorySuggestion": {
+            "type": "object",
+            "properties": {
+                "category_id": {
+                    "type": "string"
+                },
+                "category_name": {
+                    "type": "string"
+                },
+                "confidence": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.Import": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "file_hash": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "imported_rows": {
+                    "type": "integer"
+                },
+                "rejections": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.ImportRejection"
+                    }
+                },
+                "skipped_rows": {
+                    "type": "integer"
+                },
+                "total_rows": {
+                    "type": "integer"
+                },
+                "uploaded_by": {
+                    "type": "string"
+                },
+                "uploaded_by_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ImportProfile": {
+            "type": "object",
+            "properties": {
+                "amount_column": {
+                    "type": "integer"
+                },
+                "amount_format": {
+                    "type": "string"
+                },
+                "card_number_column": {
+                    "type": "integer"
+                },
+                "category_column": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "credit_column": {
+                    "type": "integer"
+                },
+                "date_format": {
+                    "type": "string"
+                },
+                "debit_column": {
+                    "type": "integer"
+                },
+                "decimal_separator": {
+                    "type": "string"
+                },
+                "delimiter": {
+                    "type": "string"
+                },
+                "description_column": {
+                    "type": "integer"
+                },
+                "expense_sign": {
+                    "type": "string"
+                },
+                "has_header": {
+                    "type": "boolean"
+                },
+                "header_columns": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "posted_date_column": {
+                    "type": "integer"
+                },
+                "transaction_date_column": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ImportRejection": {
+            "type": "object",
+            "properties": {
+                "line": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "raw": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Person": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PersonTotal": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.Rule": {
+            "type": "object",
+            "properties": {
+                "assign_to": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "card_number": {
+                    "type": "string"
+                },
+                "category_id": {
+                    "type": "string"
+                },
+                "category_name": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "direction": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "hit_count": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_matched_at": {
+                    "type": "string"
+                },
+                "match_field": {
+                    "type": "string"
+                },
+                "match_type": {
+                    "type": "string"
+                },
+                "match_value": {
+                    "type": "string"
+                },
+                "max_amount": {
+                    "type": "number"
+                },
+                "min_amount": {
+                    "type": "number"
+                },
+                "override_count": {
+                    "type": "integer"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "splits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.RuleSplit"
+                    }
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.RuleWarning"
+                    }
+                }
+            }
+        },
+        "main.RuleReport": {
+            "type": "object",
+            "properties": {
+                "frequently_overridden": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Rule"
+                    }
+                },
+                "never_matched": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Rule"
+                    }
+                },
+                "stale": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Rule"
+                    }
+                }
+            }
+        },
+        "main.RuleSet": {
+            "type": "object",
+            "properties": {
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.RuleSetRule"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.RuleSetRule": {
+            "type": "object",
+            "properties": {
+                "assign_to": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
+                },
+                "card_number": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "direction": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "match_field": {
+                    "type": "string"
+                },
+                "match_type": {
+                    "type": "string"
+                },
+                "match_value": {
+                    "type": "string"
+                },
+                "max_amount": {
+                    "type": "number"
+                },
+                "min_amount": {
+                    "type": "number"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "splits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.RuleSetSplit"
+                    }
+                },
+                "start_date": {
+                    "type": "string"
                 }
             }
-        }
-    },
-    "definitions": {
-        "main.Archive": {
+        },
+        "main.RuleSetSplit": {
             "type": "object",
             "properties": {
-                "archived_at": {
+                "category": {
                     "type": "string"
                 },
-                "created_at": {
+                "percentage": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.RuleSplit": {
+            "type": "object",
+            "properties": {
+                "category_id": {
                     "type": "string"
                 },
-                "description": {
+                "category_name": {
                     "type": "string"
                 },
-                "id": {
+                "percentage": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.RuleSuggestion": {
+            "type": "object",
+            "properties": {
+                "category_id": {
                     "type": "string"
                 },
-                "person_totals": {
+                "category_name": {
+                    "type": "string"
+                },
+                "confidence": {
+                    "type": "number"
+                },
+                "examples": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.PersonTotal"
+                        "type": "string"
                     }
                 },
-                "total_amount": {
-                    "type": "number"
+                "match_field": {
+                    "type": "string"
                 },
-                "transaction_count": {
-                    "type": "integer"
+                "match_type": {
+                    "type": "string"
                 },
-                "updated_at": {
+                "match_value": {
                     "type": "string"
+                },
+                "transaction_count": {
+                    "type": "integer"
                 }
             }
         },
-        "main.ArchiveRequest": {
+        "main.RuleSuggestionAcceptRequest": {
             "type": "object",
             "properties": {
-                "description": {
+                "apply": {
+                    "type": "boolean"
+                },
+                "category_id": {
+                    "type": "string"
+                },
+                "match_value": {
                     "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
                 }
             }
         },
-        "main.Category": {
+        "main.RuleTestMatch": {
             "type": "object",
             "properties": {
-                "color": {
+                "assign_to": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "card_number": {
+                    "type": "string"
+                },
+                "category_id": {
+                    "type": "string"
+                },
+                "category_name": {
                     "type": "string"
                 },
                 "created_at": {
                     "type": "string"
                 },
-                "description": {
+                "direction": {
+                    "type": "string"
+                },
+                "end_date": {
                     "type": "string"
                 },
+                "hit_count": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "string"
                 },
-                "name": {
+                "last_matched_at": {
                     "type": "string"
                 },
-                "parent_id": {
+                "match_field": {
                     "type": "string"
                 },
-                "subcategories": {
+                "match_type": {
+                    "type": "string"
+                },
+                "match_value": {
+                    "type": "string"
+                },
+                "matched_field": {
+                    "type": "string"
+                },
+                "max_amount": {
+                    "type": "number"
+                },
+                "min_amount": {
+                    "type": "number"
+                },
+                "override_count": {
+                    "type": "integer"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "splits": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.Category"
+                        "$ref": "#/definitions/main.RuleSplit"
                     }
                 },
+                "start_date": {
+                    "type": "string"
+                },
                 "updated_at": {
                     "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.RuleWarning"
+                    }
                 }
             }
         },
-        "main.Person": {
+        "main.RuleTestRequest": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
+                "amount": {
+                    "type": "number"
                 },
-                "email": {
+                "card_number": {
                     "type": "string"
                 },
-                "id": {
+                "category": {
                     "type": "string"
                 },
-                "name": {
+                "description": {
                     "type": "string"
                 },
-                "updated_at": {
+                "transaction_date": {
                     "type": "string"
                 }
             }
         },
-        "main.PersonTotal": {
+        "main.RuleTestResult": {
             "type": "object",
             "properties": {
-                "name": {
+                "category_id": {
                     "type": "string"
                 },
-                "total": {
+                "category_name": {
+                    "type": "string"
+                },
+                "confidence": {
                     "type": "number"
+                },
+                "fallback": {
+                    "type": "boolean"
+                },
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.RuleTestMatch"
+                    }
+                },
+                "winning_rule": {
+                    "$ref": "#/definitions/main.Rule"
                 }
             }
         },
-        "main.Rule": {
+        "main.RuleWarning": {
             "type": "object",
             "properties": {
-                "category_id": {
-                    "type": "string"
-                },
-                "category_name": {
-                    "type": "string"
-                },
-                "created_at": {
+                "message": {
                     "type": "string"
                 },
-                "id": {
+                "other_rule_id": {
                     "type": "string"
                 },
-                "match_value": {
+                "rule_id": {
                     "type": "string"
                 },
-                "priority": {
-                    "type": "integer"
-                },
-                "updated_at": {
+                "type": {
                     "type": "string"
                 }
             }
@@ -1191,6 +3157,12 @@ const docTemplate = `{
                 "posted_date": {
                     "type": "string"
                 },
+                "shares": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.TransactionShare"
+                    }
+                },
                 "splits": {
                     "type": "array",
                     "items": {
@@ -1205,12 +3177,61 @@ const docTemplate = `{
                 }
             }
         },
+        "main.TransactionCategorySuggestions": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "suggestions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.CategorySuggestion"
+                    }
+                },
+                "transaction_date": {
+                    "type": "string"
+                },
+                "transaction_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.TransactionShare": {
+            "type": "object",
+            "properties": {
+                "fixed_amount": {
+                    "type": "number"
+                },
+                "person_id": {
+                    "type": "string"
+                },
+                "person_name": {
+                    "type": "string"
+                },
+                "share": {
+                    "type": "number"
+                },
+                "weight": {
+                    "type": "number"
+                }
+            }
+        },
         "main.TransactionSplit": {
             "type": "object",
             "properties": {
                 "amount": {
                     "type": "number"
                 },
+                "assigned_to": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "category_id": {
                     "type": "string"
                 },
@@ -1231,6 +3252,63 @@ const docTemplate = `{
                 }
             }
         },
+        "main.ruleApplicationRequest": {
+            "type": "object",
+            "properties": {
+                "include_protected": {
+                    "type": "boolean"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "transaction_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.ruleImportResult": {
+            "type": "object",
+            "properties": {
+                "categories_created": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created": {
+                    "type": "integer"
+                },
+                "deleted": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.shareRequest": {
+            "type": "object",
+            "properties": {
+                "fixed_amount": {
+                    "type": "number"
+                },
+                "person_id": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "number"
+                }
+            }
+        },
         "main.splitRequest": {
             "type": "object",
             "properties": {
@@ -1242,6 +3320,13 @@ const docTemplate = `{
                             "amount": {
                                 "type": "number"
                             },
+                            "assigned_to": {
+                                "description": "People the split is assigned to, overriding the transaction's assigned_to.\nOmit it to use the transaction's assignment.",
+                                "type": "array",
+                                "items": {
+                                    "type": "string"
+                                }
+                            },
                             "category_id": {
                                 "type": "string"
                             },
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	decimalSeparatorPoint = "."
+	decimalSeparatorComma = ","
+)
+
+// isoDateLayouts are accepted whatever the profile's date format, since ISO dates
+// cannot be mistaken for another layout
+var isoDateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// dateFormatPlaceholders converts YYYY/MM/DD style placeholders to Go reference
+// layout elements. Longer placeholders are listed first so YYYY wins over YY.
+var dateFormatPlaceholders = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02")
+
+// unpaddedDateElements relaxes zero-padded month and day elements so that 1/5/2024
+// is accepted by a 01/02/2006 layout
+var unpaddedDateElements = strings.NewReplacer("01", "1", "02", "2", "_2", "2")
+
+// dateLayout converts a date format to a Go reference layout. Formats may use
+// placeholders (e.g. DD.MM.YYYY, MM/DD/YYYY) or be a Go layout (e.g. 02.01.2006).
+func dateLayout(format string) string {
+	return dateFormatPlaceholders.Replace(format)
+}
+
+// parseStatementDate parses a date column value with the given date format, also
+// accepting unpadded months and days and ISO dates. Empty values are NULL dates;
+// values that match no layout are an invalid_date row error.
+func parseStatementDate(value, format string) (pgtype.Date, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return pgtype.Date{Valid: false}, nil
+	}
+
+	layout := dateLayout(format)
+	layouts := append([]string{layout, unpaddedDateElements.Replace(layout)}, isoDateLayouts...)
+	for _, l := range layouts {
+		parsed, err := time.Parse(l, value)
+		if err == nil {
+			year, month, day := parsed.Date()
+			return pgtype.Date{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Valid: true}, nil
+		}
+	}
+
+	return pgtype.Date{Valid: false}, newImportRowError(rejectInvalidDate, "invalid date %q, expected format %s", value, format)
+}
+
+// parseStatementAmount parses an amount written with the given decimal separator.
+// It accepts currency symbols and three-letter currency codes, thousands separators
+// (the other of '.' and ',', spaces and apostrophes), a leading or trailing sign,
+// and parenthesised negatives such as (45.00).
+func parseStatementAmount(raw, decimalSeparator string) (float64, error) {
+	value := strings.TrimSpace(raw)
+
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = value[1 : len(value)-1]
+	}
+
+	// Currency symbols may sit on either side of the sign, e.g. -$12.00 or $-12.00
+	value = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Sc, r) {
+			return -1
+		}
+		return r
+	}, value)
+	value = trimCurrencyCode(strings.TrimSpace(value))
+
+	switch {
+	case strings.HasPrefix(value, "-"):
+		negative = !negative
+		value = value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	case strings.HasSuffix(value, "-"):
+		negative = !negative
+		value = value[:len(value)-1]
+	}
+	value = strings.TrimSpace(value)
+
+	if decimalSeparator != decimalSeparatorComma {
+		decimalSeparator = decimalSeparatorPoint
+	}
+	integer, fraction, _ := strings.Cut(value, decimalSeparator)
+
+	digits, err := ungroupDigits(integer, decimalSeparator)
+	if err != nil {
+		return 0, err
+	}
+	if !isDigits(fraction) || (digits == "" && fraction == "") {
+		return 0, fmt.Errorf("not a number")
+	}
+
+	amount, err := strconv.ParseFloat(digits+"."+fraction, 64)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// ungroupDigits removes thousands separators from the integer part of an amount.
+// Groups after a separator must have exactly three digits, so 12,50 read with a
+// decimal point is rejected rather than taken as 1250.
+func ungroupDigits(integer, decimalSeparator string) (string, error) {
+	isSeparator := func(r rune) bool {
+		switch r {
+		case ' ', '\u00a0', '\u202f', '\'':
+			return true
+		case '.':
+			return decimalSeparator == decimalSeparatorComma
+		case ',':
+			return decimalSeparator == decimalSeparatorPoint
+		}
+		return false
+	}
+
+	groups := strings.FieldsFunc(integer, isSeparator)
+	if len(groups) == 0 {
+		return "", nil
+	}
+	for i, group := range groups {
+		if !isDigits(group) {
+			return "", fmt.Errorf("not a number")
+		}
+		if i > 0 && len(group) != 3 {
+			return "", fmt.Errorf("misplaced thousands separator")
+		}
+	}
+	if len(groups) > 1 && len(groups[0]) > 3 {
+		return "", fmt.Errorf("misplaced thousands separator")
+	}
+	return strings.Join(groups, ""), nil
+}
+
+// trimCurrencyCode removes a three-letter currency code (e.g. USD, EUR) from
+// either end of an amount
+func trimCurrencyCode(value string) string {
+	if len(value) > 3 && isASCIILetters(value[:3]) && !isASCIILetters(value[3:4]) {
+		value = strings.TrimSpace(value[3:])
+	}
+	if n := len(value); n > 3 && isASCIILetters(value[n-3:]) && !isASCIILetters(value[n-4:n-3]) {
+		value = strings.TrimSpace(value[:n-3])
+	}
+	return value
+}
+
+// isDigits reports whether s consists only of ASCII digits
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isASCIILetters reports whether s is non-empty and consists only of ASCII letters
+func isASCIILetters(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
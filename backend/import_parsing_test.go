@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatementAmount(t *testing.T) {
+	valid := []struct {
+		raw       string
+		separator string
+		expected  float64
+	}{
+		{"12.00", ".", 12.00},
+		{"1,234.56", ".", 1234.56},
+		{"$12.00", ".", 12.00},
+		{"-$12.00", ".", -12.00},
+		{"$-12.00", ".", -12.00},
+		{"(45.00)", ".", -45.00},
+		{"($1,045.00)", ".", -1045.00},
+		{"45.00-", ".", -45.00},
+		{"+7", ".", 7},
+		{"USD 19.99", ".", 19.99},
+		{"12,50", ",", 12.50},
+		{"1.234,56", ",", 1234.56},
+		{"1 234,56 €", ",", 1234.56},
+		{"1\u00a0234,56", ",", 1234.56},
+		{"1'234.56", ".", 1234.56},
+		{"12,50 EUR", ",", 12.50},
+		{".50", ".", 0.50},
+	}
+	for _, tc := range valid {
+		t.Run(tc.raw, func(t *testing.T) {
+			amount, err := parseStatementAmount(tc.raw, tc.separator)
+			require.NoError(t, err)
+			assert.InDelta(t, tc.expected, amount, 0.0001)
+		})
+	}
+
+	invalid := []struct {
+		raw       string
+		separator string
+	}{
+		{"12,50", "."},     // decimal comma read with a decimal point
+		{"1,2345.00", "."}, // misplaced thousands separator
+		{"1234,567.00", "."},
+		{"abc", "."},
+		{"invalid-amount", "."},
+		{"1e5", "."},
+		{"NaN", "."},
+		{"$", "."},
+		{"1.2.3", ","},
+	}
+	for _, tc := range invalid {
+		t.Run("rejects "+tc.raw, func(t *testing.T) {
+			_, err := parseStatementAmount(tc.raw, tc.separator)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseStatementDate(t *testing.T) {
+	valid := []struct {
+		value    string
+		format   string
+		expected string
+	}{
+		{"2024-01-31", "2006-01-02", "2024-01-31"},
+		{"01/31/2024", "MM/DD/YYYY", "2024-01-31"},
+		{"1/5/2024", "MM/DD/YYYY", "2024-01-05"},
+		{"31.01.2024", "DD.MM.YYYY", "2024-01-31"},
+		{"5.1.2024", "02.01.2006", "2024-01-05"},
+		{"31/01/24", "DD/MM/YY", "2024-01-31"},
+		{"20240131", "YYYYMMDD", "2024-01-31"},
+		{"2024-01-31", "DD.MM.YYYY", "2024-01-31"}, // ISO is always accepted
+		{"2024-01-31T10:15:00", "MM/DD/YYYY", "2024-01-31"},
+		{"31 Jan 2024", "02 Jan 2006", "2024-01-31"},
+	}
+	for _, tc := range valid {
+		t.Run(tc.value+" as "+tc.format, func(t *testing.T) {
+			date, err := parseStatementDate(tc.value, tc.format)
+			require.NoError(t, err)
+			require.True(t, date.Valid)
+			assert.Equal(t, tc.expected, date.Time.Format("2006-01-02"))
+		})
+	}
+
+	t.Run("leaves empty values NULL", func(t *testing.T) {
+		date, err := parseStatementDate("  ", "MM/DD/YYYY")
+		require.NoError(t, err)
+		assert.False(t, date.Valid)
+	})
+
+	t.Run("rejects dates that do not match the format", func(t *testing.T) {
+		_, err := parseStatementDate("31/01/2024", "MM/DD/YYYY")
+		require.Error(t, err)
+		assert.Equal(t, rejectInvalidDate, rejectionReason(err))
+	})
+}
+
+func TestValidateDateFormat(t *testing.T) {
+	assert.NoError(t, validateDateFormat("DD.MM.YYYY"))
+	assert.NoError(t, validateDateFormat("01/02/2006"))
+	assert.Error(t, validateDateFormat("MM/YYYY"))
+	assert.Error(t, validateDateFormat("not a format"))
+}
@@ -0,0 +1,646 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"jointanalysis/db/generated"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	amountFormatDebitCredit = "debit_credit"
+	amountFormatSigned      = "signed"
+
+	expenseSignPositive = "positive"
+	expenseSignNegative = "negative"
+
+	// defaultImportProfileName is the seeded profile used when no profile is
+	// requested and none matches the file's header row
+	defaultImportProfileName = "Default"
+)
+
+// importRecord holds the transaction fields extracted from a single statement row.
+// ExternalID is the bank's stable transaction identifier (OFX FITID) when the
+// format provides one; it replaces the field-based duplicate check.
+type importRecord struct {
+	TransactionDate pgtype.Date
+	PostedDate      pgtype.Date
+	CardNumber      string
+	Description     string
+	CsvCategory     string
+	Amount          float64
+	ExternalID      string
+}
+
+// Import profile conversion and validation functions
+
+// convertImportProfile converts a generated.ImportProfile to our ImportProfile struct
+func convertImportProfile(p generated.ImportProfile) ImportProfile {
+	descriptionColumn := p.DescriptionColumn
+	headerColumns := p.HeaderColumns
+	if headerColumns == nil {
+		headerColumns = []string{}
+	}
+
+	return ImportProfile{
+		ID:                    uuid.UUID(p.ID.Bytes).String(),
+		Name:                  p.Name,
+		Delimiter:             p.Delimiter,
+		HasHeader:             p.HasHeader,
+		HeaderColumns:         headerColumns,
+		DateFormat:            p.DateFormat,
+		DecimalSeparator:      p.DecimalSeparator,
+		AmountFormat:          p.AmountFormat,
+		ExpenseSign:           p.ExpenseSign,
+		TransactionDateColumn: int4Ptr(p.TransactionDateColumn),
+		PostedDateColumn:      int4Ptr(p.PostedDateColumn),
+		CardNumberColumn:      int4Ptr(p.CardNumberColumn),
+		DescriptionColumn:     &descriptionColumn,
+		CategoryColumn:        int4Ptr(p.CategoryColumn),
+		AmountColumn:          int4Ptr(p.AmountColumn),
+		DebitColumn:           int4Ptr(p.DebitColumn),
+		CreditColumn:          int4Ptr(p.CreditColumn),
+		CreatedAt:             p.CreatedAt.Time,
+		UpdatedAt:             p.UpdatedAt.Time,
+	}
+}
+
+// int4Ptr converts a nullable pgtype.Int4 to an *int32
+func int4Ptr(v pgtype.Int4) *int32 {
+	if !v.Valid {
+		return nil
+	}
+	value := v.Int32
+	return &value
+}
+
+// int4FromPtr converts an *int32 to a nullable pgtype.Int4
+func int4FromPtr(v *int32) pgtype.Int4 {
+	if v == nil {
+		return pgtype.Int4{Valid: false}
+	}
+	return pgtype.Int4{Int32: *v, Valid: true}
+}
+
+// applyImportProfileDefaults fills in optional settings left empty in a request
+func applyImportProfileDefaults(p *ImportProfile) {
+	if p.Delimiter == "" {
+		p.Delimiter = ","
+	}
+	if p.DateFormat == "" {
+		p.DateFormat = "2006-01-02"
+	}
+	if p.DecimalSeparator == "" {
+		p.DecimalSeparator = decimalSeparatorPoint
+	}
+	if p.AmountFormat == "" {
+		p.AmountFormat = amountFormatDebitCredit
+	}
+	if p.ExpenseSign == "" {
+		p.ExpenseSign = expenseSignPositive
+	}
+	if p.HeaderColumns == nil {
+		p.HeaderColumns = []string{}
+	}
+}
+
+// validateImportProfile checks that a profile describes a readable CSV layout
+func validateImportProfile(p ImportProfile) error {
+	if err := validateName(p.Name); err != nil {
+		return err
+	}
+
+	if utf8.RuneCountInString(p.Delimiter) != 1 {
+		return fmt.Errorf("delimiter must be a single character")
+	}
+	if strings.ContainsAny(p.Delimiter, "\"\r\n") {
+		return fmt.Errorf("delimiter cannot be a quote or newline")
+	}
+
+	if p.HasHeader && len(p.HeaderColumns) == 0 {
+		return fmt.Errorf("header_columns are required when has_header is true")
+	}
+
+	if err := validateDateFormat(p.DateFormat); err != nil {
+		return err
+	}
+
+	if err := validateDecimalSeparator(p.DecimalSeparator); err != nil {
+		return err
+	}
+
+	if p.ExpenseSign != expenseSignPositive && p.ExpenseSign != expenseSignNegative {
+		return fmt.Errorf("expense_sign must be 'positive' or 'negative'")
+	}
+
+	if p.DescriptionColumn == nil {
+		return fmt.Errorf("description_column is required")
+	}
+
+	switch p.AmountFormat {
+	case amountFormatDebitCredit:
+		if p.DebitColumn == nil || p.CreditColumn == nil {
+			return fmt.Errorf("debit_column and credit_column are required for the debit_credit amount format")
+		}
+	case amountFormatSigned:
+		if p.AmountColumn == nil {
+			return fmt.Errorf("amount_column is required for the signed amount format")
+		}
+	default:
+		return fmt.Errorf("amount_format must be 'debit_credit' or 'signed'")
+	}
+
+	for _, column := range p.mappedColumns() {
+		if *column < 0 {
+			return fmt.Errorf("column indexes cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+// validateDateFormat checks that a date format round-trips a known date
+func validateDateFormat(format string) error {
+	layout := dateLayout(format)
+	reference := time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC)
+	parsed, err := time.Parse(layout, reference.Format(layout))
+	if err != nil || !parsed.Equal(reference) {
+		return fmt.Errorf("date_format must contain year, month and day, either as placeholders (e.g. DD.MM.YYYY) or as a Go reference layout (e.g. 2006-01-02)")
+	}
+	return nil
+}
+
+// validateDecimalSeparator checks that amounts use a decimal point or comma
+func validateDecimalSeparator(separator string) error {
+	if separator != decimalSeparatorPoint && separator != decimalSeparatorComma {
+		return fmt.Errorf("decimal_separator must be '.' or ','")
+	}
+	return nil
+}
+
+// applyFormatOverrides replaces the profile's date format and decimal separator
+// with those given for a single upload, when set
+func (p *ImportProfile) applyFormatOverrides(dateFormat, decimalSeparator string) error {
+	if dateFormat != "" {
+		if err := validateDateFormat(dateFormat); err != nil {
+			return err
+		}
+		p.DateFormat = dateFormat
+	}
+	if decimalSeparator != "" {
+		if err := validateDecimalSeparator(decimalSeparator); err != nil {
+			return err
+		}
+		p.DecimalSeparator = decimalSeparator
+	}
+	return nil
+}
+
+// Import profile parsing functions
+
+// mappedColumns returns every column index the profile reads from
+func (p ImportProfile) mappedColumns() []*int32 {
+	candidates := []*int32{
+		p.TransactionDateColumn, p.PostedDateColumn, p.CardNumberColumn, p.DescriptionColumn,
+		p.CategoryColumn, p.AmountColumn, p.DebitColumn, p.CreditColumn,
+	}
+
+	columns := make([]*int32, 0, len(candidates))
+	for _, column := range candidates {
+		if column != nil {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// minColumns returns how many columns a record needs to cover every mapped column
+func (p ImportProfile) minColumns() int {
+	minimum := 0
+	for _, column := range p.mappedColumns() {
+		if int(*column)+1 > minimum {
+			minimum = int(*column) + 1
+		}
+	}
+	return minimum
+}
+
+// delimiterRune returns the profile delimiter as a rune for csv.Reader
+func (p ImportProfile) delimiterRune() rune {
+	r, _ := utf8.DecodeRuneInString(p.Delimiter)
+	if r == utf8.RuneError {
+		return ','
+	}
+	return r
+}
+
+// newCSVReader creates a csv.Reader configured for the profile's delimiter.
+// Rows may have varying lengths; short rows are reported per record instead.
+func (p ImportProfile) newCSVReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = p.delimiterRune()
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+// field returns the raw value of a mapped column, or "" when the column is unmapped
+func (p ImportProfile) field(record []string, column *int32) string {
+	if column == nil || int(*column) >= len(record) {
+		return ""
+	}
+	return record[*column]
+}
+
+// isHeaderRecord reports whether a record is this profile's header row: every
+// mapped column that has a header name must hold that name. Unlike matchesHeader
+// it ignores unmapped columns, so a header that gained a column is still skipped.
+func (p ImportProfile) isHeaderRecord(record []string) bool {
+	if !p.HasHeader {
+		return false
+	}
+	compared := 0
+	for _, column := range p.mappedColumns() {
+		index := int(*column)
+		if index >= len(p.HeaderColumns) {
+			continue
+		}
+		if index >= len(record) || !strings.EqualFold(normalizeHeaderCell(record[index]), normalizeHeaderCell(p.HeaderColumns[index])) {
+			return false
+		}
+		compared++
+	}
+	return compared > 0
+}
+
+// matchesHeader reports whether a record matches every header column of the profile
+func (p ImportProfile) matchesHeader(record []string) bool {
+	if !p.HasHeader || len(record) != len(p.HeaderColumns) {
+		return false
+	}
+	for i := range record {
+		if !strings.EqualFold(normalizeHeaderCell(record[i]), normalizeHeaderCell(p.HeaderColumns[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeHeaderCell strips whitespace and a UTF-8 byte order mark from a header cell
+func normalizeHeaderCell(cell string) string {
+	return strings.TrimSpace(strings.TrimPrefix(cell, "\ufeff"))
+}
+
+// parseRecord extracts transaction fields from a CSV record using the profile's column mapping
+func (p ImportProfile) parseRecord(record []string) (importRecord, error) {
+	if minimum := p.minColumns(); len(record) < minimum {
+		return importRecord{}, newImportRowError(rejectTooFewColumns, "expected at least %d columns, got %d", minimum, len(record))
+	}
+
+	amount, err := p.parseAmount(record)
+	if err != nil {
+		return importRecord{}, err
+	}
+
+	transactionDate, err := parseStatementDate(p.field(record, p.TransactionDateColumn), p.DateFormat)
+	if err != nil {
+		return importRecord{}, err
+	}
+	postedDate, err := parseStatementDate(p.field(record, p.PostedDateColumn), p.DateFormat)
+	if err != nil {
+		return importRecord{}, err
+	}
+
+	return importRecord{
+		TransactionDate: transactionDate,
+		PostedDate:      postedDate,
+		CardNumber:      p.field(record, p.CardNumberColumn),
+		Description:     p.field(record, p.DescriptionColumn),
+		CsvCategory:     p.field(record, p.CategoryColumn),
+		Amount:          amount,
+	}, nil
+}
+
+// parseAmount reads the transaction amount, returning expenses as positive and
+// credits as negative regardless of the bank's sign convention
+func (p ImportProfile) parseAmount(record []string) (float64, error) {
+	if p.AmountFormat == amountFormatSigned {
+		raw := strings.TrimSpace(p.field(record, p.AmountColumn))
+		if raw == "" {
+			return 0, newImportRowError(rejectMissingAmount, "no amount found")
+		}
+		amount, err := parseStatementAmount(raw, p.DecimalSeparator)
+		if err != nil {
+			return 0, newImportRowError(rejectInvalidAmount, "invalid amount %q", raw)
+		}
+		if p.ExpenseSign == expenseSignNegative {
+			amount = -amount
+		}
+		return amount, nil
+	}
+
+	// Debit amounts are expenses (positive), credit amounts are income/refunds (negative)
+	if debit := strings.TrimSpace(p.field(record, p.DebitColumn)); debit != "" {
+		amount, err := parseStatementAmount(debit, p.DecimalSeparator)
+		if err != nil {
+			return 0, newImportRowError(rejectInvalidAmount, "invalid debit amount %q", debit)
+		}
+		return amount, nil
+	}
+	if credit := strings.TrimSpace(p.field(record, p.CreditColumn)); credit != "" {
+		amount, err := parseStatementAmount(credit, p.DecimalSeparator)
+		if err != nil {
+			return 0, newImportRowError(rejectInvalidAmount, "invalid credit amount %q", credit)
+		}
+		return -amount, nil
+	}
+	return 0, newImportRowError(rejectMissingAmount, "no amount found")
+}
+
+// detectImportProfile returns the first profile whose header columns match the
+// file's first line, or nil when no profile matches
+func detectImportProfile(profiles []ImportProfile, firstLine string) *ImportProfile {
+	for i := range profiles {
+		if !profiles[i].HasHeader || len(profiles[i].HeaderColumns) == 0 {
+			continue
+		}
+		record, err := profiles[i].newCSVReader(strings.NewReader(firstLine)).Read()
+		if err != nil {
+			continue
+		}
+		if profiles[i].matchesHeader(record) {
+			return &profiles[i]
+		}
+	}
+	return nil
+}
+
+// importProfileLookupError wraps a database error met while loading the import
+// profile for an upload, which is a server error rather than a bad request
+type importProfileLookupError struct {
+	err error
+}
+
+func (e *importProfileLookupError) Error() string {
+	return e.err.Error()
+}
+
+func (e *importProfileLookupError) Unwrap() error {
+	return e.err
+}
+
+// resolveImportProfile picks the profile for an upload: the explicitly requested
+// profile, else the profile whose header matches the file, else the default profile.
+// Database errors other than a missing profile are returned as importProfileLookupError.
+func resolveImportProfile(profileID string, firstLine string) (ImportProfile, error) {
+	if profileID != "" {
+		parsedID, err := uuid.Parse(profileID)
+		if err != nil {
+			return ImportProfile{}, fmt.Errorf("invalid profile_id format")
+		}
+		dbProfile, err := queries.GetImportProfileByID(context.Background(), pgtype.UUID{Bytes: parsedID, Valid: true})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ImportProfile{}, fmt.Errorf("import profile not found")
+		}
+		if err != nil {
+			return ImportProfile{}, &importProfileLookupError{err: err}
+		}
+		return convertImportProfile(dbProfile), nil
+	}
+
+	dbProfiles, err := queries.GetImportProfiles(context.Background())
+	if err != nil {
+		return ImportProfile{}, &importProfileLookupError{err: err}
+	}
+	profiles := make([]ImportProfile, 0, len(dbProfiles))
+	for _, dbProfile := range dbProfiles {
+		profiles = append(profiles, convertImportProfile(dbProfile))
+	}
+	if detected := detectImportProfile(profiles, firstLine); detected != nil {
+		return *detected, nil
+	}
+
+	dbProfile, err := queries.GetImportProfileByName(context.Background(), defaultImportProfileName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ImportProfile{}, fmt.Errorf("no import profile matches this file")
+	}
+	if err != nil {
+		return ImportProfile{}, &importProfileLookupError{err: err}
+	}
+	return convertImportProfile(dbProfile), nil
+}
+
+// isDefaultImportProfile reports whether id is the seeded Default profile, which
+// uploads fall back to by name and so cannot be deleted or renamed
+func isDefaultImportProfile(ctx context.Context, q *generated.Queries, id pgtype.UUID) (bool, error) {
+	profile, err := q.GetImportProfileByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return profile.Name == defaultImportProfileName, nil
+}
+
+// Import profile handler functions
+
+// @Summary Get all import profiles
+// @Description Retrieve all CSV import profiles ordered by name
+// @Tags import-profiles
+// @Produce json
+// @Success 200 {array} ImportProfile "List of import profiles"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/import-profiles [get]
+func getImportProfiles(c *gin.Context) {
+	dbProfiles, err := queries.GetImportProfiles(context.Background())
+	if err != nil {
+		log.Printf("Error fetching import profiles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching import profiles"})
+		return
+	}
+
+	profiles := make([]ImportProfile, 0, len(dbProfiles))
+	for _, dbProfile := range dbProfiles {
+		profiles = append(profiles, convertImportProfile(dbProfile))
+	}
+
+	c.JSON(http.StatusOK, profiles)
+}
+
+// @Summary Create import profile
+// @Description Create a CSV import profile describing a bank's export layout. Column fields are zero-based indexes.
+// @Tags import-profiles
+// @Accept json
+// @Produce json
+// @Param profile body ImportProfile true "Import profile (name and description_column required; debit_column/credit_column or amount_column depending on amount_format)"
+// @Success 201 {object} ImportProfile "Created import profile"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 409 {object} map[string]interface{} "Import profile already exists"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/import-profiles [post]
+func createImportProfile(c *gin.Context) {
+	var req ImportProfile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	applyImportProfileDefaults(&req)
+	if err := validateImportProfile(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dbProfile, err := queries.CreateImportProfile(context.Background(), generated.CreateImportProfileParams{
+		Name:                  req.Name,
+		Delimiter:             req.Delimiter,
+		HasHeader:             req.HasHeader,
+		HeaderColumns:         req.HeaderColumns,
+		DateFormat:            req.DateFormat,
+		AmountFormat:          req.AmountFormat,
+		ExpenseSign:           req.ExpenseSign,
+		TransactionDateColumn: int4FromPtr(req.TransactionDateColumn),
+		PostedDateColumn:      int4FromPtr(req.PostedDateColumn),
+		CardNumberColumn:      int4FromPtr(req.CardNumberColumn),
+		DescriptionColumn:     *req.DescriptionColumn,
+		CategoryColumn:        int4FromPtr(req.CategoryColumn),
+		AmountColumn:          int4FromPtr(req.AmountColumn),
+		DebitColumn:           int4FromPtr(req.DebitColumn),
+		CreditColumn:          int4FromPtr(req.CreditColumn),
+		DecimalSeparator:      req.DecimalSeparator,
+	})
+	if err != nil {
+		log.Printf("Error creating import profile: %v", err)
+		statusCode, message := handleDatabaseError(err)
+		c.JSON(statusCode, gin.H{"error": message})
+		return
+	}
+
+	c.JSON(http.StatusCreated, convertImportProfile(dbProfile))
+}
+
+// @Summary Update import profile
+// @Description Update an existing CSV import profile. The Default profile, which uploads fall back to, cannot be renamed.
+// @Tags import-profiles
+// @Accept json
+// @Produce json
+// @Param id path string true "Import profile ID"
+// @Param profile body ImportProfile true "Import profile data"
+// @Success 200 {object} ImportProfile "Updated import profile"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Import profile not found"
+// @Failure 409 {object} map[string]interface{} "Import profile already exists"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/import-profiles/{id} [put]
+func updateImportProfile(c *gin.Context) {
+	parsedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import profile ID"})
+		return
+	}
+
+	var req ImportProfile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	applyImportProfileDefaults(&req)
+	if err := validateImportProfile(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	profileID := pgtype.UUID{Bytes: parsedID, Valid: true}
+	if req.Name != defaultImportProfileName {
+		isDefault, err := isDefaultImportProfile(ctx, queries, profileID)
+		if err != nil {
+			log.Printf("Error fetching import profile: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating import profile"})
+			return
+		}
+		if isDefault {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The Default import profile cannot be renamed"})
+			return
+		}
+	}
+
+	dbProfile, err := queries.UpdateImportProfile(ctx, generated.UpdateImportProfileParams{
+		ID:                    profileID,
+		Name:                  req.Name,
+		Delimiter:             req.Delimiter,
+		HasHeader:             req.HasHeader,
+		HeaderColumns:         req.HeaderColumns,
+		DateFormat:            req.DateFormat,
+		AmountFormat:          req.AmountFormat,
+		ExpenseSign:           req.ExpenseSign,
+		TransactionDateColumn: int4FromPtr(req.TransactionDateColumn),
+		PostedDateColumn:      int4FromPtr(req.PostedDateColumn),
+		CardNumberColumn:      int4FromPtr(req.CardNumberColumn),
+		DescriptionColumn:     *req.DescriptionColumn,
+		CategoryColumn:        int4FromPtr(req.CategoryColumn),
+		AmountColumn:          int4FromPtr(req.AmountColumn),
+		DebitColumn:           int4FromPtr(req.DebitColumn),
+		CreditColumn:          int4FromPtr(req.CreditColumn),
+		DecimalSeparator:      req.DecimalSeparator,
+	})
+	if err != nil {
+		log.Printf("Error updating import profile: %v", err)
+		statusCode, message := handleDatabaseError(err)
+		c.JSON(statusCode, gin.H{"error": message})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertImportProfile(dbProfile))
+}
+
+// @Summary Delete import profile
+// @Description Delete a CSV import profile. The Default profile, which uploads fall back to, cannot be deleted.
+// @Tags import-profiles
+// @Param id path string true "Import profile ID"
+// @Success 204 "No content"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/import-profiles/{id} [delete]
+func deleteImportProfile(c *gin.Context) {
+	parsedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import profile ID"})
+		return
+	}
+
+	ctx := context.Background()
+	profileID := pgtype.UUID{Bytes: parsedID, Valid: true}
+	isDefault, err := isDefaultImportProfile(ctx, queries, profileID)
+	if err != nil {
+		log.Printf("Error fetching import profile: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting import profile"})
+		return
+	}
+	if isDefault {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The Default import profile cannot be deleted"})
+		return
+	}
+
+	if err := queries.DeleteImportProfile(ctx, profileID); err != nil {
+		log.Printf("Error deleting import profile: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting import profile"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// columnIndex returns a pointer to a column index for building test profiles
+func columnIndex(i int32) *int32 {
+	return &i
+}
+
+// signedTestProfile returns a semicolon-delimited profile with a single signed amount column
+func signedTestProfile(name string) ImportProfile {
+	return ImportProfile{
+		Name:                  name,
+		Delimiter:             ";",
+		HasHeader:             true,
+		HeaderColumns:         []string{"Date", "Payee", "Amount"},
+		DateFormat:            "02/01/2006",
+		AmountFormat:          amountFormatSigned,
+		ExpenseSign:           expenseSignNegative,
+		TransactionDateColumn: columnIndex(0),
+		DescriptionColumn:     columnIndex(1),
+		AmountColumn:          columnIndex(2),
+	}
+}
+
+// createCSVFileWithFields creates a multipart form with a CSV file and extra form fields
+func createCSVFileWithFields(t *testing.T, filename, content string, fields map[string]string) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			t.Fatalf("Failed to write form field: %v", err)
+		}
+	}
+
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+
+	if _, err := part.Write([]byte(content)); err != nil {
+		t.Fatalf("Failed to write to form file: %v", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	return &body, writer.FormDataContentType()
+}
+
+func TestValidateImportProfile(t *testing.T) {
+	t.Run("accepts a signed profile", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+		applyImportProfileDefaults(&profile)
+
+		assert.NoError(t, validateImportProfile(profile))
+	})
+
+	t.Run("requires debit and credit columns for debit_credit", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+		profile.AmountFormat = amountFormatDebitCredit
+
+		assert.Error(t, validateImportProfile(profile))
+	})
+
+	t.Run("rejects multi-character delimiters", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+		profile.Delimiter = ";;"
+
+		assert.Error(t, validateImportProfile(profile))
+	})
+
+	t.Run("rejects date formats without a day", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+		profile.DateFormat = "2006-01"
+
+		assert.Error(t, validateImportProfile(profile))
+	})
+
+	t.Run("rejects unknown decimal separators", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+		applyImportProfileDefaults(&profile)
+		profile.DecimalSeparator = ";"
+
+		assert.Error(t, validateImportProfile(profile))
+	})
+
+	t.Run("rejects negative column indexes", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+		profile.AmountColumn = columnIndex(-1)
+
+		assert.Error(t, validateImportProfile(profile))
+	})
+}
+
+func TestImportProfileParseRecord(t *testing.T) {
+	t.Run("negative expense sign makes bank debits positive", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+
+		parsed, err := profile.parseRecord([]string{"31/01/2024", "Coffee", "-4.50"})
+		require.NoError(t, err)
+		assert.Equal(t, "Coffee", parsed.Description)
+		assert.Equal(t, 4.50, parsed.Amount)
+
+		assert.True(t, parsed.TransactionDate.Valid)
+		assert.Equal(t, "2024-01-31", parsed.TransactionDate.Time.Format("2006-01-02"))
+	})
+
+	t.Run("positive expense sign keeps amounts as-is", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+		profile.ExpenseSign = expenseSignPositive
+
+		parsed, err := profile.parseRecord([]string{"31/01/2024", "Refund", "-10"})
+		require.NoError(t, err)
+		assert.Equal(t, -10.0, parsed.Amount)
+	})
+
+	t.Run("rejects rows missing mapped columns", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+
+		_, err := profile.parseRecord([]string{"31/01/2024", "Coffee"})
+		assert.Error(t, err)
+	})
+
+	t.Run("reads decimal-comma amounts", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+		profile.DecimalSeparator = decimalSeparatorComma
+
+		parsed, err := profile.parseRecord([]string{"31/01/2024", "Rent", "-1.250,00 €"})
+		require.NoError(t, err)
+		assert.Equal(t, 1250.0, parsed.Amount)
+	})
+
+	t.Run("rejects dates that do not match the profile", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+
+		_, err := profile.parseRecord([]string{"01/31/2024", "Coffee", "-4.50"})
+		require.Error(t, err)
+		assert.Equal(t, rejectInvalidDate, rejectionReason(err))
+	})
+}
+
+func TestApplyFormatOverrides(t *testing.T) {
+	t.Run("replaces the profile's formats", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+		require.NoError(t, profile.applyFormatOverrides("MM/DD/YYYY", ","))
+		assert.Equal(t, "MM/DD/YYYY", profile.DateFormat)
+		assert.Equal(t, decimalSeparatorComma, profile.DecimalSeparator)
+	})
+
+	t.Run("keeps the profile's formats when none are given", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+		require.NoError(t, profile.applyFormatOverrides("", ""))
+		assert.Equal(t, "02/01/2006", profile.DateFormat)
+	})
+
+	t.Run("rejects invalid overrides", func(t *testing.T) {
+		profile := signedTestProfile("Bank")
+		assert.Error(t, profile.applyFormatOverrides("YYYY", ""))
+		assert.Error(t, profile.applyFormatOverrides("", "x"))
+	})
+}
+
+func TestDetectImportProfile(t *testing.T) {
+	profiles := []ImportProfile{signedTestProfile("Bank")}
+
+	t.Run("matches header ignoring case and whitespace", func(t *testing.T) {
+		detected := detectImportProfile(profiles, "\ufeffdate; payee ;AMOUNT")
+		require.NotNil(t, detected)
+		assert.Equal(t, "Bank", detected.Name)
+	})
+
+	t.Run("returns nil when no header matches", func(t *testing.T) {
+		assert.Nil(t, detectImportProfile(profiles, "Date;Amount"))
+	})
+}
+
+func TestIsHeaderRecord(t *testing.T) {
+	profile := signedTestProfile("Bank")
+	profile.HeaderColumns = []string{"Date", "Payee", "Amount", "Balance"}
+
+	t.Run("matches the mapped columns ignoring case and whitespace", func(t *testing.T) {
+		assert.True(t, profile.isHeaderRecord([]string{"\ufeffdate", " payee ", "AMOUNT", "Balance"}))
+	})
+
+	t.Run("ignores unmapped columns", func(t *testing.T) {
+		assert.True(t, profile.isHeaderRecord([]string{"Date", "Payee", "Amount", "Saldo", "Memo"}))
+	})
+
+	t.Run("rejects a data row that starts like the header", func(t *testing.T) {
+		assert.False(t, profile.isHeaderRecord([]string{"Date", "COFFEE SHOP", "-4,50", "100,00"}))
+	})
+
+	t.Run("rejects a record missing a mapped column", func(t *testing.T) {
+		assert.False(t, profile.isHeaderRecord([]string{"Date", "Payee"}))
+	})
+
+	t.Run("never matches without a header", func(t *testing.T) {
+		withoutHeader := profile
+		withoutHeader.HasHeader = false
+		assert.False(t, withoutHeader.isHeaderRecord([]string{"Date", "Payee", "Amount", "Balance"}))
+	})
+}
+
+func TestRespondUploadError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("reports profile lookup failures as server errors", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		respondUploadError(c, &importProfileLookupError{err: errors.New("connection refused")})
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.NotContains(t, recorder.Body.String(), "connection refused")
+	})
+
+	t.Run("reports other errors as bad requests", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		respondUploadError(c, errors.New("import profile not found"))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "import profile not found")
+	})
+}
+
+func TestImportProfileEndpoints(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	t.Run("should list the seeded default profile", func(t *testing.T) {
+		resp := makeRequest("GET", "/api/import-profiles", nil)
+
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var profiles []ImportProfile
+		assertNoError(t, parseJSONResponse(resp, &profiles))
+
+		if len(profiles) != 1 || profiles[0].Name != defaultImportProfileName {
+			t.Errorf("Expected only the default profile, got %+v", profiles)
+		}
+	})
+
+	t.Run("should create, update and delete a profile", func(t *testing.T) {
+		body, _ := json.Marshal(signedTestProfile("Test Bank"))
+		resp := makeRequest("POST", "/api/import-profiles", bytes.NewBuffer(body))
+
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		var created ImportProfile
+		assertNoError(t, parseJSONResponse(resp, &created))
+		if created.Delimiter != ";" || created.AmountFormat != amountFormatSigned {
+			t.Errorf("Unexpected created profile: %+v", created)
+		}
+
+		update := signedTestProfile("Test Bank Renamed")
+		update.ExpenseSign = expenseSignPositive
+		body, _ = json.Marshal(update)
+		resp = makeRequest("PUT", "/api/import-profiles/"+created.ID, bytes.NewBuffer(body))
+
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var updated ImportProfile
+		assertNoError(t, parseJSONResponse(resp, &updated))
+		if updated.Name != "Test Bank Renamed" || updated.ExpenseSign != expenseSignPositive {
+			t.Errorf("Unexpected updated profile: %+v", updated)
+		}
+
+		resp = makeRequest("DELETE", "/api/import-profiles/"+created.ID, nil)
+		assertStatusCode(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("should protect the default profile", func(t *testing.T) {
+		resp := makeRequest("GET", "/api/import-profiles", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var profiles []ImportProfile
+		assertNoError(t, parseJSONResponse(resp, &profiles))
+		if len(profiles) != 1 {
+			t.Fatalf("Expected only the default profile, got %+v", profiles)
+		}
+		defaultProfile := profiles[0]
+
+		resp = makeRequest("DELETE", "/api/import-profiles/"+defaultProfile.ID, nil)
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+
+		renamed := defaultProfile
+		renamed.Name = "My Bank"
+		body, _ := json.Marshal(renamed)
+		resp = makeRequest("PUT", "/api/import-profiles/"+defaultProfile.ID, bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+
+		// Its other settings can still change
+		edited := defaultProfile
+		edited.Delimiter = ";"
+		body, _ = json.Marshal(edited)
+		resp = makeRequest("PUT", "/api/import-profiles/"+defaultProfile.ID, bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		body, _ = json.Marshal(defaultProfile)
+		resp = makeRequest("PUT", "/api/import-profiles/"+defaultProfile.ID, bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("should reject duplicate profile names", func(t *testing.T) {
+		body, _ := json.Marshal(signedTestProfile(defaultImportProfileName))
+		resp := makeRequest("POST", "/api/import-profiles", bytes.NewBuffer(body))
+
+		assertStatusCode(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("should reject an invalid profile", func(t *testing.T) {
+		profile := signedTestProfile("Broken")
+		profile.AmountColumn = nil
+		body, _ := json.Marshal(profile)
+		resp := makeRequest("POST", "/api/import-profiles", bytes.NewBuffer(body))
+
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+}
+
+func TestUploadCSVWithImportProfile(t *testing.T) {
+	const signedCSV = "Date;Payee;Amount\n31/01/2024;Coffee;-4.50\n01/02/2024;Refund;12.00\n"
+
+	t.Run("should detect the profile from the header row", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		body, _ := json.Marshal(signedTestProfile("Test Bank"))
+		resp := makeRequest("POST", "/api/import-profiles", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		csvBody, contentType := createCSVFile(t, "bank.csv", signedCSV)
+		req, err := http.NewRequest("POST", "/api/upload-csv", csvBody)
+		assertNoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		resp = makeRequestWithCustomRequest(req)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result struct {
+			Transactions  []Transaction `json:"transactions"`
+			ImportProfile string        `json:"import_profile"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &result))
+
+		if result.ImportProfile != "Test Bank" {
+			t.Errorf("Expected profile 'Test Bank', got %q", result.ImportProfile)
+		}
+		if len(result.Transactions) != 2 {
+			t.Fatalf("Expected 2 transactions, got %d", len(result.Transactions))
+		}
+		if result.Transactions[0].Amount != 4.50 || result.Transactions[1].Amount != -12.00 {
+			t.Errorf("Unexpected amounts: %v, %v", result.Transactions[0].Amount, result.Transactions[1].Amount)
+		}
+	})
+
+	t.Run("should use the requested profile", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		profile := signedTestProfile("Test Bank")
+		profile.HasHeader = false
+		profile.HeaderColumns = nil
+		body, _ := json.Marshal(profile)
+		resp := makeRequest("POST", "/api/import-profiles", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		var created ImportProfile
+		assertNoError(t, parseJSONResponse(resp, &created))
+
+		csvBody, contentType := createCSVFileWithFields(t, "bank.csv", "31/01/2024;Coffee;-4.50\n",
+			map[string]string{"profile_id": created.ID})
+		req, err := http.NewRequest("POST", "/api/upload-csv", csvBody)
+		assertNoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		resp = makeRequestWithCustomRequest(req)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result struct {
+			Transactions []Transaction `json:"transactions"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &result))
+
+		if len(result.Transactions) != 1 {
+			t.Errorf("Expected 1 transaction, got %d", len(result.Transactions))
+		}
+	})
+
+	t.Run("should apply date and number formats given with the upload", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		profile := signedTestProfile("Euro Bank")
+		profile.HeaderColumns = []string{"Datum", "Beschreibung", "Betrag"}
+		body, _ := json.Marshal(profile)
+		resp := makeRequest("POST", "/api/import-profiles", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		euroCSV := "Datum;Beschreibung;Betrag\n31.01.2024;Miete;-1.250,00 €\n"
+		csvBody, contentType := createCSVFileWithFields(t, "euro.csv", euroCSV,
+			map[string]string{"date_format": "DD.MM.YYYY", "decimal_separator": ","})
+		req, err := http.NewRequest("POST", "/api/upload-csv", csvBody)
+		assertNoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		resp = makeRequestWithCustomRequest(req)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result struct {
+			Transactions []Transaction `json:"transactions"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &result))
+
+		if len(result.Transactions) != 1 {
+			t.Fatalf("Expected 1 transaction, got %d", len(result.Transactions))
+		}
+		if result.Transactions[0].Amount != 1250.00 {
+			t.Errorf("Expected amount 1250.00, got %v", result.Transactions[0].Amount)
+		}
+		if date := result.Transactions[0].TransactionDate; date == nil || *date != "2024-01-31" {
+			t.Errorf("Expected transaction date 2024-01-31, got %v", date)
+		}
+	})
+
+	t.Run("should reject an invalid decimal separator", func(t *testing.T) {
+		csvBody, contentType := createCSVFileWithFields(t, "bank.csv", signedCSV,
+			map[string]string{"decimal_separator": ";"})
+		req, err := http.NewRequest("POST", "/api/upload-csv", csvBody)
+		assertNoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		resp := makeRequestWithCustomRequest(req)
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("should reject an unknown profile", func(t *testing.T) {
+		csvBody, contentType := createCSVFileWithFields(t, "bank.csv", signedCSV,
+			map[string]string{"profile_id": "00000000-0000-0000-0000-000000000000"})
+		req, err := http.NewRequest("POST", "/api/upload-csv", csvBody)
+		assertNoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		resp := makeRequestWithCustomRequest(req)
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+}
@@ -0,0 +1,1089 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"jointanalysis/db/generated"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	importFormatCSV = "csv"
+	importFormatOFX = "ofx"
+)
+
+// importBatchSize is how many rows are planned and bulk-loaded at a time, so an
+// upload's rows are never all held in memory
+const importBatchSize = 1000
+
+// errUnreadableCSV is returned while reading rows from a file that is not valid CSV
+var errUnreadableCSV = errors.New("Error reading CSV file")
+
+// Rejection reason codes reported for rows that are not imported
+const (
+	rejectTooFewColumns     = "too_few_columns"
+	rejectMissingAmount     = "missing_amount"
+	rejectInvalidAmount     = "invalid_amount"
+	rejectInvalidDate       = "invalid_date"
+	rejectMissingExternalID = "missing_external_id"
+	rejectNoCategory        = "no_category"
+	rejectDuplicate         = "duplicate"
+	rejectInsertFailed      = "insert_failed"
+)
+
+// importRowError is a row-level import failure carrying a rejection reason code
+type importRowError struct {
+	Reason  string
+	Message string
+}
+
+func (e *importRowError) Error() string {
+	return e.Message
+}
+
+// newImportRowError creates an importRowError with a formatted message
+func newImportRowError(reason, format string, args ...interface{}) error {
+	return &importRowError{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// rejectionReason returns the reason code of a row error, defaulting to insert_failed
+// for unexpected errors
+func rejectionReason(err error) string {
+	var rowErr *importRowError
+	if errors.As(err, &rowErr) {
+		return rowErr.Reason
+	}
+	return rejectInsertFailed
+}
+
+// importRow is one row read from an uploaded statement file. Raw holds the row's
+// original text. Err is set when the row could not be parsed into a transaction.
+type importRow struct {
+	Line   int
+	Raw    string
+	Record importRecord
+	Err    error
+}
+
+// parsedUpload is an uploaded statement file, hashed and ready to be read row by
+// row. It keeps the file open until Close.
+type parsedUpload struct {
+	FileName   string
+	FileHash   string
+	Format     string
+	Profile    *ImportProfile
+	UploadedBy pgtype.UUID
+	Rows       rowSource
+	// Forced uploads are imported even if the same file was, and record no hash
+	Forced bool
+	file   io.Closer
+}
+
+// Close releases the uploaded file
+func (u parsedUpload) Close() error {
+	if u.file == nil {
+		return nil
+	}
+	return u.file.Close()
+}
+
+// rowSource yields the rows of an upload in file order, and io.EOF after the last
+type rowSource interface {
+	next() (importRow, error)
+}
+
+// readRows reads the next n rows from source, fewer only at the end of the file
+func readRows(source rowSource, n int) ([]importRow, error) {
+	var rows []importRow
+	for len(rows) < n {
+		row, err := source.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// sliceRows is a rowSource over rows already in memory, such as an OFX statement's
+type sliceRows []importRow
+
+func (r *sliceRows) next() (importRow, error) {
+	if len(*r) == 0 {
+		return importRow{}, io.EOF
+	}
+	row := (*r)[0]
+	*r = (*r)[1:]
+	return row, nil
+}
+
+// plannedImport is the import pipeline's decision for a single row: the category
+// and rule it maps to, whether it duplicates an existing transaction, and the
+// params, splits and assignees it would be inserted with. Confidence is set when
+// no rule matched and the category classifier chose the category.
+type plannedImport struct {
+	Row        importRow
+	Params     generated.CreateTransactionsParams
+	Splits     []plannedSplit
+	AssignedTo []string
+	Category   *generated.GetCategoriesRow
+	Rule       *generated.GetRulesForMatchingRow
+	Confidence *float64
+	Duplicate  bool
+	Err        error
+}
+
+// plannedSplit is a split an imported transaction would be created with. Amounts
+// are in cents so template shares always add up to the transaction amount.
+type plannedSplit struct {
+	CategoryID   pgtype.UUID
+	CategoryName string
+	Cents        int64
+	// Notes and AssignedTo of an existing split, kept when rules re-create it
+	Notes      pgtype.Text
+	AssignedTo []pgtype.UUID
+}
+
+// numeric converts the split amount to a pgtype.Numeric
+func (s plannedSplit) numeric() (pgtype.Numeric, error) {
+	var amount pgtype.Numeric
+	err := amount.Scan(fmt.Sprintf("%d.%02d", s.Cents/100, s.Cents%100))
+	return amount, err
+}
+
+// importResult is what an import saved: the batch, its transactions and the rows
+// that were skipped
+type importResult struct {
+	ImportID     pgtype.UUID
+	Transactions []Transaction
+	Rejections   []ImportRejection
+}
+
+// rawRecorder keeps the bytes a CSV reader consumes so each record's original text
+// can be recovered from its input offsets. Bytes before the last recovered record
+// are released, so only the reader's look-ahead is held in memory.
+type rawRecorder struct {
+	r      io.Reader
+	buf    []byte
+	offset int64 // input offset of buf[0]
+}
+
+func (rr *rawRecorder) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.buf = append(rr.buf, p[:n]...)
+	return n, err
+}
+
+// text returns the input between two offsets and releases everything before end
+func (rr *rawRecorder) text(start, end int64) string {
+	text := string(rr.buf[start-rr.offset : end-rr.offset])
+	rr.buf = rr.buf[end-rr.offset:]
+	rr.offset = end
+	return text
+}
+
+// importable reports whether the row would be inserted
+func (p plannedImport) importable() bool {
+	return p.Err == nil && !p.Duplicate
+}
+
+// rejection describes why the row is not imported
+func (p plannedImport) rejection() ImportRejection {
+	rejection := ImportRejection{Line: p.Row.Line, Raw: p.Row.Raw}
+	if p.Err != nil {
+		rejection.Reason = rejectionReason(p.Err)
+		rejection.Message = p.Err.Error()
+	} else if p.Duplicate {
+		rejection.Reason = rejectDuplicate
+		rejection.Message = "transaction already exists"
+	}
+	return rejection
+}
+
+// Import pipeline functions
+
+// readUpload opens the uploaded file from the request and prepares its rows to be
+// read, as OFX when it looks like an OFX/QFX statement and otherwise as CSV using
+// the requested or detected import profile. The file is hashed first, so an
+// identical earlier import is found before anything is parsed. The caller must
+// Close the upload.
+func readUpload(c *gin.Context) (parsedUpload, error) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		return parsedUpload{}, errors.New("No file uploaded")
+	}
+
+	upload := parsedUpload{FileName: header.Filename, file: file}
+	ok := false
+	defer func() {
+		if !ok {
+			file.Close()
+		}
+	}()
+
+	if uploadedBy := c.PostForm("uploaded_by"); uploadedBy != "" {
+		personID, err := uuid.Parse(uploadedBy)
+		if err != nil {
+			return parsedUpload{}, errors.New("Invalid uploaded_by person ID")
+		}
+		upload.UploadedBy = pgtype.UUID{Bytes: personID, Valid: true}
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return parsedUpload{}, errUnreadableCSV
+	}
+	upload.FileHash = hex.EncodeToString(hash.Sum(nil))
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return parsedUpload{}, errUnreadableCSV
+	}
+	reader := bufio.NewReader(file)
+
+	// The start of the file identifies the format and, for CSV, the header row
+	head, err := reader.Peek(reader.Size())
+	if err != nil && err != io.EOF {
+		return parsedUpload{}, errUnreadableCSV
+	}
+
+	if isOFXFile(upload.FileName, head) {
+		// OFX elements are not line-oriented, so statements are parsed whole
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return parsedUpload{}, errors.New("Error reading OFX file")
+		}
+		rows, err := parseOFX(data)
+		if err != nil {
+			return parsedUpload{}, errors.New("Error reading OFX file")
+		}
+		upload.Format = importFormatOFX
+		upload.Rows = (*sliceRows)(&rows)
+	} else {
+		// Use the requested import profile, or detect one from the header row
+		firstLine, _, _ := strings.Cut(string(head), "\n")
+		profile, err := resolveImportProfile(c.PostForm("profile_id"), strings.TrimSuffix(firstLine, "\r"))
+		if err != nil {
+			return parsedUpload{}, err
+		}
+		if err := profile.applyFormatOverrides(c.PostForm("date_format"), c.PostForm("decimal_separator")); err != nil {
+			return parsedUpload{}, err
+		}
+
+		upload.Format = importFormatCSV
+		upload.Profile = &profile
+		upload.Rows = newCSVRowReader(profile, reader)
+	}
+
+	ok = true
+	return upload, nil
+}
+
+// respondUploadError reports an error from readUpload: database errors met while
+// loading the import profile as server errors, and anything else as a bad request
+func respondUploadError(c *gin.Context, err error) {
+	var lookupErr *importProfileLookupError
+	if errors.As(err, &lookupErr) {
+		log.Printf("Error loading import profile: %v", lookupErr.err)
+		statusCode, message := handleDatabaseError(lookupErr.err)
+		c.JSON(statusCode, gin.H{"error": message})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// csvRowReader reads CSV records one at a time with the profile's column mapping,
+// skipping the header row if present. Rows that are too short or have no parseable
+// amount are returned with Err set.
+type csvRowReader struct {
+	profile ImportProfile
+	source  *rawRecorder
+	reader  *csv.Reader
+	first   bool
+}
+
+func newCSVRowReader(profile ImportProfile, r io.Reader) *csvRowReader {
+	source := &rawRecorder{r: r}
+	return &csvRowReader{profile: profile, source: source, reader: profile.newCSVReader(source), first: true}
+}
+
+func (cr *csvRowReader) next() (importRow, error) {
+	for {
+		start := cr.reader.InputOffset()
+		record, err := cr.reader.Read()
+		if err == io.EOF {
+			return importRow{}, io.EOF
+		}
+		if err != nil {
+			return importRow{}, fmt.Errorf("%w: %v", errUnreadableCSV, err)
+		}
+		line, _ := cr.reader.FieldPos(0)
+		raw := strings.TrimRight(cr.source.text(start, cr.reader.InputOffset()), "\r\n")
+
+		// Skip header row if present
+		if cr.first {
+			cr.first = false
+			if cr.profile.isHeaderRecord(record) {
+				continue
+			}
+		}
+
+		parsed, err := cr.profile.parseRecord(record)
+		return importRow{Line: line, Raw: raw, Record: parsed, Err: err}, nil
+	}
+}
+
+// importPlanner categorizes and deduplicates an upload's rows without writing
+// anything, one batch at a time. Rows are categorized with the cached rule matcher,
+// and the duplicates in each batch are found with a single query: rows with a
+// bank-provided external ID (OFX FITID) match on that ID against all transactions,
+// archived included, and repeats of an ID within the file are duplicates. Other
+// rows match on their identifying fields against active transactions and are
+// duplicates only while the database already holds as many copies as have
+// appeared in the file so far, so identical rows within one CSV are all imported
+// on first upload but not re-imported. The planner counts the rows it has seen
+// by those fields, so a repeat is found in a later batch as in the same one.
+type importPlanner struct {
+	q               *generated.Queries
+	fileName        string
+	importID        pgtype.UUID
+	categoryMapping *CategoryMapping
+	matcher         *ruleMatcher
+	splitsByRule    map[pgtype.UUID][]generated.GetRuleSplitsRow
+	classifier      *categoryClassifier
+	personNames     map[pgtype.UUID]string
+	seen            map[importRowKey]int32
+}
+
+// importRowKey identifies rows that duplicate each other: the external ID and card
+// number when the bank provides an ID, else the identifying fields
+type importRowKey struct {
+	ExternalID      string
+	CardNumber      string
+	Description     string
+	Amount          string
+	TransactionDate string
+	PostedDate      string
+}
+
+// newImportPlanner loads what planning needs once per upload. ImportID is the batch
+// the rows are saved to, whose saved rows are not duplicates of its later ones; it
+// is invalid for a preview.
+func newImportPlanner(ctx context.Context, q *generated.Queries, fileName string, importID pgtype.UUID) (*importPlanner, error) {
+	categoryMapping := categoryMappings.get()
+	matcher, err := ruleMatchers.get(ctx, categoryMapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load categorization rules: %w", err)
+	}
+	ruleSplits, err := q.GetRuleSplits(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rule split templates: %w", err)
+	}
+	classifier, err := categoryClassifiers.get(ctx, categoryMapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to train category classifier: %w", err)
+	}
+	people, err := q.GetPeople(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load people: %w", err)
+	}
+	personNames := make(map[pgtype.UUID]string, len(people))
+	for _, person := range people {
+		personNames[person.ID] = person.Name
+	}
+
+	return &importPlanner{
+		q:               q,
+		fileName:        fileName,
+		importID:        importID,
+		categoryMapping: categoryMapping,
+		matcher:         matcher,
+		splitsByRule:    groupRuleSplits(ruleSplits),
+		classifier:      classifier,
+		personNames:     personNames,
+		seen:            make(map[importRowKey]int32),
+	}, nil
+}
+
+// plan decides what happens to the next batch of rows
+func (p *importPlanner) plan(ctx context.Context, rows []importRow) ([]plannedImport, error) {
+	plans := make([]plannedImport, 0, len(rows))
+	var candidates generated.FindDuplicateImportRowsParams
+
+	for _, row := range rows {
+		plan := plannedImport{Row: row, Err: row.Err}
+		if plan.Err != nil {
+			plans = append(plans, plan)
+			continue
+		}
+		record := row.Record
+
+		// Convert float64 to pgtype.Numeric
+		amountBig := big.NewFloat(record.Amount)
+		amountStr := amountBig.Text('f', 2) // Format to 2 decimal places
+		var amountNumeric pgtype.Numeric
+		if err := amountNumeric.Scan(amountStr); err != nil {
+			plan.Err = newImportRowError(rejectInvalidAmount, "error converting amount to numeric: %v", err)
+			plans = append(plans, plan)
+			continue
+		}
+
+		// Splits hold the absolute amount and must be positive
+		cents := int64(math.Round(math.Abs(record.Amount) * 100))
+		if cents == 0 {
+			plan.Err = newImportRowError(rejectInvalidAmount, "amount is zero")
+			plans = append(plans, plan)
+			continue
+		}
+
+		plan.Params = generated.CreateTransactionsParams{
+			Description:     record.Description,
+			Amount:          amountNumeric,
+			FileName:        pgtype.Text{String: p.fileName, Valid: true},
+			TransactionDate: record.TransactionDate,
+			PostedDate:      record.PostedDate,
+		}
+		if record.CardNumber != "" {
+			plan.Params.CardNumber = pgtype.Text{String: record.CardNumber, Valid: true}
+		}
+		if record.ExternalID != "" {
+			plan.Params.ExternalID = pgtype.Text{String: record.ExternalID, Valid: true}
+		}
+		if record.CsvCategory != "" {
+			plan.Params.CsvCategory = pgtype.Text{String: record.CsvCategory, Valid: true}
+		}
+
+		// Map category if category mapping is available
+		if p.categoryMapping != nil {
+			plan.Category, plan.Rule = p.matcher.match(record)
+			if plan.Rule == nil {
+				if predicted, confidence := p.categoryMapping.classifyRecord(p.classifier, record); predicted != nil {
+					plan.Category = predicted
+					plan.Confidence = &confidence
+				}
+			}
+			if plan.Category == nil {
+				if fallback, exists := p.categoryMapping.categoriesByName["Other"]; exists {
+					plan.Category = &fallback
+				}
+			}
+		}
+
+		if plan.Category == nil || !plan.Category.ID.Valid {
+			plan.Err = newImportRowError(rejectNoCategory, "no category available")
+			plans = append(plans, plan)
+			continue
+		}
+
+		plan.Splits = planSplits(plan.Category, plan.Rule, cents, p.splitsByRule)
+		if plan.Rule != nil {
+			plan.Params.RuleID = plan.Rule.ID
+			for _, personID := range plan.Rule.AssignTo {
+				if name, exists := p.personNames[personID]; exists {
+					plan.Params.AssignedTo = append(plan.Params.AssignedTo, personID)
+					plan.AssignedTo = append(plan.AssignedTo, name)
+				}
+			}
+		}
+
+		key := importRowKey{ExternalID: record.ExternalID, CardNumber: record.CardNumber}
+		if record.ExternalID == "" {
+			key.Description = record.Description
+			key.Amount = amountStr
+			key.TransactionDate = dateKey(record.TransactionDate)
+			key.PostedDate = dateKey(record.PostedDate)
+		}
+		p.seen[key]++
+
+		candidates.Positions = append(candidates.Positions, int32(len(plans)))
+		candidates.Descriptions = append(candidates.Descriptions, plan.Params.Description)
+		candidates.Amounts = append(candidates.Amounts, plan.Params.Amount)
+		candidates.TransactionDates = append(candidates.TransactionDates, plan.Params.TransactionDate)
+		candidates.PostedDates = append(candidates.PostedDates, plan.Params.PostedDate)
+		candidates.CardNumbers = append(candidates.CardNumbers, plan.Params.CardNumber.String)
+		candidates.ExternalIDs = append(candidates.ExternalIDs, plan.Params.ExternalID.String)
+		candidates.Seen = append(candidates.Seen, p.seen[key])
+
+		plans = append(plans, plan)
+	}
+
+	if len(candidates.Positions) == 0 {
+		return plans, nil
+	}
+
+	candidates.ImportID = p.importID
+	duplicates, err := p.q.FindDuplicateImportRows(ctx, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for duplicate transactions: %w", err)
+	}
+	for _, position := range duplicates {
+		plans[position].Duplicate = true
+	}
+
+	return plans, nil
+}
+
+// dateKey formats a date for an importRowKey
+func dateKey(d pgtype.Date) string {
+	if !d.Valid {
+		return ""
+	}
+	return d.Time.Format("2006-01-02")
+}
+
+// importTransactions saves an upload as a new import batch in a single database
+// transaction. Rows are read, planned and bulk-loaded with COPY importBatchSize at
+// a time, so only one batch of rows is held in memory, creating a single split per
+// transaction. If any step fails, nothing is saved.
+func importTransactions(ctx context.Context, upload parsedUpload) (importResult, error) {
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return importResult{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	batch, err := qtx.CreateImport(ctx, generated.CreateImportParams{
+		FileName:   pgtype.Text{String: upload.FileName, Valid: upload.FileName != ""},
+		Format:     upload.Format,
+		FileHash:   pgtype.Text{String: upload.FileHash, Valid: upload.FileHash != "" && !upload.Forced},
+		UploadedBy: upload.UploadedBy,
+	})
+	if err != nil {
+		return importResult{}, fmt.Errorf("failed to create import: %w", err)
+	}
+
+	planner, err := newImportPlanner(ctx, qtx, upload.FileName, batch.ID)
+	if err != nil {
+		return importResult{}, err
+	}
+
+	result := importResult{
+		ImportID:     batch.ID,
+		Transactions: make([]Transaction, 0),
+		Rejections:   make([]ImportRejection, 0),
+	}
+	ruleHits := make(map[pgtype.UUID]int32)
+	totalRows := 0
+
+	for {
+		rows, err := readRows(upload.Rows, importBatchSize)
+		if err != nil {
+			return importResult{}, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		totalRows += len(rows)
+
+		plans, err := planner.plan(ctx, rows)
+		if err != nil {
+			return importResult{}, err
+		}
+		if err := saveImportBatch(ctx, qtx, batch.ID, upload.FileName, plans, &result, ruleHits); err != nil {
+			return importResult{}, err
+		}
+	}
+
+	if err := recordRuleHits(ctx, qtx, ruleHits); err != nil {
+		return importResult{}, fmt.Errorf("failed to record rule hits: %w", err)
+	}
+
+	err = qtx.UpdateImportCounts(ctx, generated.UpdateImportCountsParams{
+		ID:           batch.ID,
+		TotalRows:    int32(totalRows),
+		ImportedRows: int32(len(result.Transactions)),
+		SkippedRows:  int32(len(result.Rejections)),
+	})
+	if err != nil {
+		return importResult{}, fmt.Errorf("failed to update import counts: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return importResult{}, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	return result, nil
+}
+
+// saveImportBatch bulk-loads one batch of planned rows into an import: the
+// transactions, their splits and the rejected rows. It adds them to result and
+// counts the rules that matched in ruleHits.
+func saveImportBatch(ctx context.Context, q *generated.Queries, importID pgtype.UUID, fileName string, plans []plannedImport, result *importResult, ruleHits map[pgtype.UUID]int32) error {
+	transactionRows := make([]generated.CreateTransactionsParams, 0, len(plans))
+	splitRows := make([]generated.CreateTransactionSplitsParams, 0, len(plans))
+	rejectionRows := make([]generated.CreateImportRejectionsParams, 0)
+
+	for _, plan := range plans {
+		if !plan.importable() {
+			rejection := plan.rejection()
+			result.Rejections = append(result.Rejections, rejection)
+			rejectionRows = append(rejectionRows, generated.CreateImportRejectionsParams{
+				ImportID:   importID,
+				LineNumber: int32(rejection.Line),
+				RawContent: rejection.Raw,
+				Reason:     rejection.Reason,
+				Message:    rejection.Message,
+			})
+			continue
+		}
+
+		// IDs are generated here so splits can reference their transaction in the same COPY batch
+		transactionID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+		plan.Params.ID = transactionID
+		plan.Params.ImportID = importID
+		transactionRows = append(transactionRows, plan.Params)
+		if plan.Rule != nil {
+			ruleHits[plan.Rule.ID]++
+		}
+		for _, split := range plan.Splits {
+			amount, err := split.numeric()
+			if err != nil {
+				return fmt.Errorf("failed to convert split amount: %w", err)
+			}
+			splitRows = append(splitRows, generated.CreateTransactionSplitsParams{
+				TransactionID: transactionID,
+				Amount:        amount,
+				CategoryID:    split.CategoryID,
+			})
+		}
+
+		transaction := importedTransaction(plan.Row.Record, fileName)
+		transaction.ID = uuid.UUID(transactionID.Bytes).String()
+		if len(plan.AssignedTo) > 0 {
+			transaction.AssignedTo = plan.AssignedTo
+		}
+		result.Transactions = append(result.Transactions, transaction)
+	}
+
+	if _, err := q.CreateTransactions(ctx, transactionRows); err != nil {
+		return fmt.Errorf("failed to insert transactions: %w", err)
+	}
+	if _, err := q.CreateTransactionSplits(ctx, splitRows); err != nil {
+		return fmt.Errorf("failed to insert transaction splits: %w", err)
+	}
+	if _, err := q.CreateImportRejections(ctx, rejectionRows); err != nil {
+		return fmt.Errorf("failed to insert import rejections: %w", err)
+	}
+	return nil
+}
+
+// findImportByFileHash returns the import of a file with the same content, or an
+// invalid UUID when the file has not been imported before. Forced re-imports record
+// no hash, so at most one import has it.
+func findImportByFileHash(fileHash string) (pgtype.UUID, error) {
+	importID, err := queries.FindImportByFileHash(context.Background(), pgtype.Text{String: fileHash, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pgtype.UUID{Valid: false}, nil
+	}
+	return importID, err
+}
+
+// convertImport converts a generated.GetImportsRow to our Import struct
+func convertImport(i generated.GetImportsRow) Import {
+	result := Import{
+		ID:           uuid.UUID(i.ID.Bytes).String(),
+		Format:       i.Format,
+		TotalRows:    int(i.TotalRows),
+		ImportedRows: int(i.ImportedRows),
+		SkippedRows:  int(i.SkippedRows),
+		CreatedAt:    i.CreatedAt.Time,
+	}
+	if i.FileName.Valid {
+		result.FileName = &i.FileName.String
+	}
+	if i.FileHash.Valid {
+		result.FileHash = &i.FileHash.String
+	}
+	if i.UploadedBy.Valid {
+		uploadedBy := uuid.UUID(i.UploadedBy.Bytes).String()
+		result.UploadedBy = &uploadedBy
+	}
+	if i.UploadedByName.Valid {
+		result.UploadedByName = &i.UploadedByName.String
+	}
+	return result
+}
+
+// allocateSplitTemplate divides an amount in cents between a rule's split template
+// lines by percentage. Each line gets its share rounded down and the cents left
+// over go one at a time to the first lines, so the splits always add up to the
+// amount. Percentages are weighed against their sum, so a template left short of
+// 100% by a deleted category still allocates the whole amount. Lines whose share
+// is zero are dropped since splits must be positive.
+func allocateSplitTemplate(cents int64, template []generated.GetRuleSplitsRow) []plannedSplit {
+	basisPoints := make([]int64, len(template))
+	var totalBasisPoints int64
+	for i, line := range template {
+		percentage, _ := line.Percentage.Float64Value()
+		basisPoints[i] = int64(math.Round(percentage.Float64 * 100))
+		totalBasisPoints += basisPoints[i]
+	}
+	if totalBasisPoints == 0 {
+		return nil
+	}
+
+	shares := make([]int64, len(template))
+	remaining := cents
+	for i := range template {
+		shares[i] = cents * basisPoints[i] / totalBasisPoints
+		remaining -= shares[i]
+	}
+	for i := 0; remaining > 0; i = (i + 1) % len(shares) {
+		shares[i]++
+		remaining--
+	}
+
+	splits := make([]plannedSplit, 0, len(template))
+	for i, line := range template {
+		if shares[i] == 0 {
+			continue
+		}
+		splits = append(splits, plannedSplit{
+			CategoryID:   line.CategoryID,
+			CategoryName: line.CategoryName,
+			Cents:        shares[i],
+		})
+	}
+	return splits
+}
+
+// planSplits returns the splits a transaction categorized by a rule (or by the CSV
+// category when rule is nil) is created with: the rule's split template when it has
+// one, otherwise a single split of the whole amount to the category.
+func planSplits(category *generated.GetCategoriesRow, rule *generated.GetRulesForMatchingRow, cents int64, splitsByRule map[pgtype.UUID][]generated.GetRuleSplitsRow) []plannedSplit {
+	if rule != nil {
+		if splits := allocateSplitTemplate(cents, splitsByRule[rule.ID]); len(splits) > 0 {
+			return splits
+		}
+	}
+	return []plannedSplit{{CategoryID: category.ID, CategoryName: category.Name, Cents: cents}}
+}
+
+// importedTransaction builds the API representation of an imported row
+func importedTransaction(record importRecord, fileName string) Transaction {
+	transaction := Transaction{
+		Description: record.Description,
+		Amount:      record.Amount,
+		FileName:    &fileName,
+	}
+
+	// Add the additional fields if they exist
+	if record.TransactionDate.Valid {
+		transactionDate := record.TransactionDate.Time.Format("2006-01-02")
+		transaction.TransactionDate = &transactionDate
+	}
+	if record.PostedDate.Valid {
+		postedDate := record.PostedDate.Time.Format("2006-01-02")
+		transaction.PostedDate = &postedDate
+	}
+	if record.CardNumber != "" {
+		cardNumber := record.CardNumber
+		transaction.CardNumber = &cardNumber
+	}
+
+	return transaction
+}
+
+// convertPlannedImport converts a pipeline decision to an ImportPreviewRow
+func convertPlannedImport(plan plannedImport) ImportPreviewRow {
+	transaction := importedTransaction(plan.Row.Record, "")
+
+	row := ImportPreviewRow{
+		Line:            plan.Row.Line,
+		Description:     transaction.Description,
+		Amount:          transaction.Amount,
+		TransactionDate: transaction.TransactionDate,
+		PostedDate:      transaction.PostedDate,
+		CardNumber:      transaction.CardNumber,
+		Duplicate:       plan.Duplicate,
+		WouldImport:     plan.importable(),
+	}
+
+	if plan.Category != nil && plan.Category.ID.Valid {
+		categoryID := uuid.UUID(plan.Category.ID.Bytes).String()
+		categoryName := plan.Category.Name
+		row.CategoryID = &categoryID
+		row.CategoryName = &categoryName
+	}
+	if plan.Rule != nil {
+		ruleID := uuid.UUID(plan.Rule.ID.Bytes).String()
+		matchValue := plan.Rule.MatchValue
+		row.RuleID = &ruleID
+		row.RuleMatchValue = &matchValue
+	}
+	row.Confidence = plan.Confidence
+	row.AssignedTo = make([]string, 0, len(plan.AssignedTo))
+	row.AssignedTo = append(row.AssignedTo, plan.AssignedTo...)
+	row.Splits = make([]ImportPreviewSplit, 0, len(plan.Splits))
+	for _, split := range plan.Splits {
+		row.Splits = append(row.Splits, ImportPreviewSplit{
+			CategoryID:   uuid.UUID(split.CategoryID.Bytes).String(),
+			CategoryName: split.CategoryName,
+			Amount:       float64(split.Cents) / 100,
+		})
+	}
+	if !plan.importable() {
+		rejection := plan.rejection()
+		row.Reason = &rejection.Reason
+		row.Error = &rejection.Message
+	}
+
+	return row
+}
+
+// Import handler functions
+
+// @Summary Preview file import
+// @Description Run the upload pipeline on a CSV or OFX/QFX file without saving anything. Returns, for each row, the category and rule that would be applied, whether it is a duplicate, and whether it would be imported.
+// @Tags transactions
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or OFX/QFX file to preview"
+// @Param profile_id formData string false "Import profile ID for CSV files (detected from the header row when omitted)"
+// @Param date_format formData string false "Date format for CSV files, overriding the profile's (e.g. DD.MM.YYYY)"
+// @Param decimal_separator formData string false "Decimal separator for CSV amounts, overriding the profile's ('.' or ',')"
+// @Success 200 {object} map[string]interface{} "Preview - returns format, rows array, would_import and would_skip counts, the import_profile used for CSV files, and duplicate_import_id when the same file was already imported"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/upload-csv/preview [post]
+func previewImport(c *gin.Context) {
+	upload, err := readUpload(c)
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+	defer upload.Close()
+
+	ctx := context.Background()
+	planner, err := newImportPlanner(ctx, queries, upload.FileName, pgtype.UUID{Valid: false})
+	if err != nil {
+		log.Printf("Error planning import: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error previewing import"})
+		return
+	}
+
+	rows := make([]ImportPreviewRow, 0)
+	wouldImport := 0
+	for {
+		batch, err := readRows(upload.Rows, importBatchSize)
+		if errors.Is(err, errUnreadableCSV) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errUnreadableCSV.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("Error reading import rows: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error previewing import"})
+			return
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		plans, err := planner.plan(ctx, batch)
+		if err != nil {
+			log.Printf("Error planning import: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error previewing import"})
+			return
+		}
+		for _, plan := range plans {
+			row := convertPlannedImport(plan)
+			if row.WouldImport {
+				wouldImport++
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	response := gin.H{
+		"format":       upload.Format,
+		"rows":         rows,
+		"would_import": wouldImport,
+		"would_skip":   len(rows) - wouldImport,
+	}
+	if upload.Profile != nil {
+		response["import_profile"] = upload.Profile.Name
+	}
+
+	// Flag files that were already imported; the upload would be rejected
+	existingImportID, err := findImportByFileHash(upload.FileHash)
+	if err != nil {
+		log.Printf("Error checking for duplicate import: %v", err)
+	} else if existingImportID.Valid {
+		response["duplicate_import_id"] = uuid.UUID(existingImportID.Bytes).String()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Get all imports
+// @Description Retrieve all import batches, most recent first
+// @Tags imports
+// @Produce json
+// @Success 200 {array} Import "List of imports"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/imports [get]
+func getImports(c *gin.Context) {
+	dbImports, err := queries.GetImports(context.Background())
+	if err != nil {
+		log.Printf("Error fetching imports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching imports"})
+		return
+	}
+
+	imports := make([]Import, 0, len(dbImports))
+	for _, dbImport := range dbImports {
+		imports = append(imports, convertImport(dbImport))
+	}
+
+	c.JSON(http.StatusOK, imports)
+}
+
+// @Summary Get import
+// @Description Retrieve an import batch, including every rejected row with its line number, raw content and reason code
+// @Tags imports
+// @Produce json
+// @Param id path string true "Import ID"
+// @Success 200 {object} Import "Import with rejected rows"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Import not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/imports/{id} [get]
+func getImport(c *gin.Context) {
+	parsedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+	importID := pgtype.UUID{Bytes: parsedID, Valid: true}
+
+	dbImport, err := queries.GetImportByID(context.Background(), importID)
+	if err != nil {
+		statusCode, message := handleDatabaseError(err)
+		if statusCode == http.StatusNotFound {
+			message = "Import not found"
+		}
+		c.JSON(statusCode, gin.H{"error": message})
+		return
+	}
+
+	dbRejections, err := queries.GetImportRejectionsByImportID(context.Background(), importID)
+	if err != nil {
+		log.Printf("Error fetching import rejections: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching import"})
+		return
+	}
+
+	result := convertImport(generated.GetImportsRow(dbImport))
+	result.Rejections = make([]ImportRejection, 0, len(dbRejections))
+	for _, r := range dbRejections {
+		result.Rejections = append(result.Rejections, ImportRejection{
+			Line:    int(r.LineNumber),
+			Raw:     r.RawContent,
+			Reason:  r.Reason,
+			Message: r.Message,
+		})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Undo import
+// @Description Roll back an import batch by deleting every transaction and split it created. Imports with archived transactions cannot be undone.
+// @Tags imports
+// @Produce json
+// @Param id path string true "Import ID"
+// @Success 200 {object} map[string]interface{} "Import rolled back - returns deleted_transactions count"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Import not found"
+// @Failure 409 {object} map[string]interface{} "Import contains archived transactions"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/imports/{id} [delete]
+func deleteImport(c *gin.Context) {
+	parsedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+	importID := pgtype.UUID{Bytes: parsedID, Valid: true}
+
+	ctx := context.Background()
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error undoing import"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if _, err := qtx.GetImportByID(ctx, importID); err != nil {
+		statusCode, message := handleDatabaseError(err)
+		if statusCode == http.StatusNotFound {
+			message = "Import not found"
+		}
+		c.JSON(statusCode, gin.H{"error": message})
+		return
+	}
+
+	archivedCount, err := qtx.CountArchivedTransactionsByImportID(ctx, importID)
+	if err != nil {
+		log.Printf("Error checking archived transactions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error undoing import"})
+		return
+	}
+	if archivedCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Import contains archived transactions and cannot be undone"})
+		return
+	}
+
+	// The rules no longer categorize the transactions, so their hits go with them
+	if err := qtx.RemoveImportRuleHits(ctx, importID); err != nil {
+		log.Printf("Error removing import rule hits: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error undoing import"})
+		return
+	}
+
+	// Splits are removed by ON DELETE CASCADE
+	deletedCount, err := qtx.DeleteTransactionsByImportID(ctx, importID)
+	if err != nil {
+		log.Printf("Error deleting import transactions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error undoing import"})
+		return
+	}
+
+	if err := qtx.DeleteImport(ctx, importID); err != nil {
+		log.Printf("Error deleting import: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error undoing import"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing import undo: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error undoing import"})
+		return
+	}
+	categoryClassifiers.invalidate()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Import undone successfully",
+		"deleted_transactions": deletedCount,
+	})
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"jointanalysis/db/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// defaultTestProfile mirrors the seeded Default import profile
+func defaultTestProfile() ImportProfile {
+	return ImportProfile{
+		Name:                  defaultImportProfileName,
+		Delimiter:             ",",
+		HasHeader:             true,
+		HeaderColumns:         []string{"Transaction Date", "Posted Date", "Card No.", "Description", "Category", "Debit", "Credit"},
+		DateFormat:            "2006-01-02",
+		AmountFormat:          amountFormatDebitCredit,
+		ExpenseSign:           expenseSignPositive,
+		TransactionDateColumn: columnIndex(0),
+		PostedDateColumn:      columnIndex(1),
+		CardNumberColumn:      columnIndex(2),
+		DescriptionColumn:     columnIndex(3),
+		CategoryColumn:        columnIndex(4),
+		DebitColumn:           columnIndex(5),
+		CreditColumn:          columnIndex(6),
+	}
+}
+
+// readCSVRows reads every row of a CSV file with the profile
+func readCSVRows(profile ImportProfile, r io.Reader) ([]importRow, error) {
+	return readRows(newCSVRowReader(profile, r), math.MaxInt)
+}
+
+func TestReadCSVRows(t *testing.T) {
+	t.Run("numbers rows by file line and flags unparseable rows", func(t *testing.T) {
+		csvData := "Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit\n" +
+			"2024-01-01,2024-01-02,1234,Coffee,Dining,4.50,\n" +
+			"2024-01-03,2024-01-04,1234,\"Multi\nline\",Dining,,2.00\n" +
+			"2024-01-05,2024-01-06,1234,Broken,Dining,abc,\n"
+
+		rows, err := readCSVRows(defaultTestProfile(), strings.NewReader(csvData))
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+
+		assert.Equal(t, 2, rows[0].Line)
+		assert.NoError(t, rows[0].Err)
+		assert.Equal(t, 4.50, rows[0].Record.Amount)
+
+		assert.Equal(t, 3, rows[1].Line)
+		assert.NoError(t, rows[1].Err)
+		assert.Equal(t, -2.0, rows[1].Record.Amount)
+		assert.Equal(t, "2024-01-03,2024-01-04,1234,\"Multi\nline\",Dining,,2.00", rows[1].Raw)
+
+		assert.Equal(t, 5, rows[2].Line)
+		assert.Equal(t, "2024-01-05,2024-01-06,1234,Broken,Dining,abc,", rows[2].Raw)
+		require.Error(t, rows[2].Err)
+		assert.Equal(t, rejectInvalidAmount, rejectionReason(rows[2].Err))
+	})
+
+	t.Run("flags short rows and rows without an amount", func(t *testing.T) {
+		csvData := "2024-01-01,2024-01-02,1234,Coffee\n" +
+			"2024-01-01,2024-01-02,1234,Coffee,Dining,,\n"
+
+		rows, err := readCSVRows(defaultTestProfile(), strings.NewReader(csvData))
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+
+		assert.Equal(t, rejectTooFewColumns, rejectionReason(rows[0].Err))
+		assert.Equal(t, rejectMissingAmount, rejectionReason(rows[1].Err))
+	})
+
+	t.Run("keeps raw rows intact when the file arrives in small reads", func(t *testing.T) {
+		csvData := "2024-01-01,2024-01-02,1234,\"Multi\nline\",Dining,4.50,\r\n" +
+			"2024-01-03,2024-01-04,1234,Lunch,Dining,12.00,\r\n"
+
+		rows, err := readCSVRows(defaultTestProfile(), iotest.OneByteReader(strings.NewReader(csvData)))
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+
+		assert.Equal(t, "2024-01-01,2024-01-02,1234,\"Multi\nline\",Dining,4.50,", rows[0].Raw)
+		assert.Equal(t, 3, rows[1].Line)
+		assert.Equal(t, "2024-01-03,2024-01-04,1234,Lunch,Dining,12.00,", rows[1].Raw)
+	})
+}
+
+func TestReadRows(t *testing.T) {
+	t.Run("reads rows in batches until the end of the file", func(t *testing.T) {
+		rows := sliceRows{{Line: 1}, {Line: 2}, {Line: 3}}
+		source := &rows
+
+		batch, err := readRows(source, 2)
+		require.NoError(t, err)
+		require.Len(t, batch, 2)
+		assert.Equal(t, 2, batch[1].Line)
+
+		batch, err = readRows(source, 2)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		assert.Equal(t, 3, batch[0].Line)
+
+		batch, err = readRows(source, 2)
+		require.NoError(t, err)
+		assert.Empty(t, batch)
+	})
+
+	t.Run("reports files that are not valid CSV", func(t *testing.T) {
+		source := newCSVRowReader(defaultTestProfile(), strings.NewReader("2024-01-01,2024-01-02,1234,\"Coffee,Dining,4.50,\n"))
+
+		_, err := readRows(source, importBatchSize)
+		assert.ErrorIs(t, err, errUnreadableCSV)
+	})
+}
+
+// uploadTestFile posts a file to the upload endpoint with extra form fields
+func uploadTestFile(t *testing.T, fileName, content string, fields map[string]string) *httptest.ResponseRecorder {
+	body, contentType := createCSVFileWithFields(t, fileName, content, fields)
+	req, err := http.NewRequest("POST", "/api/upload-csv", body)
+	assertNoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	return makeRequestWithCustomRequest(req)
+}
+
+// importIDOf returns the import_id of an upload response
+func importIDOf(t *testing.T, resp *httptest.ResponseRecorder) string {
+	var uploaded map[string]interface{}
+	assertNoError(t, parseJSONResponse(resp, &uploaded))
+	importID, _ := uploaded["import_id"].(string)
+	return importID
+}
+
+func TestImportRejectionReport(t *testing.T) {
+	t.Run("should report and store rejected rows", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-01-01,2024-01-02,1234,Coffee,Dining,4.50,
+2024-01-01,2024-01-02,1234,Short
+2024-01-03,2024-01-04,1234,Lunch,Dining,abc,`
+
+		upload := func() map[string]interface{} {
+			resp := uploadTestFile(t, "report.csv", csvContent, map[string]string{"force": "true"})
+			assertStatusCode(t, http.StatusOK, resp.Code)
+
+			var result map[string]interface{}
+			assertNoError(t, parseJSONResponse(resp, &result))
+			return result
+		}
+
+		result := upload()
+		rejections, _ := result["rejections"].([]interface{})
+		if len(rejections) != 2 {
+			t.Fatalf("Expected 2 rejections, got %d", len(rejections))
+		}
+		first := rejections[0].(map[string]interface{})
+		if first["line"] != float64(3) || first["reason"] != rejectTooFewColumns || first["raw"] != "2024-01-01,2024-01-02,1234,Short" {
+			t.Errorf("Unexpected first rejection: %v", first)
+		}
+
+		// Re-uploading reports the imported row as a duplicate
+		result = upload()
+		importID, ok := result["import_id"].(string)
+		if !ok {
+			t.Fatal("Expected import_id in response")
+		}
+
+		resp := makeRequest("GET", "/api/imports/"+importID, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var report Import
+		assertNoError(t, parseJSONResponse(resp, &report))
+
+		if report.TotalRows != 3 || report.ImportedRows != 0 || report.SkippedRows != 3 || len(report.Rejections) != 3 {
+			t.Fatalf("Unexpected report: %+v", report)
+		}
+		reasons := []string{report.Rejections[0].Reason, report.Rejections[1].Reason, report.Rejections[2].Reason}
+		expected := []string{rejectDuplicate, rejectTooFewColumns, rejectInvalidAmount}
+		for i := range expected {
+			if reasons[i] != expected[i] {
+				t.Errorf("Expected reasons %v, got %v", expected, reasons)
+				break
+			}
+		}
+	})
+
+	t.Run("should return 404 for unknown imports", func(t *testing.T) {
+		resp := makeRequest("GET", "/api/imports/"+uuid.New().String(), nil)
+		assertStatusCode(t, http.StatusNotFound, resp.Code)
+	})
+}
+
+func TestImports(t *testing.T) {
+	csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-02-01,2024-02-02,1234,Coffee,Dining,4.50,
+2024-02-03,2024-02-04,1234,Lunch,Dining,12.00,`
+
+	t.Run("should record the batch and reject identical files", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		personID, err := createTestPerson("Uploader", "")
+		assertNoError(t, err)
+
+		resp := uploadTestFile(t, "batch.csv", csvContent, map[string]string{"uploaded_by": personID})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var uploaded map[string]interface{}
+		assertNoError(t, parseJSONResponse(resp, &uploaded))
+		importID, _ := uploaded["import_id"].(string)
+
+		resp = makeRequest("GET", "/api/imports", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var imports []Import
+		assertNoError(t, parseJSONResponse(resp, &imports))
+		require.Len(t, imports, 1)
+		assert.Equal(t, importID, imports[0].ID)
+		assert.Equal(t, 2, imports[0].TotalRows)
+		assert.Equal(t, 2, imports[0].ImportedRows)
+		require.NotNil(t, imports[0].FileHash)
+		assert.Len(t, *imports[0].FileHash, 64)
+		require.NotNil(t, imports[0].UploadedByName)
+		assert.Equal(t, "Uploader", *imports[0].UploadedByName)
+
+		// The same content under another name is still the same file
+		resp = uploadTestFile(t, "batch-copy.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusConflict, resp.Code)
+
+		var conflict map[string]interface{}
+		assertNoError(t, parseJSONResponse(resp, &conflict))
+		assert.Equal(t, importID, conflict["import_id"])
+	})
+
+	t.Run("should keep one import per file hash", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		resp := uploadTestFile(t, "batch.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var uploaded Import
+		assertNoError(t, parseJSONResponse(makeRequest("GET", "/api/imports/"+importIDOf(t, resp), nil), &uploaded))
+		require.NotNil(t, uploaded.FileHash)
+
+		// A concurrent upload of the same file passes the hash check before either
+		// import is saved, so only the unique index stops it
+		_, err := importTransactions(context.Background(), parsedUpload{FileName: "batch.csv", FileHash: *uploaded.FileHash, Format: importFormatCSV})
+		require.Error(t, err)
+		statusCode, _ := handleDatabaseError(err)
+		assert.Equal(t, http.StatusConflict, statusCode)
+
+		// Forced re-imports record no hash
+		resp = uploadTestFile(t, "batch.csv", csvContent, map[string]string{"force": "true"})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var forced Import
+		assertNoError(t, parseJSONResponse(makeRequest("GET", "/api/imports/"+importIDOf(t, resp), nil), &forced))
+		assert.Nil(t, forced.FileHash)
+	})
+
+	t.Run("should undo an import", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		resp := uploadTestFile(t, "batch.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var uploaded map[string]interface{}
+		assertNoError(t, parseJSONResponse(resp, &uploaded))
+		importID, _ := uploaded["import_id"].(string)
+
+		resp = makeRequest("DELETE", "/api/imports/"+importID, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result map[string]interface{}
+		assertNoError(t, parseJSONResponse(resp, &result))
+		assert.Equal(t, float64(2), result["deleted_transactions"])
+
+		var count int
+		assertNoError(t, testDB.QueryRow(context.Background(), "SELECT COUNT(*) FROM transactions").Scan(&count))
+		assert.Equal(t, 0, count)
+
+		resp = makeRequest("GET", "/api/imports/"+importID, nil)
+		assertStatusCode(t, http.StatusNotFound, resp.Code)
+
+		// Once undone, the file can be imported again
+		resp = uploadTestFile(t, "batch.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("should refuse to undo an import with archived transactions", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		resp := uploadTestFile(t, "batch.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var uploaded map[string]interface{}
+		assertNoError(t, parseJSONResponse(resp, &uploaded))
+		importID, _ := uploaded["import_id"].(string)
+
+		ctx := context.Background()
+		var archiveID string
+		assertNoError(t, testDB.QueryRow(ctx, "INSERT INTO archives (description) VALUES ('Test') RETURNING id").Scan(&archiveID))
+		_, err := testDB.Exec(ctx, "UPDATE transactions SET archive_id = $1", archiveID)
+		assertNoError(t, err)
+
+		resp = makeRequest("DELETE", "/api/imports/"+importID, nil)
+		assertStatusCode(t, http.StatusConflict, resp.Code)
+	})
+}
+
+func TestAtomicImport(t *testing.T) {
+	t.Run("should bulk import and deduplicate a large file", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		var csvContent strings.Builder
+		csvContent.WriteString("Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit\n")
+		for i := 0; i < 5000; i++ {
+			fmt.Fprintf(&csvContent, "2024-03-01,2024-03-02,1234,Purchase %d,Shopping,%d.25,\n", i%2500, i%2500+1)
+		}
+
+		resp := uploadTestFile(t, "backfill.csv", csvContent.String(), nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result map[string]interface{}
+		assertNoError(t, parseJSONResponse(resp, &result))
+		transactions, _ := result["transactions"].([]interface{})
+		assert.Len(t, transactions, 5000)
+
+		var splitCount int
+		assertNoError(t, testDB.QueryRow(context.Background(), "SELECT COUNT(*) FROM transaction_splits").Scan(&splitCount))
+		assert.Equal(t, 5000, splitCount)
+
+		// Every row, including the repeated ones, already exists
+		resp = uploadTestFile(t, "backfill.csv", csvContent.String(), map[string]string{"force": "true"})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		assertNoError(t, parseJSONResponse(resp, &result))
+		assert.Equal(t, float64(5000), result["skipped_rows"])
+	})
+
+	t.Run("should save nothing when the import fails", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		// A category that does not exist makes the split insert fail after the
+		// transactions have been loaded
+		originalMapping := categoryMappings.get()
+		defer categoryMappings.set(originalMapping)
+		categoryMappings.set(newCategoryMapping([]generated.GetCategoriesRow{
+			{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, Name: "Other"},
+		}))
+
+		csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-03-01,2024-03-02,1234,Coffee,Dining,4.50,
+2024-03-03,2024-03-04,1234,Broken,Dining,abc,`
+
+		resp := uploadTestFile(t, "failing.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusInternalServerError, resp.Code)
+
+		ctx := context.Background()
+		for _, table := range []string{"transactions", "transaction_splits", "imports", "import_rejections"} {
+			var count int
+			assertNoError(t, testDB.QueryRow(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count))
+			assert.Equal(t, 0, count, "expected no rows in %s", table)
+		}
+	})
+}
+
+func TestPreviewImport(t *testing.T) {
+	t.Run("should report per-row outcomes without saving", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		// Rules can only target categories loaded into the category mapping
+		catID := testCategoryID("Food & Dining")
+		ruleID, err := createTestRule("starbucks", catID, 0)
+		assertNoError(t, err)
+
+		// Existing transaction that the second row duplicates
+		existingCSV := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-01-02,2024-01-03,1234,GROCERY OUTLET,Groceries,45.20,`
+		body, contentType := createCSVFile(t, "existing.csv", existingCSV)
+		req, err := http.NewRequest("POST", "/api/upload-csv", body)
+		assertNoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		assertStatusCode(t, http.StatusOK, makeRequestWithCustomRequest(req).Code)
+
+		previewCSV := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-01-01,2024-01-02,1234,STARBUCKS #123,Dining,5.75,
+2024-01-02,2024-01-03,1234,GROCERY OUTLET,Groceries,45.20,
+2024-01-04,2024-01-05,1234,Broken,Dining,abc,`
+		body, contentType = createCSVFile(t, "preview.csv", previewCSV)
+		req, err = http.NewRequest("POST", "/api/upload-csv/preview", body)
+		assertNoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		resp := makeRequestWithCustomRequest(req)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result struct {
+			Rows        []ImportPreviewRow `json:"rows"`
+			WouldImport int                `json:"would_import"`
+			WouldSkip   int                `json:"would_skip"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &result))
+
+		if len(result.Rows) != 3 {
+			t.Fatalf("Expected 3 preview rows, got %d", len(result.Rows))
+		}
+		if result.WouldImport != 1 || result.WouldSkip != 2 {
+			t.Errorf("Expected 1 import and 2 skips, got %d and %d", result.WouldImport, result.WouldSkip)
+		}
+
+		matched := result.Rows[0]
+		if !matched.WouldImport || matched.RuleID == nil || *matched.RuleID != ruleID {
+			t.Errorf("Expected first row to match rule %s, got %+v", ruleID, matched)
+		}
+		if matched.CategoryName == nil || *matched.CategoryName != "Food & Dining" {
+			t.Errorf("Expected first row category 'Food & Dining', got %v", matched.CategoryName)
+		}
+
+		duplicate := result.Rows[1]
+		if !duplicate.Duplicate || duplicate.WouldImport {
+			t.Errorf("Expected second row to be a duplicate, got %+v", duplicate)
+		}
+		if duplicate.RuleID != nil {
+			t.Errorf("Expected second row to fall back without a rule, got %v", *duplicate.RuleID)
+		}
+
+		broken := result.Rows[2]
+		if broken.Line != 4 || broken.Reason == nil || *broken.Reason != rejectInvalidAmount || broken.WouldImport {
+			t.Errorf("Expected fourth line to be rejected, got %+v", broken)
+		}
+
+		// Nothing from the preview should have been saved
+		var count int
+		assertNoError(t, testDB.QueryRow(context.Background(), "SELECT COUNT(*) FROM transactions").Scan(&count))
+		if count != 1 {
+			t.Errorf("Expected only the existing transaction, got %d", count)
+		}
+	})
+}
+
+func TestAllocateSplitTemplate(t *testing.T) {
+	line := func(name, percentage string) generated.GetRuleSplitsRow {
+		var pct pgtype.Numeric
+		require.NoError(t, pct.Scan(percentage))
+		return generated.GetRuleSplitsRow{
+			CategoryID:   pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			CategoryName: name,
+			Percentage:   pct,
+		}
+	}
+	cents := func(splits []plannedSplit) map[string]int64 {
+		result := make(map[string]int64, len(splits))
+		for _, split := range splits {
+			result[split.CategoryName] = split.Cents
+		}
+		return result
+	}
+
+	t.Run("divides by percentage", func(t *testing.T) {
+		splits := allocateSplitTemplate(10000, []generated.GetRuleSplitsRow{line("Food", "60"), line("Reimbursable", "40")})
+		assert.Equal(t, map[string]int64{"Food": 6000, "Reimbursable": 4000}, cents(splits))
+	})
+
+	t.Run("gives leftover cents to the first lines", func(t *testing.T) {
+		template := []generated.GetRuleSplitsRow{line("A", "33.33"), line("B", "33.33"), line("C", "33.34")}
+		splits := allocateSplitTemplate(100, template)
+		assert.Equal(t, map[string]int64{"A": 34, "B": 33, "C": 33}, cents(splits))
+	})
+
+	t.Run("drops lines with no share", func(t *testing.T) {
+		splits := allocateSplitTemplate(1, []generated.GetRuleSplitsRow{line("Food", "60"), line("Reimbursable", "40")})
+		assert.Equal(t, map[string]int64{"Food": 1}, cents(splits))
+	})
+
+	t.Run("allocates everything when the template is short of 100%", func(t *testing.T) {
+		splits := allocateSplitTemplate(999, []generated.GetRuleSplitsRow{line("Food", "30"), line("Travel", "30")})
+		assert.Equal(t, map[string]int64{"Food": 500, "Travel": 499}, cents(splits))
+	})
+
+	t.Run("returns nothing without a template", func(t *testing.T) {
+		assert.Empty(t, allocateSplitTemplate(1000, nil))
+	})
+}
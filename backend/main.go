@@ -27,7 +27,6 @@ import (
 
 var dbPool *pgxpool.Pool
 var queries *generated.Queries
-var categoryMapping *CategoryMapping
 
 func main() {
 	var err error
@@ -105,11 +104,13 @@ func main() {
 	}
 
 	// Initialize category mapping after migrations
-	categoryMapping, err = initializeCategoryMapping()
-	if err != nil {
+	if _, err := categoryMappings.reload(); err != nil {
 		log.Printf("Warning: Failed to initialize category mapping: %v", err)
 		log.Println("Transactions will be created without categories")
 	}
+	// Reload it, and the rule matcher, whenever the categories or rules change,
+	// whoever changes them
+	go listenForCacheChanges(context.Background(), dbPool)
 
 	r := gin.Default()
 
@@ -125,8 +126,13 @@ func main() {
 	// Routes
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 	r.POST("/api/upload-csv", uploadCSV)
+	r.POST("/api/upload-csv/preview", previewImport)
+	r.GET("/api/imports", getImports)
+	r.GET("/api/imports/:id", getImport)
+	r.DELETE("/api/imports/:id", deleteImport)
 	r.GET("/api/transactions", getTransactions)
 	r.DELETE("/api/transactions", clearAllTransactions)
+	r.GET("/api/transactions/suggestions", getCategorySuggestions)
 	r.DELETE("/api/transactions/:id", deleteTransaction)
 	r.PUT("/api/transactions/:id/assign", assignTransaction)
 	r.GET("/api/transactions/:id/splits", getTransactionSplits)
@@ -138,14 +144,35 @@ func main() {
 	r.POST("/api/categories", createCategory)
 	r.PUT("/api/categories/:id", updateCategory)
 	r.DELETE("/api/categories/:id", deleteCategory)
+	r.POST("/api/categories/:id/merge", mergeCategory)
+	r.POST("/api/categories/:id/retire", retireCategory)
+	r.POST("/api/categories/:id/restore", restoreCategory)
 	r.GET("/api/totals", getTotals)
 	r.POST("/api/archives", createArchive)
 	r.GET("/api/archives", getArchives)
 	r.GET("/api/archives/:id/transactions", getArchiveTransactions)
 	r.GET("/api/rules", getRules)
+	r.GET("/api/rules/report", getRuleReport)
+	r.GET("/api/rules/lint", lintRuleSet)
+	r.GET("/api/rules/suggestions", getRuleSuggestions)
+	r.POST("/api/rules/suggestions/accept", acceptRuleSuggestion)
 	r.POST("/api/rules", createRule)
 	r.PUT("/api/rules/:id", updateRule)
 	r.DELETE("/api/rules/:id", deleteRule)
+	r.POST("/api/rules/apply/preview", previewRuleApplication)
+	r.POST("/api/rules/apply", applyRules)
+	r.POST("/api/rules/test", testRules)
+	r.GET("/api/rules/export", exportRules)
+	r.POST("/api/rules/import", importRules)
+	r.GET("/api/import-profiles", getImportProfiles)
+	r.POST("/api/import-profiles", createImportProfile)
+	r.PUT("/api/import-profiles/:id", updateImportProfile)
+	r.DELETE("/api/import-profiles/:id", deleteImportProfile)
+	r.GET("/api/budgets", getBudgets)
+	r.POST("/api/budgets", createBudget)
+	r.GET("/api/budgets/report", getBudgetReport)
+	r.PUT("/api/budgets/:id", updateBudget)
+	r.DELETE("/api/budgets/:id", deleteBudget)
 
 	port := os.Getenv("PORT")
 	if port == "" {
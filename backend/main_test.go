@@ -15,6 +15,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
@@ -104,8 +105,7 @@ func setupTestDB() error {
 	setupTestRouter()
 
 	// Initialize category mapping for tests (after queries is set)
-	categoryMapping, err = initializeCategoryMapping()
-	if err != nil {
+	if _, err := categoryMappings.reload(); err != nil {
 		return fmt.Errorf("failed to initialize category mapping for tests: %w", err)
 	}
 
@@ -130,8 +130,13 @@ func setupTestRouter() {
 
 	// Add routes (same as main function)
 	testRouter.POST("/api/upload-csv", uploadCSV)
+	testRouter.POST("/api/upload-csv/preview", previewImport)
+	testRouter.GET("/api/imports", getImports)
+	testRouter.GET("/api/imports/:id", getImport)
+	testRouter.DELETE("/api/imports/:id", deleteImport)
 	testRouter.GET("/api/transactions", getTransactions)
 	testRouter.DELETE("/api/transactions", clearAllTransactions)
+	testRouter.GET("/api/transactions/suggestions", getCategorySuggestions)
 	testRouter.PUT("/api/transactions/:id/assign", assignTransaction)
 	testRouter.GET("/api/transactions/:id/splits", getTransactionSplits)
 	testRouter.PUT("/api/transactions/:id/splits", replaceTransactionSplits)
@@ -142,14 +147,35 @@ func setupTestRouter() {
 	testRouter.POST("/api/categories", createCategory)
 	testRouter.PUT("/api/categories/:id", updateCategory)
 	testRouter.DELETE("/api/categories/:id", deleteCategory)
+	testRouter.POST("/api/categories/:id/merge", mergeCategory)
+	testRouter.POST("/api/categories/:id/retire", retireCategory)
+	testRouter.POST("/api/categories/:id/restore", restoreCategory)
 	testRouter.GET("/api/totals", getTotals)
 	testRouter.POST("/api/archives", createArchive)
 	testRouter.GET("/api/archives", getArchives)
 	testRouter.GET("/api/archives/:id/transactions", getArchiveTransactions)
 	testRouter.GET("/api/rules", getRules)
+	testRouter.GET("/api/rules/report", getRuleReport)
+	testRouter.GET("/api/rules/lint", lintRuleSet)
+	testRouter.GET("/api/rules/suggestions", getRuleSuggestions)
+	testRouter.POST("/api/rules/suggestions/accept", acceptRuleSuggestion)
 	testRouter.POST("/api/rules", createRule)
 	testRouter.PUT("/api/rules/:id", updateRule)
 	testRouter.DELETE("/api/rules/:id", deleteRule)
+	testRouter.POST("/api/rules/apply/preview", previewRuleApplication)
+	testRouter.POST("/api/rules/apply", applyRules)
+	testRouter.POST("/api/rules/test", testRules)
+	testRouter.GET("/api/rules/export", exportRules)
+	testRouter.POST("/api/rules/import", importRules)
+	testRouter.GET("/api/import-profiles", getImportProfiles)
+	testRouter.POST("/api/import-profiles", createImportProfile)
+	testRouter.PUT("/api/import-profiles/:id", updateImportProfile)
+	testRouter.DELETE("/api/import-profiles/:id", deleteImportProfile)
+	testRouter.GET("/api/budgets", getBudgets)
+	testRouter.POST("/api/budgets", createBudget)
+	testRouter.GET("/api/budgets/report", getBudgetReport)
+	testRouter.PUT("/api/budgets/:id", updateBudget)
+	testRouter.DELETE("/api/budgets/:id", deleteBudget)
 }
 
 // cleanupTestData removes all data from test tables
@@ -164,6 +190,11 @@ func cleanupTestData() error {
 	if _, err := testDB.Exec(ctx, "DELETE FROM transactions"); err != nil {
 		return fmt.Errorf("failed to clean transactions: %w", err)
 	}
+	categoryClassifiers.invalidate()
+
+	if _, err := testDB.Exec(ctx, "DELETE FROM imports"); err != nil {
+		return fmt.Errorf("failed to clean imports: %w", err)
+	}
 
 	if _, err := testDB.Exec(ctx, "DELETE FROM archives"); err != nil {
 		return fmt.Errorf("failed to clean archives: %w", err)
@@ -181,6 +212,12 @@ func cleanupTestData() error {
 	if _, err := testDB.Exec(ctx, "DELETE FROM categorization_rules"); err != nil {
 		return fmt.Errorf("failed to clean categorization_rules: %w", err)
 	}
+	ruleMatchers.invalidate()
+
+	// Keep the seeded Default import profile; uploads fall back to it
+	if _, err := testDB.Exec(ctx, "DELETE FROM import_profiles WHERE name <> 'Default'"); err != nil {
+		return fmt.Errorf("failed to clean import_profiles: %w", err)
+	}
 
 	// Reinitialize default data
 	if err := reinitializeDefaultData(ctx); err != nil {
@@ -188,9 +225,7 @@ func cleanupTestData() error {
 	}
 
 	// Reinitialize category mapping after data is restored
-	var err error
-	categoryMapping, err = initializeCategoryMapping()
-	if err != nil {
+	if _, err := categoryMappings.reload(); err != nil {
 		return fmt.Errorf("failed to reinitialize category mapping: %w", err)
 	}
 
@@ -224,6 +259,10 @@ func reinitializeDefaultData(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to insert default categories: %w", err)
 	}
+	_, err = testDB.Exec(ctx, `UPDATE categories SET exclude_from_totals = TRUE WHERE name = 'Reimbursable'`)
+	if err != nil {
+		return fmt.Errorf("failed to exclude default categories from totals: %w", err)
+	}
 
 	return nil
 }
@@ -236,6 +275,11 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// testCategoryID returns the ID of a category in the category mapping
+func testCategoryID(name string) string {
+	return uuid.UUID(categoryMappings.get().categoriesByName[name].ID.Bytes).String()
+}
+
 // createTestPerson creates a test person and returns the ID
 func createTestPerson(name, email string) (string, error) {
 	var emailText pgtype.Text
@@ -274,6 +318,7 @@ func createTestCategory(name, description, color string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	categoryMappings.invalidate()
 
 	return category.ID.String(), nil
 }
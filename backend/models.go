@@ -14,21 +14,36 @@ type Transaction struct {
 	PostedDate      *string            `json:"posted_date"`
 	CardNumber      *string            `json:"card_number"`
 	Splits          []TransactionSplit `json:"splits,omitempty"`
+	Shares          []TransactionShare `json:"shares,omitempty"`
 	CreatedAt       time.Time          `json:"created_at"`
 	UpdatedAt       time.Time          `json:"updated_at"`
 }
 
-// TransactionSplit represents a split allocation row for a transaction
+// TransactionSplit represents a split allocation row for a transaction.
+// AssignedTo names the people the split is assigned to, overriding the
+// transaction's assigned_to; it is null when the split uses the transaction's.
 type TransactionSplit struct {
 	ID            string    `json:"id"`
 	TransactionID string    `json:"transaction_id"`
 	Amount        float64   `json:"amount"`
 	CategoryID    string    `json:"category_id"`
 	Notes         *string   `json:"notes"`
+	AssignedTo    []string  `json:"assigned_to"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// TransactionShare is an assigned person's part of a transaction. Weight or
+// FixedAmount is set when the person's share was given, and Share is the
+// resulting fraction of the transaction amount that counts for them.
+type TransactionShare struct {
+	PersonID    string   `json:"person_id"`
+	PersonName  string   `json:"person_name"`
+	Weight      *float64 `json:"weight"`
+	FixedAmount *float64 `json:"fixed_amount"`
+	Share       float64  `json:"share"`
+}
+
 // Person represents a person who can be assigned to transactions
 type Person struct {
 	ID        string    `json:"id"`
@@ -38,16 +53,56 @@ type Person struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// Category represents a transaction category
+// Category represents a transaction category. RetiredAt is set once the category
+// is retired: it is kept for existing splits but can no longer be chosen.
+// ExcludeFromTotals leaves the category and its subcategories out of person
+// totals and spending reports.
 type Category struct {
-	ID            string     `json:"id"`
-	Name          string     `json:"name"`
-	Description   *string    `json:"description"`
-	Color         *string    `json:"color"`
-	ParentID      *string    `json:"parent_id,omitempty"`
-	Subcategories []Category `json:"subcategories,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	ID                string     `json:"id"`
+	Name              string     `json:"name"`
+	Description       *string    `json:"description"`
+	Color             *string    `json:"color"`
+	ParentID          *string    `json:"parent_id,omitempty"`
+	Subcategories     []Category `json:"subcategories,omitempty"`
+	RetiredAt         *time.Time `json:"retired_at,omitempty"`
+	ExcludeFromTotals bool       `json:"exclude_from_totals"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// CategoryMergeRequest names the category another category is merged into
+type CategoryMergeRequest struct {
+	TargetID string `json:"target_id"`
+}
+
+// CategoryMergeResult reports what merging a category moved into the target. The
+// merged category's subcategories are re-parented under the target, or merged into
+// it as well when the target is itself a subcategory.
+type CategoryMergeResult struct {
+	Target                  Category `json:"target"`
+	SplitsMoved             int64    `json:"splits_moved"`
+	RulesMoved              int64    `json:"rules_moved"`
+	RuleSplitsMoved         int64    `json:"rule_splits_moved"`
+	BudgetsMoved            int64    `json:"budgets_moved"`
+	SubcategoriesReparented int64    `json:"subcategories_reparented"`
+	SubcategoriesMerged     []string `json:"subcategories_merged"`
+}
+
+// CategoryRetireRequest optionally names the category that the rules of a
+// retired category are reassigned to
+type CategoryRetireRequest struct {
+	ReassignRulesTo *string `json:"reassign_rules_to"`
+}
+
+// CategoryRetireResult reports the retired category and what retiring it changed.
+// Rules that still use a retired category, as their category or in their split
+// template, are kept but no longer match; RulesDisabled counts them.
+type CategoryRetireResult struct {
+	Category             Category `json:"category"`
+	SubcategoriesRetired int      `json:"subcategories_retired"`
+	RulesReassigned      int64    `json:"rules_reassigned"`
+	RuleSplitsReassigned int64    `json:"rule_splits_reassigned"`
+	RulesDisabled        int      `json:"rules_disabled"`
 }
 
 // PersonTotal represents the total amount for a person
@@ -79,13 +134,307 @@ type ArchiveRequest struct {
 	Description string `json:"description"`
 }
 
-// Rule represents a categorization rule
+// Rule represents a categorization rule. MatchType is one of contains, exact,
+// prefix, suffix or regex, and MatchField is the field it is tested against: the
+// description, the CSV category, or either. The remaining fields are optional
+// conditions the transaction must also meet; amount bounds apply to the absolute
+// amount and dates are YYYY-MM-DD. AssignTo and Splits are actions applied to
+// matched transactions besides setting the category. HitCount, LastMatchedAt and
+// OverrideCount are read-only statistics: how many transactions the rule
+// categorized, when it last did, and how many of those were re-split by hand into
+// categories the rule does not assign. Warnings come from analysing the rule set.
 type Rule struct {
+	ID            string        `json:"id"`
+	MatchValue    string        `json:"match_value"`
+	MatchType     string        `json:"match_type"`
+	MatchField    string        `json:"match_field"`
+	MinAmount     *float64      `json:"min_amount"`
+	MaxAmount     *float64      `json:"max_amount"`
+	Direction     *string       `json:"direction"`
+	CardNumber    *string       `json:"card_number"`
+	StartDate     *string       `json:"start_date"`
+	EndDate       *string       `json:"end_date"`
+	CategoryID    string        `json:"category_id"`
+	CategoryName  string        `json:"category_name"`
+	AssignTo      []string      `json:"assign_to"`
+	Splits        []RuleSplit   `json:"splits"`
+	Priority      int32         `json:"priority"`
+	HitCount      int32         `json:"hit_count"`
+	LastMatchedAt *time.Time    `json:"last_matched_at"`
+	OverrideCount int32         `json:"override_count"`
+	Warnings      []RuleWarning `json:"warnings,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// RuleWarning is a problem found by analysing the rule set. Type is shadowed when
+// RuleID can never match because OtherRuleID is tried first and matches everything
+// it does, and conflict when the two have the same priority, can match the same
+// transaction and assign different categories.
+type RuleWarning struct {
+	Type        string `json:"type"`
+	RuleID      string `json:"rule_id"`
+	OtherRuleID string `json:"other_rule_id"`
+	Message     string `json:"message"`
+}
+
+// RuleSplit is one line of a rule's split template. A matched transaction is split
+// between the template's categories by percentage; the percentages add up to 100.
+type RuleSplit struct {
+	CategoryID   string  `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// RuleReport lists rules that may need attention: rules that never categorized a
+// transaction, rules that have not matched recently, and rules whose results are
+// often re-split by hand
+type RuleReport struct {
+	NeverMatched         []Rule `json:"never_matched"`
+	Stale                []Rule `json:"stale"`
+	FrequentlyOverridden []Rule `json:"frequently_overridden"`
+}
+
+// RuleSet is a portable export of the categorization rules. Categories and people
+// are referenced by name rather than ID so a rule set can be imported into another
+// installation.
+type RuleSet struct {
+	Version int           `json:"version" yaml:"version"`
+	Rules   []RuleSetRule `json:"rules" yaml:"rules"`
+}
+
+// RuleSetRule is a Rule in a RuleSet
+type RuleSetRule struct {
+	MatchValue string         `json:"match_value" yaml:"match_value"`
+	MatchType  string         `json:"match_type" yaml:"match_type"`
+	MatchField string         `json:"match_field" yaml:"match_field"`
+	MinAmount  *float64       `json:"min_amount,omitempty" yaml:"min_amount,omitempty"`
+	MaxAmount  *float64       `json:"max_amount,omitempty" yaml:"max_amount,omitempty"`
+	Direction  *string        `json:"direction,omitempty" yaml:"direction,omitempty"`
+	CardNumber *string        `json:"card_number,omitempty" yaml:"card_number,omitempty"`
+	StartDate  *string        `json:"start_date,omitempty" yaml:"start_date,omitempty"`
+	EndDate    *string        `json:"end_date,omitempty" yaml:"end_date,omitempty"`
+	Category   string         `json:"category" yaml:"category"`
+	AssignTo   []string       `json:"assign_to,omitempty" yaml:"assign_to,omitempty"`
+	Splits     []RuleSetSplit `json:"splits,omitempty" yaml:"splits,omitempty"`
+	Priority   int32          `json:"priority" yaml:"priority"`
+}
+
+// RuleSetSplit is a split template line in a RuleSet
+type RuleSetSplit struct {
+	Category   string  `json:"category" yaml:"category"`
+	Percentage float64 `json:"percentage" yaml:"percentage"`
+}
+
+// RuleTestRequest is a sample transaction to run through the categorization rules
+type RuleTestRequest struct {
+	Description     string  `json:"description"`
+	Category        string  `json:"category"`
+	Amount          float64 `json:"amount"`
+	CardNumber      *string `json:"card_number"`
+	TransactionDate *string `json:"transaction_date"`
+}
+
+// RuleTestMatch is a rule that matches a RuleTestRequest and the field it matched on
+type RuleTestMatch struct {
+	Rule
+	MatchedField string `json:"matched_field"`
+}
+
+// RuleTestResult explains how a RuleTestRequest would be categorized. WinningRule
+// is nil when no rule matched; the category is then the category classifier's,
+// with its Confidence, or else Other, with Fallback true.
+type RuleTestResult struct {
+	Matches      []RuleTestMatch `json:"matches"`
+	WinningRule  *Rule           `json:"winning_rule"`
+	CategoryID   *string         `json:"category_id"`
+	CategoryName *string         `json:"category_name"`
+	Fallback     bool            `json:"fallback"`
+	Confidence   *float64        `json:"confidence"`
+}
+
+// RuleSuggestion is a rule proposed for transactions left in Other that share a
+// merchant. The category is the classifier's best guess, or nil when it has none.
+type RuleSuggestion struct {
+	MatchValue       string   `json:"match_value"`
+	MatchType        string   `json:"match_type"`
+	MatchField       string   `json:"match_field"`
+	CategoryID       *string  `json:"category_id"`
+	CategoryName     *string  `json:"category_name"`
+	Confidence       *float64 `json:"confidence"`
+	TransactionCount int      `json:"transaction_count"`
+	Examples         []string `json:"examples"`
+}
+
+// RuleSuggestionAcceptRequest creates the rule a RuleSuggestion proposes, with the
+// category chosen by the user, and optionally applies it to existing transactions
+type RuleSuggestionAcceptRequest struct {
+	MatchValue string `json:"match_value"`
+	CategoryID string `json:"category_id"`
+	Priority   int32  `json:"priority"`
+	Apply      bool   `json:"apply"`
+}
+
+// ImportProfile describes how to read a bank's CSV export. Column fields are
+// zero-based indexes into each CSV record. DateFormat is a Go reference layout or
+// uses YYYY/MM/DD placeholders.
+type ImportProfile struct {
+	ID                    string    `json:"id"`
+	Name                  string    `json:"name"`
+	Delimiter             string    `json:"delimiter"`
+	HasHeader             bool      `json:"has_header"`
+	HeaderColumns         []string  `json:"header_columns"`
+	DateFormat            string    `json:"date_format"`
+	DecimalSeparator      string    `json:"decimal_separator"`
+	AmountFormat          string    `json:"amount_format"`
+	ExpenseSign           string    `json:"expense_sign"`
+	TransactionDateColumn *int32    `json:"transaction_date_column"`
+	PostedDateColumn      *int32    `json:"posted_date_column"`
+	CardNumberColumn      *int32    `json:"card_number_column"`
+	DescriptionColumn     *int32    `json:"description_column"`
+	CategoryColumn        *int32    `json:"category_column"`
+	AmountColumn          *int32    `json:"amount_column"`
+	DebitColumn           *int32    `json:"debit_column"`
+	CreditColumn          *int32    `json:"credit_column"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// ImportPreviewRow describes what an import would do with one row of an uploaded file
+type ImportPreviewRow struct {
+	Line            int                  `json:"line"`
+	Description     string               `json:"description"`
+	Amount          float64              `json:"amount"`
+	TransactionDate *string              `json:"transaction_date"`
+	PostedDate      *string              `json:"posted_date"`
+	CardNumber      *string              `json:"card_number"`
+	CategoryID      *string              `json:"category_id"`
+	CategoryName    *string              `json:"category_name"`
+	RuleID          *string              `json:"rule_id"`
+	RuleMatchValue  *string              `json:"rule_match_value"`
+	Confidence      *float64             `json:"confidence"`
+	AssignedTo      []string             `json:"assigned_to"`
+	Splits          []ImportPreviewSplit `json:"splits"`
+	Duplicate       bool                 `json:"duplicate"`
+	WouldImport     bool                 `json:"would_import"`
+	Reason          *string              `json:"reason"`
+	Error           *string              `json:"error"`
+}
+
+// ImportPreviewSplit is a split an imported row would be created with
+type ImportPreviewSplit struct {
+	CategoryID   string  `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	Amount       float64 `json:"amount"`
+}
+
+// RuleApplicationChange describes how re-applying the rules would change an
+// existing transaction's splits, and the names of the people it is assigned to
+// for rules that assign it
+type RuleApplicationChange struct {
+	TransactionID   string                 `json:"transaction_id"`
+	Description     string                 `json:"description"`
+	Amount          float64                `json:"amount"`
+	TransactionDate *string                `json:"transaction_date"`
+	Archived        bool                   `json:"archived"`
+	RuleID          string                 `json:"rule_id"`
+	RuleMatchValue  string                 `json:"rule_match_value"`
+	CurrentSplits   []RuleApplicationSplit `json:"current_splits"`
+	ProposedSplits  []RuleApplicationSplit `json:"proposed_splits"`
+	CurrentPeople   []string               `json:"current_people"`
+	ProposedPeople  []string               `json:"proposed_people"`
+	Protected       bool                   `json:"protected"`
+	ProtectedReason *string                `json:"protected_reason"`
+}
+
+// RuleApplicationSplit is a current or proposed split of a RuleApplicationChange
+type RuleApplicationSplit struct {
+	CategoryID   string  `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	Amount       float64 `json:"amount"`
+}
+
+// TransactionCategorySuggestions lists the categories the classifier suggests for a
+// transaction in Other
+type TransactionCategorySuggestions struct {
+	TransactionID   string               `json:"transaction_id"`
+	Description     string               `json:"description"`
+	Amount          float64              `json:"amount"`
+	TransactionDate *string              `json:"transaction_date"`
+	Suggestions     []CategorySuggestion `json:"suggestions"`
+}
+
+// CategorySuggestion is a suggested category and the classifier's confidence in
+// it, between 0 and 1
+type CategorySuggestion struct {
+	CategoryID   string  `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	Confidence   float64 `json:"confidence"`
+}
+
+// ImportRejection describes an uploaded row that was not imported
+type ImportRejection struct {
+	Line    int    `json:"line"`
+	Raw     string `json:"raw"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// Import is a batch of transactions created by a single upload
+type Import struct {
+	ID             string            `json:"id"`
+	FileName       *string           `json:"file_name"`
+	Format         string            `json:"format"`
+	FileHash       *string           `json:"file_hash"`
+	TotalRows      int               `json:"total_rows"`
+	ImportedRows   int               `json:"imported_rows"`
+	SkippedRows    int               `json:"skipped_rows"`
+	UploadedBy     *string           `json:"uploaded_by"`
+	UploadedByName *string           `json:"uploaded_by_name"`
+	Rejections     []ImportRejection `json:"rejections,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// Budget is a monthly spending limit for a category, for one person or, without
+// PersonID, for the household. It applies from Month (YYYY-MM) until a later
+// budget for the same category and person replaces it. With Rollover, unspent
+// amounts carry over into the next month. CategoryName and PersonName are
+// read-only.
+type Budget struct {
 	ID           string    `json:"id"`
-	MatchValue   string    `json:"match_value"`
 	CategoryID   string    `json:"category_id"`
 	CategoryName string    `json:"category_name"`
-	Priority     int32     `json:"priority"`
+	PersonID     *string   `json:"person_id"`
+	PersonName   *string   `json:"person_name"`
+	Month        string    `json:"month"`
+	Amount       float64   `json:"amount"`
+	Rollover     bool      `json:"rollover"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
+
+// BudgetReport compares the spending of a month with the budgets in effect.
+// OverBudget counts the lines whose status is over.
+type BudgetReport struct {
+	Month      string             `json:"month"`
+	Lines      []BudgetReportLine `json:"lines"`
+	OverBudget int                `json:"over_budget"`
+}
+
+// BudgetReportLine is one budget's spending in a BudgetReport. Available is the
+// budgeted amount plus the unspent amount rolled over from the previous month, and
+// Spent includes the subcategories of a top-level category. Status is over when
+// Spent exceeds Available, otherwise ok.
+type BudgetReportLine struct {
+	BudgetID     string  `json:"budget_id"`
+	CategoryID   string  `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	PersonID     *string `json:"person_id"`
+	PersonName   *string `json:"person_name"`
+	Budgeted     float64 `json:"budgeted"`
+	RolledOver   float64 `json:"rolled_over"`
+	Available    float64 `json:"available"`
+	Spent        float64 `json:"spent"`
+	Remaining    float64 `json:"remaining"`
+	Status       string  `json:"status"`
+}
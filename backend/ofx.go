@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ofxEntityReplacer decodes the character entities allowed in OFX element content
+var ofxEntityReplacer = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", "\"", "&apos;", "'", "&nbsp;", " ")
+
+// ofxTransaction collects the elements of a single <STMTTRN> aggregate
+type ofxTransaction struct {
+	fitID    string
+	datePost string
+	dateUser string
+	amount   string
+	name     string
+	memo     string
+}
+
+// isOFXFile reports whether an upload is an OFX/QFX statement rather than a CSV,
+// based on the file extension or the OFX header at the start of the file
+func isOFXFile(fileName string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".ofx", ".qfx":
+		return true
+	}
+
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	head = bytes.ToUpper(bytes.TrimPrefix(head, []byte("\ufeff")))
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>"))
+}
+
+// parseOFX extracts statement transactions from an OFX 1.x (SGML) or 2.x (XML) file.
+// Each <STMTTRN> aggregate becomes one row, numbered by the line it starts on.
+// SGML leaf elements have no closing tags, so elements are read as a flat stream of
+// tags and text rather than with an XML decoder; this handles both versions.
+func parseOFX(data []byte) ([]importRow, error) {
+	document := string(data)
+	start := strings.Index(strings.ToUpper(document), "<OFX>")
+	if start < 0 {
+		return nil, fmt.Errorf("no <OFX> element found")
+	}
+	content := document[start:]
+
+	rows := make([]importRow, 0)
+	var accountID string
+	var current *ofxTransaction
+	currentLine, currentStart := 0, 0
+
+	// Line numbers are counted incrementally as the content is consumed
+	line, counted := 1, 0
+	lineAt := func(offset int) int {
+		line += strings.Count(document[counted:offset], "\n")
+		counted = offset
+		return line
+	}
+
+	for len(content) > 0 {
+		open := strings.IndexByte(content, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(content[open:], '>')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated tag")
+		}
+		tag := strings.ToUpper(strings.TrimSpace(content[open+1 : open+end]))
+		tagOffset := len(document) - len(content) + open
+		content = content[open+end+1:]
+
+		// Element text runs until the next tag
+		text := content
+		if next := strings.IndexByte(content, '<'); next >= 0 {
+			text = content[:next]
+		}
+		value := strings.TrimSpace(ofxEntityReplacer.Replace(text))
+
+		switch tag {
+		case "STMTTRN":
+			current = &ofxTransaction{}
+			currentLine, currentStart = lineAt(tagOffset), tagOffset
+		case "/STMTTRN":
+			if current == nil {
+				continue
+			}
+			record, err := current.toImportRecord(accountID)
+			raw := document[currentStart : len(document)-len(content)]
+			rows = append(rows, importRow{Line: currentLine, Raw: raw, Record: record, Err: err})
+			current = nil
+		case "ACCTID":
+			accountID = value
+		case "FITID", "DTPOSTED", "DTUSER", "TRNAMT", "NAME", "MEMO":
+			if current != nil {
+				current.set(tag, value)
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+// set stores the value of a <STMTTRN> child element
+func (t *ofxTransaction) set(tag, value string) {
+	switch tag {
+	case "FITID":
+		t.fitID = value
+	case "DTPOSTED":
+		t.datePost = value
+	case "DTUSER":
+		t.dateUser = value
+	case "TRNAMT":
+		t.amount = value
+	case "NAME":
+		t.name = value
+	case "MEMO":
+		t.memo = value
+	}
+}
+
+// toImportRecord converts an OFX transaction to the shared import representation.
+// OFX amounts are signed from the account's point of view (negative = money out),
+// so they are negated to match our convention of positive expenses.
+func (t *ofxTransaction) toImportRecord(accountID string) (importRecord, error) {
+	if t.fitID == "" {
+		return importRecord{}, newImportRowError(rejectMissingExternalID, "transaction without FITID")
+	}
+
+	amount, err := strconv.ParseFloat(t.amount, 64)
+	if err != nil {
+		return importRecord{}, newImportRowError(rejectInvalidAmount, "invalid TRNAMT %q for FITID %s", t.amount, t.fitID)
+	}
+
+	description := t.name
+	if description == "" {
+		description = t.memo
+	}
+
+	postedDate := parseOFXDate(t.datePost)
+	transactionDate := parseOFXDate(t.dateUser)
+	if !transactionDate.Valid {
+		transactionDate = postedDate
+	}
+
+	return importRecord{
+		TransactionDate: transactionDate,
+		PostedDate:      postedDate,
+		CardNumber:      ofxCardNumber(accountID),
+		Description:     description,
+		Amount:          -amount,
+		ExternalID:      t.fitID,
+	}, nil
+}
+
+// parseOFXDate parses the date part of an OFX datetime (YYYYMMDD[HHMMSS[.XXX]][[TZ]])
+func parseOFXDate(value string) pgtype.Date {
+	if len(value) < 8 {
+		return pgtype.Date{Valid: false}
+	}
+	parsedDate, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return pgtype.Date{Valid: false}
+	}
+	return pgtype.Date{Time: parsedDate, Valid: true}
+}
+
+// ofxCardNumber keeps the last four characters of an account ID, matching the
+// card suffix that bank CSV exports put in the Card No. column
+func ofxCardNumber(accountID string) string {
+	if len(accountID) <= 4 {
+		return accountID
+	}
+	return accountID[len(accountID)-4:]
+}
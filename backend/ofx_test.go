@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOFXSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20240201120000<LANGUAGE>ENG</SONRS></SIGNONMSGSRSV1>
+<CREDITCARDMSGSRSV1><CCSTMTTRNRS><TRNUID>1<STATUS><CODE>0<SEVERITY>INFO</STATUS>
+<CCSTMTRS><CURDEF>USD<CCACCTFROM><ACCTID>4111111111119364</CCACCTFROM>
+<BANKTRANLIST><DTSTART>20240101<DTEND>20240131
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240116120000.000[-8:PST]<DTUSER>20240115<TRNAMT>-5.75<FITID>2024011500001<NAME>STARBUCKS #12345<MEMO>COFFEE</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240120<TRNAMT>20.00<FITID>2024012000002<NAME>RETURN &amp; REFUND</STMTTRN>
+</BANKTRANLIST></CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+const testOFXXML = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <BANKMSGSRSV1>
+    <STMTTRNRS>
+      <STMTRS>
+        <CURDEF>USD</CURDEF>
+        <BANKACCTFROM>
+          <BANKID>121000248</BANKID>
+          <ACCTID>000123456789</ACCTID>
+          <ACCTTYPE>CHECKING</ACCTTYPE>
+        </BANKACCTFROM>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240203</DTPOSTED>
+            <TRNAMT>-45.20</TRNAMT>
+            <FITID>A1B2C3</FITID>
+            <NAME>GROCERY OUTLET</NAME>
+          </STMTTRN>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240204</DTPOSTED>
+            <TRNAMT>not-a-number</TRNAMT>
+            <FITID>A1B2C4</FITID>
+            <NAME>BROKEN ROW</NAME>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </STMTRS>
+    </STMTTRNRS>
+  </BANKMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	t.Run("parses OFX 1.x SGML statements", func(t *testing.T) {
+		rows, err := parseOFX([]byte(testOFXSGML))
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		require.NoError(t, rows[0].Err)
+		require.NoError(t, rows[1].Err)
+		assert.Equal(t, 16, rows[0].Line)
+		assert.Equal(t, 17, rows[1].Line)
+
+		records := []importRecord{rows[0].Record, rows[1].Record}
+
+		assert.Equal(t, "STARBUCKS #12345", records[0].Description)
+		assert.Equal(t, 5.75, records[0].Amount)
+		assert.Equal(t, "2024011500001", records[0].ExternalID)
+		assert.Equal(t, "9364", records[0].CardNumber)
+		assert.Equal(t, "2024-01-15", records[0].TransactionDate.Time.Format("2006-01-02"))
+		assert.Equal(t, "2024-01-16", records[0].PostedDate.Time.Format("2006-01-02"))
+
+		assert.Equal(t, "RETURN & REFUND", records[1].Description)
+		assert.Equal(t, -20.0, records[1].Amount)
+		assert.Equal(t, "2024-01-20", records[1].TransactionDate.Time.Format("2006-01-02"))
+	})
+
+	t.Run("parses OFX 2.x XML statements and flags malformed transactions", func(t *testing.T) {
+		rows, err := parseOFX([]byte(testOFXXML))
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+
+		require.NoError(t, rows[0].Err)
+		assert.Equal(t, 14, rows[0].Line)
+		assert.Equal(t, "GROCERY OUTLET", rows[0].Record.Description)
+		assert.Equal(t, 45.20, rows[0].Record.Amount)
+		assert.Equal(t, "A1B2C3", rows[0].Record.ExternalID)
+		assert.Equal(t, "6789", rows[0].Record.CardNumber)
+
+		assert.Equal(t, rejectInvalidAmount, rejectionReason(rows[1].Err))
+		assert.Equal(t, 21, rows[1].Line)
+		assert.Contains(t, rows[1].Raw, "<NAME>BROKEN ROW</NAME>")
+		assert.True(t, strings.HasPrefix(rows[1].Raw, "<STMTTRN>"))
+		assert.True(t, strings.HasSuffix(rows[1].Raw, "</STMTTRN>"))
+	})
+
+	t.Run("rejects files without an OFX element", func(t *testing.T) {
+		_, err := parseOFX([]byte("Transaction Date,Description"))
+		assert.Error(t, err)
+	})
+}
+
+func TestIsOFXFile(t *testing.T) {
+	assert.True(t, isOFXFile("statement.QFX", []byte("anything")))
+	assert.True(t, isOFXFile("download", []byte(testOFXSGML)))
+	assert.True(t, isOFXFile("download", []byte(testOFXXML)))
+	assert.False(t, isOFXFile("statement.csv", []byte("Transaction Date,Posted Date")))
+}
+
+func TestUploadOFX(t *testing.T) {
+	t.Run("should import OFX transactions and dedup by FITID", func(t *testing.T) {
+		if err := cleanupTestData(); err != nil {
+			t.Fatalf("Failed to cleanup test data: %v", err)
+		}
+
+		// Identical files are rejected unless forced, so force every upload
+		upload := func() map[string]interface{} {
+			body, contentType := createCSVFileWithFields(t, "statement.qfx", testOFXSGML, map[string]string{"force": "true"})
+			req, err := http.NewRequest("POST", "/api/upload-csv", body)
+			assertNoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			resp := makeRequestWithCustomRequest(req)
+			assertStatusCode(t, http.StatusOK, resp.Code)
+
+			var result map[string]interface{}
+			assertNoError(t, parseJSONResponse(resp, &result))
+			return result
+		}
+
+		result := upload()
+		if result["format"] != "ofx" {
+			t.Errorf("Expected format 'ofx', got %v", result["format"])
+		}
+		if transactions, _ := result["transactions"].([]interface{}); len(transactions) != 2 {
+			t.Errorf("Expected 2 transactions, got %d", len(transactions))
+		}
+
+		// Re-uploading the same statement must not create duplicates
+		result = upload()
+		if transactions, _ := result["transactions"].([]interface{}); len(transactions) != 0 {
+			t.Errorf("Expected 0 transactions on re-upload, got %d", len(transactions))
+		}
+		if result["skipped_rows"] != float64(2) {
+			t.Errorf("Expected 2 skipped rows on re-upload, got %v", result["skipped_rows"])
+		}
+	})
+
+	t.Run("should reject a malformed OFX file", func(t *testing.T) {
+		body, contentType := createCSVFile(t, "statement.ofx", "OFXHEADER:100\n<OFX><STMTTRN")
+		req, err := http.NewRequest("POST", "/api/upload-csv", body)
+		assertNoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		resp := makeRequestWithCustomRequest(req)
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+}
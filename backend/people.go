@@ -144,6 +144,22 @@ func deletePerson(c *gin.Context) {
 		return
 	}
 
+	// Splits left without anyone fall back to their transaction's assignment
+	err = queries.UnassignTransactionSplitsByPerson(context.Background(), personUUIDpg)
+	if err != nil {
+		log.Printf("Error unassigning transaction splits for person %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error unassigning transactions"})
+		return
+	}
+
+	// Stop rules from assigning future transactions to this person
+	err = queries.RemovePersonFromRules(context.Background(), personUUIDpg)
+	if err != nil {
+		log.Printf("Error removing person %s from rules: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error removing person from rules"})
+		return
+	}
+
 	// Now delete the person
 	err = queries.DeletePerson(context.Background(), personUUIDpg)
 	if err != nil {
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+
+	"jointanalysis/db/generated"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Rule application scopes: which transactions rules are re-applied to
+const (
+	ruleScopeActive   = "active"
+	ruleScopeArchived = "archived"
+	ruleScopeAll      = "all"
+)
+
+// Reasons a transaction is protected from rule application
+const (
+	protectedManuallyEdited = "manually_edited"
+	protectedMultipleSplits = "multiple_splits"
+)
+
+// ruleApplicationRequest selects the transactions to re-apply rules to. An empty
+// scope means active transactions, and empty TransactionIDs means every change.
+type ruleApplicationRequest struct {
+	Scope            string   `json:"scope"`
+	TransactionIDs   []string `json:"transaction_ids"`
+	IncludeProtected bool     `json:"include_protected"`
+}
+
+// plannedRuleApplication is a transaction whose splits or people differ from what
+// the rules would give it now. AssignedTo is set when the rule assigns the
+// transaction to other people than it has; otherwise it keeps its own.
+type plannedRuleApplication struct {
+	Transaction     generated.GetTransactionsForRuleApplicationRow
+	Rule            *generated.GetRulesForMatchingRow
+	Current         []plannedSplit
+	Proposed        []plannedSplit
+	AssignedTo      []pgtype.UUID
+	CurrentPeople   []string
+	ProposedPeople  []string
+	ProtectedReason string
+}
+
+// ruleApplicationScope converts a scope to the query's active/archived flags
+func ruleApplicationScope(scope string) (generated.GetTransactionsForRuleApplicationParams, error) {
+	switch scope {
+	case "", ruleScopeActive:
+		return generated.GetTransactionsForRuleApplicationParams{IncludeActive: true}, nil
+	case ruleScopeArchived:
+		return generated.GetTransactionsForRuleApplicationParams{IncludeArchived: true}, nil
+	case ruleScopeAll:
+		return generated.GetTransactionsForRuleApplicationParams{IncludeActive: true, IncludeArchived: true}, nil
+	default:
+		return generated.GetTransactionsForRuleApplicationParams{}, fmt.Errorf("scope must be one of: active, archived, all")
+	}
+}
+
+// numericCents converts an amount to absolute cents
+func numericCents(n pgtype.Numeric) int64 {
+	value, err := n.Float64Value()
+	if err != nil {
+		return 0
+	}
+	return int64(math.Round(math.Abs(value.Float64) * 100))
+}
+
+// sameSplits reports whether two sets of splits give the same amounts to the same
+// categories, regardless of order
+func sameSplits(a, b []plannedSplit) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sorted := func(splits []plannedSplit) []plannedSplit {
+		out := append([]plannedSplit(nil), splits...)
+		sort.Slice(out, func(i, j int) bool {
+			if c := bytes.Compare(out[i].CategoryID.Bytes[:], out[j].CategoryID.Bytes[:]); c != 0 {
+				return c < 0
+			}
+			return out[i].Cents < out[j].Cents
+		})
+		return out
+	}
+	a, b = sorted(a), sorted(b)
+	for i := range a {
+		if a[i].CategoryID != b[i].CategoryID || a[i].Cents != b[i].Cents {
+			return false
+		}
+	}
+	return true
+}
+
+// samePeople reports whether two lists of people have the same people, regardless
+// of order
+func samePeople(a, b []pgtype.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[pgtype.UUID]int, len(a))
+	for _, personID := range a {
+		counts[personID]++
+	}
+	for _, personID := range b {
+		if counts[personID] == 0 {
+			return false
+		}
+		counts[personID]--
+	}
+	return true
+}
+
+// keepSplitDetails copies the notes and people of the current splits to the
+// proposed splits that replace them. A proposed split takes the details of a
+// current split in the same category, else of the first current split left, so a
+// re-categorized single split keeps its own. Details of current splits left over
+// when the proposal has fewer splits are dropped.
+func keepSplitDetails(current, proposed []plannedSplit) []plannedSplit {
+	result := append([]plannedSplit(nil), proposed...)
+	used := make([]bool, len(current))
+	matched := make([]bool, len(result))
+	for i := range result {
+		for j := range current {
+			if !used[j] && current[j].CategoryID == result[i].CategoryID {
+				result[i].Notes, result[i].AssignedTo = current[j].Notes, current[j].AssignedTo
+				used[j], matched[i] = true, true
+				break
+			}
+		}
+	}
+	for i := range result {
+		if matched[i] {
+			continue
+		}
+		for j := range current {
+			if !used[j] {
+				result[i].Notes, result[i].AssignedTo = current[j].Notes, current[j].AssignedTo
+				used[j] = true
+				break
+			}
+		}
+	}
+	return result
+}
+
+// planRuleApplication re-evaluates the rules against the transactions in scope and
+// returns those whose splits, or people for rules that assign them, would change.
+// Rules see the CSV category the transaction was imported with. Transactions no
+// rule matches are left as they are rather than reverted to Other. A transaction
+// is protected when its splits were edited by hand, or when it has several splits
+// that no rule created.
+func planRuleApplication(ctx context.Context, q *generated.Queries, scope generated.GetTransactionsForRuleApplicationParams) ([]plannedRuleApplication, error) {
+	rules, err := q.GetRulesForMatching(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load categorization rules: %w", err)
+	}
+	ruleSplits, err := q.GetRuleSplits(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rule split templates: %w", err)
+	}
+	splitsByRule := groupRuleSplits(ruleSplits)
+	people, err := q.GetPeople(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load people: %w", err)
+	}
+	personNames := make(map[pgtype.UUID]string, len(people))
+	for _, person := range people {
+		personNames[person.ID] = person.Name
+	}
+	namesOf := func(personIDs []pgtype.UUID) []string {
+		names := make([]string, 0, len(personIDs))
+		for _, personID := range personIDs {
+			if name, exists := personNames[personID]; exists {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	transactions, err := q.GetTransactionsForRuleApplication(ctx, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %w", err)
+	}
+	splits, err := q.GetSplitsForRuleApplication(ctx, generated.GetSplitsForRuleApplicationParams(scope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transaction splits: %w", err)
+	}
+	currentSplits := make(map[pgtype.UUID][]plannedSplit)
+	for _, split := range splits {
+		currentSplits[split.TransactionID] = append(currentSplits[split.TransactionID], plannedSplit{
+			CategoryID:   split.CategoryID,
+			CategoryName: split.CategoryName,
+			Cents:        numericCents(split.Amount),
+			Notes:        split.Notes,
+			AssignedTo:   split.AssignedTo,
+		})
+	}
+
+	plans := make([]plannedRuleApplication, 0)
+	categoryMapping := categoryMappings.get()
+	if categoryMapping == nil {
+		return plans, nil
+	}
+	// The rules are compiled afresh rather than taken from the cache, as they may
+	// have been changed earlier in the same database transaction
+	matcher := categoryMapping.compileRules(rules)
+	for _, transaction := range transactions {
+		cents := numericCents(transaction.Amount)
+		if cents == 0 {
+			continue
+		}
+		amount, _ := transaction.Amount.Float64Value()
+		record := importRecord{
+			TransactionDate: transaction.TransactionDate,
+			PostedDate:      transaction.PostedDate,
+			CardNumber:      transaction.CardNumber.String,
+			Description:     transaction.Description,
+			CsvCategory:     transaction.CsvCategory.String,
+			Amount:          amount.Float64,
+		}
+		category, rule := matcher.match(record)
+		if rule == nil || category == nil {
+			continue
+		}
+
+		plan := plannedRuleApplication{
+			Transaction:    transaction,
+			Rule:           rule,
+			Current:        currentSplits[transaction.ID],
+			Proposed:       planSplits(category, rule, cents, splitsByRule),
+			CurrentPeople:  namesOf(transaction.AssignedTo),
+			ProposedPeople: namesOf(transaction.AssignedTo),
+		}
+		// As on import, the rule's people who still exist replace the transaction's
+		var assignedTo []pgtype.UUID
+		for _, personID := range rule.AssignTo {
+			if _, exists := personNames[personID]; exists {
+				assignedTo = append(assignedTo, personID)
+			}
+		}
+		if len(assignedTo) > 0 && !samePeople(transaction.AssignedTo, assignedTo) {
+			plan.AssignedTo = assignedTo
+			plan.ProposedPeople = namesOf(assignedTo)
+		}
+		if sameSplits(plan.Current, plan.Proposed) && plan.AssignedTo == nil {
+			continue
+		}
+		if transaction.SplitsEditedAt.Valid {
+			plan.ProtectedReason = protectedManuallyEdited
+		} else if len(plan.Current) > 1 && !transaction.RuleID.Valid {
+			plan.ProtectedReason = protectedMultipleSplits
+		}
+		plans = append(plans, plan)
+	}
+
+	return plans, nil
+}
+
+// convertRuleApplicationSplits converts planned splits to RuleApplicationSplits
+func convertRuleApplicationSplits(splits []plannedSplit) []RuleApplicationSplit {
+	result := make([]RuleApplicationSplit, 0, len(splits))
+	for _, split := range splits {
+		result = append(result, RuleApplicationSplit{
+			CategoryID:   uuid.UUID(split.CategoryID.Bytes).String(),
+			CategoryName: split.CategoryName,
+			Amount:       float64(split.Cents) / 100,
+		})
+	}
+	return result
+}
+
+// convertRuleApplication converts a planned change to a RuleApplicationChange
+func convertRuleApplication(plan plannedRuleApplication) RuleApplicationChange {
+	amount, _ := plan.Transaction.Amount.Float64Value()
+	change := RuleApplicationChange{
+		TransactionID:  uuid.UUID(plan.Transaction.ID.Bytes).String(),
+		Description:    plan.Transaction.Description,
+		Amount:         amount.Float64,
+		Archived:       plan.Transaction.ArchiveID.Valid,
+		RuleID:         uuid.UUID(plan.Rule.ID.Bytes).String(),
+		RuleMatchValue: plan.Rule.MatchValue,
+		CurrentSplits:  convertRuleApplicationSplits(plan.Current),
+		ProposedSplits: convertRuleApplicationSplits(plan.Proposed),
+		CurrentPeople:  plan.CurrentPeople,
+		ProposedPeople: plan.ProposedPeople,
+		Protected:      plan.ProtectedReason != "",
+	}
+	if plan.Transaction.TransactionDate.Valid {
+		transactionDate := plan.Transaction.TransactionDate.Time.Format("2006-01-02")
+		change.TransactionDate = &transactionDate
+	}
+	if plan.ProtectedReason != "" {
+		reason := plan.ProtectedReason
+		change.ProtectedReason = &reason
+	}
+	return change
+}
+
+// applyRuleChanges replaces the splits of the planned transactions the filter
+// accepts, keeping their notes and people, reassigns those the rules assign to
+// other people, dropping their shares, and records the rules that categorized
+// them. Protected transactions are skipped, and counted, unless includeProtected
+// is set.
+func applyRuleChanges(ctx context.Context, q *generated.Queries, plans []plannedRuleApplication, include func(plannedRuleApplication) bool, includeProtected bool) ([]RuleApplicationChange, int, error) {
+	changes := make([]RuleApplicationChange, 0, len(plans))
+	skipped := 0
+	var transactionIDs, ruleIDs []pgtype.UUID
+	var splitRows []generated.CreateTransactionSplitsParams
+	ruleHits := make(map[pgtype.UUID]int32)
+	for _, plan := range plans {
+		if !include(plan) {
+			continue
+		}
+		if plan.ProtectedReason != "" && !includeProtected {
+			skipped++
+			continue
+		}
+
+		if plan.AssignedTo != nil {
+			_, err := q.UpdateTransactionAssignment(ctx, generated.UpdateTransactionAssignmentParams{
+				ID:         plan.Transaction.ID,
+				AssignedTo: plan.AssignedTo,
+			})
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to reassign transaction: %w", err)
+			}
+			// Shares belong to the people the transaction was assigned to
+			if err := q.DeleteTransactionShares(ctx, plan.Transaction.ID); err != nil {
+				return nil, 0, fmt.Errorf("failed to delete transaction shares: %w", err)
+			}
+		}
+
+		transactionIDs = append(transactionIDs, plan.Transaction.ID)
+		ruleIDs = append(ruleIDs, plan.Rule.ID)
+		ruleHits[plan.Rule.ID]++
+		for _, split := range keepSplitDetails(plan.Current, plan.Proposed) {
+			amount, err := split.numeric()
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to convert split amount: %w", err)
+			}
+			splitRows = append(splitRows, generated.CreateTransactionSplitsParams{
+				TransactionID: plan.Transaction.ID,
+				Amount:        amount,
+				CategoryID:    split.CategoryID,
+				Notes:         split.Notes,
+				AssignedTo:    split.AssignedTo,
+			})
+		}
+		changes = append(changes, convertRuleApplication(plan))
+	}
+	if len(transactionIDs) == 0 {
+		return changes, skipped, nil
+	}
+
+	if err := q.DeleteTransactionSplitsByTransactionIDs(ctx, transactionIDs); err != nil {
+		return nil, 0, fmt.Errorf("failed to delete transaction splits: %w", err)
+	}
+	if _, err := q.CreateTransactionSplits(ctx, splitRows); err != nil {
+		return nil, 0, fmt.Errorf("failed to insert transaction splits: %w", err)
+	}
+	err := q.SetTransactionRules(ctx, generated.SetTransactionRulesParams{
+		TransactionIds: transactionIDs,
+		RuleIds:        ruleIDs,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to set transaction rules: %w", err)
+	}
+	if err := recordRuleHits(ctx, q, ruleHits); err != nil {
+		return nil, 0, fmt.Errorf("failed to record rule hits: %w", err)
+	}
+	return changes, skipped, nil
+}
+
+// bindRuleApplicationRequest reads the request body, which is optional, and
+// returns the scope flags and the selected transaction IDs
+func bindRuleApplicationRequest(c *gin.Context) (ruleApplicationRequest, generated.GetTransactionsForRuleApplicationParams, map[pgtype.UUID]bool, error) {
+	var req ruleApplicationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return req, generated.GetTransactionsForRuleApplicationParams{}, nil, errors.New("Invalid request body")
+		}
+	}
+	scope, err := ruleApplicationScope(req.Scope)
+	if err != nil {
+		return req, scope, nil, err
+	}
+
+	var selected map[pgtype.UUID]bool
+	if len(req.TransactionIDs) > 0 {
+		selected = make(map[pgtype.UUID]bool, len(req.TransactionIDs))
+		for _, id := range req.TransactionIDs {
+			transactionUUID, err := uuid.Parse(id)
+			if err != nil {
+				return req, scope, nil, errors.New("Invalid transaction ID")
+			}
+			selected[pgtype.UUID{Bytes: transactionUUID, Valid: true}] = true
+		}
+	}
+	return req, scope, selected, nil
+}
+
+// Rule application handler functions
+
+// @Summary Preview rule application
+// @Description Re-evaluate the categorization rules against existing transactions without saving anything. Rules see the CSV category each transaction was imported with. Returns each transaction whose splits would change, or whose people would for rules that assign people, with its current and proposed splits and people. Transactions whose splits were edited by hand, or split into several rows that no rule created, are marked protected.
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param request body ruleApplicationRequest false "scope (active, archived or all; defaults to active)"
+// @Success 200 {object} map[string]interface{} "Preview - returns changes array, would_apply and protected counts"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/rules/apply/preview [post]
+func previewRuleApplication(c *gin.Context) {
+	_, scope, _, err := bindRuleApplicationRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plans, err := planRuleApplication(context.Background(), queries, scope)
+	if err != nil {
+		log.Printf("Error planning rule application: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error previewing rule application"})
+		return
+	}
+
+	changes := make([]RuleApplicationChange, 0, len(plans))
+	protected := 0
+	for _, plan := range plans {
+		changes = append(changes, convertRuleApplication(plan))
+		if plan.ProtectedReason != "" {
+			protected++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"changes":     changes,
+		"would_apply": len(changes) - protected,
+		"protected":   protected,
+	})
+}
+
+// @Summary Apply rules to existing transactions
+// @Description Re-evaluate the categorization rules against existing transactions and replace the splits, and for rules that assign people the people, of those that change, in a single database transaction. transaction_ids limits the changes to a chosen subset of the preview. Protected transactions are skipped unless include_protected is true.
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param request body ruleApplicationRequest false "scope (active, archived or all; defaults to active), transaction_ids and include_protected"
+// @Success 200 {object} map[string]interface{} "Result - returns applied and skipped counts and the applied changes"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/rules/apply [post]
+func applyRules(c *gin.Context) {
+	req, scope, selected, err := bindRuleApplicationRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error applying rules"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	// Changes are planned again inside the transaction so the splits replaced are
+	// the ones the decision was based on
+	plans, err := planRuleApplication(ctx, qtx, scope)
+	if err != nil {
+		log.Printf("Error planning rule application: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error applying rules"})
+		return
+	}
+
+	changes, skipped, err := applyRuleChanges(ctx, qtx, plans, func(plan plannedRuleApplication) bool {
+		return selected == nil || selected[plan.Transaction.ID]
+	}, req.IncludeProtected)
+	if err != nil {
+		log.Printf("Error applying rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error applying rules"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing rule application: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error applying rules"})
+		return
+	}
+	// Protected transactions the rules replaced are no longer hand-edited
+	categoryClassifiers.invalidate()
+
+	c.JSON(http.StatusOK, gin.H{
+		"applied": len(changes),
+		"skipped": skipped,
+		"changes": changes,
+	})
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSameSplits(t *testing.T) {
+	food := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	shopping := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+
+	tests := []struct {
+		name string
+		a, b []plannedSplit
+		want bool
+	}{
+		{"both empty", nil, nil, true},
+		{"same single split", []plannedSplit{{CategoryID: food, Cents: 1000}}, []plannedSplit{{CategoryID: food, Cents: 1000}}, true},
+		{"different category", []plannedSplit{{CategoryID: food, Cents: 1000}}, []plannedSplit{{CategoryID: shopping, Cents: 1000}}, false},
+		{"different amount", []plannedSplit{{CategoryID: food, Cents: 1000}}, []plannedSplit{{CategoryID: food, Cents: 999}}, false},
+		{"different count", []plannedSplit{{CategoryID: food, Cents: 1000}}, []plannedSplit{{CategoryID: food, Cents: 500}, {CategoryID: food, Cents: 500}}, false},
+		{
+			"order ignored",
+			[]plannedSplit{{CategoryID: food, Cents: 600}, {CategoryID: shopping, Cents: 400}},
+			[]plannedSplit{{CategoryID: shopping, Cents: 400}, {CategoryID: food, Cents: 600}},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sameSplits(tt.a, tt.b))
+		})
+	}
+}
+
+func TestKeepSplitDetails(t *testing.T) {
+	food := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	shopping := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	other := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	alice := []pgtype.UUID{{Bytes: uuid.New(), Valid: true}}
+	gift := pgtype.Text{String: "gift", Valid: true}
+	tape := pgtype.Text{String: "tape", Valid: true}
+
+	t.Run("keeps the details of a split in the same category", func(t *testing.T) {
+		current := []plannedSplit{
+			{CategoryID: other, Cents: 1000, Notes: tape},
+			{CategoryID: shopping, Cents: 2000, Notes: gift, AssignedTo: alice},
+		}
+		result := keepSplitDetails(current, []plannedSplit{{CategoryID: shopping, Cents: 3000}})
+		assert.Len(t, result, 1)
+		assert.Equal(t, gift, result[0].Notes)
+		assert.Equal(t, alice, result[0].AssignedTo)
+		assert.Equal(t, int64(3000), result[0].Cents)
+	})
+
+	t.Run("keeps the details of a re-categorized split", func(t *testing.T) {
+		current := []plannedSplit{{CategoryID: other, Cents: 1000, Notes: gift, AssignedTo: alice}}
+		result := keepSplitDetails(current, []plannedSplit{{CategoryID: food, Cents: 1000}})
+		assert.Equal(t, food, result[0].CategoryID)
+		assert.Equal(t, gift, result[0].Notes)
+		assert.Equal(t, alice, result[0].AssignedTo)
+	})
+
+	t.Run("leaves extra proposed splits without details", func(t *testing.T) {
+		current := []plannedSplit{{CategoryID: other, Cents: 1000, Notes: gift}}
+		proposed := []plannedSplit{{CategoryID: food, Cents: 500}, {CategoryID: other, Cents: 500}}
+		result := keepSplitDetails(current, proposed)
+		assert.False(t, result[0].Notes.Valid)
+		assert.Equal(t, gift, result[1].Notes)
+		assert.False(t, proposed[1].Notes.Valid, "the proposal is not modified")
+	})
+}
+
+func TestRuleApplication(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	foodID := testCategoryID("Food & Dining")
+	shoppingID := testCategoryID("Shopping")
+	otherID := testCategoryID("Other")
+
+	// Imported before any rule exists, so every row is categorized as Other
+	csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-04-01,2024-04-02,1234,CORNER COFFEE,,4.50,
+2024-04-02,2024-04-03,1234,CITY BOOKSTORE,,30.00,
+2024-04-03,2024-04-04,1234,HARDWARE STORE,,12.00,`
+	resp := uploadTestFile(t, "apply.csv", csvContent, nil)
+	assertStatusCode(t, http.StatusOK, resp.Code)
+
+	var imported struct {
+		Transactions []Transaction `json:"transactions"`
+	}
+	assertNoError(t, parseJSONResponse(resp, &imported))
+	if len(imported.Transactions) != 3 {
+		t.Fatalf("Expected 3 transactions, got %d", len(imported.Transactions))
+	}
+	coffeeID := imported.Transactions[0].ID
+	bookstoreID := imported.Transactions[1].ID
+
+	personID, err := createTestPerson("Apply Alice", "")
+	assertNoError(t, err)
+
+	// The bookstore purchase is split by hand before the rules are added
+	splitBody, err := json.Marshal(map[string]interface{}{
+		"splits": []map[string]interface{}{
+			{"category_id": shoppingID, "amount": 20.00, "notes": "gift", "assigned_to": []string{personID}},
+			{"category_id": otherID, "amount": 10.00},
+		},
+	})
+	assertNoError(t, err)
+	resp = makeRequest("PUT", "/api/transactions/"+bookstoreID+"/splits", bytes.NewBuffer(splitBody))
+	assertStatusCode(t, http.StatusOK, resp.Code)
+
+	_, err = createTestRule("COFFEE", foodID, 0)
+	assertNoError(t, err)
+	_, err = createTestRule("BOOKSTORE", shoppingID, 1)
+	assertNoError(t, err)
+
+	post := func(url string, requestBody map[string]interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(requestBody)
+		assertNoError(t, err)
+		return makeRequest("POST", url, bytes.NewBuffer(body))
+	}
+	changesByID := func(changes []RuleApplicationChange) map[string]RuleApplicationChange {
+		byID := make(map[string]RuleApplicationChange, len(changes))
+		for _, change := range changes {
+			byID[change.TransactionID] = change
+		}
+		return byID
+	}
+
+	t.Run("should preview split changes and protect edited transactions", func(t *testing.T) {
+		resp := post("/api/rules/apply/preview", map[string]interface{}{})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var preview struct {
+			Changes    []RuleApplicationChange `json:"changes"`
+			WouldApply int                     `json:"would_apply"`
+			Protected  int                     `json:"protected"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &preview))
+		if len(preview.Changes) != 2 {
+			t.Fatalf("Expected 2 changes, got %d", len(preview.Changes))
+		}
+		if preview.WouldApply != 1 || preview.Protected != 1 {
+			t.Errorf("Expected would_apply 1 and protected 1, got %d and %d", preview.WouldApply, preview.Protected)
+		}
+
+		changes := changesByID(preview.Changes)
+		coffee, exists := changes[coffeeID]
+		if !exists {
+			t.Fatalf("Expected a change for the coffee transaction")
+		}
+		if coffee.Protected {
+			t.Errorf("Expected coffee change not to be protected")
+		}
+		if len(coffee.CurrentSplits) != 1 || coffee.CurrentSplits[0].CategoryName != "Other" {
+			t.Errorf("Expected current split Other, got %+v", coffee.CurrentSplits)
+		}
+		if len(coffee.ProposedSplits) != 1 || coffee.ProposedSplits[0].CategoryName != "Food & Dining" || coffee.ProposedSplits[0].Amount != 4.50 {
+			t.Errorf("Expected proposed split 4.50 Food & Dining, got %+v", coffee.ProposedSplits)
+		}
+
+		bookstore := changes[bookstoreID]
+		if !bookstore.Protected || bookstore.ProtectedReason == nil || *bookstore.ProtectedReason != protectedManuallyEdited {
+			t.Errorf("Expected bookstore change to be protected as manually edited, got %+v", bookstore)
+		}
+	})
+
+	t.Run("should apply only the chosen transactions", func(t *testing.T) {
+		resp := post("/api/rules/apply", map[string]interface{}{
+			"transaction_ids": []string{coffeeID},
+		})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result struct {
+			Applied int `json:"applied"`
+			Skipped int `json:"skipped"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if result.Applied != 1 || result.Skipped != 0 {
+			t.Errorf("Expected 1 applied and 0 skipped, got %d and %d", result.Applied, result.Skipped)
+		}
+
+		resp = makeRequest("GET", "/api/transactions/"+coffeeID+"/splits", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var splits []TransactionSplit
+		assertNoError(t, parseJSONResponse(resp, &splits))
+		if len(splits) != 1 || splits[0].CategoryID != foodID {
+			t.Errorf("Expected coffee to be split to Food & Dining, got %+v", splits)
+		}
+	})
+
+	t.Run("should skip protected transactions unless overridden", func(t *testing.T) {
+		resp := post("/api/rules/apply", map[string]interface{}{})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result struct {
+			Applied int `json:"applied"`
+			Skipped int `json:"skipped"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if result.Applied != 0 || result.Skipped != 1 {
+			t.Errorf("Expected 0 applied and 1 skipped, got %d and %d", result.Applied, result.Skipped)
+		}
+
+		resp = post("/api/rules/apply", map[string]interface{}{"include_protected": true})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if result.Applied != 1 {
+			t.Errorf("Expected 1 applied, got %d", result.Applied)
+		}
+
+		// The re-created split keeps the notes and people of the split it replaces
+		resp = makeRequest("GET", "/api/transactions/"+bookstoreID+"/splits", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var splits []TransactionSplit
+		assertNoError(t, parseJSONResponse(resp, &splits))
+		if len(splits) != 1 || splits[0].CategoryID != shoppingID || splits[0].Notes == nil || *splits[0].Notes != "gift" ||
+			len(splits[0].AssignedTo) != 1 || splits[0].AssignedTo[0] != "Apply Alice" {
+			t.Errorf("Expected one Shopping split with the note and person kept, got %+v", splits)
+		}
+
+		// Nothing is left to change once every change is applied
+		resp = post("/api/rules/apply/preview", map[string]interface{}{})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var preview struct {
+			Changes []RuleApplicationChange `json:"changes"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &preview))
+		if len(preview.Changes) != 0 {
+			t.Errorf("Expected no changes, got %d", len(preview.Changes))
+		}
+	})
+
+	t.Run("should only consider archived transactions for the archived scope", func(t *testing.T) {
+		resp := post("/api/rules/apply/preview", map[string]interface{}{"scope": "archived"})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var preview struct {
+			Changes []RuleApplicationChange `json:"changes"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &preview))
+		if len(preview.Changes) != 0 {
+			t.Errorf("Expected no archived changes, got %d", len(preview.Changes))
+		}
+	})
+
+	t.Run("should return 400 for invalid requests", func(t *testing.T) {
+		invalid := []map[string]interface{}{
+			{"scope": "everything"},
+			{"transaction_ids": []string{"not-a-uuid"}},
+		}
+		for _, requestBody := range invalid {
+			resp := post("/api/rules/apply", requestBody)
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %v, got %d", requestBody, resp.Code)
+			}
+		}
+	})
+}
+
+// TestRuleApplicationCategoryAndPeople tests re-applying rules that match the CSV
+// category and assign people
+func TestRuleApplicationCategoryAndPeople(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	foodID := testCategoryID("Food & Dining")
+	aliceID, err := createTestPerson("Category Alice", "")
+	assertNoError(t, err)
+	bobID, err := createTestPerson("Category Bob", "")
+	assertNoError(t, err)
+
+	// Imported before any rule exists, so every row is categorized as Other
+	csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-04-01,2024-04-02,1234,BLUE ROCK MARKET,Groceries,25.00,
+2024-04-02,2024-04-03,1234,HARDWARE STORE,Home,12.00,`
+	resp := uploadTestFile(t, "category.csv", csvContent, nil)
+	assertStatusCode(t, http.StatusOK, resp.Code)
+	var imported struct {
+		Transactions []Transaction `json:"transactions"`
+	}
+	assertNoError(t, parseJSONResponse(resp, &imported))
+	if len(imported.Transactions) != 2 {
+		t.Fatalf("Expected 2 transactions, got %d", len(imported.Transactions))
+	}
+	marketID := imported.Transactions[0].ID
+
+	body, err := json.Marshal(map[string]interface{}{"assigned_to": []string{aliceID}})
+	assertNoError(t, err)
+	resp = makeRequest("PUT", "/api/transactions/"+marketID+"/assign", bytes.NewBuffer(body))
+	assertStatusCode(t, http.StatusOK, resp.Code)
+
+	body, err = json.Marshal(map[string]interface{}{
+		"match_value": "groceries",
+		"match_field": ruleFieldCategory,
+		"category_id": foodID,
+		"priority":    0,
+		"assign_to":   []string{bobID},
+	})
+	assertNoError(t, err)
+	resp = makeRequest("POST", "/api/rules", bytes.NewBuffer(body))
+	assertStatusCode(t, http.StatusCreated, resp.Code)
+
+	t.Run("should match the CSV category and propose the rule's people", func(t *testing.T) {
+		resp := makeRequest("POST", "/api/rules/apply/preview", bytes.NewBufferString(`{}`))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var preview struct {
+			Changes []RuleApplicationChange `json:"changes"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &preview))
+		if len(preview.Changes) != 1 || preview.Changes[0].TransactionID != marketID {
+			t.Fatalf("Expected a change for the market transaction only, got %+v", preview.Changes)
+		}
+		change := preview.Changes[0]
+		if len(change.ProposedSplits) != 1 || change.ProposedSplits[0].CategoryName != "Food & Dining" {
+			t.Errorf("Expected proposed split Food & Dining, got %+v", change.ProposedSplits)
+		}
+		assert.Equal(t, []string{"Category Alice"}, change.CurrentPeople)
+		assert.Equal(t, []string{"Category Bob"}, change.ProposedPeople)
+	})
+
+	t.Run("should reassign the transaction when applied", func(t *testing.T) {
+		resp := makeRequest("POST", "/api/rules/apply", bytes.NewBufferString(`{}`))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		resp = makeRequest("GET", "/api/transactions", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var transactions []Transaction
+		assertNoError(t, parseJSONResponse(resp, &transactions))
+		for _, transaction := range transactions {
+			if transaction.ID == marketID {
+				assert.Equal(t, []string{"Category Bob"}, transaction.AssignedTo)
+			}
+		}
+
+		// A rule that now agrees with the transaction proposes nothing
+		resp = makeRequest("POST", "/api/rules/apply/preview", bytes.NewBufferString(`{}`))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var preview struct {
+			Changes []RuleApplicationChange `json:"changes"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &preview))
+		assert.Empty(t, preview.Changes)
+	})
+}
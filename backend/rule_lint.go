@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"jointanalysis/db/generated"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Rule warning types
+const (
+	ruleWarningShadowed = "shadowed"
+	ruleWarningConflict = "conflict"
+)
+
+// ruleTextCovers reports whether every text rule b's match value matches is also
+// matched by rule a's. Regex patterns are only compared for equality.
+func ruleTextCovers(a, b generated.GetRulesRow) bool {
+	x := strings.ToLower(strings.TrimSpace(a.MatchValue))
+	y := strings.ToLower(strings.TrimSpace(b.MatchValue))
+	if a.MatchType == ruleMatchRegex || b.MatchType == ruleMatchRegex {
+		return a.MatchType == b.MatchType && a.MatchValue == b.MatchValue
+	}
+
+	switch a.MatchType {
+	case ruleMatchExact:
+		return b.MatchType == ruleMatchExact && x == y
+	case ruleMatchPrefix:
+		return (b.MatchType == ruleMatchPrefix || b.MatchType == ruleMatchExact) && strings.HasPrefix(y, x)
+	case ruleMatchSuffix:
+		return (b.MatchType == ruleMatchSuffix || b.MatchType == ruleMatchExact) && strings.HasSuffix(y, x)
+	default:
+		// Any text containing, starting or ending with y, or equal to it, contains y
+		return strings.Contains(y, x)
+	}
+}
+
+// ruleTextOverlaps reports whether the two rules' match values can match the same
+// text, judged by whether either rule matches the other's match value
+func ruleTextOverlaps(a, b generated.GetRulesRow) bool {
+	ruleA := compileRule(generated.GetRulesForMatchingRow{MatchType: a.MatchType, MatchValue: a.MatchValue})
+	ruleB := compileRule(generated.GetRulesForMatchingRow{MatchType: b.MatchType, MatchValue: b.MatchValue})
+	return ruleA.matches(newRuleText(b.MatchValue)) || ruleB.matches(newRuleText(a.MatchValue))
+}
+
+// ruleFieldCovers reports whether a rule tested against field a sees every field
+// a rule tested against field b does
+func ruleFieldCovers(a, b string) bool {
+	return a == ruleFieldAny || a == b
+}
+
+// ruleFieldsOverlap reports whether two rules test at least one common field
+func ruleFieldsOverlap(a, b string) bool {
+	return a == ruleFieldAny || b == ruleFieldAny || a == b
+}
+
+// numericValue returns a numeric's value and whether it is set
+func numericValue(n pgtype.Numeric) (float64, bool) {
+	if !n.Valid {
+		return 0, false
+	}
+	value, err := n.Float64Value()
+	if err != nil {
+		return 0, false
+	}
+	return value.Float64, true
+}
+
+// ruleConditionsCover reports whether rule a's conditions hold for every
+// transaction that meets rule b's: each condition a has, b has at least as strictly
+func ruleConditionsCover(a, b generated.GetRulesRow) bool {
+	if aMin, ok := numericValue(a.MinAmount); ok {
+		if bMin, ok := numericValue(b.MinAmount); !ok || bMin < aMin {
+			return false
+		}
+	}
+	if aMax, ok := numericValue(a.MaxAmount); ok {
+		if bMax, ok := numericValue(b.MaxAmount); !ok || bMax > aMax {
+			return false
+		}
+	}
+	if a.Direction.Valid && a.Direction != b.Direction {
+		return false
+	}
+	// Card numbers match the end of the transaction's card
+	if a.CardNumber.Valid && (!b.CardNumber.Valid || !strings.HasSuffix(b.CardNumber.String, a.CardNumber.String)) {
+		return false
+	}
+	if a.StartDate.Valid && (!b.StartDate.Valid || b.StartDate.Time.Before(a.StartDate.Time)) {
+		return false
+	}
+	if a.EndDate.Valid && (!b.EndDate.Valid || b.EndDate.Time.After(a.EndDate.Time)) {
+		return false
+	}
+	return true
+}
+
+// ruleConditionsOverlap reports whether some transaction can meet both rules'
+// conditions
+func ruleConditionsOverlap(a, b generated.GetRulesRow) bool {
+	lowest := func(x, y pgtype.Numeric) (float64, bool) {
+		xv, xok := numericValue(x)
+		yv, yok := numericValue(y)
+		switch {
+		case xok && yok:
+			return max(xv, yv), true
+		case xok:
+			return xv, true
+		default:
+			return yv, yok
+		}
+	}
+	highest := func(x, y pgtype.Numeric) (float64, bool) {
+		xv, xok := numericValue(x)
+		yv, yok := numericValue(y)
+		switch {
+		case xok && yok:
+			return min(xv, yv), true
+		case xok:
+			return xv, true
+		default:
+			return yv, yok
+		}
+	}
+	if low, ok := lowest(a.MinAmount, b.MinAmount); ok {
+		if high, ok := highest(a.MaxAmount, b.MaxAmount); ok && low > high {
+			return false
+		}
+	}
+
+	if a.Direction.Valid && b.Direction.Valid && a.Direction != b.Direction {
+		return false
+	}
+	if a.CardNumber.Valid && b.CardNumber.Valid &&
+		!strings.HasSuffix(a.CardNumber.String, b.CardNumber.String) &&
+		!strings.HasSuffix(b.CardNumber.String, a.CardNumber.String) {
+		return false
+	}
+
+	start, end := a.StartDate, a.EndDate
+	if b.StartDate.Valid && (!start.Valid || b.StartDate.Time.After(start.Time)) {
+		start = b.StartDate
+	}
+	if b.EndDate.Valid && (!end.Valid || b.EndDate.Time.Before(end.Time)) {
+		end = b.EndDate
+	}
+	return !(start.Valid && end.Valid && start.Time.After(end.Time))
+}
+
+// lintRules analyses a rule set in evaluation order (priority, then creation).
+// A rule is shadowed when an earlier rule matches every transaction it matches,
+// so it can never win. Two rules conflict when they have the same priority, can
+// match the same transaction and assign different categories, so the winner
+// depends only on which was created first.
+func lintRules(rules []generated.GetRulesRow) []RuleWarning {
+	warnings := make([]RuleWarning, 0)
+	shadowed := make(map[int]bool)
+
+	for j, b := range rules {
+		for i := 0; i < j; i++ {
+			a := rules[i]
+			if ruleTextCovers(a, b) && ruleFieldCovers(a.MatchField, b.MatchField) && ruleConditionsCover(a, b) {
+				warnings = append(warnings, RuleWarning{
+					Type:        ruleWarningShadowed,
+					RuleID:      uuid.UUID(b.ID.Bytes).String(),
+					OtherRuleID: uuid.UUID(a.ID.Bytes).String(),
+					Message: fmt.Sprintf("Rule %q can never match: rule %q (priority %d) is tried first and matches everything it does",
+						b.MatchValue, a.MatchValue, a.Priority),
+				})
+				shadowed[j] = true
+				break
+			}
+		}
+	}
+
+	for j, b := range rules {
+		if shadowed[j] {
+			continue
+		}
+		for i := 0; i < j; i++ {
+			a := rules[i]
+			if a.Priority != b.Priority || a.CategoryID == b.CategoryID {
+				continue
+			}
+			if ruleTextOverlaps(a, b) && ruleFieldsOverlap(a.MatchField, b.MatchField) && ruleConditionsOverlap(a, b) {
+				warnings = append(warnings, RuleWarning{
+					Type:        ruleWarningConflict,
+					RuleID:      uuid.UUID(b.ID.Bytes).String(),
+					OtherRuleID: uuid.UUID(a.ID.Bytes).String(),
+					Message: fmt.Sprintf("Rules %q (%s) and %q (%s) have the same priority %d and can match the same transaction; the older rule %q wins",
+						a.MatchValue, a.CategoryName, b.MatchValue, b.CategoryName, a.Priority, a.MatchValue),
+				})
+			}
+		}
+	}
+
+	return warnings
+}
+
+// ruleWarnings lints the current rule set and returns the warnings involving the
+// given rule
+func ruleWarnings(ctx context.Context, ruleID string) ([]RuleWarning, error) {
+	rules, err := queries.GetRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := make([]RuleWarning, 0)
+	for _, warning := range lintRules(rules) {
+		if warning.RuleID == ruleID || warning.OtherRuleID == ruleID {
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings, nil
+}
+
+// @Summary Lint rules
+// @Description Analyse the whole rule set for problems: rules that can never match because an earlier rule matches everything they do (shadowed), and rules with the same priority that can match the same transaction but assign different categories (conflict)
+// @Tags rules
+// @Produce json
+// @Success 200 {array} RuleWarning "Warnings, empty when the rule set has no problems"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/rules/lint [get]
+func lintRuleSet(c *gin.Context) {
+	rules, err := queries.GetRules(context.Background())
+	if err != nil {
+		log.Printf("Error fetching rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lintRules(rules))
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"jointanalysis/db/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintRules(t *testing.T) {
+	gas := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	travel := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	numeric := func(value string) pgtype.Numeric {
+		var n pgtype.Numeric
+		require.NoError(t, n.Scan(value))
+		return n
+	}
+	date := func(value string) pgtype.Date {
+		parsed, err := time.Parse("2006-01-02", value)
+		require.NoError(t, err)
+		return pgtype.Date{Time: parsed, Valid: true}
+	}
+	rule := func(matchValue, matchType string, priority int32, categoryID pgtype.UUID) generated.GetRulesRow {
+		return generated.GetRulesRow{
+			ID:         pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			MatchValue: matchValue,
+			MatchType:  matchType,
+			MatchField: ruleFieldAny,
+			Priority:   priority,
+			CategoryID: categoryID,
+		}
+	}
+	lint := func(rules ...generated.GetRulesRow) []string {
+		types := make([]string, 0)
+		for _, warning := range lintRules(rules) {
+			types = append(types, warning.Type+" "+warning.RuleID)
+		}
+		return types
+	}
+	id := func(r generated.GetRulesRow) string {
+		return uuid.UUID(r.ID.Bytes).String()
+	}
+
+	t.Run("should flag a rule containing an earlier rule's value", func(t *testing.T) {
+		first := rule("Gas", ruleMatchContains, 0, gas)
+		second := rule("Gas Station", ruleMatchContains, 1, travel)
+		assert.Equal(t, []string{"shadowed " + id(second)}, lint(first, second))
+
+		// The other way round both can win
+		assert.Empty(t, lint(second, first))
+	})
+
+	t.Run("should compare match types", func(t *testing.T) {
+		prefix := rule("SHELL", ruleMatchPrefix, 0, gas)
+		assert.NotEmpty(t, lint(prefix, rule("shell oil 123", ruleMatchExact, 1, travel)))
+		assert.NotEmpty(t, lint(prefix, rule("SHELL OIL", ruleMatchPrefix, 1, travel)))
+		assert.Empty(t, lint(prefix, rule("OIL SHELL", ruleMatchContains, 1, travel)))
+		assert.Empty(t, lint(rule("SHELL", ruleMatchExact, 0, gas), rule("SHELL", ruleMatchContains, 1, travel)))
+		assert.NotEmpty(t, lint(rule("^SHELL", ruleMatchRegex, 0, gas), rule("^SHELL", ruleMatchRegex, 1, travel)))
+		assert.Empty(t, lint(rule("SHELL", ruleMatchRegex, 0, gas), rule("SHELL OIL", ruleMatchContains, 1, travel)))
+	})
+
+	t.Run("should respect fields and conditions", func(t *testing.T) {
+		first := rule("Gas", ruleMatchContains, 0, gas)
+		first.MatchField = ruleFieldDescription
+		second := rule("Gas Station", ruleMatchContains, 1, travel)
+		assert.Empty(t, lint(first, second), "an any-field rule can still match on the category")
+
+		first = rule("Gas", ruleMatchContains, 0, gas)
+		first.MaxAmount = numeric("50")
+		assert.Empty(t, lint(first, second), "a bounded rule does not cover an unbounded one")
+		second.MaxAmount = numeric("20")
+		assert.NotEmpty(t, lint(first, second))
+
+		first.CardNumber = pgtype.Text{String: "1234", Valid: true}
+		second.CardNumber = pgtype.Text{String: "00001234", Valid: true}
+		assert.NotEmpty(t, lint(first, second))
+
+		first.StartDate = date("2024-01-01")
+		assert.Empty(t, lint(first, second))
+		second.StartDate = date("2024-06-01")
+		assert.NotEmpty(t, lint(first, second))
+	})
+
+	t.Run("should flag conflicting rules with the same priority", func(t *testing.T) {
+		first := rule("AMAZON", ruleMatchPrefix, 1, gas)
+		second := rule("AMAZON PRIME", ruleMatchContains, 1, travel)
+		assert.Equal(t, []string{"conflict " + id(second)}, lint(first, second))
+
+		second.CategoryID = gas
+		assert.Empty(t, lint(first, second), "same category")
+
+		second.CategoryID = travel
+		second.Priority = 2
+		assert.Empty(t, lint(first, second), "different priority")
+
+		second.Priority = 1
+		first.Direction = pgtype.Text{String: ruleDirectionDebit, Valid: true}
+		second.Direction = pgtype.Text{String: ruleDirectionCredit, Valid: true}
+		assert.Empty(t, lint(first, second), "disjoint conditions")
+
+		first.Direction = pgtype.Text{}
+		second.Direction = pgtype.Text{}
+		first.MinAmount = numeric("100")
+		second.MaxAmount = numeric("50")
+		assert.Empty(t, lint(first, second), "disjoint amounts")
+	})
+}
+
+func TestRuleWarnings(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	transportationID := testCategoryID("Transportation")
+	shoppingID := testCategoryID("Shopping")
+	gasRuleID, err := createTestRule("Gas", transportationID, 0)
+	assertNoError(t, err)
+
+	var stationRule Rule
+	t.Run("should return warnings when a rule is created", func(t *testing.T) {
+		body, err := json.Marshal(map[string]interface{}{
+			"match_value": "Gas Station Snacks",
+			"category_id": shoppingID,
+			"priority":    1,
+		})
+		assertNoError(t, err)
+		resp := makeRequest("POST", "/api/rules", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		assertNoError(t, parseJSONResponse(resp, &stationRule))
+		if len(stationRule.Warnings) != 1 {
+			t.Fatalf("Expected 1 warning, got %+v", stationRule.Warnings)
+		}
+		warning := stationRule.Warnings[0]
+		if warning.Type != ruleWarningShadowed || warning.RuleID != stationRule.ID || warning.OtherRuleID != gasRuleID {
+			t.Errorf("Expected the rule to be shadowed by Gas, got %+v", warning)
+		}
+	})
+
+	t.Run("should clear warnings when the rule is fixed", func(t *testing.T) {
+		body, err := json.Marshal(map[string]interface{}{
+			"match_value": "Gas Station Snacks",
+			"match_type":  ruleMatchContains,
+			"category_id": shoppingID,
+			"priority":    -1,
+		})
+		assertNoError(t, err)
+		resp := makeRequest("PUT", "/api/rules/"+stationRule.ID, bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var updated Rule
+		assertNoError(t, parseJSONResponse(resp, &updated))
+		if len(updated.Warnings) != 0 {
+			t.Errorf("Expected no warnings, got %+v", updated.Warnings)
+		}
+	})
+
+	t.Run("should lint the whole rule set", func(t *testing.T) {
+		_, err := createTestRule("Gas", shoppingID, 0)
+		assertNoError(t, err)
+
+		resp := makeRequest("GET", "/api/rules/lint", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var warnings []RuleWarning
+		assertNoError(t, parseJSONResponse(resp, &warnings))
+		if len(warnings) != 1 || warnings[0].Type != ruleWarningShadowed || warnings[0].OtherRuleID != gasRuleID {
+			t.Errorf("Expected the duplicate Gas rule to be shadowed, got %+v", warnings)
+		}
+	})
+}
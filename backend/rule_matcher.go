@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"jointanalysis/db/generated"
+)
+
+// ahoCorasick finds every pattern occurring in a text in a single pass over it,
+// however many patterns there are. Patterns and texts are compared byte by byte,
+// so callers lower-case both.
+type ahoCorasick struct {
+	nodes []ahoCorasickNode
+}
+
+// ahoCorasickNode is a trie node. fail points to the node for the longest proper
+// suffix of this node's path that is also a path in the trie, and outputs lists
+// the values of every pattern ending here, including through fail links.
+type ahoCorasickNode struct {
+	next    map[byte]int32
+	fail    int32
+	outputs []int
+}
+
+// newAhoCorasick builds an automaton that reports values[i] wherever patterns[i]
+// occurs
+func newAhoCorasick(patterns []string, values []int) *ahoCorasick {
+	ac := &ahoCorasick{nodes: []ahoCorasickNode{{next: make(map[byte]int32)}}}
+	for i, pattern := range patterns {
+		node := int32(0)
+		for j := 0; j < len(pattern); j++ {
+			child, exists := ac.nodes[node].next[pattern[j]]
+			if !exists {
+				child = int32(len(ac.nodes))
+				ac.nodes = append(ac.nodes, ahoCorasickNode{next: make(map[byte]int32)})
+				ac.nodes[node].next[pattern[j]] = child
+			}
+			node = child
+		}
+		ac.nodes[node].outputs = append(ac.nodes[node].outputs, values[i])
+	}
+
+	// Breadth-first, so a node's fail target is complete before its children's
+	queue := make([]int32, 0, len(ac.nodes))
+	for _, child := range ac.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for c, child := range ac.nodes[node].next {
+			fail := ac.nodes[node].fail
+			for fail != 0 && !ac.hasNext(fail, c) {
+				fail = ac.nodes[fail].fail
+			}
+			if target, exists := ac.nodes[fail].next[c]; exists {
+				fail = target
+			}
+			ac.nodes[child].fail = fail
+			ac.nodes[child].outputs = append(ac.nodes[child].outputs, ac.nodes[fail].outputs...)
+			queue = append(queue, child)
+		}
+	}
+	return ac
+}
+
+// hasNext reports whether node has a child for byte c
+func (ac *ahoCorasick) hasNext(node int32, c byte) bool {
+	_, exists := ac.nodes[node].next[c]
+	return exists
+}
+
+// search returns the values of the patterns occurring in text, sorted and without
+// duplicates
+func (ac *ahoCorasick) search(text string) []int {
+	var found []int
+	node := int32(0)
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		for node != 0 && !ac.hasNext(node, c) {
+			node = ac.nodes[node].fail
+		}
+		if next, exists := ac.nodes[node].next[c]; exists {
+			node = next
+		}
+		found = append(found, ac.nodes[node].outputs...)
+	}
+	if len(found) < 2 {
+		return found
+	}
+
+	sort.Ints(found)
+	unique := found[:1]
+	for _, value := range found[1:] {
+		if value != unique[len(unique)-1] {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// compiledRule is a rule prepared for matching: its match value normalized once,
+// its regex compiled and its category resolved. scanned is set for contains rules
+// found by the matcher's automaton.
+type compiledRule struct {
+	rule     generated.GetRulesForMatchingRow
+	category *generated.GetCategoriesRow
+	value    string
+	pattern  *regexp.Regexp
+	scanned  bool
+}
+
+// ruleText is a field of a record in the forms the match types compare
+type ruleText struct {
+	raw     string
+	lower   string
+	trimmed string
+}
+
+func newRuleText(text string) ruleText {
+	return ruleText{
+		raw:     text,
+		lower:   strings.ToLower(text),
+		trimmed: strings.ToLower(strings.TrimSpace(text)),
+	}
+}
+
+// compileRule prepares a rule's match value for matching. Its category is left for
+// the caller to resolve. A regex that does not compile matches nothing.
+func compileRule(rule generated.GetRulesForMatchingRow) compiledRule {
+	compiled := compiledRule{rule: rule}
+	switch rule.MatchType {
+	case ruleMatchExact:
+		compiled.value = strings.TrimSpace(rule.MatchValue)
+	case ruleMatchRegex:
+		pattern, err := compileRulePattern(rule.MatchValue)
+		if err != nil {
+			log.Printf("Warning: skipping rule with invalid pattern %q: %v", rule.MatchValue, err)
+		}
+		compiled.pattern = pattern
+	default:
+		compiled.value = strings.ToLower(rule.MatchValue)
+	}
+	return compiled
+}
+
+// matches reports whether text matches the rule's match value. All match types
+// are case-insensitive; exact, prefix and suffix ignore surrounding whitespace.
+func (r *compiledRule) matches(text ruleText) bool {
+	switch r.rule.MatchType {
+	case ruleMatchExact:
+		return strings.EqualFold(strings.TrimSpace(text.raw), r.value)
+	case ruleMatchPrefix:
+		return strings.HasPrefix(text.trimmed, r.value)
+	case ruleMatchSuffix:
+		return strings.HasSuffix(text.trimmed, r.value)
+	case ruleMatchRegex:
+		return r.pattern != nil && r.pattern.MatchString(text.raw)
+	default:
+		return strings.Contains(text.lower, r.value)
+	}
+}
+
+// ruleMatcher categorizes records with a compiled rule set, giving the same
+// results as testing each rule in priority order. Contains
+// rules, usually most of a rule set, are found with one Aho-Corasick scan of each
+// field; other rules are tested one by one. A ruleMatcher is never modified after
+// it is built, so concurrent requests can share it.
+type ruleMatcher struct {
+	mapping  *CategoryMapping
+	rules    []compiledRule
+	others   []int
+	contains *ahoCorasick
+	fallback *generated.GetCategoriesRow
+}
+
+// compileRules builds a matcher for rules, which must be in priority order. Rules
+// whose category is not in the mapping can never be chosen and are left out.
+func (cm *CategoryMapping) compileRules(rules []generated.GetRulesForMatchingRow) *ruleMatcher {
+	matcher := &ruleMatcher{mapping: cm}
+	if cm == nil {
+		matcher.contains = newAhoCorasick(nil, nil)
+		return matcher
+	}
+
+	if other, exists := cm.categoriesByName["Other"]; exists {
+		matcher.fallback = &other
+	}
+
+	var patterns []string
+	var indexes []int
+	for _, rule := range rules {
+		category, exists := cm.categoriesByID[rule.CategoryID]
+		if !exists {
+			continue
+		}
+		compiled := compileRule(rule)
+		compiled.category = category
+		index := len(matcher.rules)
+
+		switch rule.MatchType {
+		case ruleMatchExact, ruleMatchPrefix, ruleMatchSuffix, ruleMatchRegex:
+			matcher.others = append(matcher.others, index)
+		default:
+			if compiled.value == "" {
+				matcher.others = append(matcher.others, index)
+				break
+			}
+			compiled.scanned = true
+			patterns = append(patterns, compiled.value)
+			indexes = append(indexes, index)
+		}
+		matcher.rules = append(matcher.rules, compiled)
+	}
+	matcher.contains = newAhoCorasick(patterns, indexes)
+
+	return matcher
+}
+
+// match returns the category for a record and the rule that selected it. The rule
+// is nil when no rule matched and "Other" was used.
+func (m *ruleMatcher) match(record importRecord) (*generated.GetCategoriesRow, *generated.GetRulesForMatchingRow) {
+	if hits := m.scan(record, true); len(hits) > 0 {
+		return hits[0].rule.category, &hits[0].rule.rule
+	}
+	return m.fallback, nil
+}
+
+// ruleHit is a rule that matched a record and the field it matched on,
+// description or category. An any-field rule reports the description when both
+// match.
+type ruleHit struct {
+	rule  *compiledRule
+	field string
+}
+
+// matchAll returns every rule that matches a record in priority order, so the
+// first is the one match chooses. Rules whose category is not in the mapping are
+// not in the matcher and never returned.
+func (m *ruleMatcher) matchAll(record importRecord) []ruleHit {
+	return m.scan(record, false)
+}
+
+// scan tests the rules against a record in priority order, stopping at the first
+// match when first is set
+func (m *ruleMatcher) scan(record importRecord, first bool) []ruleHit {
+	description := newRuleText(record.Description)
+	descriptionHits := m.contains.search(description.lower)
+	var category ruleText
+	var categoryHits []int
+	if record.CsvCategory != "" {
+		category = newRuleText(record.CsvCategory)
+		categoryHits = m.contains.search(category.lower)
+	}
+
+	// The candidates are the contains rules found in either field and every other
+	// rule, tried in priority order
+	candidates := make([]int, 0, len(descriptionHits)+len(categoryHits)+len(m.others))
+	candidates = append(candidates, descriptionHits...)
+	candidates = append(candidates, categoryHits...)
+	candidates = append(candidates, m.others...)
+	sort.Ints(candidates)
+
+	found := func(hits []int, index int) bool {
+		i := sort.SearchInts(hits, index)
+		return i < len(hits) && hits[i] == index
+	}
+	var hits []ruleHit
+	for i, index := range candidates {
+		if i > 0 && candidates[i-1] == index {
+			continue
+		}
+		r := &m.rules[index]
+
+		var descriptionMatches, categoryMatches bool
+		if r.scanned {
+			descriptionMatches = found(descriptionHits, index)
+			categoryMatches = found(categoryHits, index)
+		} else {
+			descriptionMatches = r.rule.MatchField != ruleFieldCategory && r.matches(description)
+			categoryMatches = record.CsvCategory != "" && r.rule.MatchField != ruleFieldDescription && r.matches(category)
+		}
+
+		field := ""
+		if r.rule.MatchField != ruleFieldCategory && descriptionMatches {
+			field = ruleFieldDescription
+		} else if r.rule.MatchField != ruleFieldDescription && categoryMatches {
+			field = ruleFieldCategory
+		}
+		if field == "" || !ruleConditionsMet(r.rule, record) {
+			continue
+		}
+
+		hits = append(hits, ruleHit{rule: r, field: field})
+		if first {
+			break
+		}
+	}
+
+	return hits
+}
+
+// ruleMatcherCache holds the compiled matcher for the rules in the database, so
+// the rules are loaded and compiled once rather than for every import. Every
+// change to the rules calls invalidate, whether made by a handler or notified on
+// rulesChannel, and the matcher is rebuilt on next use; it is also rebuilt for a
+// different category mapping.
+type ruleMatcherCache struct {
+	mu         sync.Mutex
+	matcher    *ruleMatcher
+	generation uint64
+}
+
+// ruleMatchers is the shared rule matcher cache
+var ruleMatchers ruleMatcherCache
+
+// get returns the matcher for the current rules and the given category mapping
+func (c *ruleMatcherCache) get(ctx context.Context, mapping *CategoryMapping) (*ruleMatcher, error) {
+	c.mu.Lock()
+	matcher, generation := c.matcher, c.generation
+	c.mu.Unlock()
+	if matcher != nil && matcher.mapping == mapping {
+		return matcher, nil
+	}
+
+	rules, err := queries.GetRulesForMatching(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matcher = mapping.compileRules(rules)
+
+	// Rules changed while these were loading may be missing from them, so the
+	// matcher is only good for this caller
+	c.mu.Lock()
+	if c.generation == generation {
+		c.matcher = matcher
+	}
+	c.mu.Unlock()
+	return matcher, nil
+}
+
+// invalidate discards the cached matcher. Call it after committing a change to
+// the rules.
+func (c *ruleMatcherCache) invalidate() {
+	c.mu.Lock()
+	c.matcher = nil
+	c.generation++
+	c.mu.Unlock()
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"jointanalysis/db/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// naiveMatchRules tests each rule in priority order, as imports did before rules
+// were compiled
+func naiveMatchRules(cm *CategoryMapping, rules []generated.GetRulesForMatchingRow, record importRecord) (*generated.GetCategoriesRow, *generated.GetRulesForMatchingRow) {
+	for _, rule := range rules {
+		if ruleMatchesRecord(rule, record) {
+			if category := cm.categoryByID(rule.CategoryID); category != nil {
+				return category, &rule
+			}
+		}
+	}
+	if category, exists := cm.categoriesByName["Other"]; exists {
+		return &category, nil
+	}
+	return nil, nil
+}
+
+// ruleMatchesRecord is the reference the compiled matcher is checked against: it
+// reports whether a record meets all of a rule's conditions and its match value
+// matches the rule's field. The CSV category is only tested when
+// the row has one.
+func ruleMatchesRecord(rule generated.GetRulesForMatchingRow, record importRecord) bool {
+	return ruleMatchedField(rule, record) != ""
+}
+
+// ruleMatchedField returns the field a rule matched a record on, description or
+// category, or "" when the rule does not match. An any-field rule reports the
+// description when both match.
+func ruleMatchedField(rule generated.GetRulesForMatchingRow, record importRecord) string {
+	if !ruleConditionsMet(rule, record) {
+		return ""
+	}
+
+	descriptionMatches := func() bool {
+		return ruleMatches(rule.MatchType, rule.MatchValue, record.Description)
+	}
+	categoryMatches := func() bool {
+		return record.CsvCategory != "" && ruleMatches(rule.MatchType, rule.MatchValue, record.CsvCategory)
+	}
+
+	if rule.MatchField != ruleFieldCategory && descriptionMatches() {
+		return ruleFieldDescription
+	}
+	if rule.MatchField != ruleFieldDescription && categoryMatches() {
+		return ruleFieldCategory
+	}
+	return ""
+}
+
+// ruleMatches reports whether text matches a rule's match value. All match types
+// are case-insensitive; exact, prefix and suffix ignore surrounding whitespace.
+func ruleMatches(matchType, matchValue, text string) bool {
+	switch matchType {
+	case ruleMatchExact:
+		return strings.EqualFold(strings.TrimSpace(text), strings.TrimSpace(matchValue))
+	case ruleMatchPrefix:
+		return strings.HasPrefix(strings.ToLower(strings.TrimSpace(text)), strings.ToLower(matchValue))
+	case ruleMatchSuffix:
+		return strings.HasSuffix(strings.ToLower(strings.TrimSpace(text)), strings.ToLower(matchValue))
+	case ruleMatchRegex:
+		re, err := compileRulePattern(matchValue)
+		return err == nil && re.MatchString(text)
+	default:
+		return strings.Contains(strings.ToLower(text), strings.ToLower(matchValue))
+	}
+}
+
+// testMatcherMapping returns a mapping with Other and the given number of other
+// categories
+func testMatcherMapping(categories int) (*CategoryMapping, []generated.GetCategoriesRow) {
+	rows := make([]generated.GetCategoriesRow, 0, categories)
+	for i := 0; i < categories; i++ {
+		category := generated.GetCategoriesRow{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, Name: fmt.Sprintf("Category %d", i)}
+		rows = append(rows, category)
+	}
+	other := generated.GetCategoriesRow{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, Name: "Other"}
+	mapping := newCategoryMapping(append(append([]generated.GetCategoriesRow(nil), rows...), other))
+	return mapping, rows
+}
+
+func testMatcherRule(matchValue, matchType, matchField string, category generated.GetCategoriesRow) generated.GetRulesForMatchingRow {
+	return generated.GetRulesForMatchingRow{
+		ID:         pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		MatchValue: matchValue,
+		MatchType:  matchType,
+		MatchField: matchField,
+		CategoryID: category.ID,
+	}
+}
+
+func TestAhoCorasick(t *testing.T) {
+	ac := newAhoCorasick([]string{"he", "she", "his", "hers", "he"}, []int{0, 1, 2, 3, 4})
+
+	assert.Equal(t, []int{0, 1, 3, 4}, ac.search("ushers"))
+	assert.Equal(t, []int{2}, ac.search("this"))
+	assert.Equal(t, []int{0, 4}, ac.search("hhe"))
+	assert.Empty(t, ac.search("xyz"))
+	assert.Empty(t, ac.search(""))
+	assert.Empty(t, newAhoCorasick(nil, nil).search("anything"))
+}
+
+func TestRuleMatcher(t *testing.T) {
+	mapping, categories := testMatcherMapping(3)
+	food, shopping, travel := categories[0], categories[1], categories[2]
+
+	debit := testMatcherRule("coffee", ruleMatchContains, ruleFieldAny, travel)
+	debit.Direction = pgtype.Text{String: ruleDirectionDebit, Valid: true}
+	rules := []generated.GetRulesForMatchingRow{
+		debit,
+		testMatcherRule("COFFEE", ruleMatchContains, ruleFieldDescription, food),
+		testMatcherRule("^amzn", ruleMatchRegex, ruleFieldAny, shopping),
+		testMatcherRule("books", ruleMatchContains, ruleFieldCategory, shopping),
+		testMatcherRule("[", ruleMatchRegex, ruleFieldAny, travel),
+		testMatcherRule("hotel", ruleMatchContains, ruleFieldAny, generated.GetCategoriesRow{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}}),
+	}
+	matcher := mapping.compileRules(rules)
+
+	t.Run("should choose the first matching rule", func(t *testing.T) {
+		category, rule := matcher.match(importRecord{Description: "BLUE BOTTLE COFFEE", Amount: 5})
+		require.NotNil(t, rule)
+		assert.Equal(t, debit.ID, rule.ID)
+		assert.Equal(t, travel.Name, category.Name)
+
+		category, rule = matcher.match(importRecord{Description: "BLUE BOTTLE COFFEE", Amount: -5})
+		require.NotNil(t, rule)
+		assert.Equal(t, rules[1].ID, rule.ID)
+		assert.Equal(t, food.Name, category.Name)
+	})
+
+	t.Run("should respect the match field", func(t *testing.T) {
+		_, rule := matcher.match(importRecord{Description: "BOOKS PLUS", Amount: -5})
+		assert.Nil(t, rule, "the books rule only matches the CSV category")
+
+		_, rule = matcher.match(importRecord{Description: "CITY", CsvCategory: "Books & Music", Amount: 5})
+		require.NotNil(t, rule)
+		assert.Equal(t, rules[3].ID, rule.ID)
+
+		_, rule = matcher.match(importRecord{Description: "CITY", CsvCategory: "Coffee", Amount: -5})
+		assert.Nil(t, rule, "the description-only coffee rule ignores the CSV category")
+	})
+
+	t.Run("should skip invalid patterns and unknown categories", func(t *testing.T) {
+		category, rule := matcher.match(importRecord{Description: "GRAND HOTEL [1]", Amount: 5})
+		assert.Nil(t, rule)
+		require.NotNil(t, category)
+		assert.Equal(t, "Other", category.Name)
+
+		_, rule = matcher.match(importRecord{Description: "AMZN MKTP", Amount: 5})
+		require.NotNil(t, rule)
+		assert.Equal(t, rules[2].ID, rule.ID)
+	})
+
+	t.Run("should list every match in priority order", func(t *testing.T) {
+		hits := matcher.matchAll(importRecord{Description: "AMZN COFFEE", CsvCategory: "Books", Amount: 5})
+		require.Len(t, hits, 4)
+		assert.Equal(t, debit.ID, hits[0].rule.rule.ID)
+		assert.Equal(t, ruleFieldDescription, hits[0].field)
+		assert.Equal(t, rules[1].ID, hits[1].rule.rule.ID)
+		assert.Equal(t, rules[2].ID, hits[2].rule.rule.ID)
+		assert.Equal(t, rules[3].ID, hits[3].rule.rule.ID)
+		assert.Equal(t, ruleFieldCategory, hits[3].field)
+
+		_, rule := matcher.match(importRecord{Description: "AMZN COFFEE", CsvCategory: "Books", Amount: 5})
+		require.NotNil(t, rule)
+		assert.Equal(t, hits[0].rule.rule.ID, rule.ID)
+
+		assert.Empty(t, matcher.matchAll(importRecord{Description: "GRAND HOTEL", Amount: 5}), "a rule with an unknown category is not listed")
+	})
+
+	t.Run("should match nothing without a mapping", func(t *testing.T) {
+		var none *CategoryMapping
+		category, rule := none.compileRules(rules).match(importRecord{Description: "COFFEE"})
+		assert.Nil(t, category)
+		assert.Nil(t, rule)
+	})
+}
+
+// TestRuleMatcherEquivalence checks the compiled matcher against testing each
+// rule in turn on randomly generated rule sets and records
+func TestRuleMatcherEquivalence(t *testing.T) {
+	mapping, categories := testMatcherMapping(5)
+	words := []string{"coffee", "Coffee Shop", "shop", "SHELL", "shell oil", "oil", "amzn", "books", "", " pizza ", "pizza", "héllo", "a"}
+	matchTypes := []string{ruleMatchContains, ruleMatchContains, ruleMatchContains, ruleMatchExact, ruleMatchPrefix, ruleMatchSuffix, ruleMatchRegex}
+	matchFields := []string{ruleFieldAny, ruleFieldDescription, ruleFieldCategory}
+	random := rand.New(rand.NewSource(1))
+	pick := func(values []string) string { return values[random.Intn(len(values))] }
+
+	for round := 0; round < 200; round++ {
+		rules := make([]generated.GetRulesForMatchingRow, 0)
+		for i := random.Intn(20); i > 0; i-- {
+			matchType := pick(matchTypes)
+			matchValue := pick(words)
+			if matchType == ruleMatchRegex {
+				matchValue = "(?i)^" + matchValue
+			}
+			rule := testMatcherRule(matchValue, matchType, pick(matchFields), categories[random.Intn(len(categories))])
+			if random.Intn(4) == 0 {
+				rule.Direction = pgtype.Text{String: pick([]string{ruleDirectionDebit, ruleDirectionCredit}), Valid: true}
+			}
+			rules = append(rules, rule)
+		}
+		matcher := mapping.compileRules(rules)
+
+		for i := 0; i < 20; i++ {
+			record := importRecord{
+				Description: pick(words) + " " + pick(words),
+				Amount:      float64(random.Intn(3) - 1),
+			}
+			if random.Intn(2) == 0 {
+				record.CsvCategory = pick(words)
+			}
+
+			wantCategory, wantRule := naiveMatchRules(mapping, rules, record)
+			category, rule := matcher.match(record)
+			require.Equal(t, wantCategory, category, "record %+v", record)
+			if wantRule == nil {
+				require.Nil(t, rule, "record %+v", record)
+			} else {
+				require.NotNil(t, rule, "record %+v", record)
+				require.Equal(t, wantRule.ID, rule.ID, "record %+v", record)
+			}
+
+			var wantHits, hits []string
+			for _, r := range rules {
+				if field := ruleMatchedField(r, record); field != "" && mapping.categoryByID(r.CategoryID) != nil {
+					wantHits = append(wantHits, fmt.Sprintf("%x %s", r.ID.Bytes, field))
+				}
+			}
+			for _, hit := range matcher.matchAll(record) {
+				hits = append(hits, fmt.Sprintf("%x %s", hit.rule.rule.ID.Bytes, hit.field))
+			}
+			require.Equal(t, wantHits, hits, "record %+v", record)
+		}
+	}
+}
+
+func TestRuleMatcherConcurrency(t *testing.T) {
+	mapping, categories := testMatcherMapping(1)
+	matcher := mapping.compileRules([]generated.GetRulesForMatchingRow{
+		testMatcherRule("coffee", ruleMatchContains, ruleFieldAny, categories[0]),
+	})
+	cache := ruleMatcherCache{matcher: matcher}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cached, err := cache.get(context.Background(), mapping)
+				if !assert.NoError(t, err) {
+					return
+				}
+				_, rule := cached.match(importRecord{Description: "BLUE BOTTLE COFFEE"})
+				assert.NotNil(t, rule)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cache.invalidate()
+	assert.Nil(t, cache.matcher)
+	assert.Equal(t, uint64(1), cache.generation)
+}
+
+// BenchmarkRuleMatcher compares the compiled matcher with testing each rule in
+// turn. Run it with:
+//
+//	go test -run '^$' -bench BenchmarkRuleMatcher .
+func BenchmarkRuleMatcher(b *testing.B) {
+	mapping, categories := testMatcherMapping(10)
+	random := rand.New(rand.NewSource(1))
+	records := make([]importRecord, 100)
+	for i := range records {
+		records[i] = importRecord{
+			Description: fmt.Sprintf("POS PURCHASE MERCHANT%d #%d", random.Intn(20000), random.Intn(1000)),
+			Amount:      float64(random.Intn(100) + 1),
+		}
+	}
+
+	for _, size := range []int{10, 100, 1000, 10000} {
+		rules := make([]generated.GetRulesForMatchingRow, 0, size)
+		for i := 0; i < size; i++ {
+			matchType := ruleMatchContains
+			if i%10 == 9 {
+				matchType = ruleMatchPrefix
+			}
+			rules = append(rules, testMatcherRule(fmt.Sprintf("merchant%d ", i), matchType, ruleFieldAny, categories[i%len(categories)]))
+		}
+
+		b.Run(fmt.Sprintf("compiled/%d", size), func(b *testing.B) {
+			matcher := mapping.compileRules(rules)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				matcher.match(records[i%len(records)])
+			}
+		})
+		b.Run(fmt.Sprintf("naive/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				naiveMatchRules(mapping, rules, records[i%len(records)])
+			}
+		})
+		b.Run(fmt.Sprintf("compile/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				mapping.compileRules(rules)
+			}
+		})
+	}
+}
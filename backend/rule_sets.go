@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"jointanalysis/db/generated"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"gopkg.in/yaml.v3"
+)
+
+// ruleSetVersion is the version of the rule set format written by exports
+const ruleSetVersion = 1
+
+// Rule set import modes
+const (
+	ruleImportMerge   = "merge"
+	ruleImportReplace = "replace"
+)
+
+// ruleImportResult is what a rule set import changed
+type ruleImportResult struct {
+	Created           int      `json:"created"`
+	Updated           int      `json:"updated"`
+	Deleted           int64    `json:"deleted"`
+	CategoriesCreated []string `json:"categories_created"`
+	Warnings          []string `json:"warnings"`
+}
+
+// ruleCriteriaKey identifies a rule by what it matches: its match type, field and
+// value and its conditions. A merge import updates the existing rule with the same
+// criteria instead of adding a second one.
+func ruleCriteriaKey(matchType, matchField, matchValue string, minAmount, maxAmount pgtype.Numeric, direction, cardNumber pgtype.Text, startDate, endDate pgtype.Date) string {
+	numeric := func(n pgtype.Numeric) string {
+		if !n.Valid {
+			return ""
+		}
+		value, _ := n.Float64Value()
+		return fmt.Sprintf("%.2f", value.Float64)
+	}
+	date := func(d pgtype.Date) string {
+		if !d.Valid {
+			return ""
+		}
+		return d.Time.Format("2006-01-02")
+	}
+	return strings.Join([]string{
+		matchType, matchField, strings.ToLower(matchValue),
+		numeric(minAmount), numeric(maxAmount),
+		direction.String, cardNumber.String,
+		date(startDate), date(endDate),
+	}, "\x00")
+}
+
+// convertRuleSetRule converts a Rule to a RuleSetRule, naming its people
+func convertRuleSetRule(rule Rule, personNames map[string]string) RuleSetRule {
+	entry := RuleSetRule{
+		MatchValue: rule.MatchValue,
+		MatchType:  rule.MatchType,
+		MatchField: rule.MatchField,
+		MinAmount:  rule.MinAmount,
+		MaxAmount:  rule.MaxAmount,
+		Direction:  rule.Direction,
+		CardNumber: rule.CardNumber,
+		StartDate:  rule.StartDate,
+		EndDate:    rule.EndDate,
+		Category:   rule.CategoryName,
+		Priority:   rule.Priority,
+	}
+	for _, personID := range rule.AssignTo {
+		if name, exists := personNames[personID]; exists {
+			entry.AssignTo = append(entry.AssignTo, name)
+		}
+	}
+	for _, split := range rule.Splits {
+		entry.Splits = append(entry.Splits, RuleSetSplit{
+			Category:   split.CategoryName,
+			Percentage: split.Percentage,
+		})
+	}
+	return entry
+}
+
+// parseRuleSet decodes a rule set. YAML is a superset of JSON, so one decoder
+// reads both formats; unknown fields are rejected to catch typos.
+func parseRuleSet(data []byte) (RuleSet, error) {
+	var set RuleSet
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&set); err != nil {
+		if errors.Is(err, io.EOF) {
+			return set, fmt.Errorf("rule set is empty")
+		}
+		return set, fmt.Errorf("invalid rule set: %v", err)
+	}
+	if set.Version != ruleSetVersion {
+		return set, fmt.Errorf("unsupported rule set version %d", set.Version)
+	}
+	return set, nil
+}
+
+// ruleSetResolver maps the category and person names of a rule set to IDs,
+// creating missing categories when allowed
+type ruleSetResolver struct {
+	ctx              context.Context
+	q                *generated.Queries
+	createCategories bool
+	categories       map[string]pgtype.UUID
+	retired          map[string]bool
+	people           map[string]pgtype.UUID
+	result           *ruleImportResult
+}
+
+// newRuleSetResolver loads the existing categories and people. Names are matched
+// case-insensitively.
+func newRuleSetResolver(ctx context.Context, q *generated.Queries, createCategories bool, result *ruleImportResult) (*ruleSetResolver, error) {
+	categories, err := q.GetCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+	people, err := q.GetPeople(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load people: %w", err)
+	}
+
+	resolver := &ruleSetResolver{
+		ctx:              ctx,
+		q:                q,
+		createCategories: createCategories,
+		categories:       make(map[string]pgtype.UUID, len(categories)),
+		retired:          make(map[string]bool),
+		people:           make(map[string]pgtype.UUID, len(people)),
+		result:           result,
+	}
+	for _, category := range categories {
+		resolver.categories[strings.ToLower(category.Name)] = category.ID
+		if category.RetiredAt.Valid {
+			resolver.retired[strings.ToLower(category.Name)] = true
+		}
+	}
+	for _, person := range people {
+		resolver.people[strings.ToLower(person.Name)] = person.ID
+	}
+	return resolver, nil
+}
+
+// errRuleSetCategory is returned for a category that does not exist and may not
+// be created
+var errRuleSetCategory = errors.New("category not found")
+
+// errRuleSetCategoryRetired is returned for a retired category, which new rules
+// may not use
+var errRuleSetCategoryRetired = errors.New("category is retired")
+
+// category returns the ID of the named category, creating it if allowed
+func (r *ruleSetResolver) category(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if r.retired[strings.ToLower(name)] {
+		return "", fmt.Errorf("%w: %q", errRuleSetCategoryRetired, name)
+	}
+	if id, exists := r.categories[strings.ToLower(name)]; exists {
+		return uuid.UUID(id.Bytes).String(), nil
+	}
+	if !r.createCategories || validateName(name) != nil {
+		return "", fmt.Errorf("%w: %q", errRuleSetCategory, name)
+	}
+
+	created, err := r.q.CreateCategory(r.ctx, generated.CreateCategoryParams{Name: name})
+	if err != nil {
+		return "", fmt.Errorf("failed to create category %q: %w", name, err)
+	}
+	r.categories[strings.ToLower(name)] = created.ID
+	r.result.CategoriesCreated = append(r.result.CategoriesCreated, name)
+	return uuid.UUID(created.ID.Bytes).String(), nil
+}
+
+// rule converts a rule set entry to a Rule request with IDs. People who do not
+// exist are left out with a warning since people differ between installations.
+func (r *ruleSetResolver) rule(position int, entry RuleSetRule) (Rule, error) {
+	categoryID, err := r.category(entry.Category)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	req := Rule{
+		MatchValue: entry.MatchValue,
+		MatchType:  entry.MatchType,
+		MatchField: entry.MatchField,
+		MinAmount:  entry.MinAmount,
+		MaxAmount:  entry.MaxAmount,
+		Direction:  entry.Direction,
+		CardNumber: entry.CardNumber,
+		StartDate:  entry.StartDate,
+		EndDate:    entry.EndDate,
+		CategoryID: categoryID,
+		Priority:   entry.Priority,
+	}
+	for _, name := range entry.AssignTo {
+		personID, exists := r.people[strings.ToLower(strings.TrimSpace(name))]
+		if !exists {
+			r.result.Warnings = append(r.result.Warnings, fmt.Sprintf("rule %d: person %q not found, not assigned", position, name))
+			continue
+		}
+		req.AssignTo = append(req.AssignTo, uuid.UUID(personID.Bytes).String())
+	}
+	for _, split := range entry.Splits {
+		splitCategoryID, err := r.category(split.Category)
+		if err != nil {
+			return Rule{}, err
+		}
+		req.Splits = append(req.Splits, RuleSplit{CategoryID: splitCategoryID, Percentage: split.Percentage})
+	}
+	return req, nil
+}
+
+// Rule set handler functions
+
+// @Summary Export rules
+// @Description Export all categorization rules as a portable rule set. Categories and people are referenced by name so the rule set can be imported into another installation.
+// @Tags rules
+// @Produce json
+// @Produce application/x-yaml
+// @Param format query string false "json (default) or yaml"
+// @Success 200 {object} RuleSet "Rule set"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/rules/export [get]
+func exportRules(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "yaml" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or yaml"})
+		return
+	}
+
+	ctx := context.Background()
+	dbRules, err := queries.GetRules(ctx)
+	if err != nil {
+		log.Printf("Error fetching rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting rules"})
+		return
+	}
+	dbSplits, err := queries.GetRuleSplits(ctx)
+	if err != nil {
+		log.Printf("Error fetching rule splits: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting rules"})
+		return
+	}
+	people, err := queries.GetPeople(ctx)
+	if err != nil {
+		log.Printf("Error fetching people: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting rules"})
+		return
+	}
+
+	splitsByRule := groupRuleSplits(dbSplits)
+	personNames := make(map[string]string, len(people))
+	for _, person := range people {
+		personNames[uuid.UUID(person.ID.Bytes).String()] = person.Name
+	}
+
+	set := RuleSet{Version: ruleSetVersion, Rules: make([]RuleSetRule, 0, len(dbRules))}
+	for _, r := range dbRules {
+		set.Rules = append(set.Rules, convertRuleSetRule(convertRule(r, splitsByRule[r.ID]), personNames))
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=rules.%s", format))
+	if format == "yaml" {
+		c.YAML(http.StatusOK, set)
+		return
+	}
+	c.JSON(http.StatusOK, set)
+}
+
+// @Summary Import rules
+// @Description Import a rule set in the export's JSON or YAML format, in a single database transaction. Categories and people are resolved by name, case-insensitively. With mode=merge (the default) a rule with the same match type, field, value and conditions as an existing one updates it, and other rules are added; mode=replace deletes all existing rules first. Missing categories are created when create_categories is true and are an error otherwise. Missing people are skipped with a warning.
+// @Tags rules
+// @Accept json
+// @Accept application/x-yaml
+// @Produce json
+// @Param rules body RuleSet true "Rule set"
+// @Param mode query string false "merge (default) or replace"
+// @Param create_categories query bool false "Create categories that do not exist"
+// @Success 200 {object} ruleImportResult "Import result"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/rules/import [post]
+func importRules(c *gin.Context) {
+	mode := c.DefaultQuery("mode", ruleImportMerge)
+	if mode != ruleImportMerge && mode != ruleImportReplace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be merge or replace"})
+		return
+	}
+	createCategories := false
+	if value := c.Query("create_categories"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "create_categories must be true or false"})
+			return
+		}
+		createCategories = parsed
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	set, err := parseRuleSet(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error importing rules"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	result := ruleImportResult{CategoriesCreated: make([]string, 0), Warnings: make([]string, 0)}
+	resolver, err := newRuleSetResolver(ctx, qtx, createCategories, &result)
+	if err != nil {
+		log.Printf("Error loading rule set references: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error importing rules"})
+		return
+	}
+
+	existing := make(map[string]pgtype.UUID)
+	if mode == ruleImportReplace {
+		result.Deleted, err = qtx.DeleteAllRules(ctx)
+		if err != nil {
+			log.Printf("Error deleting rules: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error importing rules"})
+			return
+		}
+	} else {
+		// Rules of retired categories no longer match but still count as existing
+		rules, err := qtx.GetRules(ctx)
+		if err != nil {
+			log.Printf("Error fetching rules: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error importing rules"})
+			return
+		}
+		for _, r := range rules {
+			key := ruleCriteriaKey(r.MatchType, r.MatchField, r.MatchValue, r.MinAmount, r.MaxAmount, r.Direction, r.CardNumber, r.StartDate, r.EndDate)
+			if _, exists := existing[key]; !exists {
+				existing[key] = r.ID
+			}
+		}
+	}
+
+	for i, entry := range set.Rules {
+		position := i + 1
+		req, err := resolver.rule(position, entry)
+		if err != nil {
+			if errors.Is(err, errRuleSetCategory) || errors.Is(err, errRuleSetCategoryRetired) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rule %d: %v", position, err)})
+				return
+			}
+			log.Printf("Error resolving rule %d: %v", position, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error importing rules"})
+			return
+		}
+		params, err := ruleParamsFromRequest(req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rule %d: %v", position, err)})
+			return
+		}
+		splits, err := ruleSplitsFromRequest(req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rule %d: %v", position, err)})
+			return
+		}
+
+		key := ruleCriteriaKey(params.MatchType, params.MatchField, params.MatchValue, params.MinAmount, params.MaxAmount, params.Direction, params.CardNumber, params.StartDate, params.EndDate)
+		ruleID, exists := existing[key]
+		if exists {
+			_, err = qtx.UpdateRule(ctx, generated.UpdateRuleParams{
+				ID:         ruleID,
+				MatchValue: params.MatchValue,
+				CategoryID: params.CategoryID,
+				Priority:   params.Priority,
+				MatchType:  params.MatchType,
+				MatchField: params.MatchField,
+				MinAmount:  params.MinAmount,
+				MaxAmount:  params.MaxAmount,
+				Direction:  params.Direction,
+				CardNumber: params.CardNumber,
+				StartDate:  params.StartDate,
+				EndDate:    params.EndDate,
+				AssignTo:   params.AssignTo,
+			})
+			result.Updated++
+		} else {
+			var created generated.CategorizationRule
+			created, err = qtx.CreateRule(ctx, params)
+			ruleID = created.ID
+			existing[key] = ruleID
+			result.Created++
+		}
+		if err != nil {
+			log.Printf("Error saving rule %d: %v", position, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error importing rules"})
+			return
+		}
+		if err := saveRuleSplits(ctx, qtx, ruleID, splits); err != nil {
+			log.Printf("Error saving rule %d splits: %v", position, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error importing rules"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing rule import: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error importing rules"})
+		return
+	}
+	if len(result.CategoriesCreated) > 0 {
+		categoryMappings.invalidate()
+	}
+	ruleMatchers.invalidate()
+
+	c.JSON(http.StatusOK, result)
+}
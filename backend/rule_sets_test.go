@@ -0,0 +1,189 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRuleSet(t *testing.T) {
+	t.Run("should parse YAML", func(t *testing.T) {
+		set, err := parseRuleSet([]byte(`version: 1
+rules:
+  - match_value: NETFLIX
+    match_type: contains
+    match_field: any
+    category: Entertainment
+    assign_to: [Joint]
+    priority: 0
+  - match_value: TEAM LUNCH
+    category: Food & Dining
+    max_amount: 200
+    splits:
+      - category: Food & Dining
+        percentage: 60
+      - category: Reimbursable
+        percentage: 40
+    priority: 1
+`))
+		require.NoError(t, err)
+		require.Len(t, set.Rules, 2)
+		assert.Equal(t, "Entertainment", set.Rules[0].Category)
+		assert.Equal(t, []string{"Joint"}, set.Rules[0].AssignTo)
+		require.NotNil(t, set.Rules[1].MaxAmount)
+		assert.Equal(t, 200.0, *set.Rules[1].MaxAmount)
+		assert.Equal(t, []RuleSetSplit{{Category: "Food & Dining", Percentage: 60}, {Category: "Reimbursable", Percentage: 40}}, set.Rules[1].Splits)
+	})
+
+	t.Run("should parse JSON", func(t *testing.T) {
+		set, err := parseRuleSet([]byte(`{"version": 1, "rules": [{"match_value": "UBER", "category": "Transportation", "priority": 2}]}`))
+		require.NoError(t, err)
+		require.Len(t, set.Rules, 1)
+		assert.Equal(t, "UBER", set.Rules[0].MatchValue)
+		assert.Equal(t, int32(2), set.Rules[0].Priority)
+	})
+
+	t.Run("should reject invalid rule sets", func(t *testing.T) {
+		invalid := map[string]string{
+			"empty":           "",
+			"missing version": `rules: []`,
+			"unknown version": `{"version": 2, "rules": []}`,
+			"unknown field":   `{"version": 1, "rules": [{"match_valu": "UBER", "category": "Transportation"}]}`,
+			"malformed":       `{"version": 1, "rules": [`,
+		}
+		for name, data := range invalid {
+			_, err := parseRuleSet([]byte(data))
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestRuleSets(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	foodID := testCategoryID("Food & Dining")
+	coffeeRuleID, err := createTestRule("COFFEE", foodID, 0)
+	assertNoError(t, err)
+
+	importRuleSet := func(query, content string) *ruleImportResult {
+		t.Helper()
+		resp := makeRequest("POST", "/api/rules/import"+query, strings.NewReader(content))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var result ruleImportResult
+		assertNoError(t, parseJSONResponse(resp, &result))
+		return &result
+	}
+	getRuleList := func() []Rule {
+		t.Helper()
+		resp := makeRequest("GET", "/api/rules", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var rules []Rule
+		assertNoError(t, parseJSONResponse(resp, &rules))
+		return rules
+	}
+
+	t.Run("should export rules with names", func(t *testing.T) {
+		resp := makeRequest("GET", "/api/rules/export", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var set RuleSet
+		assertNoError(t, parseJSONResponse(resp, &set))
+		if set.Version != ruleSetVersion {
+			t.Errorf("Expected version %d, got %d", ruleSetVersion, set.Version)
+		}
+		if len(set.Rules) != 1 || set.Rules[0].MatchValue != "COFFEE" || set.Rules[0].Category != "Food & Dining" {
+			t.Errorf("Expected the COFFEE rule for Food & Dining, got %+v", set.Rules)
+		}
+
+		resp = makeRequest("GET", "/api/rules/export?format=yaml", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		yamlSet, err := parseRuleSet(resp.Body.Bytes())
+		assertNoError(t, err)
+		if len(yamlSet.Rules) != 1 || yamlSet.Rules[0].Category != "Food & Dining" {
+			t.Errorf("Expected the YAML export to round-trip, got %+v", yamlSet.Rules)
+		}
+	})
+
+	t.Run("should merge rules by their criteria", func(t *testing.T) {
+		result := importRuleSet("", `version: 1
+rules:
+  - match_value: coffee
+    match_type: contains
+    match_field: any
+    category: shopping
+    priority: 5
+  - match_value: UBER
+    category: Transportation
+    assign_to: [Joint, Nobody]
+    priority: 1
+`)
+		if result.Created != 1 || result.Updated != 1 || result.Deleted != 0 {
+			t.Errorf("Expected 1 created and 1 updated, got %+v", result)
+		}
+		if len(result.Warnings) != 1 {
+			t.Errorf("Expected a warning for the unknown person, got %v", result.Warnings)
+		}
+
+		rules := getRuleList()
+		if len(rules) != 2 {
+			t.Fatalf("Expected 2 rules, got %d", len(rules))
+		}
+		for _, rule := range rules {
+			if rule.MatchValue == "coffee" && (rule.ID != coffeeRuleID || rule.CategoryName != "Shopping") {
+				t.Errorf("Expected the COFFEE rule to be updated to Shopping, got %+v", rule)
+			}
+			if rule.MatchValue == "UBER" && len(rule.AssignTo) != 1 {
+				t.Errorf("Expected UBER to be assigned to Joint only, got %v", rule.AssignTo)
+			}
+		}
+	})
+
+	t.Run("should reject unknown categories unless creation is allowed", func(t *testing.T) {
+		content := `{"version": 1, "rules": [{"match_value": "KENNEL", "category": "Boarding", "priority": 0}]}`
+		resp := makeRequest("POST", "/api/rules/import", strings.NewReader(content))
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+		if len(getRuleList()) != 2 {
+			t.Errorf("Expected a failed import to change nothing")
+		}
+
+		result := importRuleSet("?create_categories=true", content)
+		if result.Created != 1 || len(result.CategoriesCreated) != 1 || result.CategoriesCreated[0] != "Boarding" {
+			t.Errorf("Expected the Boarding category to be created, got %+v", result)
+		}
+	})
+
+	t.Run("should replace all rules", func(t *testing.T) {
+		result := importRuleSet("?mode=replace", `{"version": 1, "rules": [{"match_value": "GYM", "category": "Hobby", "priority": 0}]}`)
+		if result.Deleted != 3 || result.Created != 1 {
+			t.Errorf("Expected 3 deleted and 1 created, got %+v", result)
+		}
+
+		rules := getRuleList()
+		if len(rules) != 1 || rules[0].MatchValue != "GYM" {
+			t.Errorf("Expected only the GYM rule, got %+v", rules)
+		}
+	})
+
+	t.Run("should return 400 for invalid imports", func(t *testing.T) {
+		invalid := []struct {
+			query   string
+			content string
+		}{
+			{"?mode=overwrite", `{"version": 1, "rules": []}`},
+			{"?create_categories=maybe", `{"version": 1, "rules": []}`},
+			{"", `{"version": 1, "rules": [{"match_value": "", "category": "Hobby"}]}`},
+			{"", `{"version": 1, "rules": [{"match_value": "[", "match_type": "regex", "category": "Hobby"}]}`},
+		}
+		for _, tc := range invalid {
+			resp := makeRequest("POST", "/api/rules/import"+tc.query, strings.NewReader(tc.content))
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s %s, got %d", tc.query, tc.content, resp.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"jointanalysis/db/generated"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// defaultRuleSuggestionMinCount is how many transactions must share a merchant
+// before a rule is suggested for them
+const defaultRuleSuggestionMinCount = 2
+
+// ruleSuggestionExamples is how many example descriptions a suggestion shows
+const ruleSuggestionExamples = 3
+
+// merchantNoiseTokens are words that banks and payment processors put in front of
+// merchant names, such as "TST* JOES PIZZA" or "POS PURCHASE SHELL OIL"
+var merchantNoiseTokens = map[string]bool{
+	"the":       true,
+	"pos":       true,
+	"tst":       true,
+	"purchase":  true,
+	"debit":     true,
+	"credit":    true,
+	"card":      true,
+	"checkcard": true,
+	"recurring": true,
+	"payment":   true,
+	"paypal":    true,
+	"www":       true,
+	"ach":       true,
+}
+
+// merchantTokens returns a description's words starting from the first one that
+// is not noise, which is taken to be the merchant
+func merchantTokens(description string) []string {
+	tokens := descriptionTokens(description)
+	for i, token := range tokens {
+		if !merchantNoiseTokens[token] {
+			return tokens[i:]
+		}
+	}
+	return nil
+}
+
+// commonTokenPrefix returns the leading words two word lists share
+func commonTokenPrefix(a, b []string) []string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:n]
+}
+
+// compileSuggestedRule compiles the contains rule suggested for a match value
+func compileSuggestedRule(matchValue string) compiledRule {
+	return compileRule(generated.GetRulesForMatchingRow{
+		MatchValue: strings.ToUpper(matchValue),
+		MatchType:  ruleMatchContains,
+		MatchField: ruleFieldDescription,
+	})
+}
+
+// mineRuleSuggestions groups transactions by merchant and proposes a contains rule
+// on the description for each group of at least minCount transactions. The match
+// value is the words every description in the group starts with from the merchant
+// on, or the merchant alone when those words do not appear together in each
+// description. The suggested category is the one the classifier is most confident
+// in across the group. Suggestions are ordered by how many of the transactions
+// their rule would match.
+func (cm *CategoryMapping) mineRuleSuggestions(transactions []generated.GetTransactionsInCategoryRow, classifier *categoryClassifier, minCount int) []RuleSuggestion {
+	var merchants []string
+	groups := make(map[string][]generated.GetTransactionsInCategoryRow)
+	shared := make(map[string][]string)
+	for _, t := range transactions {
+		tokens := merchantTokens(t.Description)
+		if len(tokens) == 0 {
+			continue
+		}
+		merchant := tokens[0]
+		if _, exists := groups[merchant]; exists {
+			shared[merchant] = commonTokenPrefix(shared[merchant], tokens)
+		} else {
+			merchants = append(merchants, merchant)
+			shared[merchant] = tokens
+		}
+		groups[merchant] = append(groups[merchant], t)
+	}
+
+	descriptions := make([]ruleText, len(transactions))
+	for i, t := range transactions {
+		descriptions[i] = newRuleText(t.Description)
+	}
+
+	suggestions := make([]RuleSuggestion, 0)
+	for _, merchant := range merchants {
+		group := groups[merchant]
+		if len(group) < minCount {
+			continue
+		}
+
+		rule := compileSuggestedRule(strings.Join(shared[merchant], " "))
+		for _, t := range group {
+			if !rule.matches(newRuleText(t.Description)) {
+				rule = compileSuggestedRule(merchant)
+				break
+			}
+		}
+
+		suggestion := RuleSuggestion{
+			MatchValue: rule.rule.MatchValue,
+			MatchType:  ruleMatchContains,
+			MatchField: ruleFieldDescription,
+			Examples:   make([]string, 0, ruleSuggestionExamples),
+		}
+		for i := range transactions {
+			if rule.matches(descriptions[i]) {
+				suggestion.TransactionCount++
+			}
+		}
+		seen := make(map[string]bool)
+		for _, t := range group {
+			if len(suggestion.Examples) == ruleSuggestionExamples {
+				break
+			}
+			if !seen[t.Description] {
+				seen[t.Description] = true
+				suggestion.Examples = append(suggestion.Examples, t.Description)
+			}
+		}
+
+		// Each transaction votes for categories with the classifier's confidence
+		votes := make(map[pgtype.UUID]float64)
+		var best pgtype.UUID
+		for _, t := range group {
+			for _, prediction := range classifier.classify(t.Description) {
+				votes[prediction.CategoryID] += prediction.Confidence
+				if !best.Valid || votes[prediction.CategoryID] > votes[best] {
+					best = prediction.CategoryID
+				}
+			}
+		}
+		if category := cm.categoryByID(best); best.Valid && category != nil {
+			categoryID := uuid.UUID(category.ID.Bytes).String()
+			categoryName := category.Name
+			confidence := votes[best] / float64(len(group))
+			suggestion.CategoryID = &categoryID
+			suggestion.CategoryName = &categoryName
+			suggestion.Confidence = &confidence
+		}
+
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].TransactionCount > suggestions[j].TransactionCount
+	})
+	return suggestions
+}
+
+// Rule suggestion handler functions
+
+// @Summary Get rule suggestions
+// @Description Propose new rules for active transactions that are entirely in Other. Transactions are grouped by merchant, the first meaningful word of the description, and each group of at least min_count gets a contains rule on the description with the category the classifier suggests and the number of Other transactions the rule would match.
+// @Tags rules
+// @Produce json
+// @Param min_count query int false "Minimum number of transactions sharing a merchant (default 2)"
+// @Success 200 {array} RuleSuggestion "Suggested rules, most transactions first"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/rules/suggestions [get]
+func getRuleSuggestions(c *gin.Context) {
+	minCount := defaultRuleSuggestionMinCount
+	if value := c.Query("min_count"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_count must be a positive number"})
+			return
+		}
+		minCount = parsed
+	}
+
+	categoryMapping := categoryMappings.get()
+	if categoryMapping == nil {
+		c.JSON(http.StatusOK, []RuleSuggestion{})
+		return
+	}
+	other, exists := categoryMapping.categoriesByName["Other"]
+	if !exists {
+		c.JSON(http.StatusOK, []RuleSuggestion{})
+		return
+	}
+
+	ctx := context.Background()
+	transactions, err := queries.GetTransactionsInCategory(ctx, other.ID)
+	if err != nil {
+		log.Printf("Error fetching uncategorized transactions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error suggesting rules"})
+		return
+	}
+	classifier, err := categoryClassifiers.get(ctx, categoryMapping)
+	if err != nil {
+		log.Printf("Error training category classifier: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error suggesting rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, categoryMapping.mineRuleSuggestions(transactions, classifier, minCount))
+}
+
+// @Summary Accept rule suggestion
+// @Description Create the contains rule on the description that a rule suggestion proposes, with the chosen category. With apply set, the rule is also applied to the active transactions it wins, in the same database transaction; transactions whose splits were edited by hand are skipped.
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param request body RuleSuggestionAcceptRequest true "match_value and category_id required; priority defaults to 0"
+// @Success 201 {object} map[string]interface{} "Result - returns the created rule, applied and skipped counts and the applied changes"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/rules/suggestions/accept [post]
+func acceptRuleSuggestion(c *gin.Context) {
+	var req RuleSuggestionAcceptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	params, err := ruleParamsFromRequest(Rule{
+		MatchValue: req.MatchValue,
+		MatchType:  ruleMatchContains,
+		MatchField: ruleFieldDescription,
+		CategoryID: req.CategoryID,
+		Priority:   req.Priority,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	if err := validateRuleReferences(ctx, queries, params.CategoryID, nil, nil); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error accepting rule suggestion"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	dbRule, err := qtx.CreateRule(ctx, params)
+	if err != nil {
+		log.Printf("Error creating rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error accepting rule suggestion"})
+		return
+	}
+
+	changes := make([]RuleApplicationChange, 0)
+	skipped := 0
+	if req.Apply {
+		scope, _ := ruleApplicationScope(ruleScopeActive)
+		plans, err := planRuleApplication(ctx, qtx, scope)
+		if err != nil {
+			log.Printf("Error planning rule application: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error accepting rule suggestion"})
+			return
+		}
+		changes, skipped, err = applyRuleChanges(ctx, qtx, plans, func(plan plannedRuleApplication) bool {
+			return plan.Rule.ID == dbRule.ID
+		}, false)
+		if err != nil {
+			log.Printf("Error applying rules: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error accepting rule suggestion"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing rule suggestion: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error accepting rule suggestion"})
+		return
+	}
+	ruleMatchers.invalidate()
+	categoryClassifiers.invalidate()
+
+	rule, err := fetchRule(ctx, dbRule.ID)
+	if err != nil {
+		log.Printf("Error fetching created rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching created rule"})
+		return
+	}
+	// Warnings are advisory, so a failed analysis does not fail the request
+	rule.Warnings, err = ruleWarnings(ctx, rule.ID)
+	if err != nil {
+		log.Printf("Error linting rules: %v", err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"rule":    rule,
+		"applied": len(changes),
+		"skipped": skipped,
+		"changes": changes,
+	})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jointanalysis/db/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerchantTokens(t *testing.T) {
+	assert.Equal(t, []string{"joes", "pizza"}, merchantTokens("TST* JOES PIZZA #12"))
+	assert.Equal(t, []string{"shell", "oil"}, merchantTokens("POS PURCHASE SHELL OIL 5732"))
+	assert.Equal(t, []string{"netflix", "com"}, merchantTokens("NETFLIX.COM"))
+	assert.Empty(t, merchantTokens("POS 1234"))
+}
+
+func TestMineRuleSuggestions(t *testing.T) {
+	food := generated.GetCategoriesRow{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, Name: "Food & Dining"}
+	other := generated.GetCategoriesRow{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, Name: "Other"}
+	mapping := newCategoryMapping([]generated.GetCategoriesRow{food, other})
+	classifier := trainCategoryClassifier([]generated.GetCategoryTrainingExamplesRow{
+		{Description: "PIZZA PALACE", CategoryID: food.ID, Weight: 1},
+	}, other.ID)
+
+	var transactions []generated.GetTransactionsInCategoryRow
+	for _, description := range []string{
+		"TST* JOES PIZZA #12",
+		"SHELL OIL 123",
+		"TST* JOES PIZZA #7",
+		"SHELL  OIL 456",
+		"JOES PIZZA DELIVERY",
+		"SHELLFISH SHACK",
+		"NETFLIX.COM",
+		"12345",
+	} {
+		transactions = append(transactions, generated.GetTransactionsInCategoryRow{
+			ID:          pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			Description: description,
+		})
+	}
+
+	suggestions := mapping.mineRuleSuggestions(transactions, classifier, 2)
+	require.Len(t, suggestions, 2)
+
+	pizza := suggestions[0]
+	assert.Equal(t, "JOES PIZZA", pizza.MatchValue)
+	assert.Equal(t, ruleMatchContains, pizza.MatchType)
+	assert.Equal(t, ruleFieldDescription, pizza.MatchField)
+	assert.Equal(t, 3, pizza.TransactionCount)
+	assert.Equal(t, []string{"TST* JOES PIZZA #12", "TST* JOES PIZZA #7", "JOES PIZZA DELIVERY"}, pizza.Examples)
+	require.NotNil(t, pizza.CategoryName)
+	assert.Equal(t, food.Name, *pizza.CategoryName)
+	require.NotNil(t, pizza.Confidence)
+	assert.InDelta(t, 1, *pizza.Confidence, 1e-9)
+
+	// The shared words are separated differently, so only the merchant is used,
+	// and it also matches the shellfish restaurant
+	shell := suggestions[1]
+	assert.Equal(t, "SHELL", shell.MatchValue)
+	assert.Equal(t, 3, shell.TransactionCount)
+	assert.Nil(t, shell.CategoryID)
+	assert.Nil(t, shell.Confidence)
+
+	assert.Len(t, mapping.mineRuleSuggestions(transactions, classifier, 3), 1, "SHELL groups two transactions")
+	assert.Empty(t, mapping.mineRuleSuggestions(transactions, classifier, 4))
+}
+
+func TestRuleSuggestions(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	foodID := testCategoryID("Food & Dining")
+
+	// Imported before any rule exists, so every row is categorized as Other
+	csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-07-01,2024-07-02,1234,TST* JOES PIZZA #12,,18.00,
+2024-07-02,2024-07-03,1234,TST* JOES PIZZA #7,,22.00,
+2024-07-03,2024-07-04,1234,HARDWARE STORE,,12.00,`
+	resp := uploadTestFile(t, "other.csv", csvContent, nil)
+	assertStatusCode(t, http.StatusOK, resp.Code)
+
+	accept := func(requestBody map[string]interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(requestBody)
+		assertNoError(t, err)
+		return makeRequest("POST", "/api/rules/suggestions/accept", bytes.NewBuffer(body))
+	}
+
+	t.Run("should suggest rules for merchants in Other", func(t *testing.T) {
+		resp := makeRequest("GET", "/api/rules/suggestions", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var suggestions []RuleSuggestion
+		assertNoError(t, parseJSONResponse(resp, &suggestions))
+		if len(suggestions) != 1 {
+			t.Fatalf("Expected 1 suggestion, got %+v", suggestions)
+		}
+		if suggestions[0].MatchValue != "JOES PIZZA" || suggestions[0].TransactionCount != 2 {
+			t.Errorf("Expected JOES PIZZA for 2 transactions, got %+v", suggestions[0])
+		}
+
+		resp = makeRequest("GET", "/api/rules/suggestions?min_count=3", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		assertNoError(t, parseJSONResponse(resp, &suggestions))
+		if len(suggestions) != 0 {
+			t.Errorf("Expected no suggestions with min_count=3, got %+v", suggestions)
+		}
+
+		resp = makeRequest("GET", "/api/rules/suggestions?min_count=abc", nil)
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("should create and apply an accepted suggestion", func(t *testing.T) {
+		resp := accept(map[string]interface{}{
+			"match_value": "JOES PIZZA",
+			"category_id": foodID,
+			"apply":       true,
+		})
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		var result struct {
+			Rule    Rule `json:"rule"`
+			Applied int  `json:"applied"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if result.Rule.MatchValue != "JOES PIZZA" || result.Rule.MatchField != ruleFieldDescription || result.Rule.CategoryName != "Food & Dining" {
+			t.Errorf("Expected a JOES PIZZA description rule for Food & Dining, got %+v", result.Rule)
+		}
+		if result.Applied != 2 || result.Rule.HitCount != 2 {
+			t.Errorf("Expected the rule to be applied to 2 transactions, got %d applied and %d hits", result.Applied, result.Rule.HitCount)
+		}
+
+		resp = makeRequest("GET", "/api/rules/suggestions", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var suggestions []RuleSuggestion
+		assertNoError(t, parseJSONResponse(resp, &suggestions))
+		if len(suggestions) != 0 {
+			t.Errorf("Expected no suggestions once the pizza transactions are categorized, got %+v", suggestions)
+		}
+	})
+
+	t.Run("should create without applying", func(t *testing.T) {
+		resp := accept(map[string]interface{}{
+			"match_value": "HARDWARE",
+			"category_id": foodID,
+			"priority":    2,
+		})
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		var result struct {
+			Rule    Rule `json:"rule"`
+			Applied int  `json:"applied"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if result.Applied != 0 || result.Rule.Priority != 2 {
+			t.Errorf("Expected a rule with priority 2 and nothing applied, got %+v", result)
+		}
+	})
+
+	t.Run("should return 400 for invalid requests", func(t *testing.T) {
+		invalid := []map[string]interface{}{
+			{"category_id": foodID},
+			{"match_value": "JOES PIZZA"},
+			{"match_value": "JOES PIZZA", "category_id": "not-a-uuid"},
+		}
+		for _, requestBody := range invalid {
+			resp := accept(requestBody)
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %v, got %d", requestBody, resp.Code)
+			}
+		}
+	})
+}
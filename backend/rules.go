@@ -2,20 +2,406 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"jointanalysis/db/generated"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// Rule match types
+const (
+	ruleMatchContains = "contains"
+	ruleMatchExact    = "exact"
+	ruleMatchPrefix   = "prefix"
+	ruleMatchSuffix   = "suffix"
+	ruleMatchRegex    = "regex"
+)
+
+// Rule match fields: the CSV category column, the description, or either
+const (
+	ruleFieldAny         = "any"
+	ruleFieldDescription = "description"
+	ruleFieldCategory    = "category"
+)
+
+// Rule directions. Expenses are stored as positive amounts and credits as negative.
+const (
+	ruleDirectionDebit  = "debit"
+	ruleDirectionCredit = "credit"
+)
+
+// compileRulePattern compiles a regex rule's match value. Patterns match
+// case-insensitively like the other match types.
+func compileRulePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// validateRuleMatch checks a rule's match type and, for regex rules, that the
+// pattern compiles. It returns the match type to store; empty means contains.
+func validateRuleMatch(matchType, matchValue string) (string, error) {
+	switch matchType {
+	case "":
+		return ruleMatchContains, nil
+	case ruleMatchContains, ruleMatchExact, ruleMatchPrefix, ruleMatchSuffix:
+		return matchType, nil
+	case ruleMatchRegex:
+		if _, err := compileRulePattern(matchValue); err != nil {
+			return "", fmt.Errorf("invalid regex pattern: %v", err)
+		}
+		return matchType, nil
+	default:
+		return "", fmt.Errorf("match_type must be one of contains, exact, prefix, suffix or regex")
+	}
+}
+
+// ruleParamsFromRequest validates a rule request and converts it to insert params
+func ruleParamsFromRequest(req Rule) (generated.CreateRuleParams, error) {
+	var params generated.CreateRuleParams
+
+	if req.MatchValue == "" {
+		return params, fmt.Errorf("match_value cannot be empty")
+	}
+	if req.CategoryID == "" {
+		return params, fmt.Errorf("category_id cannot be empty")
+	}
+	categoryUUID, err := uuid.Parse(req.CategoryID)
+	if err != nil {
+		return params, fmt.Errorf("invalid category_id format")
+	}
+
+	matchType, err := validateRuleMatch(req.MatchType, req.MatchValue)
+	if err != nil {
+		return params, err
+	}
+
+	matchField := req.MatchField
+	switch matchField {
+	case "":
+		matchField = ruleFieldAny
+	case ruleFieldAny, ruleFieldDescription, ruleFieldCategory:
+	default:
+		return params, fmt.Errorf("match_field must be one of any, description or category")
+	}
+
+	params = generated.CreateRuleParams{
+		MatchValue: req.MatchValue,
+		CategoryID: pgtype.UUID{Bytes: categoryUUID, Valid: true},
+		Priority:   req.Priority,
+		MatchType:  matchType,
+		MatchField: matchField,
+	}
+
+	if req.MinAmount != nil && req.MaxAmount != nil && *req.MinAmount > *req.MaxAmount {
+		return params, fmt.Errorf("min_amount cannot be greater than max_amount")
+	}
+	for _, bound := range []struct {
+		name   string
+		value  *float64
+		target *pgtype.Numeric
+	}{
+		{"min_amount", req.MinAmount, &params.MinAmount},
+		{"max_amount", req.MaxAmount, &params.MaxAmount},
+	} {
+		if bound.value == nil {
+			continue
+		}
+		if *bound.value < 0 {
+			return params, fmt.Errorf("%s cannot be negative", bound.name)
+		}
+		if err := bound.target.Scan(fmt.Sprintf("%.2f", *bound.value)); err != nil {
+			return params, fmt.Errorf("invalid %s", bound.name)
+		}
+	}
+
+	if req.Direction != nil && *req.Direction != "" {
+		if *req.Direction != ruleDirectionDebit && *req.Direction != ruleDirectionCredit {
+			return params, fmt.Errorf("direction must be debit or credit")
+		}
+		params.Direction = pgtype.Text{String: *req.Direction, Valid: true}
+	}
+
+	if req.CardNumber != nil && strings.TrimSpace(*req.CardNumber) != "" {
+		params.CardNumber = pgtype.Text{String: strings.TrimSpace(*req.CardNumber), Valid: true}
+	}
+
+	for _, bound := range []struct {
+		name   string
+		value  *string
+		target *pgtype.Date
+	}{
+		{"start_date", req.StartDate, &params.StartDate},
+		{"end_date", req.EndDate, &params.EndDate},
+	} {
+		if bound.value == nil || *bound.value == "" {
+			continue
+		}
+		parsed, err := time.Parse("2006-01-02", *bound.value)
+		if err != nil {
+			return params, fmt.Errorf("%s must be a date in YYYY-MM-DD format", bound.name)
+		}
+		*bound.target = pgtype.Date{Time: parsed, Valid: true}
+	}
+	if params.StartDate.Valid && params.EndDate.Valid && params.StartDate.Time.After(params.EndDate.Time) {
+		return params, fmt.Errorf("start_date cannot be after end_date")
+	}
+
+	if len(req.AssignTo) > 0 {
+		assignTo, err := convertUUIDStringsToArray(req.AssignTo)
+		if err != nil {
+			return params, fmt.Errorf("assign_to must contain person IDs")
+		}
+		seen := make(map[pgtype.UUID]bool, len(assignTo))
+		for _, personID := range assignTo {
+			if seen[personID] {
+				return params, fmt.Errorf("assign_to contains the same person more than once")
+			}
+			seen[personID] = true
+		}
+		params.AssignTo = assignTo
+	}
+
+	return params, nil
+}
+
+// ruleSplitsFromRequest validates a rule's split template. Every line needs a
+// category and a positive percentage, and the percentages must add up to 100.
+func ruleSplitsFromRequest(req Rule) ([]generated.CreateRuleSplitParams, error) {
+	if len(req.Splits) == 0 {
+		return nil, nil
+	}
+
+	splits := make([]generated.CreateRuleSplitParams, 0, len(req.Splits))
+	var totalBasisPoints int64
+	for _, split := range req.Splits {
+		categoryUUID, err := uuid.Parse(split.CategoryID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid split category_id format")
+		}
+		if split.Percentage <= 0 || split.Percentage > 100 {
+			return nil, fmt.Errorf("split percentage must be greater than 0 and at most 100")
+		}
+
+		var percentage pgtype.Numeric
+		if err := percentage.Scan(fmt.Sprintf("%.2f", split.Percentage)); err != nil {
+			return nil, fmt.Errorf("invalid split percentage")
+		}
+		totalBasisPoints += int64(math.Round(split.Percentage * 100))
+
+		splits = append(splits, generated.CreateRuleSplitParams{
+			CategoryID: pgtype.UUID{Bytes: categoryUUID, Valid: true},
+			Percentage: percentage,
+		})
+	}
+	if totalBasisPoints != 10000 {
+		return nil, fmt.Errorf("split percentages must add up to 100")
+	}
+
+	return splits, nil
+}
+
+// validateRuleReferences checks that the people a rule assigns exist and that its
+// category and the categories of its split template exist and are not retired.
+// assign_to is an array without foreign keys, so unknown people would otherwise
+// be stored silently.
+func validateRuleReferences(ctx context.Context, q *generated.Queries, categoryID pgtype.UUID, assignTo []pgtype.UUID, splits []generated.CreateRuleSplitParams) error {
+	category, err := q.GetCategoryByID(ctx, categoryID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("category %s not found", uuid.UUID(categoryID.Bytes))
+		}
+		return err
+	}
+	if category.RetiredAt.Valid {
+		return fmt.Errorf("category %s is retired", category.Name)
+	}
+	for _, personID := range assignTo {
+		if _, err := q.GetPersonByID(ctx, personID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("assign_to person %s not found", uuid.UUID(personID.Bytes))
+			}
+			return err
+		}
+	}
+	for _, split := range splits {
+		splitCategory, err := q.GetCategoryByID(ctx, split.CategoryID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("split category %s not found", uuid.UUID(split.CategoryID.Bytes))
+			}
+			return err
+		}
+		if splitCategory.RetiredAt.Valid {
+			return fmt.Errorf("split category %s is retired", splitCategory.Name)
+		}
+	}
+	return nil
+}
+
+// saveRuleSplits replaces a rule's split template
+func saveRuleSplits(ctx context.Context, q *generated.Queries, ruleID pgtype.UUID, splits []generated.CreateRuleSplitParams) error {
+	if err := q.DeleteRuleSplits(ctx, ruleID); err != nil {
+		return err
+	}
+	for i, split := range splits {
+		split.RuleID = ruleID
+		split.Position = int32(i)
+		if err := q.CreateRuleSplit(ctx, split); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordRuleHits adds the number of transactions each rule categorized to its hit
+// count and marks it as matched now
+func recordRuleHits(ctx context.Context, q *generated.Queries, hits map[pgtype.UUID]int32) error {
+	if len(hits) == 0 {
+		return nil
+	}
+	params := generated.RecordRuleHitsParams{
+		RuleIds: make([]pgtype.UUID, 0, len(hits)),
+		Hits:    make([]int32, 0, len(hits)),
+	}
+	for ruleID, count := range hits {
+		params.RuleIds = append(params.RuleIds, ruleID)
+		params.Hits = append(params.Hits, count)
+	}
+	return q.RecordRuleHits(ctx, params)
+}
+
+// groupRuleSplits groups split template lines by rule ID, keeping their order
+func groupRuleSplits(splits []generated.GetRuleSplitsRow) map[pgtype.UUID][]generated.GetRuleSplitsRow {
+	byRule := make(map[pgtype.UUID][]generated.GetRuleSplitsRow)
+	for _, split := range splits {
+		byRule[split.RuleID] = append(byRule[split.RuleID], split)
+	}
+	return byRule
+}
+
+// fetchRule loads a rule with its category name and split template
+func fetchRule(ctx context.Context, id pgtype.UUID) (Rule, error) {
+	dbRule, err := queries.GetRuleByID(ctx, id)
+	if err != nil {
+		return Rule{}, err
+	}
+	dbSplits, err := queries.GetRuleSplitsByRuleID(ctx, id)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	splits := make([]generated.GetRuleSplitsRow, 0, len(dbSplits))
+	for _, split := range dbSplits {
+		splits = append(splits, generated.GetRuleSplitsRow(split))
+	}
+	return convertRule(generated.GetRulesRow(dbRule), splits), nil
+}
+
+// convertRule converts a generated.GetRulesRow and its split template to our Rule struct
+func convertRule(r generated.GetRulesRow, splits []generated.GetRuleSplitsRow) Rule {
+	rule := Rule{
+		ID:            uuid.UUID(r.ID.Bytes).String(),
+		MatchValue:    r.MatchValue,
+		MatchType:     r.MatchType,
+		MatchField:    r.MatchField,
+		CategoryID:    uuid.UUID(r.CategoryID.Bytes).String(),
+		CategoryName:  r.CategoryName,
+		AssignTo:      make([]string, 0, len(r.AssignTo)),
+		Splits:        make([]RuleSplit, 0, len(splits)),
+		Priority:      r.Priority,
+		HitCount:      r.HitCount,
+		OverrideCount: r.OverrideCount,
+		CreatedAt:     r.CreatedAt.Time,
+		UpdatedAt:     r.UpdatedAt.Time,
+	}
+	if r.LastMatchedAt.Valid {
+		rule.LastMatchedAt = &r.LastMatchedAt.Time
+	}
+
+	for _, personID := range r.AssignTo {
+		rule.AssignTo = append(rule.AssignTo, uuid.UUID(personID.Bytes).String())
+	}
+	for _, split := range splits {
+		percentage, _ := split.Percentage.Float64Value()
+		rule.Splits = append(rule.Splits, RuleSplit{
+			CategoryID:   uuid.UUID(split.CategoryID.Bytes).String(),
+			CategoryName: split.CategoryName,
+			Percentage:   percentage.Float64,
+		})
+	}
+
+	if r.MinAmount.Valid {
+		value, _ := r.MinAmount.Float64Value()
+		rule.MinAmount = &value.Float64
+	}
+	if r.MaxAmount.Valid {
+		value, _ := r.MaxAmount.Float64Value()
+		rule.MaxAmount = &value.Float64
+	}
+	if r.Direction.Valid {
+		rule.Direction = &r.Direction.String
+	}
+	if r.CardNumber.Valid {
+		rule.CardNumber = &r.CardNumber.String
+	}
+	if r.StartDate.Valid {
+		dateStr := r.StartDate.Time.Format("2006-01-02")
+		rule.StartDate = &dateStr
+	}
+	if r.EndDate.Valid {
+		dateStr := r.EndDate.Time.Format("2006-01-02")
+		rule.EndDate = &dateStr
+	}
+
+	return rule
+}
+
+// Default thresholds of the rule report
+const (
+	defaultRuleStaleDays       = 90
+	defaultRuleMinOverrideRate = 0.25
+)
+
+// buildRuleReport sorts rules into the report's lists. A rule is stale when it
+// last matched more than staleDays before now, and frequently overridden when at
+// least minOverrideRate of the transactions it categorized were overridden.
+func buildRuleReport(rules []Rule, now time.Time, staleDays int, minOverrideRate float64) RuleReport {
+	report := RuleReport{
+		NeverMatched:         make([]Rule, 0),
+		Stale:                make([]Rule, 0),
+		FrequentlyOverridden: make([]Rule, 0),
+	}
+	staleBefore := now.AddDate(0, 0, -staleDays)
+	for _, rule := range rules {
+		if rule.HitCount == 0 {
+			report.NeverMatched = append(report.NeverMatched, rule)
+			continue
+		}
+		if rule.LastMatchedAt != nil && rule.LastMatchedAt.Before(staleBefore) {
+			report.Stale = append(report.Stale, rule)
+		}
+		if rule.OverrideCount > 0 && float64(rule.OverrideCount)/float64(rule.HitCount) >= minOverrideRate {
+			report.FrequentlyOverridden = append(report.FrequentlyOverridden, rule)
+		}
+	}
+	return report
+}
+
 // Rule handler functions
 
 // @Summary Get all rules
-// @Description Retrieve all categorization rules ordered by priority
+// @Description Retrieve all categorization rules ordered by priority, each with the warnings about it from the rule set analysis
 // @Tags rules
 // @Produce json
 // @Success 200 {array} Rule "List of categorization rules"
@@ -29,28 +415,87 @@ func getRules(c *gin.Context) {
 		return
 	}
 
+	dbSplits, err := queries.GetRuleSplits(context.Background())
+	if err != nil {
+		log.Printf("Error fetching rule splits: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching rules"})
+		return
+	}
+	splitsByRule := groupRuleSplits(dbSplits)
+
+	warningsByRule := make(map[string][]RuleWarning)
+	for _, warning := range lintRules(dbRules) {
+		warningsByRule[warning.RuleID] = append(warningsByRule[warning.RuleID], warning)
+	}
+
 	rules := make([]Rule, 0, len(dbRules))
 	for _, r := range dbRules {
-		rules = append(rules, Rule{
-			ID:           uuid.UUID(r.ID.Bytes).String(),
-			MatchValue:   r.MatchValue,
-			CategoryID:   uuid.UUID(r.CategoryID.Bytes).String(),
-			CategoryName: r.CategoryName,
-			Priority:     r.Priority,
-			CreatedAt:    r.CreatedAt.Time,
-			UpdatedAt:    r.UpdatedAt.Time,
-		})
+		rule := convertRule(r, splitsByRule[r.ID])
+		rule.Warnings = warningsByRule[rule.ID]
+		rules = append(rules, rule)
 	}
 
 	c.JSON(http.StatusOK, rules)
 }
 
+// @Summary Get rule report
+// @Description Report rules that may need attention: rules that never categorized a transaction, rules that have not matched for stale_days, and rules whose transactions were re-split by hand into other categories at least min_override_rate of the time
+// @Tags rules
+// @Produce json
+// @Param stale_days query int false "Days without a match after which a rule is stale (default 90)"
+// @Param min_override_rate query number false "Share of overridden transactions from which a rule is frequently overridden, between 0 and 1 (default 0.25)"
+// @Success 200 {object} RuleReport "Rule report"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/rules/report [get]
+func getRuleReport(c *gin.Context) {
+	staleDays := defaultRuleStaleDays
+	if value := c.Query("stale_days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stale_days must be a positive number of days"})
+			return
+		}
+		staleDays = parsed
+	}
+	minOverrideRate := defaultRuleMinOverrideRate
+	if value := c.Query("min_override_rate"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_override_rate must be greater than 0 and at most 1"})
+			return
+		}
+		minOverrideRate = parsed
+	}
+
+	dbRules, err := queries.GetRules(context.Background())
+	if err != nil {
+		log.Printf("Error fetching rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching rules"})
+		return
+	}
+	dbSplits, err := queries.GetRuleSplits(context.Background())
+	if err != nil {
+		log.Printf("Error fetching rule splits: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching rules"})
+		return
+	}
+	splitsByRule := groupRuleSplits(dbSplits)
+
+	rules := make([]Rule, 0, len(dbRules))
+	for _, r := range dbRules {
+		rules = append(rules, convertRule(r, splitsByRule[r.ID]))
+	}
+
+	c.JSON(http.StatusOK, buildRuleReport(rules, time.Now(), staleDays, minOverrideRate))
+}
+
 // @Summary Create rule
-// @Description Create a new categorization rule
+// @Description Create a new categorization rule. The response includes warnings when the rule is shadowed by, shadows or conflicts with another rule.
 // @Tags rules
 // @Accept json
 // @Produce json
-// @Param rule body Rule true "Rule data (match_value, category_id, priority required)"
+// @Param rule body Rule true "Rule data (match_value, category_id, priority required; match_type defaults to contains; splits must add up to 100%)"
 // @Success 201 {object} Rule "Created rule"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
@@ -62,55 +507,69 @@ func createRule(c *gin.Context) {
 		return
 	}
 
-	if req.MatchValue == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "match_value cannot be empty"})
+	params, err := ruleParamsFromRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if req.CategoryID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "category_id cannot be empty"})
+	splits, err := ruleSplitsFromRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	categoryUUID, err := uuid.Parse(req.CategoryID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category_id format"})
+	ctx := context.Background()
+	if err := validateRuleReferences(ctx, queries, params.CategoryID, params.AssignTo, splits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	params := generated.CreateRuleParams{
-		MatchValue: req.MatchValue,
-		CategoryID: pgtype.UUID{Bytes: categoryUUID, Valid: true},
-		Priority:   req.Priority,
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating rule"})
+		return
 	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
 
-	dbRule, err := queries.CreateRule(context.Background(), params)
+	dbRule, err := qtx.CreateRule(ctx, params)
 	if err != nil {
 		log.Printf("Error creating rule: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating rule"})
 		return
 	}
+	if err := saveRuleSplits(ctx, qtx, dbRule.ID, splits); err != nil {
+		log.Printf("Error creating rule splits: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating rule"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating rule"})
+		return
+	}
+	ruleMatchers.invalidate()
 
 	// Fetch the full row with category name
-	fullRule, err := queries.GetRuleByID(context.Background(), dbRule.ID)
+	rule, err := fetchRule(ctx, dbRule.ID)
 	if err != nil {
 		log.Printf("Error fetching created rule: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching created rule"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, Rule{
-		ID:           uuid.UUID(fullRule.ID.Bytes).String(),
-		MatchValue:   fullRule.MatchValue,
-		CategoryID:   uuid.UUID(fullRule.CategoryID.Bytes).String(),
-		CategoryName: fullRule.CategoryName,
-		Priority:     fullRule.Priority,
-		CreatedAt:    fullRule.CreatedAt.Time,
-		UpdatedAt:    fullRule.UpdatedAt.Time,
-	})
+	// Warnings are advisory, so a failed analysis does not fail the request
+	rule.Warnings, err = ruleWarnings(ctx, rule.ID)
+	if err != nil {
+		log.Printf("Error linting rules: %v", err)
+	}
+
+	c.JSON(http.StatusCreated, rule)
 }
 
 // @Summary Update rule
-// @Description Update an existing categorization rule
+// @Description Update an existing categorization rule. The response includes warnings when the rule is shadowed by, shadows or conflicts with another rule.
 // @Tags rules
 // @Accept json
 // @Produce json
@@ -135,52 +594,81 @@ func updateRule(c *gin.Context) {
 		return
 	}
 
-	if req.MatchValue == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "match_value cannot be empty"})
+	ruleParams, err := ruleParamsFromRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if req.CategoryID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "category_id cannot be empty"})
+	splits, err := ruleSplitsFromRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	categoryUUID, err := uuid.Parse(req.CategoryID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category_id format"})
+	ctx := context.Background()
+	if err := validateRuleReferences(ctx, queries, ruleParams.CategoryID, ruleParams.AssignTo, splits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	params := generated.UpdateRuleParams{
 		ID:         pgtype.UUID{Bytes: parsedID, Valid: true},
-		MatchValue: req.MatchValue,
-		CategoryID: pgtype.UUID{Bytes: categoryUUID, Valid: true},
-		Priority:   req.Priority,
+		MatchValue: ruleParams.MatchValue,
+		CategoryID: ruleParams.CategoryID,
+		Priority:   ruleParams.Priority,
+		MatchType:  ruleParams.MatchType,
+		MatchField: ruleParams.MatchField,
+		MinAmount:  ruleParams.MinAmount,
+		MaxAmount:  ruleParams.MaxAmount,
+		Direction:  ruleParams.Direction,
+		CardNumber: ruleParams.CardNumber,
+		StartDate:  ruleParams.StartDate,
+		EndDate:    ruleParams.EndDate,
+		AssignTo:   ruleParams.AssignTo,
+	}
+
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating rule"})
+		return
 	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
 
-	dbRule, err := queries.UpdateRule(context.Background(), params)
+	dbRule, err := qtx.UpdateRule(ctx, params)
 	if err != nil {
 		statusCode, msg := handleDatabaseError(err)
 		c.JSON(statusCode, gin.H{"error": msg})
 		return
 	}
+	if err := saveRuleSplits(ctx, qtx, dbRule.ID, splits); err != nil {
+		log.Printf("Error updating rule splits: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating rule"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating rule"})
+		return
+	}
+	ruleMatchers.invalidate()
 
 	// Fetch the full row with category name
-	fullRule, err := queries.GetRuleByID(context.Background(), dbRule.ID)
+	rule, err := fetchRule(ctx, dbRule.ID)
 	if err != nil {
 		log.Printf("Error fetching updated rule: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching updated rule"})
 		return
 	}
 
-	c.JSON(http.StatusOK, Rule{
-		ID:           uuid.UUID(fullRule.ID.Bytes).String(),
-		MatchValue:   fullRule.MatchValue,
-		CategoryID:   uuid.UUID(fullRule.CategoryID.Bytes).String(),
-		CategoryName: fullRule.CategoryName,
-		Priority:     fullRule.Priority,
-		CreatedAt:    fullRule.CreatedAt.Time,
-		UpdatedAt:    fullRule.UpdatedAt.Time,
-	})
+	// Warnings are advisory, so a failed analysis does not fail the request
+	rule.Warnings, err = ruleWarnings(ctx, rule.ID)
+	if err != nil {
+		log.Printf("Error linting rules: %v", err)
+	}
+
+	c.JSON(http.StatusOK, rule)
 }
 
 // @Summary Delete rule
@@ -204,6 +692,111 @@ func deleteRule(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting rule"})
 		return
 	}
+	ruleMatchers.invalidate()
 
 	c.Status(http.StatusNoContent)
 }
+
+// @Summary Test rules
+// @Description Run a sample transaction through the categorization rules without saving anything. Returns every matching rule in priority order, the rule that wins and the category it resolves to, or fallback true when no rule matched, the category classifier was not confident and Other applies. Rules whose category no longer exists are left out, since imports cannot use them.
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param transaction body RuleTestRequest true "Sample transaction (description or category required; amount is positive for expenses)"
+// @Success 200 {object} RuleTestResult "How the transaction would be categorized"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /api/rules/test [post]
+func testRules(c *gin.Context) {
+	var req RuleTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if strings.TrimSpace(req.Description) == "" && strings.TrimSpace(req.Category) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "description or category is required"})
+		return
+	}
+
+	record := importRecord{
+		Description: req.Description,
+		CsvCategory: req.Category,
+		Amount:      req.Amount,
+	}
+	if req.CardNumber != nil {
+		record.CardNumber = *req.CardNumber
+	}
+	if req.TransactionDate != nil {
+		parsed, err := time.Parse("2006-01-02", *req.TransactionDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "transaction_date must be a date in YYYY-MM-DD format"})
+			return
+		}
+		record.TransactionDate = pgtype.Date{Time: parsed, Valid: true}
+	}
+
+	ctx := context.Background()
+	dbRules, err := queries.GetRules(ctx)
+	if err != nil {
+		log.Printf("Error fetching rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error testing rules"})
+		return
+	}
+	dbSplits, err := queries.GetRuleSplits(ctx)
+	if err != nil {
+		log.Printf("Error fetching rule splits: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error testing rules"})
+		return
+	}
+	splitsByRule := groupRuleSplits(dbSplits)
+	rulesByID := make(map[pgtype.UUID]generated.GetRulesRow, len(dbRules))
+	for _, r := range dbRules {
+		rulesByID[r.ID] = r
+	}
+
+	result := RuleTestResult{Matches: make([]RuleTestMatch, 0)}
+	if categoryMapping := categoryMappings.get(); categoryMapping != nil {
+		// The matches and the winner come from the matcher imports use, so a rule
+		// whose category no longer exists is listed as it is used: not at all
+		matcher, err := ruleMatchers.get(ctx, categoryMapping)
+		if err != nil {
+			log.Printf("Error fetching rules: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error testing rules"})
+			return
+		}
+		hits := matcher.matchAll(record)
+		for _, hit := range hits {
+			result.Matches = append(result.Matches, RuleTestMatch{
+				Rule:         convertRule(rulesByID[hit.rule.rule.ID], splitsByRule[hit.rule.rule.ID]),
+				MatchedField: hit.field,
+			})
+		}
+
+		category := matcher.fallback
+		if len(hits) > 0 {
+			category = hits[0].rule.category
+			result.WinningRule = &result.Matches[0].Rule
+		} else {
+			classifier, err := categoryClassifiers.get(ctx, categoryMapping)
+			if err != nil {
+				log.Printf("Error training category classifier: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error testing rules"})
+				return
+			}
+			if predicted, confidence := categoryMapping.classifyRecord(classifier, record); predicted != nil {
+				category = predicted
+				result.Confidence = &confidence
+			}
+		}
+		if category != nil {
+			categoryID := uuid.UUID(category.ID.Bytes).String()
+			categoryName := category.Name
+			result.CategoryID = &categoryID
+			result.CategoryName = &categoryName
+		}
+		// A category chosen by the classifier is not the Other fallback
+		result.Fallback = len(hits) == 0 && result.Confidence == nil
+	}
+
+	c.JSON(http.StatusOK, result)
+}
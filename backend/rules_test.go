@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"jointanalysis/db/generated"
 
@@ -24,10 +26,14 @@ func createTestRule(matchValue, categoryID string, priority int32) (string, erro
 		MatchValue: matchValue,
 		CategoryID: pgtype.UUID{Bytes: catUUID, Valid: true},
 		Priority:   priority,
+		MatchType:  ruleMatchContains,
+		MatchField: ruleFieldAny,
 	})
 	if err != nil {
 		return "", err
 	}
+	// The rule bypasses the handlers, so the cached matcher must be dropped here
+	ruleMatchers.invalidate()
 
 	return uuid.UUID(rule.ID.Bytes).String(), nil
 }
@@ -128,6 +134,70 @@ func TestCreateRule(t *testing.T) {
 		if rule.CategoryName != "Groceries Test" {
 			t.Errorf("Expected category_name 'Groceries Test', got %q", rule.CategoryName)
 		}
+		if rule.MatchType != "contains" {
+			t.Errorf("Expected default match_type 'contains', got %q", rule.MatchType)
+		}
+	})
+
+	t.Run("should create regex rule", func(t *testing.T) {
+		catID, err := createTestCategory("Gas Test", "", "#00FF00")
+		assertNoError(t, err)
+
+		requestBody := map[string]interface{}{
+			"match_value": `\bgas\b`,
+			"match_type":  "regex",
+			"category_id": catID,
+			"priority":    0,
+		}
+
+		body, err := json.Marshal(requestBody)
+		assertNoError(t, err)
+
+		resp := makeRequest("POST", "/api/rules", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		var rule Rule
+		assertNoError(t, parseJSONResponse(resp, &rule))
+
+		if rule.MatchType != "regex" {
+			t.Errorf("Expected match_type 'regex', got %q", rule.MatchType)
+		}
+	})
+
+	t.Run("should return 400 for an invalid regex", func(t *testing.T) {
+		catID, err := createTestCategory("Bad Regex Cat", "", "#0000FF")
+		assertNoError(t, err)
+
+		requestBody := map[string]interface{}{
+			"match_value": "([a-z",
+			"match_type":  "regex",
+			"category_id": catID,
+			"priority":    0,
+		}
+
+		body, err := json.Marshal(requestBody)
+		assertNoError(t, err)
+
+		resp := makeRequest("POST", "/api/rules", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("should return 400 for an unknown match_type", func(t *testing.T) {
+		catID, err := createTestCategory("Glob Cat", "", "#0000FF")
+		assertNoError(t, err)
+
+		requestBody := map[string]interface{}{
+			"match_value": "shell*",
+			"match_type":  "glob",
+			"category_id": catID,
+			"priority":    0,
+		}
+
+		body, err := json.Marshal(requestBody)
+		assertNoError(t, err)
+
+		resp := makeRequest("POST", "/api/rules", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
 	})
 
 	t.Run("should return 400 when match_value is missing", func(t *testing.T) {
@@ -216,6 +286,40 @@ func TestUpdateRule(t *testing.T) {
 		}
 	})
 
+	t.Run("should update match_type and validate regex", func(t *testing.T) {
+		catID, err := createTestCategory("Match Type Cat", "", "#FF5733")
+		assertNoError(t, err)
+
+		ruleID, err := createTestRule("Shell", catID, 0)
+		assertNoError(t, err)
+
+		requestBody := map[string]interface{}{
+			"match_value": "Shell",
+			"match_type":  "prefix",
+			"category_id": catID,
+			"priority":    0,
+		}
+		body, err := json.Marshal(requestBody)
+		assertNoError(t, err)
+
+		resp := makeRequest("PUT", "/api/rules/"+ruleID, bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var rule Rule
+		assertNoError(t, parseJSONResponse(resp, &rule))
+		if rule.MatchType != "prefix" {
+			t.Errorf("Expected match_type 'prefix', got %q", rule.MatchType)
+		}
+
+		requestBody["match_type"] = "regex"
+		requestBody["match_value"] = "(shell"
+		body, err = json.Marshal(requestBody)
+		assertNoError(t, err)
+
+		resp = makeRequest("PUT", "/api/rules/"+ruleID, bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+
 	t.Run("should return 400 for invalid UUID", func(t *testing.T) {
 		requestBody := map[string]interface{}{
 			"match_value": "X",
@@ -263,3 +367,505 @@ func TestDeleteRule(t *testing.T) {
 		assertStatusCode(t, http.StatusBadRequest, resp.Code)
 	})
 }
+
+// TestRuleConditions tests creating rules with conditions and how they categorize imports
+func TestRuleConditions(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	// Rules can only target categories loaded into the category mapping
+	shoppingID := testCategoryID("Shopping")
+	reimbursableID := testCategoryID("Reimbursable")
+
+	createRule := func(requestBody map[string]interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(requestBody)
+		assertNoError(t, err)
+		return makeRequest("POST", "/api/rules", bytes.NewBuffer(body))
+	}
+
+	t.Run("should create rule with conditions", func(t *testing.T) {
+		resp := createRule(map[string]interface{}{
+			"match_value": "AMAZON",
+			"match_field": "description",
+			"max_amount":  20,
+			"direction":   "debit",
+			"card_number": "1234",
+			"start_date":  "2024-01-01",
+			"end_date":    "2024-12-31",
+			"category_id": shoppingID,
+			"priority":    0,
+		})
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		var rule Rule
+		assertNoError(t, parseJSONResponse(resp, &rule))
+
+		if rule.MatchField != "description" {
+			t.Errorf("Expected match_field 'description', got %q", rule.MatchField)
+		}
+		if rule.MinAmount != nil || rule.MaxAmount == nil || *rule.MaxAmount != 20 {
+			t.Errorf("Expected only max_amount 20, got min %v max %v", rule.MinAmount, rule.MaxAmount)
+		}
+		if rule.Direction == nil || *rule.Direction != "debit" {
+			t.Errorf("Expected direction 'debit', got %v", rule.Direction)
+		}
+		if rule.CardNumber == nil || *rule.CardNumber != "1234" {
+			t.Errorf("Expected card_number '1234', got %v", rule.CardNumber)
+		}
+		if rule.StartDate == nil || *rule.StartDate != "2024-01-01" || rule.EndDate == nil || *rule.EndDate != "2024-12-31" {
+			t.Errorf("Expected date window 2024-01-01 to 2024-12-31, got %v to %v", rule.StartDate, rule.EndDate)
+		}
+	})
+
+	t.Run("should return 400 for invalid conditions", func(t *testing.T) {
+		invalid := []map[string]interface{}{
+			{"match_field": "memo"},
+			{"min_amount": 50, "max_amount": 20},
+			{"min_amount": -1},
+			{"direction": "sideways"},
+			{"start_date": "01/01/2024"},
+			{"start_date": "2024-12-31", "end_date": "2024-01-01"},
+		}
+		for _, conditions := range invalid {
+			requestBody := map[string]interface{}{
+				"match_value": "AMAZON",
+				"category_id": shoppingID,
+				"priority":    0,
+			}
+			for key, value := range conditions {
+				requestBody[key] = value
+			}
+
+			resp := createRule(requestBody)
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %v, got %d", conditions, resp.Code)
+			}
+		}
+	})
+
+	t.Run("should categorize imports by conditions", func(t *testing.T) {
+		resp := createRule(map[string]interface{}{
+			"match_value": "AMAZON",
+			"match_field": "description",
+			"direction":   "credit",
+			"category_id": reimbursableID,
+			"priority":    1,
+		})
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-02-01,2024-02-02,1234,AMAZON MKTP,Shopping,12.99,
+2024-02-03,2024-02-04,1234,AMAZON MKTP,Shopping,89.00,
+2024-02-05,2024-02-06,5678,AMAZON MKTP,Shopping,9.99,
+2024-02-07,2024-02-08,1234,AMAZON REFUND,Shopping,,12.99`
+		body, contentType := createCSVFile(t, "amazon.csv", csvContent)
+		req, err := http.NewRequest("POST", "/api/upload-csv/preview", body)
+		assertNoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		resp = makeRequestWithCustomRequest(req)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result struct {
+			Rows []ImportPreviewRow `json:"rows"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &result))
+
+		expected := []string{"Shopping", "Other", "Other", "Reimbursable"}
+		if len(result.Rows) != len(expected) {
+			t.Fatalf("Expected %d preview rows, got %d", len(expected), len(result.Rows))
+		}
+		for i, row := range result.Rows {
+			if row.CategoryName == nil || *row.CategoryName != expected[i] {
+				t.Errorf("Expected row %d category %q, got %v", i+1, expected[i], row.CategoryName)
+			}
+		}
+	})
+}
+
+// TestRuleActions tests rules that assign people and apply split templates
+func TestRuleActions(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	// Rules can only target categories loaded into the category mapping
+	foodID := testCategoryID("Food & Dining")
+	reimbursableID := testCategoryID("Reimbursable")
+	aliceID, err := createTestPerson("Alice", "")
+	assertNoError(t, err)
+
+	createRule := func(requestBody map[string]interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(requestBody)
+		assertNoError(t, err)
+		return makeRequest("POST", "/api/rules", bytes.NewBuffer(body))
+	}
+
+	t.Run("should create rule with people and a split template", func(t *testing.T) {
+		resp := createRule(map[string]interface{}{
+			"match_value": "TEAM LUNCH",
+			"category_id": foodID,
+			"priority":    0,
+			"assign_to":   []string{aliceID},
+			"splits": []map[string]interface{}{
+				{"category_id": foodID, "percentage": 60},
+				{"category_id": reimbursableID, "percentage": 40},
+			},
+		})
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		var rule Rule
+		assertNoError(t, parseJSONResponse(resp, &rule))
+
+		if len(rule.AssignTo) != 1 || rule.AssignTo[0] != aliceID {
+			t.Errorf("Expected assign_to [%s], got %v", aliceID, rule.AssignTo)
+		}
+		if len(rule.Splits) != 2 {
+			t.Fatalf("Expected 2 splits, got %d", len(rule.Splits))
+		}
+		if rule.Splits[0].CategoryName != "Food & Dining" || rule.Splits[0].Percentage != 60 {
+			t.Errorf("Expected first split 60%% Food & Dining, got %+v", rule.Splits[0])
+		}
+		if rule.Splits[1].CategoryName != "Reimbursable" || rule.Splits[1].Percentage != 40 {
+			t.Errorf("Expected second split 40%% Reimbursable, got %+v", rule.Splits[1])
+		}
+	})
+
+	t.Run("should return 400 for invalid actions", func(t *testing.T) {
+		invalid := []map[string]interface{}{
+			{"assign_to": []string{"not-a-uuid"}},
+			{"assign_to": []string{uuid.New().String()}},
+			{"assign_to": []string{aliceID, aliceID}},
+			{"splits": []map[string]interface{}{{"category_id": foodID, "percentage": 60}}},
+			{"splits": []map[string]interface{}{{"category_id": foodID, "percentage": 0}, {"category_id": reimbursableID, "percentage": 100}}},
+			{"splits": []map[string]interface{}{{"category_id": uuid.New().String(), "percentage": 100}}},
+		}
+		for _, actions := range invalid {
+			requestBody := map[string]interface{}{
+				"match_value": "INVALID",
+				"category_id": foodID,
+				"priority":    0,
+			}
+			for key, value := range actions {
+				requestBody[key] = value
+			}
+
+			resp := createRule(requestBody)
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %v, got %d", actions, resp.Code)
+			}
+		}
+	})
+
+	t.Run("should assign and split imported transactions", func(t *testing.T) {
+		csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-03-01,2024-03-02,1234,TEAM LUNCH CAFE,Dining,100.01,
+2024-03-02,2024-03-03,1234,GROCERY OUTLET,Groceries,20.00,`
+		resp := uploadTestFile(t, "actions.csv", csvContent, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result struct {
+			Transactions []Transaction `json:"transactions"`
+		}
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if len(result.Transactions) != 2 {
+			t.Fatalf("Expected 2 transactions, got %d", len(result.Transactions))
+		}
+
+		lunch := result.Transactions[0]
+		if len(lunch.AssignedTo) != 1 || lunch.AssignedTo[0] != "Alice" {
+			t.Errorf("Expected lunch to be assigned to Alice, got %v", lunch.AssignedTo)
+		}
+		if len(result.Transactions[1].AssignedTo) != 0 {
+			t.Errorf("Expected unmatched transaction to stay unassigned, got %v", result.Transactions[1].AssignedTo)
+		}
+
+		splitsResp := makeRequest("GET", "/api/transactions/"+lunch.ID+"/splits", nil)
+		assertStatusCode(t, http.StatusOK, splitsResp.Code)
+		var splits []TransactionSplit
+		assertNoError(t, parseJSONResponse(splitsResp, &splits))
+
+		amounts := make(map[string]float64)
+		for _, split := range splits {
+			amounts[split.CategoryID] = split.Amount
+		}
+		if len(splits) != 2 || amounts[foodID] != 60.01 || amounts[reimbursableID] != 40.00 {
+			t.Errorf("Expected splits of 60.01 and 40.00, got %+v", splits)
+		}
+	})
+
+	t.Run("should remove deleted people from rules", func(t *testing.T) {
+		resp := makeRequest("DELETE", "/api/people/"+aliceID, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		listResp := makeRequest("GET", "/api/rules", nil)
+		var rules []Rule
+		assertNoError(t, parseJSONResponse(listResp, &rules))
+
+		for _, rule := range rules {
+			if len(rule.AssignTo) != 0 {
+				t.Errorf("Expected rule %q to assign no one, got %v", rule.MatchValue, rule.AssignTo)
+			}
+		}
+	})
+}
+
+// TestTestRules tests the POST /api/rules/test endpoint
+func TestTestRules(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	foodID := testCategoryID("Food & Dining")
+	shoppingID := testCategoryID("Shopping")
+	wholeFoodsID, err := createTestRule("WHOLE FOODS", foodID, 0)
+	assertNoError(t, err)
+	foodsID, err := createTestRule("FOODS", shoppingID, 1)
+	assertNoError(t, err)
+
+	testRules := func(requestBody map[string]interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(requestBody)
+		assertNoError(t, err)
+		return makeRequest("POST", "/api/rules/test", bytes.NewBuffer(body))
+	}
+
+	t.Run("should return every match and the winning rule", func(t *testing.T) {
+		resp := testRules(map[string]interface{}{
+			"description": "WHOLE FOODS MARKET",
+			"amount":      42.10,
+		})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result RuleTestResult
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if len(result.Matches) != 2 {
+			t.Fatalf("Expected 2 matches, got %d", len(result.Matches))
+		}
+		if result.Matches[0].ID != wholeFoodsID || result.Matches[1].ID != foodsID {
+			t.Errorf("Expected matches in priority order, got %s and %s", result.Matches[0].ID, result.Matches[1].ID)
+		}
+		if result.Matches[0].MatchedField != ruleFieldDescription {
+			t.Errorf("Expected match on description, got %s", result.Matches[0].MatchedField)
+		}
+		if result.WinningRule == nil || result.WinningRule.ID != wholeFoodsID {
+			t.Errorf("Expected winning rule %s, got %+v", wholeFoodsID, result.WinningRule)
+		}
+		if result.CategoryName == nil || *result.CategoryName != "Food & Dining" {
+			t.Errorf("Expected category Food & Dining, got %v", result.CategoryName)
+		}
+		if result.Fallback {
+			t.Errorf("Expected no fallback")
+		}
+	})
+
+	t.Run("should match the CSV category", func(t *testing.T) {
+		resp := testRules(map[string]interface{}{
+			"description": "MARKET",
+			"category":    "Foods & Groceries",
+			"amount":      10,
+		})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result RuleTestResult
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if len(result.Matches) != 1 || result.Matches[0].MatchedField != ruleFieldCategory {
+			t.Errorf("Expected one match on category, got %+v", result.Matches)
+		}
+	})
+
+	t.Run("should report the Other fallback when nothing matches", func(t *testing.T) {
+		resp := testRules(map[string]interface{}{
+			"description": "HARDWARE STORE",
+			"amount":      12,
+			"card_number": "1234",
+		})
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var result RuleTestResult
+		assertNoError(t, parseJSONResponse(resp, &result))
+		if len(result.Matches) != 0 || result.WinningRule != nil {
+			t.Errorf("Expected no matches, got %+v", result)
+		}
+		if !result.Fallback || result.CategoryName == nil || *result.CategoryName != "Other" {
+			t.Errorf("Expected the Other fallback, got %+v", result)
+		}
+	})
+
+	t.Run("should return 400 for invalid requests", func(t *testing.T) {
+		invalid := []map[string]interface{}{
+			{"amount": 10},
+			{"description": "WHOLE FOODS", "transaction_date": "03/01/2024"},
+		}
+		for _, requestBody := range invalid {
+			resp := testRules(requestBody)
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %v, got %d", requestBody, resp.Code)
+			}
+		}
+	})
+}
+
+// TestRuleStats tests rule hit statistics and the GET /api/rules/report endpoint
+func TestRuleStats(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	foodID := testCategoryID("Food & Dining")
+	shoppingID := testCategoryID("Shopping")
+	coffeeRuleID, err := createTestRule("COFFEE", foodID, 0)
+	assertNoError(t, err)
+	unusedRuleID, err := createTestRule("NEVER SEEN", shoppingID, 1)
+	assertNoError(t, err)
+
+	csvContent := `Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
+2024-05-01,2024-05-02,1234,CORNER COFFEE,,4.50,
+2024-05-02,2024-05-03,1234,COFFEE ROASTERS,,18.00,
+2024-05-03,2024-05-04,1234,HARDWARE STORE,,12.00,`
+	resp := uploadTestFile(t, "stats.csv", csvContent, nil)
+	assertStatusCode(t, http.StatusOK, resp.Code)
+	var imported struct {
+		Transactions []Transaction `json:"transactions"`
+	}
+	assertNoError(t, parseJSONResponse(resp, &imported))
+	if len(imported.Transactions) != 3 {
+		t.Fatalf("Expected 3 transactions, got %d", len(imported.Transactions))
+	}
+
+	getRule := func(id string) Rule {
+		t.Helper()
+		resp := makeRequest("GET", "/api/rules", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var rules []Rule
+		assertNoError(t, parseJSONResponse(resp, &rules))
+		for _, rule := range rules {
+			if rule.ID == id {
+				return rule
+			}
+		}
+		t.Fatalf("Rule %s not found", id)
+		return Rule{}
+	}
+	resplit := func(transactionID, categoryID string, amount float64) {
+		t.Helper()
+		body, err := json.Marshal(map[string]interface{}{
+			"splits": []map[string]interface{}{{"category_id": categoryID, "amount": amount}},
+		})
+		assertNoError(t, err)
+		resp := makeRequest("PUT", "/api/transactions/"+transactionID+"/splits", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+	}
+
+	t.Run("should count hits on import", func(t *testing.T) {
+		rule := getRule(coffeeRuleID)
+		if rule.HitCount != 2 {
+			t.Errorf("Expected hit_count 2, got %d", rule.HitCount)
+		}
+		if rule.LastMatchedAt == nil {
+			t.Errorf("Expected last_matched_at to be set")
+		}
+		if unused := getRule(unusedRuleID); unused.HitCount != 0 || unused.LastMatchedAt != nil {
+			t.Errorf("Expected the unused rule to have no hits, got %+v", unused)
+		}
+	})
+
+	t.Run("should count overrides once per transaction", func(t *testing.T) {
+		// Re-splitting to the rule's own category is not an override
+		resplit(imported.Transactions[1].ID, foodID, 18.00)
+		if rule := getRule(coffeeRuleID); rule.OverrideCount != 0 {
+			t.Errorf("Expected override_count 0, got %d", rule.OverrideCount)
+		}
+
+		resplit(imported.Transactions[0].ID, shoppingID, 4.50)
+		resplit(imported.Transactions[0].ID, shoppingID, 4.50)
+		if rule := getRule(coffeeRuleID); rule.OverrideCount != 1 {
+			t.Errorf("Expected override_count 1, got %d", rule.OverrideCount)
+		}
+	})
+
+	t.Run("should report unused and overridden rules", func(t *testing.T) {
+		resp := makeRequest("GET", "/api/rules/report?min_override_rate=0.5", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var report RuleReport
+		assertNoError(t, parseJSONResponse(resp, &report))
+		if len(report.NeverMatched) != 1 || report.NeverMatched[0].ID != unusedRuleID {
+			t.Errorf("Expected the unused rule to never match, got %+v", report.NeverMatched)
+		}
+		if len(report.FrequentlyOverridden) != 1 || report.FrequentlyOverridden[0].ID != coffeeRuleID {
+			t.Errorf("Expected the COFFEE rule to be frequently overridden, got %+v", report.FrequentlyOverridden)
+		}
+		if len(report.Stale) != 0 {
+			t.Errorf("Expected no stale rules, got %+v", report.Stale)
+		}
+	})
+
+	t.Run("should return 400 for invalid thresholds", func(t *testing.T) {
+		for _, query := range []string{"stale_days=0", "stale_days=soon", "min_override_rate=1.5", "min_override_rate=0"} {
+			resp := makeRequest("GET", "/api/rules/report?"+query, nil)
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d", query, resp.Code)
+			}
+		}
+	})
+
+	t.Run("should take back hits when the import is undone", func(t *testing.T) {
+		undo := makeRequest("DELETE", "/api/imports/"+importIDOf(t, resp), nil)
+		assertStatusCode(t, http.StatusOK, undo.Code)
+
+		rule := getRule(coffeeRuleID)
+		if rule.HitCount != 0 {
+			t.Errorf("Expected hit_count 0, got %d", rule.HitCount)
+		}
+		if rule.OverrideCount != 1 {
+			t.Errorf("Expected override_count to be kept, got %d", rule.OverrideCount)
+		}
+	})
+}
+
+// TestRuleChangeNotifications tests that rules changed outside the server reach
+// the cached rule matcher
+func TestRuleChangeNotifications(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go listenForCacheChanges(ctx, testDB)
+
+	matchedCategory := func(description string) string {
+		matcher, err := ruleMatchers.get(ctx, categoryMappings.get())
+		assertNoError(t, err)
+		category, _ := matcher.match(importRecord{Description: description, Amount: 5})
+		if category == nil {
+			return ""
+		}
+		return category.Name
+	}
+	waitFor := func(description, want string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for matchedCategory(description) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %q to be categorized as %s after the notification", description, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Compile the matcher before the rule exists
+	if name := matchedCategory("EXTERNAL CAFE"); name != "Other" {
+		t.Fatalf("Expected Other before the rule exists, got %s", name)
+	}
+
+	_, err := testDB.Exec(ctx,
+		"INSERT INTO categorization_rules (match_value, category_id, priority) VALUES ('external cafe', $1, 1)",
+		testCategoryID("Food & Dining"))
+	assertNoError(t, err)
+	waitFor("EXTERNAL CAFE", "Food & Dining")
+
+	_, err = testDB.Exec(ctx, "DELETE FROM categorization_rules WHERE match_value = 'external cafe'")
+	assertNoError(t, err)
+	waitFor("EXTERNAL CAFE", "Other")
+}
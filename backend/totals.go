@@ -11,7 +11,7 @@ import (
 // Totals handler functions
 
 // @Summary Get totals by person
-// @Description Get calculated expense totals for each person from active transactions
+// @Description Get calculated expense totals for each person from active transactions. Splits in categories excluded from totals, or whose parent category is, are left out.
 // @Tags totals
 // @Produce json
 // @Success 200 {array} Total "List of totals by person"
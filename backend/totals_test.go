@@ -1,6 +1,9 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"testing"
 )
@@ -333,4 +336,96 @@ func TestGetTotals(t *testing.T) {
 			}
 		}
 	})
-}
\ No newline at end of file
+}
+
+// TestTotalsExcludedCategories tests that categories excluded from totals, and
+// their subcategories, are left out of person totals and archive snapshots
+func TestTotalsExcludedCategories(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	personID, err := createTestPerson("Alice Johnson", "alice@example.com")
+	assertNoError(t, err)
+	replaceSplits := func(transactionID string, splits ...map[string]interface{}) {
+		body, err := json.Marshal(map[string]interface{}{"splits": splits})
+		assertNoError(t, err)
+		resp := makeRequest("PUT", fmt.Sprintf("/api/transactions/%s/splits", transactionID), bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+	}
+	totalFor := func(person string) float64 {
+		resp := makeRequest("GET", "/api/totals", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var totals []Total
+		assertNoError(t, parseJSONResponse(resp, &totals))
+		for _, total := range totals {
+			if total.Person == person {
+				return total.Total
+			}
+		}
+		return 0
+	}
+
+	reimbursableID := testCategoryID("Reimbursable")
+	body, err := json.Marshal(map[string]string{"name": "Conferences", "parent_id": reimbursableID})
+	assertNoError(t, err)
+	resp := makeRequest("POST", "/api/categories", bytes.NewBuffer(body))
+	assertStatusCode(t, http.StatusCreated, resp.Code)
+	var conferences Category
+	assertNoError(t, parseJSONResponse(resp, &conferences))
+
+	tripID, err := createTestTransaction("Business Trip", 100.00, "test.csv", []string{personID})
+	assertNoError(t, err)
+	replaceSplits(tripID,
+		map[string]interface{}{"amount": 60.0, "category_id": reimbursableID},
+		map[string]interface{}{"amount": 40.0, "category_id": testCategoryID("Other")},
+	)
+	summitID, err := createTestTransaction("Summit Ticket", 250.00, "test.csv", []string{personID})
+	assertNoError(t, err)
+	replaceSplits(summitID, map[string]interface{}{"amount": 250.0, "category_id": conferences.ID})
+	_, err = createTestTransaction("Groceries", 30.00, "test.csv", []string{personID})
+	assertNoError(t, err)
+
+	t.Run("should leave excluded categories and their subcategories out of totals", func(t *testing.T) {
+		if total := totalFor("Alice Johnson"); total != 70.00 {
+			t.Errorf("Expected a total of 70.00 without Reimbursable and Conferences, got %.2f", total)
+		}
+	})
+
+	t.Run("should include a category again once the flag is cleared", func(t *testing.T) {
+		body, err := json.Marshal(map[string]interface{}{"name": "Reimbursable", "exclude_from_totals": false})
+		assertNoError(t, err)
+		resp := makeRequest("PUT", "/api/categories/"+reimbursableID, bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var updated Category
+		assertNoError(t, parseJSONResponse(resp, &updated))
+		if updated.ExcludeFromTotals {
+			t.Error("Expected exclude_from_totals to be cleared")
+		}
+
+		if total := totalFor("Alice Johnson"); total != 380.00 {
+			t.Errorf("Expected a total of 380.00 with every category, got %.2f", total)
+		}
+
+		body, err = json.Marshal(map[string]interface{}{"name": "Reimbursable", "exclude_from_totals": true})
+		assertNoError(t, err)
+		resp = makeRequest("PUT", "/api/categories/"+reimbursableID, bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("should leave excluded categories out of archive snapshots", func(t *testing.T) {
+		body, err := json.Marshal(ArchiveRequest{Description: "Excluded categories"})
+		assertNoError(t, err)
+		resp := makeRequest("POST", "/api/archives", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+		var archive Archive
+		assertNoError(t, parseJSONResponse(resp, &archive))
+
+		if archive.TotalAmount != 70.00 || len(archive.PersonTotals) != 1 || archive.PersonTotals[0].Total != 70.00 {
+			t.Errorf("Expected an archive total of 70.00 for Alice Johnson, got %+v", archive)
+		}
+		if archive.TransactionCount != 3 {
+			t.Errorf("Expected all 3 transactions archived, got %d", archive.TransactionCount)
+		}
+	})
+}
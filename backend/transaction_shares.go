@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"jointanalysis/db/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Limits of the transaction_shares weight DECIMAL(12, 4) and fixed_amount
+// DECIMAL(12, 2) columns
+const (
+	maxShareWeight      = 1e8
+	maxShareFixedAmount = 1e10
+)
+
+// shareRequest is a person's share in an assignment request: either a weight,
+// relative to the others without a fixed amount, or a fixed amount
+type shareRequest struct {
+	PersonID    string   `json:"person_id"`
+	Weight      *float64 `json:"weight"`
+	FixedAmount *float64 `json:"fixed_amount"`
+}
+
+func convertTransactionShareRow(s generated.GetTransactionSharesRow) TransactionShare {
+	result := TransactionShare{
+		PersonID:   uuid.UUID(s.PersonID.Bytes).String(),
+		PersonName: s.PersonName,
+	}
+
+	if weight, ok := numericValue(s.Weight); ok {
+		result.Weight = &weight
+	}
+	if fixedAmount, ok := numericValue(s.FixedAmount); ok {
+		result.FixedAmount = &fixedAmount
+	}
+	if share, ok := numericValue(s.Share); ok {
+		result.Share = share
+	}
+
+	return result
+}
+
+func loadTransactionShares(transactionID pgtype.UUID) ([]TransactionShare, error) {
+	shares, err := queries.GetTransactionShares(context.Background(), transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]TransactionShare, 0, len(shares))
+	for _, share := range shares {
+		result = append(result, convertTransactionShareRow(share))
+	}
+
+	return result, nil
+}
+
+// validateTransactionShares checks the shares of a transaction between the people
+// it is assigned to and converts them to query parameters. Amount is the part of
+// the transaction they share: its splits without people of their own that count
+// in totals. The fixed amounts cannot exceed it, and must add up to it when every
+// assigned person has one, since nobody would take the remainder.
+func validateTransactionShares(transactionID pgtype.UUID, amount float64, assignedTo []pgtype.UUID, shares []shareRequest) ([]generated.CreateTransactionShareParams, error) {
+	assigned := make(map[pgtype.UUID]bool, len(assignedTo))
+	for _, personID := range assignedTo {
+		assigned[personID] = true
+	}
+
+	params := make([]generated.CreateTransactionShareParams, 0, len(shares))
+	seen := make(map[pgtype.UUID]bool, len(shares))
+	var fixedCents int64
+	fixedPeople := 0
+	for _, share := range shares {
+		personUUID, err := uuid.Parse(share.PersonID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid person_id format: %s", share.PersonID)
+		}
+		personID := pgtype.UUID{Bytes: personUUID, Valid: true}
+		if !assigned[personID] {
+			return nil, fmt.Errorf("person %s is not assigned to the transaction", personUUID)
+		}
+		if seen[personID] {
+			return nil, fmt.Errorf("person %s has more than one share", personUUID)
+		}
+		seen[personID] = true
+
+		param := generated.CreateTransactionShareParams{
+			TransactionID: transactionID,
+			PersonID:      personID,
+		}
+		switch {
+		case (share.Weight == nil) == (share.FixedAmount == nil):
+			return nil, fmt.Errorf("each share needs either a weight or a fixed_amount")
+		case share.Weight != nil:
+			// Weights are stored with 4 decimals, so smaller ones would round to 0
+			if math.Round(*share.Weight*10000) <= 0 {
+				return nil, fmt.Errorf("weight must be at least 0.0001")
+			}
+			if *share.Weight >= maxShareWeight {
+				return nil, fmt.Errorf("weight must be less than %.0f", maxShareWeight)
+			}
+			if err := param.Weight.Scan(fmt.Sprintf("%.4f", *share.Weight)); err != nil {
+				return nil, fmt.Errorf("invalid weight")
+			}
+		default:
+			if *share.FixedAmount < 0 {
+				return nil, fmt.Errorf("fixed_amount cannot be negative")
+			}
+			if *share.FixedAmount >= maxShareFixedAmount {
+				return nil, fmt.Errorf("fixed_amount must be less than %.0f", maxShareFixedAmount)
+			}
+			if err := param.FixedAmount.Scan(fmt.Sprintf("%.2f", *share.FixedAmount)); err != nil {
+				return nil, fmt.Errorf("invalid fixed_amount")
+			}
+			fixedCents += int64(math.Round(*share.FixedAmount * 100))
+			fixedPeople++
+		}
+		params = append(params, param)
+	}
+
+	amountCents := int64(math.Round(math.Abs(amount) * 100))
+	if fixedCents > amountCents {
+		return nil, fmt.Errorf("fixed amounts add up to more than the shared amount of the transaction")
+	}
+	if fixedPeople > 0 && fixedPeople == len(assigned) && fixedCents != amountCents {
+		return nil, fmt.Errorf("fixed amounts must add up to the shared amount of the transaction when every assigned person has one")
+	}
+
+	return params, nil
+}
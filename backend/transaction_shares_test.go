@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// floatPtr returns a pointer to a share weight or fixed amount
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestValidateTransactionShares(t *testing.T) {
+	transactionID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	alice := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	bob := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	assigned := []pgtype.UUID{alice, bob}
+
+	t.Run("accepts weights", func(t *testing.T) {
+		params, err := validateTransactionShares(transactionID, 100, assigned, []shareRequest{
+			{PersonID: alice.String(), Weight: floatPtr(70)},
+			{PersonID: bob.String(), Weight: floatPtr(30)},
+		})
+		require.NoError(t, err)
+		require.Len(t, params, 2)
+		assert.Equal(t, alice, params[0].PersonID)
+		assert.True(t, params[0].Weight.Valid)
+		assert.False(t, params[0].FixedAmount.Valid)
+	})
+
+	t.Run("accepts a fixed amount with the remainder for the others", func(t *testing.T) {
+		params, err := validateTransactionShares(transactionID, -50, assigned, []shareRequest{
+			{PersonID: alice.String(), FixedAmount: floatPtr(20)},
+		})
+		require.NoError(t, err)
+		require.Len(t, params, 1)
+		assert.True(t, params[0].FixedAmount.Valid)
+	})
+
+	t.Run("accepts the smallest stored weight", func(t *testing.T) {
+		params, err := validateTransactionShares(transactionID, 100, assigned, []shareRequest{
+			{PersonID: alice.String(), Weight: floatPtr(0.0001)},
+		})
+		require.NoError(t, err)
+		require.Len(t, params, 1)
+	})
+
+	t.Run("accepts no shares", func(t *testing.T) {
+		params, err := validateTransactionShares(transactionID, 100, assigned, nil)
+		require.NoError(t, err)
+		assert.Empty(t, params)
+	})
+
+	t.Run("requires fixed amounts to cover the amount when everyone has one", func(t *testing.T) {
+		_, err := validateTransactionShares(transactionID, 50, assigned, []shareRequest{
+			{PersonID: alice.String(), FixedAmount: floatPtr(20)},
+			{PersonID: bob.String(), FixedAmount: floatPtr(20)},
+		})
+		assert.Error(t, err)
+
+		_, err = validateTransactionShares(transactionID, 50, assigned, []shareRequest{
+			{PersonID: alice.String(), FixedAmount: floatPtr(20)},
+			{PersonID: bob.String(), FixedAmount: floatPtr(30)},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects fixed amounts over the transaction amount", func(t *testing.T) {
+		_, err := validateTransactionShares(transactionID, 50, assigned, []shareRequest{
+			{PersonID: alice.String(), FixedAmount: floatPtr(60)},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects invalid shares", func(t *testing.T) {
+		invalid := [][]shareRequest{
+			{{PersonID: uuid.New().String(), Weight: floatPtr(1)}},
+			{{PersonID: "not-a-uuid", Weight: floatPtr(1)}},
+			{{PersonID: alice.String()}},
+			{{PersonID: alice.String(), Weight: floatPtr(1), FixedAmount: floatPtr(10)}},
+			{{PersonID: alice.String(), Weight: floatPtr(0)}},
+			{{PersonID: alice.String(), Weight: floatPtr(0.00001)}},
+			{{PersonID: alice.String(), Weight: floatPtr(1e8)}},
+			{{PersonID: alice.String(), FixedAmount: floatPtr(1e300)}},
+			{{PersonID: alice.String(), FixedAmount: floatPtr(-5)}},
+			{{PersonID: alice.String(), Weight: floatPtr(1)}, {PersonID: alice.String(), Weight: floatPtr(2)}},
+		}
+		for _, shares := range invalid {
+			_, err := validateTransactionShares(transactionID, 100, assigned, shares)
+			assert.Error(t, err, "shares %+v", shares)
+		}
+	})
+}
+
+func TestTransactionShares(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	aliceID, err := createTestPerson("Alice", "")
+	assertNoError(t, err)
+	bobID, err := createTestPerson("Bob", "")
+	assertNoError(t, err)
+
+	assign := func(transactionID string, assignment map[string]interface{}) *Transaction {
+		body, _ := json.Marshal(assignment)
+		resp := makeRequest("PUT", "/api/transactions/"+transactionID+"/assign", bytes.NewBuffer(body))
+		if resp.Code != http.StatusOK {
+			t.Errorf("Expected status 200 assigning %v, got %d: %s", assignment, resp.Code, resp.Body.String())
+			return nil
+		}
+		var transaction Transaction
+		assertNoError(t, parseJSONResponse(resp, &transaction))
+		return &transaction
+	}
+	totals := func() map[string]float64 {
+		resp := makeRequest("GET", "/api/totals", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var result []Total
+		assertNoError(t, parseJSONResponse(resp, &result))
+		byPerson := make(map[string]float64)
+		for _, total := range result {
+			byPerson[total.Person] = total.Total
+		}
+		return byPerson
+	}
+
+	dinnerID, err := createTestTransaction("Dinner", 100.00, "test.csv", nil)
+	assertNoError(t, err)
+	taxiID, err := createTestTransaction("Taxi", 50.00, "test.csv", nil)
+	assertNoError(t, err)
+
+	t.Run("should divide by weight and fixed amount", func(t *testing.T) {
+		dinner := assign(dinnerID, map[string]interface{}{
+			"assigned_to": []string{aliceID, bobID},
+			"shares": []map[string]interface{}{
+				{"person_id": aliceID, "weight": 70},
+				{"person_id": bobID, "weight": 30},
+			},
+		})
+		if dinner != nil && len(dinner.Shares) != 2 {
+			t.Errorf("Expected 2 shares, got %+v", dinner.Shares)
+		}
+		assign(taxiID, map[string]interface{}{
+			"assigned_to": []string{aliceID, bobID},
+			"shares":      []map[string]interface{}{{"person_id": aliceID, "fixed_amount": 20}},
+		})
+
+		byPerson := totals()
+		if byPerson["Alice"] != 90.00 || byPerson["Bob"] != 60.00 {
+			t.Errorf("Expected Alice 90.00 and Bob 60.00, got %v", byPerson)
+		}
+	})
+
+	t.Run("should reject invalid shares", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"assigned_to": []string{aliceID},
+			"shares":      []map[string]interface{}{{"person_id": bobID, "weight": 1}},
+		})
+		resp := makeRequest("PUT", "/api/transactions/"+dinnerID+"/assign", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("should divide equally again when assigned without shares", func(t *testing.T) {
+		assign(dinnerID, map[string]interface{}{"assigned_to": []string{aliceID, bobID}})
+
+		byPerson := totals()
+		if byPerson["Alice"] != 70.00 || byPerson["Bob"] != 80.00 {
+			t.Errorf("Expected Alice 70.00 and Bob 80.00, got %v", byPerson)
+		}
+	})
+
+	t.Run("should store the shares in archive person totals", func(t *testing.T) {
+		body, _ := json.Marshal(ArchiveRequest{Description: "Shares"})
+		resp := makeRequest("POST", "/api/archives", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusCreated, resp.Code)
+
+		var archive Archive
+		assertNoError(t, parseJSONResponse(resp, &archive))
+		byPerson := make(map[string]float64)
+		for _, total := range archive.PersonTotals {
+			byPerson[total.Name] = total.Total
+		}
+		if byPerson["Alice"] != 70.00 || byPerson["Bob"] != 80.00 {
+			t.Errorf("Expected archived totals Alice 70.00 and Bob 80.00, got %v", byPerson)
+		}
+		if archive.TotalAmount != 150.00 {
+			t.Errorf("Expected an archived total amount of 150.00, got %.2f", archive.TotalAmount)
+		}
+
+		resp = makeRequest("GET", "/api/archives/"+archive.ID+"/transactions", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var transactions []Transaction
+		assertNoError(t, parseJSONResponse(resp, &transactions))
+		for _, transaction := range transactions {
+			if transaction.Description != "Taxi" {
+				continue
+			}
+			shares := make(map[string]float64)
+			for _, share := range transaction.Shares {
+				shares[share.PersonName] = share.Share
+			}
+			if shares["Alice"] != 0.4 || shares["Bob"] != 0.6 {
+				t.Errorf("Expected archived taxi shares of 0.4 and 0.6, got %+v", transaction.Shares)
+			}
+		}
+	})
+}
+
+func TestFixedSharesOfSharedSplits(t *testing.T) {
+	if err := cleanupTestData(); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	aliceID, err := createTestPerson("Alice", "")
+	assertNoError(t, err)
+	bobID, err := createTestPerson("Bob", "")
+	assertNoError(t, err)
+	carolID, err := createTestPerson("Carol", "")
+	assertNoError(t, err)
+
+	billID, err := createTestTransaction("Bill", 100.00, "test.csv", nil)
+	assertNoError(t, err)
+
+	setSplits := func(splits []map[string]interface{}) {
+		body, _ := json.Marshal(map[string]interface{}{"splits": splits})
+		resp := makeRequest("PUT", "/api/transactions/"+billID+"/splits", bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+	}
+	assignShares := func(fixedAmount float64) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{
+			"assigned_to": []string{aliceID, bobID},
+			"shares":      []map[string]interface{}{{"person_id": aliceID, "fixed_amount": fixedAmount}},
+		})
+		return makeRequest("PUT", "/api/transactions/"+billID+"/assign", bytes.NewBuffer(body))
+	}
+	totals := func() map[string]float64 {
+		resp := makeRequest("GET", "/api/totals", nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+		var result []Total
+		assertNoError(t, parseJSONResponse(resp, &result))
+		byPerson := make(map[string]float64)
+		for _, total := range result {
+			byPerson[total.Person] = total.Total
+		}
+		return byPerson
+	}
+
+	t.Run("should keep a fixed amount when a split has its own people", func(t *testing.T) {
+		setSplits([]map[string]interface{}{
+			{"amount": 30, "category_id": testCategoryID("Other"), "assigned_to": []string{carolID}},
+			{"amount": 70, "category_id": testCategoryID("Other")},
+		})
+		resp := assignShares(20)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		byPerson := totals()
+		if byPerson["Alice"] != 20.00 || byPerson["Bob"] != 50.00 || byPerson["Carol"] != 30.00 {
+			t.Errorf("Expected Alice 20.00, Bob 50.00 and Carol 30.00, got %v", byPerson)
+		}
+	})
+
+	t.Run("should keep a fixed amount when a split is excluded from totals", func(t *testing.T) {
+		setSplits([]map[string]interface{}{
+			{"amount": 30, "category_id": testCategoryID("Reimbursable")},
+			{"amount": 70, "category_id": testCategoryID("Other")},
+		})
+
+		byPerson := totals()
+		if byPerson["Alice"] != 20.00 || byPerson["Bob"] != 50.00 {
+			t.Errorf("Expected Alice 20.00 and Bob 50.00, got %v", byPerson)
+		}
+	})
+
+	t.Run("should reject fixed amounts over the shared amount", func(t *testing.T) {
+		resp := assignShares(80)
+		assertStatusCode(t, http.StatusBadRequest, resp.Code)
+	})
+}
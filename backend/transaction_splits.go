@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"math"
 	"net/http"
 
@@ -10,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -18,6 +21,9 @@ type splitRequest struct {
 		Amount     float64 `json:"amount"`
 		CategoryID string  `json:"category_id"`
 		Notes      *string `json:"notes"`
+		// People the split is assigned to, overriding the transaction's assigned_to.
+		// Omit it to use the transaction's assignment.
+		AssignedTo []string `json:"assigned_to"`
 	} `json:"splits"`
 }
 
@@ -36,10 +42,57 @@ func convertTransactionSplitRow(s generated.TransactionSplit) TransactionSplit {
 	if s.Notes.Valid {
 		result.Notes = &s.Notes.String
 	}
+	if s.AssignedTo != nil {
+		names, err := convertUUIDArrayToNames(s.AssignedTo)
+		if err != nil {
+			log.Printf("Error converting split assignees to names: %v", err)
+		}
+		result.AssignedTo = names
+	}
 
 	return result
 }
 
+// validateSplitAssignees checks the people a split is assigned to and converts
+// them to UUIDs. Nil means the split uses the transaction's assignment, so an
+// empty list is rejected rather than stored as a split nobody pays for.
+func validateSplitAssignees(ctx context.Context, q *generated.Queries, assignedTo []string) ([]pgtype.UUID, error) {
+	if assignedTo == nil {
+		return nil, nil
+	}
+	if len(assignedTo) == 0 {
+		return nil, fmt.Errorf("assigned_to must list at least one person, or be omitted to use the transaction's assignment")
+	}
+
+	personIDs := make([]pgtype.UUID, 0, len(assignedTo))
+	seen := make(map[pgtype.UUID]bool, len(assignedTo))
+	for _, id := range assignedTo {
+		personUUID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid person ID format: %s", id)
+		}
+		personID := pgtype.UUID{Bytes: personUUID, Valid: true}
+		if seen[personID] {
+			return nil, fmt.Errorf("person %s is assigned to a split more than once", personUUID)
+		}
+		seen[personID] = true
+		personIDs = append(personIDs, personID)
+	}
+
+	// assigned_to is an array without foreign keys, so unknown people would
+	// otherwise be stored silently
+	for _, personID := range personIDs {
+		if _, err := q.GetPersonByID(ctx, personID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, fmt.Errorf("person %s not found", uuid.UUID(personID.Bytes))
+			}
+			return nil, err
+		}
+	}
+
+	return personIDs, nil
+}
+
 func loadTransactionSplits(transactionID pgtype.UUID) ([]TransactionSplit, error) {
 	splits, err := queries.GetTransactionSplitsByTransactionID(context.Background(), transactionID)
 	if err != nil {
@@ -91,7 +144,7 @@ func getTransactionSplits(c *gin.Context) {
 }
 
 // @Summary Replace transaction splits
-// @Description Replace all split rows for a transaction.
+// @Description Replace all split rows for a transaction. A split can be assigned to its own people, overriding the transaction's assigned_to for its amount.
 // @Tags transactions
 // @Accept json
 // @Produce json
@@ -120,8 +173,9 @@ func replaceTransactionSplits(c *gin.Context) {
 		return
 	}
 
+	ctx := context.Background()
 	transactionID := pgtype.UUID{Bytes: transactionUUID, Valid: true}
-	tx, err := queries.GetTransactionByID(context.Background(), transactionID)
+	transaction, err := queries.GetTransactionByID(ctx, transactionID)
 	if err != nil {
 		statusCode, message := handleDatabaseError(err)
 		c.JSON(statusCode, gin.H{"error": message})
@@ -129,12 +183,13 @@ func replaceTransactionSplits(c *gin.Context) {
 	}
 
 	totalAbs := 0.0
-	if amountValue, err := tx.Amount.Float64Value(); err == nil {
+	if amountValue, err := transaction.Amount.Float64Value(); err == nil {
 		totalAbs = math.Abs(amountValue.Float64)
 	}
 
 	sum := 0.0
 	validatedCategoryUUIDs := make([]uuid.UUID, 0, len(request.Splits))
+	validatedAssignees := make([][]pgtype.UUID, 0, len(request.Splits))
 	for _, split := range request.Splits {
 		if split.Amount <= 0 {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "All split amounts must be positive"})
@@ -149,7 +204,13 @@ func replaceTransactionSplits(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
 			return
 		}
+		assignees, err := validateSplitAssignees(ctx, queries, split.AssignedTo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		validatedCategoryUUIDs = append(validatedCategoryUUIDs, categoryUUID)
+		validatedAssignees = append(validatedAssignees, assignees)
 		sum += split.Amount
 	}
 
@@ -158,7 +219,17 @@ func replaceTransactionSplits(c *gin.Context) {
 		return
 	}
 
-	if err := queries.DeleteTransactionSplitsByTransactionID(context.Background(), transactionID); err != nil {
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error replacing transaction splits"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if err := qtx.DeleteTransactionSplitsByTransactionID(ctx, transactionID); err != nil {
+		log.Printf("Error deleting transaction splits: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error replacing transaction splits"})
 		return
 	}
@@ -178,20 +249,52 @@ func replaceTransactionSplits(c *gin.Context) {
 			notes = pgtype.Text{String: *split.Notes, Valid: true}
 		}
 
-		row, err := queries.CreateTransactionSplit(context.Background(), generated.CreateTransactionSplitParams{
+		row, err := qtx.CreateTransactionSplit(ctx, generated.CreateTransactionSplitParams{
 			TransactionID: transactionID,
 			Amount:        amountNumeric,
 			CategoryID:    pgtype.UUID{Bytes: categoryUUID, Valid: true},
 			Notes:         notes,
+			AssignedTo:    validatedAssignees[i],
 		})
 		if err != nil {
+			log.Printf("Error creating transaction split: %v", err)
 			statusCode, message := handleDatabaseError(err)
 			c.JSON(statusCode, gin.H{"error": message})
 			return
 		}
 		created = append(created, convertTransactionSplitRow(row))
+	}
+
+	// Moving money to a category the transaction's rule does not assign counts
+	// against the rule, once per transaction
+	categoryIDs := make([]pgtype.UUID, 0, len(validatedCategoryUUIDs))
+	for _, categoryUUID := range validatedCategoryUUIDs {
+		categoryIDs = append(categoryIDs, pgtype.UUID{Bytes: categoryUUID, Valid: true})
+	}
+	err = qtx.RecordRuleOverride(ctx, generated.RecordRuleOverrideParams{
+		TransactionID: transactionID,
+		CategoryIds:   categoryIDs,
+	})
+	if err != nil {
+		log.Printf("Error recording rule override: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error replacing transaction splits"})
+		return
+	}
 
+	// Manually edited splits are left alone when rules are re-applied
+	if err := qtx.MarkTransactionSplitsEdited(ctx, transactionID); err != nil {
+		log.Printf("Error marking transaction splits edited: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error replacing transaction splits"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error replacing transaction splits"})
+		return
 	}
+	// The classifier learns from hand-edited splits
+	categoryClassifiers.invalidate()
 
 	c.JSON(http.StatusOK, created)
 }
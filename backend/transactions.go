@@ -2,14 +2,9 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
-	"fmt"
+	"errors"
 	"log"
-	"math"
-	"math/big"
 	"net/http"
-	"strconv"
-	"time"
 
 	"jointanalysis/db/generated"
 
@@ -21,221 +16,85 @@ import (
 // Transaction handler functions
 
 // @Summary Upload CSV file
-// @Description Upload a CSV file containing transaction data. Returns the successfully imported transactions and count of skipped rows.
+// @Description Upload a CSV or OFX/QFX statement containing transaction data. CSV columns are read using an import profile, either the one given by profile_id or the one whose header matches the file. OFX files are detected by extension or header and deduplicated by FITID. Each upload is saved atomically as an import batch that can be undone; a file identical to an earlier import is rejected unless force is set. Returns the successfully imported transactions and count of skipped rows.
 // @Tags transactions
 // @Accept multipart/form-data
 // @Produce json
-// @Param file formData file true "CSV file to upload"
-// @Success 200 {object} map[string]interface{} "Upload successful - returns message, transactions array, and skipped_rows count"
+// @Param file formData file true "CSV or OFX/QFX file to upload"
+// @Param profile_id formData string false "Import profile ID for CSV files (detected from the header row when omitted)"
+// @Param date_format formData string false "Date format for CSV files, overriding the profile's (e.g. DD.MM.YYYY)"
+// @Param decimal_separator formData string false "Decimal separator for CSV amounts, overriding the profile's ('.' or ',')"
+// @Param uploaded_by formData string false "ID of the person uploading the file"
+// @Param force formData bool false "Import the file even if an identical file was already imported"
+// @Success 200 {object} map[string]interface{} "Upload successful - returns message, format, import_id, transactions array, skipped_rows count, rejections array (line, raw, reason, message), and the import_profile used for CSV files"
 // @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 409 {object} map[string]interface{} "File already imported - returns the existing import_id"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /api/upload-csv [post]
 func uploadCSV(c *gin.Context) {
-	file, header, err := c.Request.FormFile("file")
+	upload, err := readUpload(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		respondUploadError(c, err)
 		return
 	}
-	defer file.Close()
+	defer upload.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error reading CSV file"})
+	upload.Forced = c.PostForm("force") == "true"
+	if !upload.Forced && rejectImportedFile(c, upload.FileHash) {
 		return
 	}
 
-	transactions := make([]Transaction, 0) // Initialize as empty slice instead of nil
-	fileName := header.Filename
-	skippedRows := 0
-
-	// Track how many times each dedup key appears in the current CSV file.
-	// This allows multiple identical rows in the same CSV to all be imported
-	// while still preventing re-import of rows that already exist in the DB.
-	seenCounts := make(map[string]int64)
-
-	// Skip header row if present
-	start := 0
-	if len(records) > 0 && records[0][0] == "Transaction Date" {
-		start = 1
-	}
-
-	for i := start; i < len(records); i++ {
-		record := records[i]
-		if len(record) < 7 { // Need exactly 7 columns for CSV format: Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
-			skippedRows++
-			continue
-		}
-
-		var description string
-		var amount float64
-		var err error
-
-		// Handle CSV format: Transaction Date,Posted Date,Card No.,Description,Category,Debit,Credit
-		transactionDate := record[0] // Transaction Date
-		postedDate := record[1]      // Posted Date
-		cardNumber := record[2]      // Card No.
-		description = record[3]      // Description
-		csvCategory := record[4]     // Category from CSV
-
-		// Parse amount from Debit (positive) or Credit (negative) columns
-		if record[5] != "" {
-			// Debit amount - keep as positive (expense)
-			amount, err = strconv.ParseFloat(record[5], 64)
-		} else if record[6] != "" {
-			// Credit amount - make negative (income/refund)
-			amount, err = strconv.ParseFloat(record[6], 64)
-			amount = -amount // Make credits negative
-		} else {
-			skippedRows++
-			continue // Skip if no amount found
-		}
-
-		if err != nil {
-			skippedRows++
-			continue
-		}
-
-		transaction := Transaction{
-			Description: description,
-			Amount:      amount,
-			FileName:    &fileName,
-		}
-
-		// Add the additional fields if they exist
-		if transactionDate != "" {
-			transaction.TransactionDate = &transactionDate
-		}
-		if postedDate != "" {
-			transaction.PostedDate = &postedDate
-		}
-		if cardNumber != "" {
-			transaction.CardNumber = &cardNumber
-		}
-
-		// Insert into database using generated query
-		// Convert float64 to pgtype.Numeric
-		amountBig := big.NewFloat(amount)
-		amountStr := amountBig.Text('f', 2) // Format to 2 decimal places
-		var amountNumeric pgtype.Numeric
-		if err := amountNumeric.Scan(amountStr); err != nil {
-			log.Printf("Error converting amount to numeric: %v", err)
-			skippedRows++
-			continue
-		}
-
-		params := generated.CreateTransactionParams{
-			Description: description,
-			Amount:      amountNumeric,
-			FileName:    pgtype.Text{String: fileName, Valid: true},
-		}
-
-		selectedCategory := (*generated.GetCategoriesRow)(nil)
-
-		// Map category if category mapping is available
-		if categoryMapping != nil {
-			if mappedCategory := categoryMapping.mapTransactionCategory(description, csvCategory); mappedCategory != nil {
-				selectedCategory = mappedCategory
-			}
-		}
-
-		if selectedCategory == nil && categoryMapping != nil {
-			if fallback, exists := categoryMapping.categoriesByName["Other"]; exists {
-				selectedCategory = &fallback
-			}
-		}
-
-		if selectedCategory == nil || !selectedCategory.ID.Valid {
-			skippedRows++
-			continue
-		}
-
-		// Add optional fields
-		if transactionDate != "" {
-			if parsedDate, err := time.Parse("2006-01-02", transactionDate); err == nil {
-				params.TransactionDate = pgtype.Date{Time: parsedDate, Valid: true}
-			}
-		}
-		if postedDate != "" {
-			if parsedDate, err := time.Parse("2006-01-02", postedDate); err == nil {
-				params.PostedDate = pgtype.Date{Time: parsedDate, Valid: true}
-			}
-		}
-		if cardNumber != "" {
-			params.CardNumber = pgtype.Text{String: cardNumber, Valid: true}
-		}
-
-		// Build a dedup key from all identifying fields and track how many
-		// times this row has appeared so far in the current CSV file.
-		dedupKey := fmt.Sprintf("%s|%s|%s|%s|%s",
-			description,
-			params.Amount.Int.String()+"e"+strconv.Itoa(int(params.Amount.Exp)),
-			params.TransactionDate.Time.Format("2006-01-02"),
-			params.PostedDate.Time.Format("2006-01-02"),
-			params.CardNumber.String,
-		)
-		seenCounts[dedupKey]++
-
-		// Check for duplicate transaction before inserting
-		duplicateParams := generated.FindDuplicateTransactionParams{
-			Description:     description,
-			Amount:          amountNumeric,
-			TransactionDate: params.TransactionDate,
-			PostedDate:      params.PostedDate,
-			CardNumber:      params.CardNumber,
-		}
-
-		count, err := queries.FindDuplicateTransaction(context.Background(), duplicateParams)
-		if err != nil {
-			log.Printf("Error checking for duplicate transaction: %v", err)
-			skippedRows++
-			continue
+	result, err := importTransactions(context.Background(), upload)
+	if err != nil {
+		// A concurrent upload of the same file can pass the check above; the
+		// unique file hash then fails this one
+		if statusCode, _ := handleDatabaseError(err); statusCode == http.StatusConflict && rejectImportedFile(c, upload.FileHash) {
+			return
 		}
-
-		// Skip only if the DB already has at least as many copies as we've
-		// seen so far in this CSV. This lets identical rows within one CSV
-		// all be imported on first upload while still preventing re-import.
-		if count >= seenCounts[dedupKey] {
-			log.Printf("Skipping duplicate transaction: %s, amount: %f", description, amount)
-			skippedRows++
-			continue
-		}
-
-		createdTransaction, err := queries.CreateTransaction(context.Background(), params)
-		if err != nil {
-			log.Printf("Error inserting transaction: %v", err)
-			skippedRows++
-			continue
+		if errors.Is(err, errUnreadableCSV) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errUnreadableCSV.Error()})
+			return
 		}
+		log.Printf("Error importing transactions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error importing transactions"})
+		return
+	}
 
-		splitAmount := math.Abs(amount)
-		var splitNumeric pgtype.Numeric
-		if err := splitNumeric.Scan(fmt.Sprintf("%.2f", splitAmount)); err != nil {
-			log.Printf("Error converting split amount to numeric: %v", err)
-			skippedRows++
-			continue
-		}
+	response := gin.H{
+		"message":      "CSV uploaded successfully",
+		"format":       upload.Format,
+		"import_id":    uuid.UUID(result.ImportID.Bytes).String(),
+		"transactions": result.Transactions,
+		"skipped_rows": len(result.Rejections),
+		"rejections":   result.Rejections,
+	}
+	if upload.Format == importFormatOFX {
+		response["message"] = "OFX uploaded successfully"
+	}
+	if upload.Profile != nil {
+		response["import_profile"] = upload.Profile.Name
+	}
 
-		_, err = queries.CreateTransactionSplit(context.Background(), generated.CreateTransactionSplitParams{
-			TransactionID: createdTransaction.ID,
-			Amount:        splitNumeric,
-			CategoryID:    selectedCategory.ID,
-			Notes:         pgtype.Text{Valid: false},
-		})
-		if err != nil {
-			log.Printf("Error inserting transaction split: %v", err)
-			skippedRows++
-			continue
-		}
+	c.JSON(http.StatusOK, response)
+}
 
-		transactions = append(transactions, transaction)
+// rejectImportedFile responds with 409 Conflict and the existing import_id when a
+// file with the same hash was already imported, and reports whether it responded
+func rejectImportedFile(c *gin.Context, fileHash string) bool {
+	existingImportID, err := findImportByFileHash(fileHash)
+	if err != nil {
+		log.Printf("Error checking for duplicate import: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking for duplicate import"})
+		return true
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":      "CSV uploaded successfully",
-		"transactions": transactions,
-		"skipped_rows": skippedRows,
+	if !existingImportID.Valid {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":     "This file has already been imported",
+		"import_id": uuid.UUID(existingImportID.Bytes).String(),
 	})
+	return true
 }
 
 // @Summary Get all transactions
@@ -265,6 +124,13 @@ func getTransactions(c *gin.Context) {
 			transaction.Splits = splits
 		}
 
+		shares, err := loadTransactionShares(t.ID)
+		if err != nil {
+			log.Printf("Error loading shares for transaction %s: %v", transaction.ID, err)
+		} else {
+			transaction.Shares = shares
+		}
+
 		transactions = append(transactions, transaction)
 	}
 
@@ -272,12 +138,12 @@ func getTransactions(c *gin.Context) {
 }
 
 // @Summary Assign transaction to person
-// @Description Assign a specific transaction to one or more people
+// @Description Assign a specific transaction to one or more people. By default the transaction is divided equally between them; shares give a person a weight, such as 70 and 30, or a fixed amount, with the remainder divided by weight between the others. The shares are replaced with the assignment.
 // @Tags transactions
 // @Accept json
 // @Produce json
 // @Param id path string true "Transaction ID"
-// @Param assignment body object{assigned_to=[]string} true "Assignment data with array of person names"
+// @Param assignment body object{assigned_to=[]string,shares=[]shareRequest} true "Assignment data with array of person IDs and optional shares"
 // @Success 200 {object} Transaction "Updated transaction with assignments"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 404 {object} map[string]interface{} "Transaction not found"
@@ -286,7 +152,8 @@ func getTransactions(c *gin.Context) {
 func assignTransaction(c *gin.Context) {
 	id := c.Param("id")
 	var request struct {
-		AssignedTo []string `json:"assigned_to"`
+		AssignedTo []string       `json:"assigned_to"`
+		Shares     []shareRequest `json:"shares"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -300,6 +167,7 @@ func assignTransaction(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
 		return
 	}
+	transactionID := pgtype.UUID{Bytes: transactionUUID, Valid: true}
 
 	// Convert UUID strings to pgtype.UUID array
 	assignedUUIDs, err := convertUUIDStringsToArray(request.AssignedTo)
@@ -308,13 +176,49 @@ func assignTransaction(c *gin.Context) {
 		return
 	}
 
+	ctx := context.Background()
+	existing, err := queries.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		statusCode, message := handleDatabaseError(err)
+		c.JSON(statusCode, gin.H{"error": message})
+		return
+	}
+
+	// Fixed amounts are taken from the splits the assigned people share
+	sharedAmount, err := queries.GetTransactionSharedAmount(ctx, existing.ID)
+	if err != nil {
+		log.Printf("Error loading shared amount: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating transaction"})
+		return
+	}
+	amount, ok := numericValue(sharedAmount)
+	if !ok {
+		log.Printf("Error converting shared amount of transaction %s", id)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating transaction"})
+		return
+	}
+	shares, err := validateTransactionShares(transactionID, amount, assignedUUIDs, request.Shares)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
 	// Create parameters for the generated function
 	params := generated.UpdateTransactionAssignmentParams{
-		ID:         pgtype.UUID{Bytes: transactionUUID, Valid: true},
+		ID:         transactionID,
 		AssignedTo: assignedUUIDs,
 	}
 
-	dbTransaction, err := queries.UpdateTransactionAssignment(context.Background(), params)
+	dbTransaction, err := qtx.UpdateTransactionAssignment(ctx, params)
 	if err != nil {
 		log.Printf("Error updating transaction: %v", err)
 		statusCode, message := handleDatabaseError(err)
@@ -322,8 +226,30 @@ func assignTransaction(c *gin.Context) {
 		return
 	}
 
+	if err := qtx.DeleteTransactionShares(ctx, transactionID); err != nil {
+		log.Printf("Error replacing transaction shares: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating transaction"})
+		return
+	}
+	for _, share := range shares {
+		if err := qtx.CreateTransactionShare(ctx, share); err != nil {
+			log.Printf("Error creating transaction share: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating transaction"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating transaction"})
+		return
+	}
+
 	// Convert and return the updated transaction
 	transaction := convertTransactionFromUpdateAssignmentRow(dbTransaction)
+	if transaction.Shares, err = loadTransactionShares(transactionID); err != nil {
+		log.Printf("Error loading shares for transaction %s: %v", transaction.ID, err)
+	}
 	c.JSON(http.StatusOK, transaction)
 }
 
@@ -362,6 +288,7 @@ func deleteTransaction(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting transaction"})
 		return
 	}
+	categoryClassifiers.invalidate()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Transaction deleted successfully"})
 }
@@ -380,6 +307,7 @@ func clearAllTransactions(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error clearing transactions"})
 		return
 	}
+	categoryClassifiers.invalidate()
 
 	c.JSON(http.StatusOK, gin.H{"message": "All transactions cleared successfully"})
 }
@@ -403,6 +403,91 @@ func TestTransactionSplits(t *testing.T) {
 		resp := makeRequest("PUT", fmt.Sprintf("/api/transactions/%s/splits", transactionID), bytes.NewBuffer(body))
 		assertStatusCode(t, http.StatusBadRequest, resp.Code)
 	})
+
+	t.Run("should total splits by their own assignees", func(t *testing.T) {
+		aliceID, err := createTestPerson("Split Alice", "")
+		assertNoError(t, err)
+		bobID, err := createTestPerson("Split Bob", "")
+		assertNoError(t, err)
+		transactionID, err := createTestTransaction("Split Restaurant", 80.00, "test.csv", []string{aliceID, bobID})
+		assertNoError(t, err)
+
+		body, err := json.Marshal(map[string]interface{}{
+			"splits": []map[string]interface{}{
+				{"amount": 30.0, "category_id": testCategoryID("Other"), "assigned_to": []string{aliceID}},
+				{"amount": 50.0, "category_id": testCategoryID("Other")},
+			},
+		})
+		assertNoError(t, err)
+
+		resp := makeRequest("PUT", fmt.Sprintf("/api/transactions/%s/splits", transactionID), bytes.NewBuffer(body))
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		var updated []TransactionSplit
+		assertNoError(t, parseJSONResponse(resp, &updated))
+		if len(updated) != 2 {
+			t.Fatalf("Expected 2 splits, got %d", len(updated))
+		}
+		if len(updated[0].AssignedTo) != 1 || updated[0].AssignedTo[0] != "Split Alice" {
+			t.Errorf("Expected the first split assigned to Split Alice, got %v", updated[0].AssignedTo)
+		}
+		if updated[1].AssignedTo != nil {
+			t.Errorf("Expected the second split to use the transaction's assignment, got %v", updated[1].AssignedTo)
+		}
+
+		totals := func() map[string]float64 {
+			resp := makeRequest("GET", "/api/totals", nil)
+			assertStatusCode(t, http.StatusOK, resp.Code)
+			var result []Total
+			assertNoError(t, parseJSONResponse(resp, &result))
+			byPerson := make(map[string]float64)
+			for _, total := range result {
+				byPerson[total.Person] = total.Total
+			}
+			return byPerson
+		}
+
+		byPerson := totals()
+		if byPerson["Split Alice"] != 55.00 || byPerson["Split Bob"] != 25.00 {
+			t.Errorf("Expected Split Alice 55.00 and Split Bob 25.00, got %v", byPerson)
+		}
+
+		// A split left without anyone falls back to the transaction's assignment
+		resp = makeRequest("DELETE", "/api/people/"+aliceID, nil)
+		assertStatusCode(t, http.StatusOK, resp.Code)
+
+		byPerson = totals()
+		if byPerson["Split Bob"] != 80.00 {
+			t.Errorf("Expected Split Bob 80.00 after deleting Split Alice, got %v", byPerson)
+		}
+	})
+
+	t.Run("should reject invalid split assignees", func(t *testing.T) {
+		personID, err := createTestPerson("Split Carol", "")
+		assertNoError(t, err)
+		transactionID, err := createTestTransaction("Split Assignees", 40.00, "test.csv", nil)
+		assertNoError(t, err)
+
+		invalid := [][]string{
+			{},
+			{"not-a-uuid"},
+			{uuid.New().String()},
+			{personID, personID},
+		}
+		for _, assignedTo := range invalid {
+			body, err := json.Marshal(map[string]interface{}{
+				"splits": []map[string]interface{}{
+					{"amount": 40.0, "category_id": testCategoryID("Other"), "assigned_to": assignedTo},
+				},
+			})
+			assertNoError(t, err)
+
+			resp := makeRequest("PUT", fmt.Sprintf("/api/transactions/%s/splits", transactionID), bytes.NewBuffer(body))
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for assigned_to %v, got %d", assignedTo, resp.Code)
+			}
+		}
+	})
 }
 
 // createCSVFile creates a multipart form with a CSV file
@@ -725,8 +810,9 @@ func TestUploadCSV(t *testing.T) {
 			t.Errorf("Expected 0 skipped rows on first upload, got %v", skipped1)
 		}
 
-		// Second upload: both rows already exist in DB, both should be skipped
-		body2, contentType2 := createCSVFile(t, "same_row2.csv", sameRowCSV)
+		// Second upload: both rows already exist in DB, both should be skipped.
+		// Identical files are rejected unless forced, so force the re-import.
+		body2, contentType2 := createCSVFileWithFields(t, "same_row2.csv", sameRowCSV, map[string]string{"force": "true"})
 		req2, err := http.NewRequest("POST", "/api/upload-csv", body2)
 		assertNoError(t, err)
 		req2.Header.Set("Content-Type", contentType2)
@@ -784,7 +870,7 @@ func TestUploadCSV(t *testing.T) {
 		}
 
 		// Second upload (duplicates should be skipped, not rejected)
-		body2, contentType2 := createCSVFile(t, "duplicate2.csv", duplicateCSV)
+		body2, contentType2 := createCSVFileWithFields(t, "duplicate2.csv", duplicateCSV, map[string]string{"force": "true"})
 		req2, err := http.NewRequest("POST", "/api/upload-csv", body2)
 		assertNoError(t, err)
 		req2.Header.Set("Content-Type", contentType2)
@@ -863,13 +949,14 @@ func TestUploadCSV(t *testing.T) {
 			t.Errorf("Expected 0 transactions for insufficient columns, got %d", len(transactions))
 		}
 
-		// Check that 2 rows were skipped due to insufficient columns
+		// Check that all 3 rows were skipped due to insufficient columns; the
+		// header names columns the profile does not map, so it is not skipped as one
 		skippedRows, ok := result["skipped_rows"].(float64)
 		if !ok {
 			t.Fatal("Expected skipped_rows field in response")
 		}
-		if skippedRows != 2 {
-			t.Errorf("Expected 2 skipped rows, got %v", skippedRows)
+		if skippedRows != 3 {
+			t.Errorf("Expected 3 skipped rows, got %v", skippedRows)
 		}
 	})
 }
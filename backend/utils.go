@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"regexp"
 	"strings"
@@ -14,9 +15,27 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-// CategoryMapping maps categories by name for lookup
+// CategoryMapping maps categories by name and by ID for lookup
 type CategoryMapping struct {
 	categoriesByName map[string]generated.GetCategoriesRow
+	categoriesByID   map[pgtype.UUID]*generated.GetCategoriesRow
+}
+
+// newCategoryMapping maps the categories that are not retired, since retired
+// categories are never chosen for new transactions
+func newCategoryMapping(categories []generated.GetCategoriesRow) *CategoryMapping {
+	cm := &CategoryMapping{
+		categoriesByName: make(map[string]generated.GetCategoriesRow, len(categories)),
+		categoriesByID:   make(map[pgtype.UUID]*generated.GetCategoriesRow, len(categories)),
+	}
+	for _, category := range categories {
+		if category.RetiredAt.Valid {
+			continue
+		}
+		cm.categoriesByName[category.Name] = category
+		cm.categoriesByID[category.ID] = &category
+	}
+	return cm
 }
 
 // Validation functions
@@ -54,6 +73,15 @@ func handleDatabaseError(err error) (statusCode int, message string) {
 		if strings.Contains(errorStr, "categories_name_key") {
 			return http.StatusConflict, "Category with this name already exists"
 		}
+		if strings.Contains(errorStr, "import_profiles_name_key") {
+			return http.StatusConflict, "Import profile with this name already exists"
+		}
+		if strings.Contains(errorStr, "idx_budgets_category_person_month") {
+			return http.StatusConflict, "Budget for this category, person and month already exists"
+		}
+		if strings.Contains(errorStr, "idx_imports_file_hash") {
+			return http.StatusConflict, "This file has already been imported"
+		}
 		return http.StatusConflict, "Resource already exists"
 	}
 
@@ -68,39 +96,58 @@ func handleDatabaseError(err error) (statusCode int, message string) {
 
 // Category mapping functions
 
-// mapTransactionCategory determines the best category for a transaction using DB rules.
-// It loads all rules ordered by priority and matches against description and csvCategory.
-func (cm *CategoryMapping) mapTransactionCategory(description, csvCategory string) *generated.GetCategoriesRow {
-	rules, err := queries.GetRulesForMatching(context.Background())
-	if err != nil {
-		log.Printf("Warning: failed to load categorization rules: %v", err)
-		// Fall back to "Other"
-		if category, exists := cm.categoriesByName["Other"]; exists {
-			return &category
+// ruleConditionsMet checks a rule's optional amount, direction, card and date
+// conditions. The date window uses the transaction date, or the posted date when
+// there is none; a record without either date never meets a date window.
+func ruleConditionsMet(rule generated.GetRulesForMatchingRow, record importRecord) bool {
+	amount := math.Abs(record.Amount)
+	if rule.MinAmount.Valid {
+		if min, err := rule.MinAmount.Float64Value(); err == nil && amount < min.Float64 {
+			return false
+		}
+	}
+	if rule.MaxAmount.Valid {
+		if max, err := rule.MaxAmount.Float64Value(); err == nil && amount > max.Float64 {
+			return false
 		}
-		return nil
 	}
 
-	descLower := strings.ToLower(description)
-	csvCatLower := strings.ToLower(csvCategory)
-
-	for _, rule := range rules {
-		matchLower := strings.ToLower(rule.MatchValue)
-		if strings.Contains(descLower, matchLower) || strings.Contains(csvCatLower, matchLower) {
-			// Load the category from the categoriesByName map by UUID match
-			for _, cat := range cm.categoriesByName {
-				if cat.ID == rule.CategoryID {
-					return &cat
-				}
+	if rule.Direction.Valid {
+		switch rule.Direction.String {
+		case ruleDirectionDebit:
+			if record.Amount <= 0 {
+				return false
+			}
+		case ruleDirectionCredit:
+			if record.Amount >= 0 {
+				return false
 			}
 		}
 	}
 
-	// Default to "Other" if no rule matched
-	if category, exists := cm.categoriesByName["Other"]; exists {
-		return &category
+	// Statements often mask all but the last digits, so the rule's card number
+	// matches the end of the transaction's
+	if rule.CardNumber.Valid && !strings.HasSuffix(strings.TrimSpace(record.CardNumber), rule.CardNumber.String) {
+		return false
 	}
-	return nil
+
+	if rule.StartDate.Valid || rule.EndDate.Valid {
+		date := record.TransactionDate
+		if !date.Valid {
+			date = record.PostedDate
+		}
+		if !date.Valid {
+			return false
+		}
+		if rule.StartDate.Valid && date.Time.Before(rule.StartDate.Time) {
+			return false
+		}
+		if rule.EndDate.Valid && date.Time.After(rule.EndDate.Time) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // initializeCategoryMapping loads categories and creates keyword mappings
@@ -110,14 +157,7 @@ func initializeCategoryMapping() (*CategoryMapping, error) {
 		return nil, fmt.Errorf("failed to load categories: %w", err)
 	}
 
-	categoriesByName := make(map[string]generated.GetCategoriesRow)
-	for _, category := range categories {
-		categoriesByName[category.Name] = category
-	}
-
-	return &CategoryMapping{
-		categoriesByName: categoriesByName,
-	}, nil
+	return newCategoryMapping(categories), nil
 }
 
 // UUID and conversion utility functions
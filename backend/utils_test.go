@@ -3,6 +3,9 @@ package main
 import (
 	"strings"
 	"testing"
+	"time"
+
+	"jointanalysis/db/generated"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -153,3 +156,166 @@ func TestConvertUUIDStringsToArray(t *testing.T) {
 		assert.True(t, result[0].Valid)
 	})
 }
+
+func TestRuleMatches(t *testing.T) {
+	cases := []struct {
+		matchType string
+		value     string
+		text      string
+		expected  bool
+	}{
+		{ruleMatchContains, "gas", "VEGAS HOTEL", true},
+		{ruleMatchExact, "gas", "VEGAS HOTEL", false},
+		{ruleMatchExact, "Shell Gas", " SHELL GAS ", true},
+		{ruleMatchExact, "Shell", "SHELL GAS", false},
+		{ruleMatchPrefix, "shell", "SHELL OIL 123", true},
+		{ruleMatchPrefix, "oil", "SHELL OIL 123", false},
+		{ruleMatchSuffix, "insurance", "GEICO INSURANCE", true},
+		{ruleMatchSuffix, "insurance", "INSURANCE REFUND", false},
+		{ruleMatchRegex, `\bgas\b`, "CHEVRON GAS #42", true},
+		{ruleMatchRegex, `\bgas\b`, "VEGAS HOTEL", false},
+		{ruleMatchRegex, `^amzn mktp`, "AMZN Mktp US*2K4", true},
+		{ruleMatchRegex, `(`, "anything", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.matchType+" "+tc.value+" in "+tc.text, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ruleMatches(tc.matchType, tc.value, tc.text))
+
+			rule := compileRule(generated.GetRulesForMatchingRow{MatchType: tc.matchType, MatchValue: tc.value})
+			assert.Equal(t, tc.expected, rule.matches(newRuleText(tc.text)))
+		})
+	}
+}
+
+func TestValidateRuleMatch(t *testing.T) {
+	matchType, err := validateRuleMatch("", "Trader Joe")
+	require.NoError(t, err)
+	assert.Equal(t, ruleMatchContains, matchType)
+
+	matchType, err = validateRuleMatch(ruleMatchRegex, `^shell\s+\d+`)
+	require.NoError(t, err)
+	assert.Equal(t, ruleMatchRegex, matchType)
+
+	_, err = validateRuleMatch(ruleMatchRegex, `([a-z`)
+	assert.Error(t, err)
+
+	_, err = validateRuleMatch("glob", "shell*")
+	assert.Error(t, err)
+}
+
+func TestRuleMatchesRecord(t *testing.T) {
+	numeric := func(value string) pgtype.Numeric {
+		var n pgtype.Numeric
+		require.NoError(t, n.Scan(value))
+		return n
+	}
+	date := func(value string) pgtype.Date {
+		parsed, err := time.Parse("2006-01-02", value)
+		require.NoError(t, err)
+		return pgtype.Date{Time: parsed, Valid: true}
+	}
+
+	smallAmazon := generated.GetRulesForMatchingRow{
+		MatchValue: "amazon",
+		MatchType:  ruleMatchContains,
+		MatchField: ruleFieldDescription,
+		MaxAmount:  numeric("20.00"),
+		Direction:  pgtype.Text{String: ruleDirectionDebit, Valid: true},
+		CardNumber: pgtype.Text{String: "1234", Valid: true},
+	}
+	amazonCredit := generated.GetRulesForMatchingRow{
+		MatchValue: "amazon",
+		MatchType:  ruleMatchContains,
+		MatchField: ruleFieldDescription,
+		Direction:  pgtype.Text{String: ruleDirectionCredit, Valid: true},
+	}
+	dining := generated.GetRulesForMatchingRow{
+		MatchValue: "dining",
+		MatchType:  ruleMatchExact,
+		MatchField: ruleFieldCategory,
+		StartDate:  date("2024-03-01"),
+		EndDate:    date("2024-03-31"),
+	}
+
+	cases := []struct {
+		name     string
+		rule     generated.GetRulesForMatchingRow
+		record   importRecord
+		expected bool
+	}{
+		{"small debit on card", smallAmazon, importRecord{Description: "AMAZON MKTP", Amount: 19.99, CardNumber: "XXXX1234"}, true},
+		{"amount at the bound", smallAmazon, importRecord{Description: "AMAZON MKTP", Amount: 20, CardNumber: "1234"}, true},
+		{"amount over the bound", smallAmazon, importRecord{Description: "AMAZON MKTP", Amount: 45, CardNumber: "1234"}, false},
+		{"other card", smallAmazon, importRecord{Description: "AMAZON MKTP", Amount: 10, CardNumber: "5678"}, false},
+		{"credit for a debit rule", smallAmazon, importRecord{Description: "AMAZON MKTP", Amount: -10, CardNumber: "1234"}, false},
+		{"credit", amazonCredit, importRecord{Description: "AMAZON REFUND", Amount: -10}, true},
+		{"debit for a credit rule", amazonCredit, importRecord{Description: "AMAZON MKTP", Amount: 10}, false},
+		{"description only", amazonCredit, importRecord{Description: "REFUND", CsvCategory: "Amazon", Amount: -10}, false},
+		{"category in window", dining, importRecord{Description: "CAFE", CsvCategory: "Dining", Amount: 8, TransactionDate: date("2024-03-31")}, true},
+		{"category before window", dining, importRecord{Description: "CAFE", CsvCategory: "Dining", Amount: 8, TransactionDate: date("2024-02-29")}, false},
+		{"posted date fallback", dining, importRecord{Description: "CAFE", CsvCategory: "Dining", Amount: 8, PostedDate: date("2024-03-02")}, true},
+		{"no date", dining, importRecord{Description: "CAFE", CsvCategory: "Dining", Amount: 8}, false},
+		{"category only", dining, importRecord{Description: "Dining", Amount: 8, TransactionDate: date("2024-03-02")}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ruleMatchesRecord(tc.rule, tc.record))
+		})
+	}
+}
+
+func TestRuleMatchedField(t *testing.T) {
+	rule := func(field string) generated.GetRulesForMatchingRow {
+		return generated.GetRulesForMatchingRow{MatchValue: "dining", MatchType: ruleMatchContains, MatchField: field}
+	}
+
+	cases := []struct {
+		name     string
+		rule     generated.GetRulesForMatchingRow
+		record   importRecord
+		expected string
+	}{
+		{"description", rule(ruleFieldAny), importRecord{Description: "FINE DINING"}, ruleFieldDescription},
+		{"category", rule(ruleFieldAny), importRecord{Description: "CAFE", CsvCategory: "Dining"}, ruleFieldCategory},
+		{"both prefer description", rule(ruleFieldAny), importRecord{Description: "FINE DINING", CsvCategory: "Dining"}, ruleFieldDescription},
+		{"category rule ignores description", rule(ruleFieldCategory), importRecord{Description: "FINE DINING", CsvCategory: "Dining"}, ruleFieldCategory},
+		{"no match", rule(ruleFieldDescription), importRecord{Description: "CAFE", CsvCategory: "Dining"}, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ruleMatchedField(tc.rule, tc.record))
+		})
+	}
+}
+
+func TestBuildRuleReport(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		at := now.AddDate(0, 0, -days)
+		return &at
+	}
+
+	rules := []Rule{
+		{MatchValue: "NEVER", HitCount: 0},
+		{MatchValue: "RECENT", HitCount: 10, LastMatchedAt: daysAgo(3)},
+		{MatchValue: "OLD", HitCount: 4, LastMatchedAt: daysAgo(120)},
+		{MatchValue: "WRONG", HitCount: 4, LastMatchedAt: daysAgo(1), OverrideCount: 2},
+		{MatchValue: "MOSTLY RIGHT", HitCount: 20, LastMatchedAt: daysAgo(1), OverrideCount: 1},
+	}
+	names := func(rules []Rule) []string {
+		result := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			result = append(result, rule.MatchValue)
+		}
+		return result
+	}
+
+	report := buildRuleReport(rules, now, 90, 0.25)
+	assert.Equal(t, []string{"NEVER"}, names(report.NeverMatched))
+	assert.Equal(t, []string{"OLD"}, names(report.Stale))
+	assert.Equal(t, []string{"WRONG"}, names(report.FrequentlyOverridden))
+
+	report = buildRuleReport(rules, now, 180, 0.05)
+	assert.Empty(t, report.Stale)
+	assert.Equal(t, []string{"WRONG", "MOSTLY RIGHT"}, names(report.FrequentlyOverridden))
+}
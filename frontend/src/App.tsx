@@ -12,11 +12,13 @@ import {
   InboxOutlined,
   FileTextOutlined,
   LineChartOutlined,
+  FundOutlined,
 } from '@ant-design/icons';
 import Dashboard from './Dashboard';
 import Settings from './Settings';
 import Archives from './Archives';
 import Trends from './Trends';
+import Budgets from './Budgets';
 
 const { Header, Content } = Layout;
 const { Title } = Typography;
@@ -37,6 +39,11 @@ const NavMenu: React.FC = () => {
       icon: <LineChartOutlined />,
       label: <Link to="/trends">Trends</Link>,
     },
+    {
+      key: '/budgets',
+      icon: <FundOutlined />,
+      label: <Link to="/budgets">Budgets</Link>,
+    },
     {
       key: '/archives',
       icon: <InboxOutlined />,
@@ -98,6 +105,7 @@ function App() {
             <Route path="/" element={<Dashboard />} />
             <Route path="/archives" element={<Archives />} />
             <Route path="/trends" element={<Trends />} />
+            <Route path="/budgets" element={<Budgets />} />
             <Route path="/settings" element={<Settings />} />
           </Routes>
         </Content>
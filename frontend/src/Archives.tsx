@@ -110,7 +110,8 @@ const Archives: React.FC = () => {
 
   const fetchCategories = async () => {
     try {
-      const response = await axios.get(`${API_URL}/api/categories`);
+      // Archived splits may use categories retired since
+      const response = await axios.get(`${API_URL}/api/categories?include_retired=true`);
       setCategories(response.data || []);
     } catch (error) {
       console.error('Error fetching categories:', error);
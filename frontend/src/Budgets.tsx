@@ -0,0 +1,428 @@
+import React, { useState, useEffect } from 'react';
+import axios from 'axios';
+import {
+  Typography,
+  Card,
+  Table,
+  Button,
+  Space,
+  Row,
+  Col,
+  Tag,
+  message,
+  Spin,
+  Modal,
+  Form,
+  Input,
+  Select,
+  Checkbox,
+  Popconfirm,
+  Alert,
+} from 'antd';
+import {
+  FundOutlined,
+  PlusOutlined,
+  EditOutlined,
+  DeleteOutlined,
+} from '@ant-design/icons';
+import { ColumnsType } from 'antd/es/table';
+import { Budget, BudgetReport, BudgetReportLine, Category, Person } from './types';
+
+const { Title } = Typography;
+const API_URL = import.meta.env.VITE_API_URL || 'http://localhost:8081';
+
+// currentMonth returns the current month as YYYY-MM, the format budgets use
+const currentMonth = (): string => {
+  const now = new Date();
+  return `${now.getFullYear()}-${String(now.getMonth() + 1).padStart(2, '0')}`;
+};
+
+const formatAmount = (amount: number) => `$${amount.toFixed(2)}`;
+
+const Budgets: React.FC = () => {
+  const [budgets, setBudgets] = useState<Budget[]>([]);
+  const [report, setReport] = useState<BudgetReport | null>(null);
+  const [categories, setCategories] = useState<Category[]>([]);
+  const [people, setPeople] = useState<Person[]>([]);
+  const [month, setMonth] = useState(currentMonth());
+  const [loading, setLoading] = useState(false);
+  const [budgetModalVisible, setBudgetModalVisible] = useState(false);
+  const [editingBudget, setEditingBudget] = useState<Budget | null>(null);
+  const [budgetForm] = Form.useForm();
+
+  useEffect(() => {
+    fetchBudgets();
+    fetchCategories();
+    fetchPeople();
+  }, []);
+
+  useEffect(() => {
+    fetchReport();
+  }, [month]);
+
+  const fetchBudgets = async () => {
+    try {
+      const response = await axios.get(`${API_URL}/api/budgets`);
+      setBudgets(response.data || []);
+    } catch (error) {
+      console.error('Error fetching budgets:', error);
+      message.error('Error fetching budgets');
+    }
+  };
+
+  const fetchReport = async () => {
+    if (!month) return;
+    try {
+      setLoading(true);
+      const response = await axios.get(`${API_URL}/api/budgets/report`, { params: { month } });
+      setReport(response.data);
+    } catch (error) {
+      console.error('Error fetching budget report:', error);
+      message.error('Error fetching budget report');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const fetchCategories = async () => {
+    try {
+      const response = await axios.get(`${API_URL}/api/categories`);
+      setCategories(response.data || []);
+    } catch (error) {
+      console.error('Error fetching categories:', error);
+    }
+  };
+
+  const fetchPeople = async () => {
+    try {
+      const response = await axios.get(`${API_URL}/api/people`);
+      setPeople(response.data || []);
+    } catch (error) {
+      console.error('Error fetching people:', error);
+    }
+  };
+
+  // Categories excluded from totals, or whose parent is, cannot be budgeted
+  const budgetCategories = categories.reduce<Category[]>((acc, cat) => {
+    if (cat.exclude_from_totals) return acc;
+    acc.push(cat);
+    (cat.subcategories || []).forEach((sub) => {
+      if (!sub.exclude_from_totals) acc.push(sub);
+    });
+    return acc;
+  }, []);
+
+  const openBudgetModal = (budget?: Budget) => {
+    setEditingBudget(budget || null);
+    setBudgetModalVisible(true);
+    if (budget) {
+      budgetForm.setFieldsValue({
+        category_id: budget.category_id,
+        person_id: budget.person_id ?? undefined,
+        month: budget.month,
+        amount: budget.amount,
+        rollover: budget.rollover,
+      });
+    } else {
+      budgetForm.resetFields();
+      budgetForm.setFieldsValue({ month });
+    }
+  };
+
+  const closeBudgetModal = () => {
+    setBudgetModalVisible(false);
+    setEditingBudget(null);
+    budgetForm.resetFields();
+  };
+
+  const refresh = () => {
+    fetchBudgets();
+    fetchReport();
+  };
+
+  const handleBudgetSubmit = async (values: any) => {
+    const payload = {
+      category_id: values.category_id,
+      person_id: values.person_id || null,
+      month: values.month,
+      amount: Number(values.amount),
+      rollover: !!values.rollover,
+    };
+    try {
+      if (editingBudget) {
+        await axios.put(`${API_URL}/api/budgets/${editingBudget.id}`, payload);
+        message.success('Budget updated successfully!');
+      } else {
+        await axios.post(`${API_URL}/api/budgets`, payload);
+        message.success('Budget created successfully!');
+      }
+      refresh();
+      closeBudgetModal();
+    } catch (error: any) {
+      console.error('Error saving budget:', error);
+      message.error(error.response?.data?.error || `Error ${editingBudget ? 'updating' : 'creating'} budget`);
+    }
+  };
+
+  const handleDeleteBudget = async (budgetId: string) => {
+    try {
+      await axios.delete(`${API_URL}/api/budgets/${budgetId}`);
+      message.success('Budget deleted successfully!');
+      refresh();
+    } catch (error) {
+      console.error('Error deleting budget:', error);
+      message.error('Error deleting budget');
+    }
+  };
+
+  const reportColumns: ColumnsType<BudgetReportLine> = [
+    {
+      title: 'Category',
+      dataIndex: 'category_name',
+      key: 'category_name',
+    },
+    {
+      title: 'Person',
+      dataIndex: 'person_name',
+      key: 'person_name',
+      render: (name: string | null) => name || 'Household',
+    },
+    {
+      title: 'Budgeted',
+      dataIndex: 'budgeted',
+      key: 'budgeted',
+      render: formatAmount,
+      align: 'right',
+    },
+    {
+      title: 'Rolled Over',
+      dataIndex: 'rolled_over',
+      key: 'rolled_over',
+      render: (amount: number) => (amount ? formatAmount(amount) : '-'),
+      align: 'right',
+    },
+    {
+      title: 'Spent',
+      dataIndex: 'spent',
+      key: 'spent',
+      render: formatAmount,
+      align: 'right',
+    },
+    {
+      title: 'Remaining',
+      dataIndex: 'remaining',
+      key: 'remaining',
+      render: (amount: number) => (
+        <span style={{ color: amount < 0 ? '#ff4d4f' : undefined }}>{formatAmount(amount)}</span>
+      ),
+      align: 'right',
+    },
+    {
+      title: 'Status',
+      dataIndex: 'status',
+      key: 'status',
+      render: (status: BudgetReportLine['status']) =>
+        status === 'over' ? <Tag color="red">Over budget</Tag> : <Tag color="green">On track</Tag>,
+      align: 'center',
+    },
+  ];
+
+  const budgetColumns: ColumnsType<Budget> = [
+    {
+      title: 'Category',
+      dataIndex: 'category_name',
+      key: 'category_name',
+    },
+    {
+      title: 'Person',
+      dataIndex: 'person_name',
+      key: 'person_name',
+      render: (name: string | null) => name || 'Household',
+    },
+    {
+      title: 'From',
+      dataIndex: 'month',
+      key: 'month',
+    },
+    {
+      title: 'Amount',
+      dataIndex: 'amount',
+      key: 'amount',
+      render: formatAmount,
+      align: 'right',
+    },
+    {
+      title: 'Rollover',
+      dataIndex: 'rollover',
+      key: 'rollover',
+      render: (rollover: boolean) => (rollover ? 'Yes' : 'No'),
+      align: 'center',
+    },
+    {
+      title: 'Actions',
+      key: 'actions',
+      align: 'right',
+      render: (_, record: Budget) => (
+        <Space size={4}>
+          <Button
+            type="text"
+            icon={<EditOutlined />}
+            size="small"
+            onClick={() => openBudgetModal(record)}
+          />
+          <Popconfirm
+            title="Delete Budget"
+            description={`Delete the ${record.category_name} budget from ${record.month}?`}
+            onConfirm={() => handleDeleteBudget(record.id)}
+            okText="Yes"
+            cancelText="No"
+          >
+            <Button
+              type="text"
+              danger
+              icon={<DeleteOutlined />}
+              size="small"
+            />
+          </Popconfirm>
+        </Space>
+      ),
+    },
+  ];
+
+  return (
+    <div style={{ padding: '24px' }}>
+      <Title level={2}>
+        <FundOutlined style={{ marginRight: 8 }} />
+        Budgets
+      </Title>
+
+      <Card
+        title="Budget vs Actual"
+        variant="borderless"
+        extra={
+          <Input
+            type="month"
+            value={month}
+            onChange={(e) => setMonth(e.target.value)}
+            style={{ width: 160 }}
+          />
+        }
+        style={{ marginBottom: 24 }}
+      >
+        <Spin spinning={loading}>
+          {report && report.over_budget > 0 && (
+            <Alert
+              type="warning"
+              showIcon
+              message={`${report.over_budget} budget${report.over_budget === 1 ? ' is' : 's are'} over for ${report.month}`}
+              style={{ marginBottom: 16 }}
+            />
+          )}
+          <Table
+            columns={reportColumns}
+            dataSource={report?.lines || []}
+            rowKey="budget_id"
+            pagination={false}
+            locale={{ emptyText: 'No budgets in effect for this month' }}
+          />
+        </Spin>
+      </Card>
+
+      <Card
+        title={`Budgets (${budgets.length})`}
+        variant="borderless"
+        extra={
+          <Button type="primary" icon={<PlusOutlined />} onClick={() => openBudgetModal()}>
+            Add Budget
+          </Button>
+        }
+      >
+        <Table
+          columns={budgetColumns}
+          dataSource={budgets}
+          rowKey="id"
+          pagination={false}
+        />
+      </Card>
+
+      <Modal
+        title={editingBudget ? 'Edit Budget' : 'Add Budget'}
+        open={budgetModalVisible}
+        onCancel={closeBudgetModal}
+        footer={null}
+        width={480}
+      >
+        <Form
+          form={budgetForm}
+          layout="vertical"
+          onFinish={handleBudgetSubmit}
+          initialValues={{ rollover: false }}
+        >
+          <Form.Item
+            name="category_id"
+            label="Category"
+            rules={[{ required: true, message: 'Please select a category' }]}
+          >
+            <Select
+              showSearch
+              optionFilterProp="children"
+              placeholder="Select a category"
+            >
+              {budgetCategories.map((cat) => (
+                <Select.Option key={cat.id} value={cat.id}>
+                  {cat.parent_id ? `↳ ${cat.name}` : cat.name}
+                </Select.Option>
+              ))}
+            </Select>
+          </Form.Item>
+
+          <Form.Item name="person_id" label="Person">
+            <Select allowClear placeholder="Household">
+              {people.map((person) => (
+                <Select.Option key={person.id} value={person.id}>
+                  {person.name}
+                </Select.Option>
+              ))}
+            </Select>
+          </Form.Item>
+
+          <Row gutter={12}>
+            <Col span={12}>
+              <Form.Item
+                name="month"
+                label="From Month"
+                rules={[{ required: true, message: 'Please select a month' }]}
+              >
+                <Input type="month" />
+              </Form.Item>
+            </Col>
+            <Col span={12}>
+              <Form.Item
+                name="amount"
+                label="Monthly Amount"
+                rules={[{ required: true, message: 'Please enter an amount' }]}
+              >
+                <Input type="number" min={0} step="0.01" />
+              </Form.Item>
+            </Col>
+          </Row>
+
+          <Form.Item name="rollover" valuePropName="checked">
+            <Checkbox>Roll unspent amounts over into the next month</Checkbox>
+          </Form.Item>
+
+          <Form.Item style={{ marginBottom: 0, textAlign: 'right' }}>
+            <Space>
+              <Button onClick={closeBudgetModal}>Cancel</Button>
+              <Button type="primary" htmlType="submit">
+                {editingBudget ? 'Update' : 'Create'}
+              </Button>
+            </Space>
+          </Form.Item>
+        </Form>
+      </Modal>
+    </div>
+  );
+};
+
+export default Budgets;
@@ -1,5 +1,6 @@
 import React, { useState, useEffect } from 'react';
 import axios from 'axios';
+import { Link } from 'react-router-dom';
 import {
   Typography,
   Card,
@@ -16,6 +17,7 @@ import {
   Checkbox,
   Modal,
   InputNumber,
+  Alert,
 } from 'antd';
 import {
   UploadOutlined,
@@ -26,22 +28,32 @@ import {
   ClearOutlined,
   PieChartOutlined,
   InboxOutlined,
+  PercentageOutlined,
 } from '@ant-design/icons';
 import { Pie } from '@ant-design/charts';
 import { UploadProps, RcFile } from 'antd/es/upload';
 import { ColumnsType } from 'antd/es/table';
-import { Transaction, Person, Category, PersonTotal, TransactionSplit } from './types';
-import { getCategoryColor, generateColorVariants } from './utils';
+import { Transaction, Person, Category, PersonTotal, TransactionSplit, BudgetReport } from './types';
+import { getCategoryColor, generateColorVariants, getSplitPersonShare, getTransactionPeople } from './utils';
 
 const { Text } = Typography;
 const { Option } = Select;
 const API_URL = import.meta.env.VITE_API_URL || 'http://localhost:8081';
 
+// ShareRow is a person's share being edited: a weight, or a fixed amount
+interface ShareRow {
+  person_id: string;
+  person_name: string;
+  mode: 'weight' | 'fixed';
+  value: number;
+}
+
 const Dashboard: React.FC = () => {
   const [transactions, setTransactions] = useState<Transaction[]>([]);
   const [people, setPeople] = useState<Person[]>([]);
   const [categories, setCategories] = useState<Category[]>([]);
   const [totals, setTotals] = useState<PersonTotal[]>([]);
+  const [budgetReport, setBudgetReport] = useState<BudgetReport | null>(null);
   const [loading, setLoading] = useState(false);
   const [uploading, setUploading] = useState(false);
   const [archiving, setArchiving] = useState(false);
@@ -52,6 +64,10 @@ const Dashboard: React.FC = () => {
   const [splitSaving, setSplitSaving] = useState(false);
   const [splitRows, setSplitRows] = useState<TransactionSplit[]>([]);
   const [splitTransaction, setSplitTransaction] = useState<Transaction | null>(null);
+  const [shareModalOpen, setShareModalOpen] = useState(false);
+  const [shareSaving, setShareSaving] = useState(false);
+  const [shareRows, setShareRows] = useState<ShareRow[]>([]);
+  const [shareTransaction, setShareTransaction] = useState<Transaction | null>(null);
   // drillDownState maps personName → top-level category ID being drilled into (null = top-level view)
   const [drillDownState, setDrillDownState] = useState<Record<string, string | null>>({});
 
@@ -85,7 +101,8 @@ const Dashboard: React.FC = () => {
 
   const fetchCategories = async () => {
     try {
-      const response = await axios.get(`${API_URL}/api/categories`);
+      // Active splits may still be in categories retired since
+      const response = await axios.get(`${API_URL}/api/categories?include_retired=true`);
       setCategories(response.data || []);
     } catch (error) {
       console.error('Error fetching categories:', error);
@@ -99,8 +116,21 @@ const Dashboard: React.FC = () => {
     } catch (error) {
       console.error('Error fetching totals:', error);
     }
+    // Spending changed, so the current month's budgets may have too
+    fetchBudgetReport();
+  };
+
+  const fetchBudgetReport = async () => {
+    try {
+      const response = await axios.get(`${API_URL}/api/budgets/report`);
+      setBudgetReport(response.data);
+    } catch (error) {
+      console.error('Error fetching budget report:', error);
+    }
   };
 
+  const overBudgetLines = (budgetReport?.lines || []).filter(line => line.status === 'over');
+
   // Flatten nested category tree into a flat list for lookups
   const flatCategories = React.useMemo(() => {
     const flat: Category[] = [];
@@ -119,27 +149,42 @@ const Dashboard: React.FC = () => {
   // Otherwise, aggregate by top-level category (subcategory transactions roll up to parent).
   const getPieChartData = (personName: string, drillDownCategoryId?: string | null) => {
     const personTransactions = transactions.filter(t =>
-      (t.assigned_to || []).includes(personName)
+      getTransactionPeople(t).includes(personName)
     );
 
     const categoryTotals: { [key: string]: number } = {};
 
     personTransactions.forEach(transaction => {
-      const assignedCount = transaction.assigned_to ? transaction.assigned_to.length : 1;
+      const assignedPeople = transaction.assigned_to || [];
       const txSign = transaction.amount < 0 ? -1 : 1;
 
       const categoryAllocations = (transaction.splits && transaction.splits.length > 0)
         ? transaction.splits.map(split => ({
             categoryId: split.category_id,
             amount: txSign * Number(split.amount || 0),
+            share: getSplitPersonShare(transaction, split, personName, assignedPeople),
           }))
         : [];
 
       categoryAllocations.forEach(allocation => {
+        // Splits assigned to other people leave nothing for this person
+        if (allocation.share === 0) {
+          return;
+        }
         const txCategory = allocation.categoryId
           ? flatCategories.find(c => c.id === allocation.categoryId)
           : undefined;
-        const amountPerPerson = allocation.amount / assignedCount;
+        const amountPerPerson = allocation.amount * allocation.share;
+
+        // Categories excluded from totals are left out, as in the person totals
+        if (txCategory) {
+          const parent = txCategory.parent_id
+            ? flatCategories.find(c => c.id === txCategory.parent_id)
+            : undefined;
+          if (txCategory.exclude_from_totals || parent?.exclude_from_totals) {
+            return;
+          }
+        }
 
         if (drillDownCategoryId) {
           // Drill-down: only include allocations in this top-level category or its subcategories
@@ -232,6 +277,109 @@ const Dashboard: React.FC = () => {
     }
   };
 
+  const openShareModal = (transaction: Transaction) => {
+    const rows: ShareRow[] = (transaction.assigned_to || []).map(name => {
+      const person = people.find(p => p.name === name);
+      const share = (transaction.shares || []).find(s => s.person_name === name);
+      if (share && share.fixed_amount !== null) {
+        return { person_id: person?.id || '', person_name: name, mode: 'fixed', value: share.fixed_amount };
+      }
+      return { person_id: person?.id || '', person_name: name, mode: 'weight', value: share?.weight ?? 1 };
+    });
+
+    setShareTransaction(transaction);
+    setShareRows(rows);
+    setShareModalOpen(true);
+  };
+
+  const closeShareModal = () => {
+    setShareModalOpen(false);
+    setShareTransaction(null);
+    setShareRows([]);
+  };
+
+  const updateShareRow = (index: number, patch: Partial<ShareRow>) => {
+    setShareRows(prev => prev.map((row, i) => (i === index ? { ...row, ...patch } : row)));
+  };
+
+  // getSharedAmount mirrors the server: fixed amounts are taken from the splits
+  // without people of their own that count in totals
+  const getSharedAmount = (transaction: Transaction): number =>
+    (transaction.splits || [])
+      .filter(split => !split.assigned_to || split.assigned_to.length === 0)
+      .filter(split => {
+        const category = flatCategories.find(c => c.id === split.category_id);
+        const parent = category?.parent_id
+          ? flatCategories.find(c => c.id === category.parent_id)
+          : undefined;
+        return !category?.exclude_from_totals && !parent?.exclude_from_totals;
+      })
+      .reduce((sum, split) => sum + Number(split.amount || 0), 0);
+
+  // getShareAmounts mirrors the server: fixed amounts first, then the remainder by weight
+  const getShareAmounts = (): number[] => {
+    const total = shareTransaction ? getSharedAmount(shareTransaction) : 0;
+    const fixedTotal = shareRows.filter(row => row.mode === 'fixed').reduce((sum, row) => sum + row.value, 0);
+    const weightTotal = shareRows.filter(row => row.mode === 'weight').reduce((sum, row) => sum + row.value, 0);
+    const remainder = Math.max(0, total - fixedTotal);
+    return shareRows.map(row => {
+      if (row.mode === 'fixed') return row.value;
+      return weightTotal > 0 ? remainder * row.value / weightTotal : 0;
+    });
+  };
+
+  const getShareValidationError = (): string | null => {
+    if (!shareTransaction) return 'No transaction selected';
+
+    for (const row of shareRows) {
+      if (row.mode === 'weight' && !(row.value >= 0.0001)) return 'Each weight must be at least 0.0001';
+      if (row.mode === 'fixed' && row.value < 0) return 'Fixed amounts cannot be negative';
+    }
+
+    const total = getSharedAmount(shareTransaction);
+    const fixedTotal = shareRows.filter(row => row.mode === 'fixed').reduce((sum, row) => sum + row.value, 0);
+    if (fixedTotal - total > 0.005) {
+      return `Fixed amounts cannot exceed $${total.toFixed(2)}`;
+    }
+    if (shareRows.every(row => row.mode === 'fixed') && Math.abs(fixedTotal - total) > 0.005) {
+      return `Fixed amounts must add up to $${total.toFixed(2)} when everyone has one`;
+    }
+
+    return null;
+  };
+
+  const saveShares = async () => {
+    if (!shareTransaction) return;
+
+    const validationError = getShareValidationError();
+    if (validationError) {
+      message.error(validationError);
+      return;
+    }
+
+    try {
+      setShareSaving(true);
+      await axios.put(`${API_URL}/api/transactions/${shareTransaction.id}/assign`, {
+        assigned_to: shareRows.map(row => row.person_id),
+        shares: shareRows.map(row => (
+          row.mode === 'fixed'
+            ? { person_id: row.person_id, fixed_amount: Number(row.value.toFixed(2)) }
+            : { person_id: row.person_id, weight: row.value }
+        )),
+      });
+
+      message.success('Transaction shares updated');
+      closeShareModal();
+      fetchTransactions();
+      fetchTotals();
+    } catch (error: any) {
+      console.error('Error saving transaction shares:', error);
+      message.error(error.response?.data?.error || 'Error saving transaction shares');
+    } finally {
+      setShareSaving(false);
+    }
+  };
+
   const clearAllTransactions = async () => {
     if (transactions.length === 0) {
       message.warning('No transactions to clear');
@@ -316,6 +464,7 @@ const Dashboard: React.FC = () => {
         amount: Number(row.amount || 0),
         category_id: row.category_id || '',
         notes: row.notes || '',
+        assigned_to: row.assigned_to || null,
       }));
 
       setSplitTransaction(transaction);
@@ -327,8 +476,16 @@ const Dashboard: React.FC = () => {
     }
   };
 
+  // Split people are shown by name but saved by ID, as for the transaction
+  const getSplitAssigneeIDs = (names?: string[] | null): string[] | undefined => {
+    if (!names || names.length === 0) return undefined;
+    return names
+      .map(name => people.find(p => p.name === name)?.id)
+      .filter((id): id is string => Boolean(id));
+  };
+
   const addSplitRow = () => {
-    const fallbackCategory = flatCategories[0]?.id || '';
+    const fallbackCategory = flatCategories.find(c => !c.retired_at)?.id || '';
     setSplitRows(prev => {
       const expectedTotal = Math.abs(splitTransaction?.amount || 0);
       const currentTotal = prev.reduce((sum, row) => sum + Number(row.amount || 0), 0);
@@ -380,6 +537,7 @@ const Dashboard: React.FC = () => {
           amount: Number(Number(row.amount || 0).toFixed(2)),
           category_id: row.category_id,
           notes: row.notes || undefined,
+          assigned_to: getSplitAssigneeIDs(row.assigned_to),
         })),
       });
 
@@ -410,6 +568,7 @@ const Dashboard: React.FC = () => {
             amount: Number(amount.toFixed(2)),
             category_id: categoryId,
             notes: existing?.notes || undefined,
+            assigned_to: getSplitAssigneeIDs(existing?.assigned_to),
           },
         ],
       });
@@ -423,7 +582,7 @@ const Dashboard: React.FC = () => {
 
   const uploadProps: UploadProps = {
     name: 'file',
-    accept: '.csv',
+    accept: '.csv,.ofx,.qfx',
     beforeUpload: handleFileUpload,
     showUploadList: false,
     multiple: false,
@@ -433,7 +592,7 @@ const Dashboard: React.FC = () => {
   const filteredTransactions = assignedFilter === 'all'
     ? transactions
     : transactions.filter(transaction =>
-        (transaction.assigned_to || []).includes(assignedFilter)
+        getTransactionPeople(transaction).includes(assignedFilter)
       );
 
   const getCategoryNameByID = (categoryID: string): string => {
@@ -536,18 +695,18 @@ const Dashboard: React.FC = () => {
                   const subs = category.subcategories || [];
                   if (subs.length > 0) {
                     return [
-                      <Option key={category.id} value={category.id} label={category.name}>
+                      <Option key={category.id} value={category.id} label={category.name} disabled={Boolean(category.retired_at)}>
                         <span style={{ color: category.color, fontWeight: 600, fontSize: 12 }}>{category.name}</span>
                       </Option>,
                       ...subs.map((sub) => (
-                        <Option key={sub.id} value={sub.id} label={`${category.name} / ${sub.name}`}>
+                        <Option key={sub.id} value={sub.id} label={`${category.name} / ${sub.name}`} disabled={Boolean(sub.retired_at)}>
                           <span style={{ color: category.color, paddingLeft: 8, fontSize: 12 }}>↳ {sub.name}</span>
                         </Option>
                       )),
                     ];
                   }
                   return (
-                    <Option key={category.id} value={category.id} label={category.name}>
+                    <Option key={category.id} value={category.id} label={category.name} disabled={Boolean(category.retired_at)}>
                       <span style={{ color: category.color, fontSize: 12 }}>
                         {category.name}
                       </span>
@@ -575,6 +734,7 @@ const Dashboard: React.FC = () => {
                     {getCategoryNameByID(split.category_id)}
                   </span>
                   : ${Number(split.amount || 0).toFixed(2)}
+                  {split.assigned_to && ` (${split.assigned_to.join(', ')})`}
                 </div>
               ))}
             </div>
@@ -653,6 +813,22 @@ const Dashboard: React.FC = () => {
               </Checkbox>
             );
           })}
+          {(record.assigned_to || []).length > 1 && (
+            <Button
+              type="text"
+              size="small"
+              icon={<PercentageOutlined />}
+              onClick={() => openShareModal(record)}
+              title="Edit shares"
+            />
+          )}
+          {(record.shares || []).some(share => share.weight !== null || share.fixed_amount !== null) && (
+            <div style={{ fontSize: 11, color: '#666', width: '100%' }}>
+              {(record.shares || [])
+                .map(share => `${share.person_name} ${Math.round(share.share * 100)}%`)
+                .join(' · ')}
+            </div>
+          )}
         </div>
       ),
       width: 200,
@@ -679,6 +855,19 @@ const Dashboard: React.FC = () => {
   return (
     <div style={{ padding: '24px', background: '#f0f2f5', minHeight: 'calc(100vh - 64px)' }}>
       <div style={{ maxWidth: 1200, margin: '0 auto' }}>
+        {/* Budget Alert */}
+        {overBudgetLines.length > 0 && (
+          <Alert
+            type="warning"
+            showIcon
+            message={`Over budget this month: ${overBudgetLines
+              .map(line => `${line.category_name}${line.person_name ? ` (${line.person_name})` : ''} by $${(-line.remaining).toFixed(2)}`)
+              .join(', ')}`}
+            action={<Link to="/budgets">View budgets</Link>}
+            style={{ marginBottom: 24 }}
+          />
+        )}
+
         {/* Totals Section */}
         <Card
           title={
@@ -1022,7 +1211,7 @@ const Dashboard: React.FC = () => {
 
           {splitRows.map((row, index) => (
             <Row key={index} gutter={8} style={{ marginBottom: 8 }} align="middle">
-              <Col span={9}>
+              <Col span={7}>
                 <Select
                   value={row.category_id || undefined}
                   placeholder="Category"
@@ -1035,18 +1224,18 @@ const Dashboard: React.FC = () => {
                     const subs = category.subcategories || [];
                     if (subs.length > 0) {
                       return [
-                        <Option key={category.id} value={category.id} label={category.name}>
+                        <Option key={category.id} value={category.id} label={category.name} disabled={Boolean(category.retired_at)}>
                           <span style={{ color: category.color, fontWeight: 600 }}>{category.name}</span>
                         </Option>,
                         ...subs.map((sub) => (
-                          <Option key={sub.id} value={sub.id} label={`${category.name} / ${sub.name}`}>
+                          <Option key={sub.id} value={sub.id} label={`${category.name} / ${sub.name}`} disabled={Boolean(sub.retired_at)}>
                             <span style={{ color: category.color, paddingLeft: 8 }}>↳ {sub.name}</span>
                           </Option>
                         )),
                       ];
                     }
                     return (
-                      <Option key={category.id} value={category.id} label={category.name}>
+                      <Option key={category.id} value={category.id} label={category.name} disabled={Boolean(category.retired_at)}>
                         <span style={{ color: category.color }}>{category.name}</span>
                       </Option>
                     );
@@ -1054,7 +1243,7 @@ const Dashboard: React.FC = () => {
                 </Select>
               </Col>
 
-              <Col span={5}>
+              <Col span={4}>
                 <InputNumber
                   min={0}
                   step={0.01}
@@ -1065,7 +1254,23 @@ const Dashboard: React.FC = () => {
                 />
               </Col>
 
-              <Col span={8}>
+              <Col span={6}>
+                <Select
+                  mode="multiple"
+                  value={row.assigned_to || []}
+                  placeholder="Transaction's people"
+                  style={{ width: '100%' }}
+                  onChange={(value: string[]) => updateSplitRow(index, { assigned_to: value.length > 0 ? value : null })}
+                >
+                  {people.map((person) => (
+                    <Option key={person.id} value={person.name}>
+                      {person.name}
+                    </Option>
+                  ))}
+                </Select>
+              </Col>
+
+              <Col span={5}>
                 <Input
                   placeholder="Notes (optional)"
                   value={row.notes || ''}
@@ -1097,6 +1302,56 @@ const Dashboard: React.FC = () => {
               : `Split total: $${splitRows.reduce((sum, row) => sum + Number(row.amount || 0), 0).toFixed(2)}`}
           </div>
         </Modal>
+
+        <Modal
+          title={shareTransaction ? `Shares: ${shareTransaction.description}` : 'Transaction Shares'}
+          open={shareModalOpen}
+          onCancel={closeShareModal}
+          onOk={saveShares}
+          okText="Save Shares"
+          confirmLoading={shareSaving}
+          width={600}
+        >
+          <div style={{ marginBottom: 12, color: '#666' }}>
+            Transaction total: <strong>${Math.abs(shareTransaction?.amount || 0).toFixed(2)}</strong>.
+            Fixed amounts are taken first and the remainder is divided by weight.
+          </div>
+
+          {shareRows.map((row, index) => (
+            <Row key={row.person_id} gutter={8} style={{ marginBottom: 8 }} align="middle">
+              <Col span={7}>{row.person_name}</Col>
+              <Col span={7}>
+                <Select
+                  value={row.mode}
+                  style={{ width: '100%' }}
+                  onChange={(mode) => updateShareRow(index, { mode, value: mode === 'weight' ? 1 : 0 })}
+                >
+                  <Option value="weight">Weight</Option>
+                  <Option value="fixed">Fixed amount</Option>
+                </Select>
+              </Col>
+              <Col span={5}>
+                <InputNumber
+                  min={0}
+                  step={row.mode === 'fixed' ? 0.01 : 1}
+                  precision={row.mode === 'fixed' ? 2 : undefined}
+                  style={{ width: '100%' }}
+                  value={row.value}
+                  onChange={(value) => updateShareRow(index, { value: Number(value || 0) })}
+                />
+              </Col>
+              <Col span={5} style={{ textAlign: 'right', color: '#666' }}>
+                ${getShareAmounts()[index].toFixed(2)}
+              </Col>
+            </Row>
+          ))}
+
+          {getShareValidationError() && (
+            <div style={{ marginTop: 12, color: '#cf1322' }}>
+              {getShareValidationError()}
+            </div>
+          )}
+        </Modal>
       </div>
     </div>
   );
@@ -14,6 +14,8 @@ import {
   ColorPicker,
   Popconfirm,
   Select,
+  Tooltip,
+  Checkbox,
 } from 'antd';
 import {
   EditOutlined,
@@ -22,8 +24,10 @@ import {
   DeleteOutlined,
   UserAddOutlined,
   OrderedListOutlined,
+  WarningOutlined,
+  BulbOutlined,
 } from '@ant-design/icons';
-import { Category, Rule } from './types';
+import { Category, Rule, RuleSuggestion } from './types';
 
 interface Person {
   id: string;
@@ -40,6 +44,8 @@ const Settings: React.FC = () => {
   const [people, setPeople] = useState<Person[]>([]);
   const [categories, setCategories] = useState<Category[]>([]);
   const [rules, setRules] = useState<Rule[]>([]);
+  const [ruleSuggestions, setRuleSuggestions] = useState<RuleSuggestion[]>([]);
+  const [suggestionCategories, setSuggestionCategories] = useState<Record<string, string>>({});
   const [ruleModalVisible, setRuleModalVisible] = useState(false);
   const [editingRule, setEditingRule] = useState<Rule | null>(null);
   const [ruleForm] = Form.useForm();
@@ -54,6 +60,7 @@ const Settings: React.FC = () => {
     fetchPeople();
     fetchCategories();
     fetchRules();
+    fetchRuleSuggestions();
   }, []);
 
   const fetchPeople = async () => {
@@ -120,12 +127,56 @@ const Settings: React.FC = () => {
     }
   };
 
+  const fetchRuleSuggestions = async () => {
+    try {
+      const response = await axios.get(`${API_URL}/api/rules/suggestions`);
+      setRuleSuggestions(response.data || []);
+    } catch (error) {
+      console.error('Error fetching rule suggestions:', error);
+    }
+  };
+
+  const handleAcceptSuggestion = async (suggestion: RuleSuggestion) => {
+    const categoryId = suggestionCategories[suggestion.match_value] ?? suggestion.category_id;
+    if (!categoryId) {
+      message.warning('Please select a category');
+      return;
+    }
+    try {
+      const response = await axios.post(`${API_URL}/api/rules/suggestions/accept`, {
+        match_value: suggestion.match_value,
+        category_id: categoryId,
+        apply: true,
+      });
+      message.success(`Rule created and applied to ${response.data.applied} transactions`);
+      (response.data.rule.warnings || []).forEach((warning: { message: string }) => message.warning(warning.message, 6));
+      fetchRules();
+      fetchRuleSuggestions();
+    } catch (error) {
+      console.error('Error accepting rule suggestion:', error);
+      message.error('Error accepting rule suggestion');
+    }
+  };
+
   const openRuleModal = (rule?: Rule) => {
     setEditingRule(rule || null);
     setRuleModalVisible(true);
     if (rule) {
       ruleForm.setFieldsValue({
         match_value: rule.match_value,
+        match_type: rule.match_type,
+        match_field: rule.match_field,
+        min_amount: rule.min_amount ?? undefined,
+        max_amount: rule.max_amount ?? undefined,
+        direction: rule.direction ?? undefined,
+        card_number: rule.card_number ?? undefined,
+        start_date: rule.start_date ?? undefined,
+        end_date: rule.end_date ?? undefined,
+        assign_to: rule.assign_to,
+        splits: rule.splits.map((split) => ({
+          category_id: split.category_id,
+          percentage: split.percentage,
+        })),
         category_id: rule.category_id,
         priority: rule.priority,
       });
@@ -141,22 +192,37 @@ const Settings: React.FC = () => {
   };
 
   const handleRuleSubmit = async (values: any) => {
+    const optionalNumber = (value: any) =>
+      value === undefined || value === null || value === '' ? null : Number(value);
+    const optionalString = (value: any) => (value ? value : null);
+    const payload = {
+      match_value: values.match_value,
+      match_type: values.match_type,
+      match_field: values.match_field,
+      min_amount: optionalNumber(values.min_amount),
+      max_amount: optionalNumber(values.max_amount),
+      direction: optionalString(values.direction),
+      card_number: optionalString(values.card_number),
+      start_date: optionalString(values.start_date),
+      end_date: optionalString(values.end_date),
+      category_id: values.category_id,
+      assign_to: values.assign_to || [],
+      splits: (values.splits || []).map((split: any) => ({
+        category_id: split.category_id,
+        percentage: Number(split.percentage),
+      })),
+      priority: Number(values.priority),
+    };
     try {
+      let saved: Rule;
       if (editingRule) {
-        await axios.put(`${API_URL}/api/rules/${editingRule.id}`, {
-          match_value: values.match_value,
-          category_id: values.category_id,
-          priority: Number(values.priority),
-        });
+        saved = (await axios.put(`${API_URL}/api/rules/${editingRule.id}`, payload)).data;
         message.success('Rule updated successfully!');
       } else {
-        await axios.post(`${API_URL}/api/rules`, {
-          match_value: values.match_value,
-          category_id: values.category_id,
-          priority: Number(values.priority),
-        });
+        saved = (await axios.post(`${API_URL}/api/rules`, payload)).data;
         message.success('Rule created successfully!');
       }
+      (saved.warnings || []).forEach((warning) => message.warning(warning.message, 6));
       fetchRules();
       closeRuleModal();
     } catch (error) {
@@ -202,6 +268,7 @@ const Settings: React.FC = () => {
         name: category.name,
         description: category.description || '',
         color: category.color || '#1890ff',
+        exclude_from_totals: !!category.exclude_from_totals,
       });
     } else {
       categoryForm.resetFields();
@@ -217,6 +284,7 @@ const Settings: React.FC = () => {
       categoryForm.setFieldsValue({
         name: subToEdit.name,
         description: subToEdit.description || '',
+        exclude_from_totals: !!subToEdit.exclude_from_totals,
       });
     } else {
       categoryForm.resetFields();
@@ -237,6 +305,7 @@ const Settings: React.FC = () => {
       const categoryData: Record<string, any> = {
         name: values.name,
         description: values.description || '',
+        exclude_from_totals: !!values.exclude_from_totals,
       };
 
       if (!isSubcategory) {
@@ -557,17 +626,34 @@ const Settings: React.FC = () => {
             <thead>
               <tr style={{ borderBottom: '1px solid #f0f0f0' }}>
                 <th style={{ textAlign: 'left', padding: '8px', fontWeight: 600 }}>Match Value</th>
+                <th style={{ textAlign: 'left', padding: '8px', fontWeight: 600 }}>Match Type</th>
                 <th style={{ textAlign: 'left', padding: '8px', fontWeight: 600 }}>Category</th>
                 <th style={{ textAlign: 'center', padding: '8px', fontWeight: 600 }}>Priority</th>
+                <th style={{ textAlign: 'center', padding: '8px', fontWeight: 600 }}>Hits</th>
+                <th style={{ textAlign: 'center', padding: '8px', fontWeight: 600 }}>Overrides</th>
                 <th style={{ textAlign: 'right', padding: '8px', fontWeight: 600 }}>Actions</th>
               </tr>
             </thead>
             <tbody>
               {rules.map((rule) => (
                 <tr key={rule.id} style={{ borderBottom: '1px solid #f0f0f0' }}>
-                  <td style={{ padding: '8px', fontFamily: 'monospace' }}>{rule.match_value}</td>
+                  <td style={{ padding: '8px', fontFamily: 'monospace' }}>
+                    {rule.match_value}
+                    {rule.warnings && rule.warnings.length > 0 && (
+                      <Tooltip title={rule.warnings.map((warning) => warning.message).join('\n')}>
+                        <WarningOutlined style={{ color: '#faad14', marginLeft: 8 }} />
+                      </Tooltip>
+                    )}
+                  </td>
+                  <td style={{ padding: '8px' }}>{rule.match_type}</td>
                   <td style={{ padding: '8px' }}>{rule.category_name}</td>
                   <td style={{ padding: '8px', textAlign: 'center' }}>{rule.priority}</td>
+                  <td style={{ padding: '8px', textAlign: 'center' }}>
+                    <span title={rule.last_matched_at ? `Last matched ${new Date(rule.last_matched_at).toLocaleDateString()}` : 'Never matched'}>
+                      {rule.hit_count}
+                    </span>
+                  </td>
+                  <td style={{ padding: '8px', textAlign: 'center' }}>{rule.override_count}</td>
                   <td style={{ padding: '8px', textAlign: 'right' }}>
                     <Space size={4}>
                       <Button
@@ -599,6 +685,73 @@ const Settings: React.FC = () => {
         )}
       </Card>
 
+      {/* Rule Suggestions Section */}
+      {ruleSuggestions.length > 0 && (
+        <Card
+          title={
+            <span>
+              <BulbOutlined style={{ marginRight: 8 }} />
+              Suggested Rules ({ruleSuggestions.length})
+            </span>
+          }
+          style={{ marginBottom: 24 }}
+        >
+          <Text type="secondary">
+            Transactions in Other that share a merchant. Accepting a suggestion creates the rule and applies it to them.
+          </Text>
+          <table style={{ width: '100%', borderCollapse: 'collapse', marginTop: 12 }}>
+            <thead>
+              <tr style={{ borderBottom: '1px solid #f0f0f0' }}>
+                <th style={{ textAlign: 'left', padding: '8px', fontWeight: 600 }}>Match Value</th>
+                <th style={{ textAlign: 'left', padding: '8px', fontWeight: 600 }}>Examples</th>
+                <th style={{ textAlign: 'center', padding: '8px', fontWeight: 600 }}>Transactions</th>
+                <th style={{ textAlign: 'left', padding: '8px', fontWeight: 600 }}>Category</th>
+                <th style={{ textAlign: 'right', padding: '8px', fontWeight: 600 }}>Actions</th>
+              </tr>
+            </thead>
+            <tbody>
+              {ruleSuggestions.map((suggestion) => (
+                <tr key={suggestion.match_value} style={{ borderBottom: '1px solid #f0f0f0' }}>
+                  <td style={{ padding: '8px', fontFamily: 'monospace' }}>{suggestion.match_value}</td>
+                  <td style={{ padding: '8px' }}>
+                    <Text type="secondary">{suggestion.examples.join(', ')}</Text>
+                  </td>
+                  <td style={{ padding: '8px', textAlign: 'center' }}>{suggestion.transaction_count}</td>
+                  <td style={{ padding: '8px' }}>
+                    <Select
+                      style={{ width: 200 }}
+                      placeholder="Select a category"
+                      showSearch
+                      optionFilterProp="label"
+                      value={suggestionCategories[suggestion.match_value] ?? suggestion.category_id ?? undefined}
+                      onChange={(value: string) =>
+                        setSuggestionCategories({ ...suggestionCategories, [suggestion.match_value]: value })
+                      }
+                    >
+                      {flatCategories.map((cat) => (
+                        <Select.Option key={cat.id} value={cat.id} label={cat.name}>
+                          {cat.parent_id ? `  ↳ ${cat.name}` : cat.name}
+                        </Select.Option>
+                      ))}
+                    </Select>
+                    {suggestion.confidence !== null && suggestionCategories[suggestion.match_value] === undefined && (
+                      <Text type="secondary" style={{ marginLeft: 8 }}>
+                        {Math.round(suggestion.confidence * 100)}%
+                      </Text>
+                    )}
+                  </td>
+                  <td style={{ padding: '8px', textAlign: 'right' }}>
+                    <Button type="primary" size="small" onClick={() => handleAcceptSuggestion(suggestion)}>
+                      Accept
+                    </Button>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </Card>
+      )}
+
       {/* Rule Modal */}
       <Modal
         title={editingRule ? 'Edit Rule' : 'Add Rule'}
@@ -611,8 +764,18 @@ const Settings: React.FC = () => {
           form={ruleForm}
           layout="vertical"
           onFinish={handleRuleSubmit}
-          initialValues={{ priority: 0 }}
+          initialValues={{ priority: 0, match_type: 'contains', match_field: 'any' }}
         >
+          <Form.Item name="match_type" label="Match Type">
+            <Select>
+              <Select.Option value="contains">Contains</Select.Option>
+              <Select.Option value="exact">Exact</Select.Option>
+              <Select.Option value="prefix">Starts with</Select.Option>
+              <Select.Option value="suffix">Ends with</Select.Option>
+              <Select.Option value="regex">Regular expression</Select.Option>
+            </Select>
+          </Form.Item>
+
           <Form.Item
             name="match_value"
             label="Match Value"
@@ -621,6 +784,56 @@ const Settings: React.FC = () => {
             <Input placeholder="e.g. Trader Joe, Whole Foods, Dining" />
           </Form.Item>
 
+          <Form.Item name="match_field" label="Match Against">
+            <Select>
+              <Select.Option value="any">Description or CSV category</Select.Option>
+              <Select.Option value="description">Description</Select.Option>
+              <Select.Option value="category">CSV category</Select.Option>
+            </Select>
+          </Form.Item>
+
+          <Row gutter={12}>
+            <Col span={12}>
+              <Form.Item name="min_amount" label="Min Amount">
+                <Input type="number" min={0} step="0.01" placeholder="Any" />
+              </Form.Item>
+            </Col>
+            <Col span={12}>
+              <Form.Item name="max_amount" label="Max Amount">
+                <Input type="number" min={0} step="0.01" placeholder="Any" />
+              </Form.Item>
+            </Col>
+          </Row>
+
+          <Row gutter={12}>
+            <Col span={12}>
+              <Form.Item name="direction" label="Direction">
+                <Select allowClear placeholder="Debits and credits">
+                  <Select.Option value="debit">Debits</Select.Option>
+                  <Select.Option value="credit">Credits</Select.Option>
+                </Select>
+              </Form.Item>
+            </Col>
+            <Col span={12}>
+              <Form.Item name="card_number" label="Card Number (ends with)">
+                <Input placeholder="e.g. 1234" />
+              </Form.Item>
+            </Col>
+          </Row>
+
+          <Row gutter={12}>
+            <Col span={12}>
+              <Form.Item name="start_date" label="From Date">
+                <Input type="date" />
+              </Form.Item>
+            </Col>
+            <Col span={12}>
+              <Form.Item name="end_date" label="To Date">
+                <Input type="date" />
+              </Form.Item>
+            </Col>
+          </Row>
+
           <Form.Item
             name="category_id"
             label="Category"
@@ -635,6 +848,72 @@ const Settings: React.FC = () => {
             </Select>
           </Form.Item>
 
+          <Form.Item name="assign_to" label="Assign To">
+            <Select mode="multiple" allowClear placeholder="Leave unassigned">
+              {people.map((person) => (
+                <Select.Option key={person.id} value={person.id}>
+                  {person.name}
+                </Select.Option>
+              ))}
+            </Select>
+          </Form.Item>
+
+          <Form.List name="splits">
+            {(fields, { add, remove }) => (
+              <>
+                <Text strong>Split Template</Text>
+                <div style={{ marginBottom: 8 }}>
+                  <Text type="secondary">
+                    Optional. Splits matched transactions between categories; percentages must add
+                    up to 100.
+                  </Text>
+                </div>
+                {fields.map(({ key, name }) => (
+                  <Row key={key} gutter={8} align="middle">
+                    <Col span={14}>
+                      <Form.Item
+                        name={[name, 'category_id']}
+                        rules={[{ required: true, message: 'Select a category' }]}
+                      >
+                        <Select placeholder="Category" showSearch optionFilterProp="label">
+                          {flatCategories.map((cat) => (
+                            <Select.Option key={cat.id} value={cat.id} label={cat.name}>
+                              {cat.name}
+                            </Select.Option>
+                          ))}
+                        </Select>
+                      </Form.Item>
+                    </Col>
+                    <Col span={7}>
+                      <Form.Item
+                        name={[name, 'percentage']}
+                        rules={[{ required: true, message: 'Enter a percentage' }]}
+                      >
+                        <Input type="number" min={0} max={100} step="0.01" suffix="%" />
+                      </Form.Item>
+                    </Col>
+                    <Col span={3}>
+                      <Form.Item>
+                        <Button
+                          type="text"
+                          danger
+                          icon={<DeleteOutlined />}
+                          size="small"
+                          onClick={() => remove(name)}
+                        />
+                      </Form.Item>
+                    </Col>
+                  </Row>
+                ))}
+                <Form.Item>
+                  <Button type="dashed" onClick={() => add()} icon={<PlusOutlined />} block>
+                    Add Split
+                  </Button>
+                </Form.Item>
+              </>
+            )}
+          </Form.List>
+
           <Form.Item
             name="priority"
             label="Priority (lower = higher priority)"
@@ -717,6 +996,16 @@ const Settings: React.FC = () => {
             </Text>
           )}
 
+          <Form.Item
+            name="exclude_from_totals"
+            valuePropName="checked"
+            extra={isSubcategoryModal
+              ? 'Also excluded when the parent category is.'
+              : 'Also excludes its subcategories.'}
+          >
+            <Checkbox>Exclude from totals and spending charts</Checkbox>
+          </Form.Item>
+
           <Form.Item style={{ marginBottom: 0, textAlign: 'right' }}>
             <Space>
               <Button onClick={closeCategoryModal}>
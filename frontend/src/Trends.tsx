@@ -15,7 +15,7 @@ import {
 } from '@ant-design/icons';
 import { Line, Pie } from '@ant-design/charts';
 import { Archive, Transaction, Person, Category, PersonTotal } from './types';
-import { getCategoryColor, generateColorVariants } from './utils';
+import { getCategoryColor, generateColorVariants, getSplitPersonShare } from './utils';
 
 interface CategorySpendingData {
   archive: string;
@@ -78,7 +78,7 @@ const Trends: React.FC = () => {
       const [archivesRes, peopleRes, categoriesRes] = await Promise.all([
         axios.get(`${API_URL}/api/archives`),
         axios.get(`${API_URL}/api/people`),
-        axios.get(`${API_URL}/api/categories`),
+        axios.get(`${API_URL}/api/categories?include_retired=true`),
       ]);
 
       setArchives(archivesRes.data || []);
@@ -103,16 +103,21 @@ const Trends: React.FC = () => {
     flattenCats(categories);
 
     // Helper: resolve the effective (top-level) category name for a transaction.
-    // Subcategories are rolled up to their parent so charts group consistently.
-    const resolveCategory = (categoryId?: string | null): { name: string; topLevelName: string } => {
-      if (!categoryId) return { name: 'Uncategorized', topLevelName: 'Uncategorized' };
+    // Subcategories are rolled up to their parent so charts group consistently,
+    // and are excluded from totals when their parent is.
+    const resolveCategory = (categoryId?: string | null): { name: string; topLevelName: string; excluded: boolean } => {
+      if (!categoryId) return { name: 'Uncategorized', topLevelName: 'Uncategorized', excluded: false };
       const cat = allCategories.find(c => c.id === categoryId);
-      if (!cat) return { name: 'Uncategorized', topLevelName: 'Uncategorized' };
+      if (!cat) return { name: 'Uncategorized', topLevelName: 'Uncategorized', excluded: false };
       if (cat.parent_id) {
         const parent = allCategories.find(c => c.id === cat.parent_id);
-        return { name: cat.name, topLevelName: parent?.name || cat.name };
+        return {
+          name: cat.name,
+          topLevelName: parent?.name || cat.name,
+          excluded: !!(cat.exclude_from_totals || parent?.exclude_from_totals),
+        };
       }
-      return { name: cat.name, topLevelName: cat.name };
+      return { name: cat.name, topLevelName: cat.name, excluded: !!cat.exclude_from_totals };
     };
 
     // Sort archives by date and limit to the most recent 12
@@ -139,20 +144,20 @@ const Trends: React.FC = () => {
             ? transaction.assigned_to
             : people.map(p => p.name); // If no assignment, assign to all people
 
-          const numPeople = assignedPeople.length;
           const txSign = transaction.amount < 0 ? -1 : 1;
           const allocations = (transaction.splits && transaction.splits.length > 0)
             ? transaction.splits.map(split => ({
+                split,
                 categoryId: split.category_id,
                 amount: txSign * Number(split.amount || 0),
               }))
             : [];
 
           for (const allocation of allocations) {
-            const { name: categoryName, topLevelName } = resolveCategory(allocation.categoryId);
+            const { name: categoryName, topLevelName, excluded } = resolveCategory(allocation.categoryId);
 
-            // Skip Reimbursable category
-            if (topLevelName === 'Reimbursable') {
+            // Skip categories excluded from totals
+            if (excluded) {
               continue;
             }
 
@@ -163,13 +168,13 @@ const Trends: React.FC = () => {
               (archiveCategoryMap.get(topLevelName) || 0) + allocation.amount
             );
 
-            const amountPerPerson = allocation.amount / numPeople;
-
-            for (const personName of assignedPeople) {
+            // A split with its own people overrides the transaction's
+            for (const personName of allocation.split.assigned_to || assignedPeople) {
               const person = people.find(p => p.name === personName);
               if (!person) {
                 continue;
               }
+              const amountPerPerson = allocation.amount * getSplitPersonShare(transaction, allocation.split, personName, assignedPeople);
 
               // Add to category spending data (store subcategory name so pie chart can drill down)
               categorySpending.push({
@@ -374,7 +379,7 @@ const Trends: React.FC = () => {
         <Col>
           <span style={{ color: '#8c8c8c', fontSize: 13 }}>
             <InfoCircleOutlined style={{ marginRight: 6 }} />
-            Transactions that are categorized as "Reimbursable" are excluded from all charts.
+            Categories excluded from totals in Settings, and their subcategories, are excluded from all charts.
           </span>
         </Col>
       </Row>
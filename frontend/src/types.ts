@@ -4,6 +4,8 @@ export interface Category {
   description?: string;
   color?: string;
   parent_id?: string;
+  retired_at?: string;
+  exclude_from_totals?: boolean;
   subcategories?: Category[];
 }
 
@@ -26,6 +28,7 @@ export interface Transaction {
   posted_date?: string;
   card_number?: string;
   splits?: TransactionSplit[];
+  shares?: TransactionShare[];
 }
 
 export interface TransactionSplit {
@@ -34,6 +37,16 @@ export interface TransactionSplit {
   amount: number;
   category_id: string;
   notes?: string;
+  // Names of the split's own people; null when it uses the transaction's
+  assigned_to?: string[] | null;
+}
+
+export interface TransactionShare {
+  person_id: string;
+  person_name: string;
+  weight: number | null;
+  fixed_amount: number | null;
+  share: number;
 }
 
 export interface Archive {
@@ -52,12 +65,86 @@ export interface PersonTotal {
   total: number;
 }
 
+export type RuleMatchType = 'contains' | 'exact' | 'prefix' | 'suffix' | 'regex';
+export type RuleMatchField = 'any' | 'description' | 'category';
+
+export interface RuleSplit {
+  category_id: string;
+  category_name: string;
+  percentage: number;
+}
+
 export interface Rule {
   id: string;
   match_value: string;
+  match_type: RuleMatchType;
+  match_field: RuleMatchField;
+  min_amount: number | null;
+  max_amount: number | null;
+  direction: 'debit' | 'credit' | null;
+  card_number: string | null;
+  start_date: string | null;
+  end_date: string | null;
+  assign_to: string[];
+  splits: RuleSplit[];
   category_id: string;
   category_name: string;
   priority: number;
+  hit_count: number;
+  last_matched_at: string | null;
+  override_count: number;
+  warnings?: RuleWarning[];
   created_at: string;
   updated_at: string;
 }
+
+export interface RuleSuggestion {
+  match_value: string;
+  match_type: RuleMatchType;
+  match_field: RuleMatchField;
+  category_id: string | null;
+  category_name: string | null;
+  confidence: number | null;
+  transaction_count: number;
+  examples: string[];
+}
+
+export interface RuleWarning {
+  type: 'shadowed' | 'conflict';
+  rule_id: string;
+  other_rule_id: string;
+  message: string;
+}
+
+export interface Budget {
+  id: string;
+  category_id: string;
+  category_name: string;
+  person_id: string | null;
+  person_name: string | null;
+  month: string;
+  amount: number;
+  rollover: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface BudgetReportLine {
+  budget_id: string;
+  category_id: string;
+  category_name: string;
+  person_id: string | null;
+  person_name: string | null;
+  budgeted: number;
+  rolled_over: number;
+  available: number;
+  spent: number;
+  remaining: number;
+  status: 'ok' | 'over';
+}
+
+export interface BudgetReport {
+  month: string;
+  lines: BudgetReportLine[];
+  over_budget: number;
+}
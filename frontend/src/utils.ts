@@ -1,4 +1,4 @@
-import { Category } from './types';
+import { Category, Transaction, TransactionSplit } from './types';
 
 /**
  * Generate N visually distinct color variants from a base hex color
@@ -79,3 +79,44 @@ export const getCategoryColor = (categoryName: string, categories: Category[]):
 
   return colorPalette[Math.abs(hash) % colorPalette.length];
 };
+
+/**
+ * Get the fraction of a transaction that counts for a person: the share the
+ * server computed from weights or fixed amounts, or else an equal part.
+ */
+export const getPersonShare = (transaction: Transaction, personName: string, assignedCount: number): number => {
+  const share = (transaction.shares || []).find(s => s.person_name === personName);
+  return share ? share.share : 1 / assignedCount;
+};
+
+/**
+ * Get the fraction of a split that counts for a person: an equal part between
+ * the split's own people, or else the person's share of the transaction when
+ * they are among its assignedPeople.
+ */
+export const getSplitPersonShare = (
+  transaction: Transaction,
+  split: TransactionSplit,
+  personName: string,
+  assignedPeople: string[],
+): number => {
+  if (split.assigned_to) {
+    return split.assigned_to.includes(personName) ? 1 / split.assigned_to.length : 0;
+  }
+  return assignedPeople.includes(personName)
+    ? getPersonShare(transaction, personName, assignedPeople.length)
+    : 0;
+};
+
+/**
+ * Get the names of everyone a transaction or one of its splits is assigned to.
+ */
+export const getTransactionPeople = (transaction: Transaction): string[] => {
+  const names = [...(transaction.assigned_to || [])];
+  (transaction.splits || []).forEach(split => {
+    (split.assigned_to || []).forEach(name => {
+      if (!names.includes(name)) names.push(name);
+    });
+  });
+  return names;
+};